@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"app/gateway"
+	"errors"
+)
+
+// AdminController serves operational endpoints restricted to administrators
+type AdminController struct {
+	BaseController
+}
+
+// Prepare rejects callers that are not administrators
+func (c *AdminController) Prepare() {
+	c.BaseController.Prepare()
+
+	userID, ok := c.apiUserID()
+	if !ok || !isAdmin(userID) {
+		c.Ctx.Output.SetStatus(403)
+		c.JSONResponse(nil, errors.New("admin role required"))
+		c.StopRun()
+	}
+}
+
+// DriverPool lists the pooled platform connections and their usage (API)
+func (c *AdminController) DriverPool() {
+	c.JSONResponse(gateway.Drivers().Stats(), nil)
+}
+
+// Polling lists the scheduled polling jobs with their last run, error and lag (API)
+func (c *AdminController) Polling() {
+	c.JSONResponse(gateway.Polling().Status(), nil)
+}
+
+// Sinks lists the telemetry sinks with their buffered, written and dropped samples (API)
+func (c *AdminController) Sinks() {
+	c.JSONResponse(gateway.Sinks().Status(), nil)
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 
 	"github.com/beego/beego/v2/core/logs"
@@ -54,13 +55,18 @@ func (c *ApiKeyController) Generate() {
 		return
 	}
 
-	validScopes := map[string]bool{"read": true, "write": true}
+	validScopes := map[string]bool{"read": true, "write": true, controlScope: true}
 	for _, scope := range req.Scopes {
 		if !validScopes[scope] {
 			c.JSONResponse(nil, fmt.Errorf("invalid Scope: %s", scope))
 			return
 		}
 	}
+	// Keys with the control scope write to devices, which only administrators may do
+	if slices.Contains(req.Scopes, controlScope) && !isAdmin(uint(userID)) {
+		c.JSONResponse(nil, errors.New("admin role required for the control scope"))
+		return
+	}
 
 	token := uuid.New().String()
 	hash, err := bcrypt.GenerateFromPassword([]byte(token), bcrypt.DefaultCost)
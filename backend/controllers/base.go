@@ -2,8 +2,10 @@ package controllers
 
 import (
 	"app/dal"
+	"app/drivers"
 	"errors"
 	"log"
+	"slices"
 	"strings"
 	"sync"
 
@@ -68,11 +70,56 @@ func (c *BaseController) Prepare() {
 	}
 }
 
+// apiUserID returns the user authenticated by ApiAuthFilter, set as a uint by API keys and
+// as an int by JWTs
+func (c *BaseController) apiUserID() (uint, bool) {
+	switch id := c.Ctx.Input.GetData("user_id").(type) {
+	case uint:
+		return id, true
+	case int:
+		return uint(id), id > 0
+	}
+	return 0, false
+}
+
+// controlScope is the API key scope required to write to devices
+const controlScope = "control"
+
+// authorizeControl checks the caller may write to devices and returns its user ID. The user
+// must be an administrator; API keys also need the control scope, and stop working when their
+// owner is no longer an administrator.
+func (c *BaseController) authorizeControl() (uint, error) {
+	userID, ok := c.apiUserID()
+	if !ok {
+		return 0, errors.New("unauthenticated")
+	}
+	if scopes, isKey := c.Ctx.Input.GetData("scopes").([]string); isKey && !slices.Contains(scopes, controlScope) {
+		return 0, errors.New("control scope required")
+	}
+	if !isAdmin(userID) {
+		return 0, errors.New("admin role required")
+	}
+	return userID, nil
+}
+
+// isAdmin reports whether a user exists and has the admin role
+func isAdmin(userID uint) bool {
+	q := dal.Q
+	user, err := q.User.Where(q.User.ID.Eq(userID)).First()
+	return err == nil && user.Role == "admin"
+}
+
 // JSONResponse standardizes API responses
 func (c *BaseController) JSONResponse(data interface{}, err error) {
 	if err != nil {
 		log.Printf("API error: %v", err)
-		c.Data["json"] = map[string]interface{}{"error": err.Error(), "code": 400}
+		response := map[string]interface{}{"error": err.Error(), "code": 400}
+		// Schema violations also list the failing fields so forms can highlight them
+		var validationErr *drivers.ValidationError
+		if errors.As(err, &validationErr) {
+			response["fields"] = validationErr.Fields
+		}
+		c.Data["json"] = response
 		c.ServeJSON()
 		return
 	}
@@ -2,12 +2,18 @@ package controllers
 
 import (
 	"app/dal"
+	"app/gateway"
 	"app/model"
 	"errors"
+	"fmt"
 	"log"
 	"strconv"
+	"strings"
+	"time"
 )
 
+const maxTelemetryPage = 1000 // Most samples returned by one telemetry request
+
 type DeviceController struct {
 	BaseController
 }
@@ -158,6 +164,64 @@ func (c *DeviceController) Delete() {
 	c.JSONResponse(map[string]string{"message": "Device deleted successfully"}, nil)
 }
 
+// Telemetry lists a device's stored samples (API). Supports start/end (RFC 3339), a
+// comma-separated resource_id filter, sort=timestamp for oldest first, and limit/offset.
+func (c *DeviceController) Telemetry() {
+	id, err := strconv.Atoi(c.Ctx.Input.Param(":id"))
+	if err != nil {
+		c.JSONResponse(nil, err)
+		return
+	}
+	limit, _ := c.GetInt("limit", 100)
+	offset, _ := c.GetInt("offset", 0)
+	if limit <= 0 || limit > maxTelemetryPage {
+		limit = maxTelemetryPage
+	}
+
+	q := dal.Q
+	if _, err := q.Device.Select(q.Device.ID).Where(q.Device.ID.Eq(uint(id))).First(); err != nil {
+		c.JSONResponse(nil, err)
+		return
+	}
+
+	query := gateway.TelemetryQuery{
+		DeviceID:  uint(id),
+		Ascending: c.GetString("sort", "-timestamp") == "timestamp",
+		Limit:     limit,
+		Offset:    offset,
+	}
+	if query.Start, err = parseTimeParam(c.GetString("start")); err != nil {
+		c.JSONResponse(nil, fmt.Errorf("invalid start: %w", err))
+		return
+	}
+	if query.End, err = parseTimeParam(c.GetString("end")); err != nil {
+		c.JSONResponse(nil, fmt.Errorf("invalid end: %w", err))
+		return
+	}
+	for _, field := range strings.Split(c.GetString("resource_id"), ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		resourceID, err := strconv.ParseUint(field, 10, 0)
+		if err != nil {
+			c.JSONResponse(nil, fmt.Errorf("invalid resource_id %q", field))
+			return
+		}
+		query.ResourceIDs = append(query.ResourceIDs, uint(resourceID))
+	}
+
+	samples, total, err := gateway.QueryTelemetry(query)
+	c.PaginatedResponse(samples, total, limit, offset, err)
+}
+
+// parseTimeParam parses an optional RFC 3339 query parameter; empty gives the zero time.
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
 // ListDevices renders the device management page (Web)
 func (c *DeviceController) ListDevices() {
 	userID := c.GetSession("user_id")
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"app/dal"
+	"app/gateway"
 	"app/model"
 	"errors"
 	"strconv"
@@ -52,6 +53,10 @@ func (c *DevicePlatformController) Post() {
 		c.JSONResponse(nil, errors.New("platform_id and device_alias are required"))
 		return
 	}
+	if _, err := gateway.PollInterval(association.Metadata); err != nil {
+		c.JSONResponse(nil, err)
+		return
+	}
 
 	association.DeviceID = uint(deviceID)
 
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"app/drivers"
+	"errors"
+
+	"github.com/beego/beego/logs"
+)
+
+// OPCUATrustController manages the server certificates trusted by OPC UA drivers. Trusting a
+// certificate lets drivers open secured sessions with its server, so only administrators may
+// change or list them.
+type OPCUATrustController struct {
+	AdminController
+}
+
+// GetAll lists the trusted OPC UA server certificates (API)
+func (c *OPCUATrustController) GetAll() {
+	certs, err := drivers.DefaultOPCUATrustStore().List()
+	if err != nil {
+		logs.Error("Failed to list trusted certificates:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+	c.JSONResponse(certs, nil)
+}
+
+// Post adds a PEM encoded server certificate to the trust store (API)
+func (c *OPCUATrustController) Post() {
+	logs.Info("Received POST request to /api/opcua/trusted-certificates")
+	var input struct {
+		Certificate string `json:"certificate"`
+	}
+	if err := c.BindJSON(&input); err != nil {
+		logs.Error("Failed to bind JSON:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+	if input.Certificate == "" {
+		c.JSONResponse(nil, errors.New("certificate is required"))
+		return
+	}
+
+	cert, err := drivers.DefaultOPCUATrustStore().Add([]byte(input.Certificate))
+	if err != nil {
+		logs.Error("Failed to trust certificate:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+
+	logs.Info("Trusted OPCUA server certificate %s (%s)", cert.Thumbprint, cert.Subject)
+	c.JSONResponse(cert, nil)
+}
+
+// Delete removes a server certificate from the trust store by thumbprint (API)
+func (c *OPCUATrustController) Delete() {
+	thumbprint := c.Ctx.Input.Param(":thumbprint")
+	if err := drivers.DefaultOPCUATrustStore().Remove(thumbprint); err != nil {
+		logs.Error("Failed to remove trusted certificate:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+
+	logs.Info("Removed trusted OPCUA server certificate:", thumbprint)
+	c.JSONResponse(map[string]string{"message": "Certificate removed successfully"}, nil)
+}
@@ -3,20 +3,19 @@ package controllers
 import (
 	"app/dal"
 	"app/drivers"
+	"app/gateway"
 	"app/model"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
-	"net/url"
+	"net"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/beego/beego/logs"
-	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
 )
 
 type PlatformController struct {
@@ -48,6 +47,9 @@ func (c *PlatformController) GetPlatforms(limit *int, offset *int, nameFilter *s
 	if err != nil {
 		return nil, err
 	}
+	for _, platform := range platforms {
+		platform.CircuitState = gateway.Drivers().CircuitState(platform.ID)
+	}
 	return platforms, nil
 }
 
@@ -107,108 +109,11 @@ func (c *PlatformController) Get() {
 		c.JSONResponse(nil, err)
 		return
 	}
+	platform.CircuitState = gateway.Drivers().CircuitState(platform.ID)
 
 	c.JSONResponse(platform, nil)
 }
 
-// validateRESTMetadata validates and sanitizes REST platform metadata
-func (c *PlatformController) validateRESTMetadata(metadataJSON string) error {
-	var metadata model.RESTMetadata
-	if err := json.Unmarshal([]byte(metadataJSON), &metadata); err != nil {
-		return errors.New("invalid metadata JSON")
-	}
-
-	// Validate and sanitize base_endpoint
-	if metadata.BaseEndpoint == "" {
-		return errors.New("base_endpoint is required for REST platforms")
-	}
-	parsedURL, err := url.Parse(metadata.BaseEndpoint)
-	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
-		return errors.New("base_endpoint must be a valid HTTP/HTTPS URL")
-	}
-	// Sanitize by reconstructing the URL to prevent malicious input
-	metadata.BaseEndpoint = parsedURL.String()
-
-	// Validate authentication
-	if metadata.Auth.Type == "" {
-		metadata.Auth.Type = "none"
-	}
-	validAuthTypes := map[string]bool{"none": true, "api_key": true, "bearer": true, "basic": true}
-	if !validAuthTypes[metadata.Auth.Type] {
-		return errors.New("auth.type must be none, api_key, bearer, or basic")
-	}
-
-	switch metadata.Auth.Type {
-	case "api_key":
-		if metadata.Auth.APIKey == nil || *metadata.Auth.APIKey == "" {
-			return errors.New("auth.api_key is required for api_key authentication")
-		}
-		*metadata.Auth.APIKey = strings.TrimSpace(*metadata.Auth.APIKey)
-	case "bearer":
-		if metadata.Auth.APIKey == nil || *metadata.Auth.BearerToken == "" {
-			return errors.New("auth.bearer_token is required for bearer authentication")
-		}
-		*metadata.Auth.BearerToken = strings.TrimSpace(*metadata.Auth.BearerToken)
-	case "basic":
-		if metadata.Auth.BasicAuth == nil || metadata.Auth.BasicAuth.Username == "" || metadata.Auth.BasicAuth.Password == "" {
-			return errors.New("auth.basic_auth.username and auth.basic_auth.password are required for basic authentication")
-		}
-		metadata.Auth.BasicAuth.Username = strings.TrimSpace(metadata.Auth.BasicAuth.Username)
-		metadata.Auth.BasicAuth.Password = strings.TrimSpace(metadata.Auth.BasicAuth.Password)
-	case "none":
-		metadata.Auth = model.RESTAuth{Type: "none"} // Clear other auth fields
-	}
-
-	// Re-serialize sanitized metadata
-	serialized, err := json.Marshal(metadata)
-	if err != nil {
-		return errors.New("failed to serialize sanitized metadata")
-	}
-	c.Ctx.Input.SetData("sanitized_metadata", string(serialized))
-	return nil
-}
-
-// validateInfluxDBMetadata validates and sanitizes InfluxDB platform metadata
-func (c *PlatformController) validateInfluxDBMetadata(metadataJSON string) error {
-	var metadata model.InfluxDBMetadata
-	if err := json.Unmarshal([]byte(metadataJSON), &metadata); err != nil {
-		return errors.New("invalid metadata JSON")
-	}
-
-	if metadata.URL == "" {
-		return errors.New("url is required for InfluxDB platforms")
-	}
-	parsedURL, err := url.Parse(metadata.URL)
-	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
-		return errors.New("url must be a valid HTTP/HTTPS URL")
-	}
-	metadata.URL = parsedURL.String()
-
-	if metadata.Token == "" {
-		return errors.New("token is required for InfluxDB platforms")
-	}
-	if metadata.Org == "" {
-		return errors.New("org is required for InfluxDB platforms")
-	}
-	if metadata.Bucket == "" {
-		return errors.New("bucket is required for InfluxDB platforms")
-	}
-	if metadata.Timeout == 0 {
-		metadata.Timeout = 10
-	}
-
-	metadata.Token = strings.TrimSpace(metadata.Token)
-	metadata.Org = strings.TrimSpace(metadata.Org)
-	metadata.Bucket = strings.TrimSpace(metadata.Bucket)
-
-	serialized, err := json.Marshal(metadata)
-	if err != nil {
-		return errors.New("failed to serialize sanitized metadata")
-	}
-	c.Ctx.Input.SetData("sanitized_metadata", string(serialized))
-	return nil
-}
-
 // Post creates a new platform with validation (API)
 func (c *PlatformController) Post() {
 	logs.Info("Received POST request to /api/platforms")
@@ -229,35 +134,13 @@ func (c *PlatformController) Post() {
 		return
 	}
 
-	switch platform.Type {
-	case "REST":
-		if err := c.validateRESTMetadata(platform.Metadata); err != nil {
-			logs.Error("REST metadata validation failed:", err)
-			c.JSONResponse(nil, err)
-			return
-		}
-		// Retrieve sanitized metadata from context
-		sanitizedMetadata, ok := c.Ctx.Input.GetData("sanitized_metadata").(string)
-		if !ok {
-			c.JSONResponse(nil, errors.New("failed to retrieve sanitized metadata"))
-			return
-		}
-		platform.Metadata = sanitizedMetadata
-	case "InfluxDB":
-		if err := c.validateInfluxDBMetadata(platform.Metadata); err != nil {
-			logs.Error("InfluxDB metadata validation failed:", err)
-			c.JSONResponse(nil, err)
-			return
-		}
-		// Retrieve sanitized metadata from context
-		sanitizedMetadata, ok := c.Ctx.Input.GetData("sanitized_metadata").(string)
-		if !ok {
-			logs.Error("Failed to retrieve sanitized metadata")
-			c.JSONResponse(nil, errors.New("failed to retrieve sanitized metadata"))
-			return
-		}
-		platform.Metadata = sanitizedMetadata
+	sanitizedMetadata, err := drivers.ValidateMetadata(platform.Type, platform.Metadata)
+	if err != nil {
+		logs.Error("%s metadata validation failed: %v", platform.Type, err)
+		c.JSONResponse(nil, err)
+		return
 	}
+	platform.Metadata = sanitizedMetadata
 
 	q := dal.Q
 	if err := q.Platform.Create(&platform); err != nil {
@@ -297,36 +180,13 @@ func (c *PlatformController) Put() {
 		return
 	}
 
-	// Validate REST platform metadata if Type is REST
-	switch platform.Type {
-	case "REST":
-		if err := c.validateRESTMetadata(platform.Metadata); err != nil {
-			logs.Error("REST metadata validation failed:", err)
-			c.JSONResponse(nil, err)
-			return
-		}
-		// Retrieve sanitized metadata from context
-		sanitizedMetadata, ok := c.Ctx.Input.GetData("sanitized_metadata").(string)
-		if !ok {
-			c.JSONResponse(nil, errors.New("failed to retrieve sanitized metadata"))
-			return
-		}
-		platform.Metadata = sanitizedMetadata
-	case "InfluxDB":
-		if err := c.validateInfluxDBMetadata(platform.Metadata); err != nil {
-			logs.Error("InfluxDB metadata validation failed:", err)
-			c.JSONResponse(nil, err)
-			return
-		}
-		// Retrieve sanitized metadata from context
-		sanitizedMetadata, ok := c.Ctx.Input.GetData("sanitized_metadata").(string)
-		if !ok {
-			logs.Error("Failed to retrieve sanitized metadata")
-			c.JSONResponse(nil, errors.New("failed to retrieve sanitized metadata"))
-			return
-		}
-		platform.Metadata = sanitizedMetadata
+	sanitizedMetadata, err := drivers.ValidateMetadata(platform.Type, platform.Metadata)
+	if err != nil {
+		logs.Error("%s metadata validation failed: %v", platform.Type, err)
+		c.JSONResponse(nil, err)
+		return
 	}
+	platform.Metadata = sanitizedMetadata
 
 	platform.ID = uint(id)
 
@@ -343,6 +203,10 @@ func (c *PlatformController) Put() {
 		return
 	}
 
+	// Open subscriptions and pooled connections still use the old configuration
+	gateway.Subscriptions().Close(platform.ID)
+	gateway.Drivers().Invalidate(platform.ID)
+
 	logs.Info("Platform updated successfully:", platform.ID)
 	c.JSONResponse(platform, info.Error)
 }
@@ -371,6 +235,9 @@ func (c *PlatformController) Delete() {
 		return
 	}
 
+	gateway.Subscriptions().Close(uint(id))
+	gateway.Drivers().Invalidate(uint(id))
+
 	c.JSONResponse(map[string]string{"message": "Platform deleted successfully"}, info.Error)
 }
 
@@ -418,125 +285,330 @@ func (c *PlatformController) FetchDeviceData() {
 		return
 	}
 
-	// Get the driver
-	driver, err := drivers.GetDriver(platform.Type, platform.Metadata)
-	if err != nil {
-		logs.Error("Failed to get driver:", err)
-		c.JSONResponse(nil, err)
-		return
-	}
+	c.fetchDeviceData(platform, dp, resources)
+}
 
-	ctx := context.Background()
-	err = driver.Connect(ctx)
+// fetchDeviceData fetches the resources of a platform for a device and writes the response of
+// FetchDeviceData.
+func (c *PlatformController) fetchDeviceData(platform *model.Platform, dp *model.DevicePlatform, resources []*model.Resource) {
+	platformID, deviceID := platform.ID, dp.DeviceID
+
+	// Fetch within the client's request, so a disconnect stops the work, and an overall deadline
+	ctx, cancel := context.WithTimeout(c.Ctx.Request.Context(), fetchDeviceTimeout)
+	defer cancel()
+
+	// Get the platform's pooled driver
+	driver, release, err := gateway.Drivers().Acquire(ctx, platform)
 	if err != nil {
 		logs.Error("Failed to connect driver:", err)
 		c.JSONResponse(nil, err)
 		return
 	}
-	defer driver.Disconnect(ctx)
 
-	// Get query parameters for customization
+	// Get query parameters for customization; include_raw is ours and not passed on to the platform
 	queryParams := c.Ctx.Request.URL.Query()
-	// Fetch data for each resource
-	results := make(map[string]interface{})
+	includeRaw, _ := strconv.ParseBool(queryParams.Get("include_raw"))
+	queryParams.Del("include_raw")
+
+	// Prepare the details of every compatible resource; drivers that can combine reads fetch
+	// all resources in one job
+	resourceResults := make(map[string]*resourceFetch)
+	_, batching := driver.(drivers.BatchFetcher)
+	var jobs []*fetchJob
+	attempted := 0
 	for _, resource := range resources {
-		if (platform.Type == "REST" && resource.Type == "rest_endpoint") || (platform.Type == "InfluxDB" && resource.Type == "influxdb_query") {
-			// Prepare resource details with query parameter overrides
-			var modifiedDetails string
-			if platform.Type == "InfluxDB" {
-				var details model.InfluxDBResourceDetails
-				if err := json.Unmarshal([]byte(resource.Details), &details); err != nil {
-					logs.Error("Failed to parse InfluxDB resource details:", err)
-					results[resource.Name] = map[string]interface{}{"error": err.Error()}
-					continue
-				}
-				// Override time_range or field if provided in query params
-				if timeRange := queryParams.Get("time_range"); timeRange != "" {
-					if strings.HasPrefix(timeRange, "-") && strings.ContainsAny(timeRange, "smhdwy") {
-						details.TimeRange = timeRange
-					} else {
-						logs.Error("Invalid time_range parameter:", timeRange)
-						results[resource.Name] = map[string]interface{}{"error": "invalid time_range, must be negative duration (e.g., '-1h')"}
-						continue
-					}
-				}
-				if field := queryParams.Get("field"); field != "" {
-					details.Field = strings.TrimSpace(field)
-				}
-				// Use DeviceAlias as measurement if specified
-				if dp.DeviceAlias != "" {
-					details.Measurement = dp.DeviceAlias
-				}
-				modifiedDetailsBytes, err := json.Marshal(details)
-				if err != nil {
-					logs.Error("Failed to serialize modified InfluxDB details:", err)
-					results[resource.Name] = map[string]interface{}{"error": err.Error()}
-					continue
-				}
-				modifiedDetails = string(modifiedDetailsBytes)
-			} else if platform.Type == "REST" {
-				var details model.RESTResourceDetails
-				if err := json.Unmarshal([]byte(resource.Details), &details); err != nil {
-					logs.Error("Failed to parse REST resource details:", err)
-					results[resource.Name] = map[string]interface{}{"error": err.Error()}
-					continue
-				}
-				// Merge query parameters from request
-				if len(queryParams) > 0 {
-					if details.QueryParams == nil {
-						details.QueryParams = make(map[string]string)
-					}
-					for key, values := range queryParams {
-						if key != "time_range" && key != "field" { // Ignore InfluxDB-specific params
-							details.QueryParams[key] = values[0]
-						}
-					}
-				}
-				// Append DeviceAlias to query params or path
-				if dp.DeviceAlias != "" {
-					if strings.Contains(details.Path, ":device_alias") {
-						details.Path = strings.ReplaceAll(details.Path, ":device_alias", url.PathEscape(dp.DeviceAlias))
-					} else {
-						details.QueryParams["device_alias"] = dp.DeviceAlias
-					}
-				}
-				modifiedDetailsBytes, err := json.Marshal(details)
-				if err != nil {
-					logs.Error("Failed to serialize modified REST details:", err)
-					results[resource.Name] = map[string]interface{}{"error": err.Error()}
-					continue
-				}
-				modifiedDetails = string(modifiedDetailsBytes)
+		if drivers.CheckResourceType(platform.Type, resource.Type) != nil {
+			continue
+		}
+		attempted++
+		// Apply the device alias and query parameter overrides of the resource type
+		modifiedDetails, err := drivers.PrepareResourceDetails(platform.Type, resource.Type, resource.Details, dp.DeviceAlias, queryParams)
+		if err != nil {
+			logs.Error("Failed to prepare details for resource %s: %v", resource.Name, err)
+			resourceResults[resource.Name] = &resourceFetch{Status: fetchError, Error: err.Error()}
+			continue
+		}
+		if batching && len(jobs) > 0 {
+			jobs[0].resources = append(jobs[0].resources, resource)
+			jobs[0].details = append(jobs[0].details, modifiedDetails)
+			continue
+		}
+		jobs = append(jobs, &fetchJob{resources: []*model.Resource{resource}, details: []string{modifiedDetails}})
+	}
+
+	started := time.Now()
+	outcomes, wait := fetchConcurrently(ctx, driver, jobs)
+
+	// Convert the results to points, in resource order. Jobs still running at the deadline
+	// are reported as timed out.
+	points := []drivers.Point{}
+	var fetchErr error
+	fetched := 0
+	for i, job := range jobs {
+		for k, resource := range job.resources {
+			outcome := outcomes[i]
+			if outcome == nil {
+				status := fetchStatus(ctx.Err(), ctx.Err() == context.DeadlineExceeded)
+				resourceResults[resource.Name] = &resourceFetch{Status: status, LatencyMs: float64(time.Since(started).Microseconds()) / 1000, Error: ctx.Err().Error()}
+				fetchErr = ctx.Err()
+				continue
 			}
-
-			// Fetch data with modified details
-			data, err := driver.FetchData(ctx, modifiedDetails)
-			if err != nil {
+			result := &resourceFetch{Status: fetchStatus(outcome.results[k].Err, outcome.deadline), LatencyMs: float64(outcome.latency.Microseconds()) / 1000}
+			resourceResults[resource.Name] = result
+			if err := outcome.results[k].Err; err != nil {
 				logs.Error("Failed to fetch data for resource %s: %v", resource.Name, err)
-				results[resource.Name] = map[string]interface{}{"error": err.Error()}
+				result.Error = err.Error()
+				fetchErr = err
+				continue
+			}
+			fetched++
+			resourcePoints, err := drivers.ResourcePoints(resource, outcome.results[k].Data, time.Now(), includeRaw)
+			if err != nil {
+				logs.Error("Failed to extract points for resource %s: %v", resource.Name, err)
+				result.Status = fetchError
+				result.Error = err.Error()
 				continue
 			}
-			results[resource.Name] = data
+			result.Points = len(resourcePoints)
+			points = append(points, resourcePoints...)
 		}
 	}
 
-	if len(results) == 0 {
+	// A request where every resource failed counts against the pooled connection. The driver
+	// is released once fetches that outlived the deadline have returned.
+	releaseErr := fetchErr
+	if fetched > 0 {
+		releaseErr = nil
+	}
+	go func() {
+		wait()
+		release(releaseErr)
+	}()
+
+	if err := c.Ctx.Request.Context().Err(); err != nil {
+		logs.Warn("Client disconnected while fetching data for platform %d, device %d", platformID, deviceID)
+		return
+	}
+	if attempted == 0 {
 		err := errors.New("no compatible resources found for platform")
 		logs.Error(err.Error())
 		c.JSONResponse(nil, err)
 		return
 	}
 
-	logs.Info("Data fetched successfully for platform %d, device %d", platformID, deviceID)
+	resourceErrors := make(map[string]string)
+	for name, result := range resourceResults {
+		if result.Error != "" {
+			resourceErrors[name] = result.Error
+		}
+	}
+	logs.Info("Data fetched for platform %d, device %d: %d of %d resources", platformID, deviceID, fetched, attempted)
 	c.JSONResponse(map[string]interface{}{
 		"device_id":   deviceID,
 		"platform_id": platformID,
 		"alias":       dp.DeviceAlias,
-		"data":        results,
+		"points":      points,
+		"errors":      resourceErrors,
+		"resources":   resourceResults,
 	}, nil)
 }
 
-// TestConnection tests connectivity to a platform's endpoint (API)
+const (
+	fetchDeviceTimeout   = 30 * time.Second // Bound on a whole FetchDeviceData request
+	fetchResourceTimeout = 15 * time.Second // Bound on fetching one resource, or one batch
+	fetchWorkers         = 8                // Fetches run at once for one request
+	browseTimeout        = 30 * time.Second // Bound on a Browse request, including the connect
+)
+
+// Outcomes of fetching a resource
+const (
+	fetchOK        = "ok"
+	fetchError     = "error"
+	fetchTimeout   = "timeout"
+	fetchCancelled = "cancelled" // The client went away
+)
+
+// resourceFetch reports how fetching one resource went in FetchDeviceData.
+type resourceFetch struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Points    int     `json:"points"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// fetchJob is one driver call of FetchDeviceData: a single resource, or every resource when
+// the driver combines reads.
+type fetchJob struct {
+	resources []*model.Resource
+	details   []string
+}
+
+// fetchOutcome is the result of a fetchJob, one entry per resource.
+type fetchOutcome struct {
+	results  []drivers.BatchResult
+	latency  time.Duration
+	deadline bool // The job ran into its timeout
+}
+
+// run fetches the job's resources with the per-resource timeout.
+func (j *fetchJob) run(ctx context.Context, driver drivers.PlatformDriver) *fetchOutcome {
+	ctx, cancel := context.WithTimeout(ctx, fetchResourceTimeout)
+	defer cancel()
+
+	started := time.Now()
+	outcome := &fetchOutcome{}
+	if batcher, ok := driver.(drivers.BatchFetcher); ok {
+		outcome.results = batcher.FetchBatch(ctx, j.details)
+	} else {
+		data, err := driver.FetchData(ctx, j.details[0])
+		outcome.results = []drivers.BatchResult{{Data: data, Err: err}}
+	}
+	outcome.latency = time.Since(started)
+	outcome.deadline = errors.Is(ctx.Err(), context.DeadlineExceeded)
+	return outcome
+}
+
+// fetchConcurrently runs jobs on up to fetchWorkers goroutines and returns their outcomes by
+// index once all are done or ctx ends; jobs unfinished by then have a nil outcome. Drivers
+// that ignore the context can keep running after that, so wait blocks until every worker has
+// returned and the driver can be released.
+func fetchConcurrently(ctx context.Context, driver drivers.PlatformDriver, jobs []*fetchJob) ([]*fetchOutcome, func()) {
+	type done struct {
+		index   int
+		outcome *fetchOutcome
+	}
+	queue := make(chan int, len(jobs))
+	for i := range jobs {
+		queue <- i
+	}
+	close(queue)
+
+	finished := make(chan done, len(jobs)) // Buffered so late workers never block
+	var wg sync.WaitGroup
+	for w := 0; w < min(fetchWorkers, len(jobs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				if ctx.Err() != nil {
+					return
+				}
+				finished <- done{i, jobs[i].run(ctx, driver)}
+			}
+		}()
+	}
+
+	outcomes := make([]*fetchOutcome, len(jobs))
+	for received := 0; received < len(jobs); received++ {
+		select {
+		case d := <-finished:
+			outcomes[d.index] = d.outcome
+		case <-ctx.Done():
+			return outcomes, wg.Wait
+		}
+	}
+	return outcomes, wg.Wait
+}
+
+// fetchStatus classifies a fetch error; deadline tells whether the fetch ran into its timeout.
+func fetchStatus(err error, deadline bool) string {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return fetchOK
+	case errors.Is(err, context.Canceled):
+		return fetchCancelled
+	case deadline || errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		return fetchTimeout
+	}
+	return fetchError
+}
+
+// Browse walks a platform's address space so resources can be picked from it (API)
+func (c *PlatformController) Browse() {
+	logs.Info("Received request to browse platform %s", c.Ctx.Input.Param(":id"))
+	id, err := strconv.Atoi(c.Ctx.Input.Param(":id"))
+	if err != nil {
+		logs.Error("Invalid platform ID:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+
+	platform, err := dal.Platform.Where(dal.Platform.ID.Eq(uint(id))).First()
+	if err != nil {
+		logs.Error("Failed to find platform:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+
+	// Browse within the client's request and a deadline, so a platform that never answers
+	// does not hold the pooled driver's connect
+	ctx, cancel := context.WithTimeout(c.Ctx.Request.Context(), browseTimeout)
+	defer cancel()
+	driver, release, err := gateway.Drivers().Acquire(ctx, platform)
+	if err != nil {
+		logs.Error("Failed to connect driver:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+	browser, ok := driver.(drivers.Browser)
+	if !ok {
+		release(nil)
+		c.JSONResponse(nil, fmt.Errorf("platform type %s does not support browsing", platform.Type))
+		return
+	}
+
+	req := drivers.BrowseRequest{NodeID: strings.TrimSpace(c.GetString("node_id"))}
+	req.Depth, _ = c.GetInt("depth", 0)
+	req.Limit, _ = c.GetInt("limit", 0)
+	req.Offset, _ = c.GetInt("offset", 0)
+
+	result, err := browser.Browse(ctx, req)
+	release(err)
+	if err != nil {
+		logs.Error("Failed to browse platform:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+
+	logs.Info("Browsed %d of %d nodes on platform %d", len(result.Nodes), result.Total, id)
+	c.JSONResponse(result, nil)
+}
+
+// PlatformTypes lists the registered platform types, the resource types each one serves and their JSON Schemas (API)
+func (c *PlatformController) PlatformTypes() {
+	type resourceTypeInfo struct {
+		Type          string               `json:"type"`
+		Label         string               `json:"label"`
+		DetailsSchema *drivers.Schema      `json:"details_schema,omitempty"`
+		Points        drivers.PointMapping `json:"points"` // Default mapping from results to points
+		Writable      bool                 `json:"writable"`
+	}
+	type platformTypeInfo struct {
+		Type           string             `json:"type"`
+		Label          string             `json:"label"`
+		MetadataSchema *drivers.Schema    `json:"metadata_schema,omitempty"`
+		ResourceTypes  []resourceTypeInfo `json:"resource_types"`
+	}
+
+	registrations := drivers.Registrations()
+	types := make([]platformTypeInfo, 0, len(registrations))
+	for _, r := range registrations {
+		info := platformTypeInfo{Type: r.Type, Label: r.Label, MetadataSchema: r.MetadataSchema, ResourceTypes: []resourceTypeInfo{}}
+		for _, rt := range r.ResourceTypes {
+			info.ResourceTypes = append(info.ResourceTypes, resourceTypeInfo{Type: rt.Type, Label: rt.Label, DetailsSchema: rt.DetailsSchema, Points: rt.Points, Writable: rt.Writable})
+		}
+		types = append(types, info)
+	}
+
+	c.JSONResponse(types, nil)
+}
+
+// connectionTestTimeout bounds a whole connection test, across all of its stages.
+const connectionTestTimeout = 30 * time.Second
+
+// TestConnection tests connectivity for an unsaved platform configuration and returns
+// diagnostics for each stage of the connection (API)
 func (c *PlatformController) TestConnection() {
 	logs.Info("Received POST request to /api/platforms/test")
 	var input struct {
@@ -549,101 +621,98 @@ func (c *PlatformController) TestConnection() {
 		return
 	}
 
-	logs.Debug("Test connection input: type=%s, metadata=%s", input.Type, input.Metadata)
+	logs.Debug("Test connection input: type=%s", input.Type)
 
-	if input.Type == "REST" {
-		if err := c.validateRESTMetadata(input.Metadata); err != nil {
-			logs.Error("REST metadata validation failed:", err)
-			c.JSONResponse(nil, err)
-			return
-		}
-		var metadata model.RESTMetadata
-		if err := json.Unmarshal([]byte(input.Metadata), &metadata); err != nil {
-			logs.Error("Invalid metadata JSON:", err)
-			c.JSONResponse(nil, errors.New("invalid metadata JSON"))
-			return
-		}
+	diag, err := diagnosePlatform(input.Type, input.Metadata)
+	if err != nil {
+		c.JSONResponse(nil, err)
+		return
+	}
+	c.JSONResponse(diag, nil)
+}
 
-		client := &http.Client{Timeout: time.Duration(metadata.Timeout) * time.Second}
-		req, err := http.NewRequest("HEAD", metadata.BaseEndpoint, nil)
-		if err != nil {
-			logs.Error("Failed to create HEAD request:", err)
-			c.JSONResponse(nil, err)
-			return
-		}
+// TestSavedConnection tests connectivity for a stored platform and records the outcome in
+// its connection state (API)
+func (c *PlatformController) TestSavedConnection() {
+	logs.Info("Received POST request to /api/platforms/%s/test", c.Ctx.Input.Param(":id"))
 
-		switch metadata.Auth.Type {
-		case "api_key":
-			req.Header.Set("X-API-Key", *metadata.Auth.APIKey)
-		case "bearer":
-			req.Header.Set("Authorization", "Bearer "+*metadata.Auth.BearerToken)
-		case "basic":
-			if metadata.Auth.BasicAuth == nil {
-				err := errors.New("basic auth configuration missing")
-				logs.Error(err.Error())
-				c.JSONResponse(nil, err)
-				return
-			}
-			auth := base64.StdEncoding.EncodeToString([]byte(metadata.Auth.BasicAuth.Username + ":" + metadata.Auth.BasicAuth.Password))
-			req.Header.Set("Authorization", "Basic "+auth)
-		}
+	id, err := strconv.Atoi(c.Ctx.Input.Param(":id"))
+	if err != nil {
+		logs.Error("Invalid platform ID:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
 
-		resp, err := client.Do(req)
-		if err != nil {
-			logs.Error("Connection failed:", err)
-			c.JSONResponse(nil, fmt.Errorf("connection failed: %w", err))
-			return
-		}
-		defer resp.Body.Close()
+	q := dal.Q
+	platform, err := q.Platform.Where(q.Platform.ID.Eq(uint(id))).First()
+	if err != nil {
+		logs.Error("Failed to find platform:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
 
-		if resp.StatusCode >= 400 {
-			err := fmt.Errorf("connection failed with status: %s", resp.Status)
-			logs.Error(err.Error())
-			c.JSONResponse(nil, err)
-			return
-		}
+	diag, err := diagnosePlatform(platform.Type, platform.Metadata)
+	if err != nil {
+		c.JSONResponse(nil, err)
+		return
+	}
 
-		logs.Info("Connection test successful for base_endpoint:", metadata.BaseEndpoint)
-	} else if input.Type == "InfluxDB" {
-		if err := c.validateInfluxDBMetadata(input.Metadata); err != nil {
-			logs.Error("InfluxDB metadata validation failed:", err)
-			c.JSONResponse(nil, err)
-			return
-		}
-		var metadata model.InfluxDBMetadata
-		if err := json.Unmarshal([]byte(input.Metadata), &metadata); err != nil {
-			logs.Error("Invalid metadata JSON:", err)
-			c.JSONResponse(nil, errors.New("invalid metadata JSON"))
-			return
-		}
+	// Manual tests feed the same state machine as the background health monitor
+	if _, err := gateway.Health().Record(platform.ID, diag); err != nil {
+		logs.Error("Failed to update connection state of platform %d: %v", platform.ID, err)
+		c.JSONResponse(nil, err)
+		return
+	}
 
-		client := influxdb2.NewClientWithOptions(metadata.URL, metadata.Token, influxdb2.DefaultOptions().
-			SetHTTPRequestTimeout(uint(metadata.Timeout)))
-		defer client.Close()
+	c.JSONResponse(diag, nil)
+}
 
-		ctx := context.Background()
-		health, err := client.Health(ctx)
-		if err != nil {
-			logs.Error("InfluxDB health check failed:", err)
-			c.JSONResponse(nil, fmt.Errorf("connection failed: %w", err))
-			return
-		}
-		if health.Status != "pass" {
-			err := fmt.Errorf("influxDB health check failed: %s", *health.Message)
-			logs.Error(err.Error())
-			c.JSONResponse(nil, err)
-			return
-		}
+// HealthHistory lists a platform's connection state changes, newest first (API)
+func (c *PlatformController) HealthHistory() {
+	logs.Info("Received GET request to /api/platforms/%s/health-history", c.Ctx.Input.Param(":id"))
 
-		logs.Info("Connection test successful for InfluxDB URL:", metadata.URL)
-	} else {
-		err := fmt.Errorf("unsupported platform type: %s", input.Type)
-		logs.Error(err.Error())
+	id, err := strconv.Atoi(c.Ctx.Input.Param(":id"))
+	if err != nil {
+		logs.Error("Invalid platform ID:", err)
 		c.JSONResponse(nil, err)
 		return
 	}
+	limit, _ := c.GetInt("limit", 50)
+	offset, _ := c.GetInt("offset", 0)
+
+	q := dal.Q
+	changes, total, err := q.PlatformStateChange.
+		Where(q.PlatformStateChange.PlatformID.Eq(uint(id))).
+		Order(q.PlatformStateChange.ChangedAt.Desc()).
+		FindByPage(offset, limit)
+	c.PaginatedResponse(changes, total, limit, offset, err)
+}
+
+// diagnosePlatform validates a platform configuration and runs a staged connection test
+// against it. Connection failures are reported in the diagnostics, not as an error.
+func diagnosePlatform(platformType, metadata string) (*drivers.Diagnostics, error) {
+	sanitizedMetadata, err := drivers.ValidateMetadata(platformType, metadata)
+	if err != nil {
+		logs.Error("%s metadata validation failed: %v", platformType, err)
+		return nil, err
+	}
+	driver, err := drivers.GetDriver(platformType, sanitizedMetadata)
+	if err != nil {
+		logs.Error("Failed to get driver:", err)
+		return nil, err
+	}
 
-	c.JSONResponse(map[string]string{"message": "Connection successful"}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTestTimeout)
+	defer cancel()
+	defer driver.Disconnect(ctx)
+
+	diag := drivers.Diagnose(ctx, driver)
+	if diag.Success {
+		logs.Info("Connection test successful for %s platform in %.1fms", platformType, diag.LatencyMs)
+	} else {
+		logs.Error("Connection test failed for %s platform at %s stage: %s", platformType, diag.FailedStage, diag.Error)
+	}
+	return diag, nil
 }
 
 // ListPlatforms renders the platform management page (Web)
@@ -814,6 +883,7 @@ func (c *PlatformController) ViewPlatform() {
 		c.TplName = "platforms/view.html"
 		return
 	}
+	platform.CircuitState = gateway.Drivers().CircuitState(platform.ID)
 
 	q := dal.Q
 	apiKey, err := dal.ApiKey.Where(
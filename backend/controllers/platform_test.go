@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"app/drivers"
+	"app/gateway"
+	"app/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	beecontext "github.com/beego/beego/v2/server/web/context"
+)
+
+// fakeFetchDriver answers "fast" details at once. "slow" details record the context's error in
+// slowErr once it ends, then keep the driver busy until slowDone is closed.
+type fakeFetchDriver struct {
+	slowStarted chan struct{}
+	slowErr     chan error
+	slowDone    chan struct{}
+}
+
+func (d *fakeFetchDriver) Connect(ctx context.Context) error    { return nil }
+func (d *fakeFetchDriver) Disconnect(ctx context.Context) error { return nil }
+
+func (d *fakeFetchDriver) FetchData(ctx context.Context, resourceDetails string) (interface{}, error) {
+	if resourceDetails != "slow" {
+		return map[string]interface{}{"value": 21.5}, nil
+	}
+	close(d.slowStarted)
+	<-ctx.Done()
+	d.slowErr <- ctx.Err()
+	<-d.slowDone
+	return nil, ctx.Err()
+}
+
+func (d *fakeFetchDriver) ValidateConfig(ctx context.Context) error { return nil }
+
+func (d *fakeFetchDriver) TestResource(ctx context.Context, resourceDetails string) (interface{}, error) {
+	return d.FetchData(ctx, resourceDetails)
+}
+
+var fakeFetch = &fakeFetchDriver{slowStarted: make(chan struct{}), slowErr: make(chan error, 1), slowDone: make(chan struct{})}
+
+func init() {
+	drivers.Register(drivers.Registration{
+		Type:             "FetchTest",
+		New:              func(metadata string) (drivers.PlatformDriver, error) { return fakeFetch, nil },
+		ValidateMetadata: func(metadata string) (string, error) { return metadata, nil },
+		ResourceTypes: []drivers.ResourceType{{
+			Type:            "fetch_test",
+			ValidateDetails: func(details string) (string, error) { return details, nil },
+			Points:          drivers.PointMapping{Value: "value"},
+		}},
+	})
+}
+
+// serveFetchDeviceData runs fetchDeviceData for a request with ctx and returns the recorded
+// response.
+func serveFetchDeviceData(ctx context.Context, platform *model.Platform, resources []*model.Resource) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	beeCtx := beecontext.NewContext()
+	beeCtx.Reset(recorder, httptest.NewRequest("GET", "/api/platforms/1/devices/7/data", nil).WithContext(ctx))
+	c := &PlatformController{}
+	c.Init(beeCtx, "PlatformController", "FetchDeviceData", c)
+	c.fetchDeviceData(platform, &model.DevicePlatform{DeviceID: 7, PlatformID: platform.ID, DeviceAlias: "press-1"}, resources)
+	return recorder
+}
+
+func TestFetchDeviceData(t *testing.T) {
+	platform := &model.Platform{Model: model.Model{ID: 901}, Type: "FetchTest", Metadata: "{}"}
+	resources := []*model.Resource{{Model: model.Model{ID: 1}, Name: "temperature", Type: "fetch_test", Details: "fast"}}
+
+	recorder := serveFetchDeviceData(context.Background(), platform, resources)
+	var response struct {
+		Data struct {
+			Points    []drivers.Point           `json:"points"`
+			Resources map[string]*resourceFetch `json:"resources"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Invalid response %q: %v", recorder.Body.String(), err)
+	}
+	if len(response.Data.Points) != 1 || response.Data.Points[0].Resource != "temperature" {
+		t.Errorf("Expected one point of temperature, got %+v", response.Data.Points)
+	}
+	if result := response.Data.Resources["temperature"]; result == nil || result.Status != fetchOK || result.Points != 1 {
+		t.Errorf("Expected an ok fetch, got %+v", result)
+	}
+}
+
+func TestFetchDeviceData_ClientDisconnect(t *testing.T) {
+	platform := &model.Platform{Model: model.Model{ID: 902}, Type: "FetchTest", Metadata: "{}"}
+	resources := []*model.Resource{
+		{Model: model.Model{ID: 1}, Name: "temperature", Type: "fetch_test", Details: "fast"},
+		{Model: model.Model{ID: 2}, Name: "pressure", Type: "fetch_test", Details: "slow"},
+	}
+
+	// The client goes away while the slow resource is being fetched
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-fakeFetch.slowStarted
+		cancel()
+	}()
+	started := time.Now()
+	recorder := serveFetchDeviceData(ctx, platform, resources)
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Expected the handler to return once the request was cancelled, took %s", elapsed)
+	}
+	if recorder.Body.Len() != 0 {
+		t.Errorf("Expected no response for a disconnected client, got %q", recorder.Body.String())
+	}
+
+	select {
+	case err := <-fakeFetch.slowErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the fetch to be cancelled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the cancellation to reach the driver")
+	}
+
+	// The pooled driver stays in use until the fetch has returned
+	if inUse := fetchDriverInUse(platform.ID); inUse != 1 {
+		t.Fatalf("Expected the driver to stay in use during the fetch, got %d", inUse)
+	}
+	close(fakeFetch.slowDone)
+	deadline := time.Now().Add(time.Second)
+	for fetchDriverInUse(platform.ID) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the driver to be released after the fetch returned")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// fetchDriverInUse returns how many requests use the pooled driver of a platform.
+func fetchDriverInUse(platformID uint) int {
+	for _, stats := range gateway.Drivers().Stats() {
+		if stats.PlatformID == platformID {
+			return stats.InUse
+		}
+	}
+	return 0
+}
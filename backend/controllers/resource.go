@@ -3,15 +3,17 @@ package controllers
 import (
 	"app/dal"
 	"app/drivers"
+	"app/gateway"
 	"app/model"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/url"
-	"path"
+	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/beego/beego/logs"
 )
@@ -121,138 +123,24 @@ func (c *ResourceController) GetResourceForEdit() {
 		"type": resource.Type,
 	}
 
-	if resource.Type == "rest_endpoint" {
-		var details model.RESTResourceDetails
-		if err := json.Unmarshal([]byte(resource.Details), &details); err != nil {
-			logs.Error("Failed to parse REST resource details:", err)
-			c.JSONResponse(nil, err)
-			return
-		}
-		response["details"] = details
-	} else if resource.Type == "influxdb_query" {
-		var details model.InfluxDBResourceDetails
-		if err := json.Unmarshal([]byte(resource.Details), &details); err != nil {
-			logs.Error("Failed to parse InfluxDB resource details:", err)
-			c.JSONResponse(nil, err)
-			return
-		}
-		response["details"] = details
-	} else {
-		err := errors.New("unsupported resource type")
+	if _, _, err := drivers.LookupResourceType(resource.Type); err != nil {
 		logs.Error("Validation failed:", err)
 		c.JSONResponse(nil, err)
 		return
 	}
+	// Details are stored sanitized by their resource type, so they only need decoding
+	var details map[string]interface{}
+	if err := json.Unmarshal([]byte(resource.Details), &details); err != nil {
+		logs.Error("Failed to parse %s resource details: %v", resource.Type, err)
+		c.JSONResponse(nil, err)
+		return
+	}
+	response["details"] = details
 
 	logs.Info("Resource %d fetched for editing", resource.ID)
 	c.JSONResponse(response, nil)
 }
 
-// validateRESTResourceDetails validates and sanitizes REST endpoint details
-func (c *ResourceController) validateRESTResourceDetails(detailsJSON string) error {
-	var details model.RESTResourceDetails
-	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
-		return errors.New("invalid details JSON")
-	}
-
-	// Validate method
-	validMethods := map[string]bool{"GET": true, "POST": true, "PUT": true, "DELETE": true}
-	if details.Method == "" {
-		return errors.New("method is required for rest_endpoint")
-	}
-	if !validMethods[strings.ToUpper(details.Method)] {
-		return errors.New("method must be GET, POST, PUT, or DELETE")
-	}
-	details.Method = strings.ToUpper(details.Method)
-
-	// Validate and sanitize path
-	if details.Path == "" {
-		return errors.New("path is required for rest_endpoint")
-	}
-	cleanPath := path.Clean("/" + strings.TrimPrefix(details.Path, "/"))
-	if cleanPath == "/" {
-		return errors.New("path must not be empty")
-	}
-	details.Path = cleanPath
-
-	// Validate headers and query parameters
-	if details.Headers == nil {
-		details.Headers = make(map[string]string)
-	}
-	if details.QueryParams == nil {
-		details.QueryParams = make(map[string]string)
-	}
-	for key, value := range details.Headers {
-		if strings.TrimSpace(key) == "" {
-			return errors.New("header keys must not be empty")
-		}
-		details.Headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
-	}
-	for key, value := range details.QueryParams {
-		if strings.TrimSpace(key) == "" {
-			return errors.New("query parameter keys must not be empty")
-		}
-		details.QueryParams[strings.TrimSpace(key)] = strings.TrimSpace(value)
-	}
-
-	// Validate body (optional, must be valid JSON if provided)
-	if details.Body != "" {
-		var body interface{}
-		if err := json.Unmarshal([]byte(details.Body), &body); err != nil {
-			return errors.New("body must be valid JSON")
-		}
-	}
-
-	// Re-serialize sanitized details
-	serialized, err := json.Marshal(details)
-	if err != nil {
-		return errors.New("failed to serialize sanitized details")
-	}
-	c.Ctx.Input.SetData("sanitized_details", string(serialized))
-	return nil
-}
-
-// validateInfluxDBResourceDetails validates and sanitizes InfluxDB query details
-func (c *ResourceController) validateInfluxDBResourceDetails(detailsJSON string) error {
-	var details model.InfluxDBResourceDetails
-	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
-		return errors.New("invalid details JSON")
-	}
-
-	// Validate required fields
-	if details.Bucket == "" {
-		return errors.New("bucket is required for influxdb_query")
-	}
-	if details.Measurement == "" {
-		return errors.New("measurement is required for influxdb_query")
-	}
-	if details.Field == "" {
-		return errors.New("field is required for influxdb_query")
-	}
-	if details.TimeRange == "" {
-		return errors.New("time_range is required for influxdb_query")
-	}
-
-	// Validate time_range format (e.g., "-1h", "-30m", "-1d")
-	if !strings.HasPrefix(details.TimeRange, "-") || !strings.ContainsAny(details.TimeRange, "smhdwy") {
-		return errors.New("time_range must be a negative duration (e.g., '-1h', '-30m', '-1d')")
-	}
-
-	// Sanitize fields
-	details.Bucket = strings.TrimSpace(details.Bucket)
-	details.Measurement = strings.TrimSpace(details.Measurement)
-	details.Field = strings.TrimSpace(details.Field)
-	details.TimeRange = strings.TrimSpace(details.TimeRange)
-
-	// Re-serialize sanitized details
-	serialized, err := json.Marshal(details)
-	if err != nil {
-		return errors.New("failed to serialize sanitized details")
-	}
-	c.Ctx.Input.SetData("sanitized_details", string(serialized))
-	return nil
-}
-
 func (c *ResourceController) Post() {
 	logs.Info("Received POST request to create resource for platform %s", c.Ctx.Input.Param(":platform_id"))
 
@@ -279,27 +167,23 @@ func (c *ResourceController) Post() {
 		return
 	}
 
-	// Validate resource details based on type
-	switch resource.Type {
-	case "rest_endpoint":
-		if err := c.validateRESTResourceDetails(resource.Details); err != nil {
-			logs.Error("REST resource details validation failed:", err)
-			c.JSONResponse(nil, err)
-			return
-		}
-		resource.Details = c.Ctx.Input.GetData("sanitized_details").(string)
-	case "influxdb_query":
-		if err := c.validateInfluxDBResourceDetails(resource.Details); err != nil {
-			logs.Error("InfluxDB resource details validation failed:", err)
+	// Validate resource details with the resource type's driver
+	sanitizedDetails, err := drivers.ValidateResourceDetails(resource.Type, resource.Details)
+	if err != nil {
+		logs.Error("%s resource details validation failed: %v", resource.Type, err)
+		c.JSONResponse(nil, err)
+		return
+	}
+	resource.Details = sanitizedDetails
+	if err := validateResourceMetadata(resource.Metadata); err != nil {
+		c.JSONResponse(nil, err)
+		return
+	}
+	if resource.Writable {
+		if err := drivers.CheckWritable(resource.Type); err != nil {
 			c.JSONResponse(nil, err)
 			return
 		}
-		resource.Details = c.Ctx.Input.GetData("sanitized_details").(string)
-	default:
-		err := errors.New("unsupported resource type")
-		logs.Error("Validation failed:", err)
-		c.JSONResponse(nil, err)
-		return
 	}
 
 	resource.PlatformID = uint(platformID)
@@ -333,8 +217,37 @@ func (c *ResourceController) BulkPost() {
 		return
 	}
 
+	createdResources, errorsList, fieldErrors := c.createResources(uint(platformID), resources)
+
+	response := map[string]interface{}{
+		"created": createdResources,
+	}
+	if len(errorsList) > 0 {
+		response["errors"] = errorsList
+	}
+	if len(fieldErrors) > 0 {
+		response["field_errors"] = fieldErrors
+	}
+
+	logs.Info("Bulk resource creation completed: %d created, %d errors", len(createdResources), len(errorsList))
+	c.JSONResponse(response, nil)
+}
+
+// validateResourceMetadata checks the polling interval and point mapping of resource metadata.
+func validateResourceMetadata(metadata string) error {
+	if _, err := gateway.PollInterval(metadata); err != nil {
+		return err
+	}
+	_, err := drivers.ParsePointMapping(metadata)
+	return err
+}
+
+// createResources validates and stores resources for a platform, collecting per-resource errors.
+// Schema violations are also returned per field, keyed by the resource's index in the request.
+func (c *ResourceController) createResources(platformID uint, resources []model.Resource) ([]model.Resource, []string, map[string][]drivers.FieldError) {
 	var createdResources []model.Resource
 	var errorsList []string
+	fieldErrors := make(map[string][]drivers.FieldError)
 
 	for i, resource := range resources {
 		if resource.Name == "" || resource.Type == "" || resource.Details == "" {
@@ -344,30 +257,30 @@ func (c *ResourceController) BulkPost() {
 			continue
 		}
 
-		// Validate resource details based on type
-		switch resource.Type {
-		case "rest_endpoint":
-			if err := c.validateRESTResourceDetails(resource.Details); err != nil {
-				logs.Error("Resource %d REST details validation failed: %v", i, err)
-				errorsList = append(errorsList, fmt.Sprintf("resource %d: %v", i, err))
-				continue
+		// Validate resource details with the resource type's driver
+		sanitizedDetails, err := drivers.ValidateResourceDetails(resource.Type, resource.Details)
+		if err != nil {
+			logs.Error("Resource %d details validation failed: %v", i, err)
+			errorsList = append(errorsList, fmt.Sprintf("resource %d: %v", i, err))
+			var validationErr *drivers.ValidationError
+			if errors.As(err, &validationErr) {
+				fieldErrors[strconv.Itoa(i)] = validationErr.Fields
 			}
-			resource.Details = c.Ctx.Input.GetData("sanitized_details").(string)
-		case "influxdb_query":
-			if err := c.validateInfluxDBResourceDetails(resource.Details); err != nil {
-				logs.Error("Resource %d InfluxDB details validation failed: %v", i, err)
+			continue
+		}
+		resource.Details = sanitizedDetails
+		if err := validateResourceMetadata(resource.Metadata); err != nil {
+			errorsList = append(errorsList, fmt.Sprintf("resource %d: %v", i, err))
+			continue
+		}
+		if resource.Writable {
+			if err := drivers.CheckWritable(resource.Type); err != nil {
 				errorsList = append(errorsList, fmt.Sprintf("resource %d: %v", i, err))
 				continue
 			}
-			resource.Details = c.Ctx.Input.GetData("sanitized_details").(string)
-		default:
-			err := fmt.Errorf("resource %d: unsupported resource type", i)
-			logs.Error("Validation failed:", err)
-			errorsList = append(errorsList, err.Error())
-			continue
 		}
 
-		resource.PlatformID = uint(platformID)
+		resource.PlatformID = platformID
 
 		q := dal.Q
 		if err := q.Resource.Create(&resource); err != nil {
@@ -379,14 +292,74 @@ func (c *ResourceController) BulkPost() {
 		createdResources = append(createdResources, resource)
 	}
 
+	return createdResources, errorsList, fieldErrors
+}
+
+// ImportNodes creates opcua_node resources from nodes selected in the address space browser (API)
+func (c *ResourceController) ImportNodes() {
+	logs.Info("Received POST request to import OPCUA nodes for platform %s", c.Ctx.Input.Param(":platform_id"))
+
+	platformID, err := strconv.Atoi(c.Ctx.Input.Param(":platform_id"))
+	if err != nil {
+		logs.Error("Invalid platform ID:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+
+	platform, err := dal.Platform.Where(dal.Platform.ID.Eq(uint(platformID))).First()
+	if err != nil {
+		logs.Error("Failed to find platform:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+	if platform.Type != "OPCUA" {
+		c.JSONResponse(nil, fmt.Errorf("platform type %s does not support node import", platform.Type))
+		return
+	}
+
+	var input struct {
+		Nodes []struct {
+			NodeID string `json:"node_id"`
+			Name   string `json:"name"`
+		} `json:"nodes"`
+	}
+	if err := c.BindJSON(&input); err != nil {
+		logs.Error("Failed to bind JSON:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+	if len(input.Nodes) == 0 {
+		c.JSONResponse(nil, errors.New("at least one node is required"))
+		return
+	}
+
+	resources := make([]model.Resource, 0, len(input.Nodes))
+	for _, node := range input.Nodes {
+		name := strings.TrimSpace(node.Name)
+		if name == "" {
+			name = node.NodeID
+		}
+		details, _ := json.Marshal(model.OPCUAResourceDetails{NodeID: node.NodeID})
+		resources = append(resources, model.Resource{
+			Name:    name,
+			Type:    "opcua_node",
+			Details: string(details),
+		})
+	}
+
+	createdResources, errorsList, fieldErrors := c.createResources(uint(platformID), resources)
+
 	response := map[string]interface{}{
 		"created": createdResources,
 	}
 	if len(errorsList) > 0 {
 		response["errors"] = errorsList
 	}
+	if len(fieldErrors) > 0 {
+		response["field_errors"] = fieldErrors
+	}
 
-	logs.Info("Bulk resource creation completed: %d created, %d errors", len(createdResources), len(errorsList))
+	logs.Info("OPCUA node import completed: %d created, %d errors", len(createdResources), len(errorsList))
 	c.JSONResponse(response, nil)
 }
 
@@ -415,27 +388,23 @@ func (c *ResourceController) Put() {
 		return
 	}
 
-	// Validate resource details based on type
-	switch resource.Type {
-	case "rest_endpoint":
-		if err := c.validateRESTResourceDetails(resource.Details); err != nil {
-			logs.Error("REST resource details validation failed:", err)
-			c.JSONResponse(nil, err)
-			return
-		}
-		resource.Details = c.Ctx.Input.GetData("sanitized_details").(string)
-	case "influxdb_query":
-		if err := c.validateInfluxDBResourceDetails(resource.Details); err != nil {
-			logs.Error("InfluxDB resource details validation failed:", err)
+	// Validate resource details with the resource type's driver
+	sanitizedDetails, err := drivers.ValidateResourceDetails(resource.Type, resource.Details)
+	if err != nil {
+		logs.Error("%s resource details validation failed: %v", resource.Type, err)
+		c.JSONResponse(nil, err)
+		return
+	}
+	resource.Details = sanitizedDetails
+	if err := validateResourceMetadata(resource.Metadata); err != nil {
+		c.JSONResponse(nil, err)
+		return
+	}
+	if resource.Writable {
+		if err := drivers.CheckWritable(resource.Type); err != nil {
 			c.JSONResponse(nil, err)
 			return
 		}
-		resource.Details = c.Ctx.Input.GetData("sanitized_details").(string)
-	default:
-		err := errors.New("unsupported resource type")
-		logs.Error("Validation failed:", err)
-		c.JSONResponse(nil, err)
-		return
 	}
 
 	resource.ID = uint(id)
@@ -451,6 +420,16 @@ func (c *ResourceController) Put() {
 		c.JSONResponse(nil, errors.New("resource not found"))
 		return
 	}
+	// Updates skips false, so clearing the writable flag needs its own update. Clients that
+	// leave the flag out keep the stored one.
+	var fields map[string]json.RawMessage
+	json.Unmarshal(c.Ctx.Input.RequestBody, &fields)
+	if _, ok := fields["writable"]; ok {
+		if _, err := q.Resource.Where(q.Resource.ID.Eq(uint(id))).Update(q.Resource.Writable, resource.Writable); err != nil {
+			c.JSONResponse(nil, err)
+			return
+		}
+	}
 
 	logs.Info("Resource updated successfully:", resource.ID)
 	c.JSONResponse(resource, info.Error)
@@ -509,85 +488,324 @@ func (c *ResourceController) TestResource() {
 		return
 	}
 
-	// Get the driver
-	driver, err := drivers.GetDriver(platform.Type, platform.Metadata)
-	if err != nil {
-		logs.Error("Failed to get driver:", err)
+	if err := drivers.CheckResourceType(platform.Type, resource.Type); err != nil {
+		logs.Error("Validation failed: %v", err)
 		c.JSONResponse(nil, err)
 		return
 	}
 
-	ctx := context.Background()
-	err = driver.Connect(ctx)
+	// Get the platform's pooled driver, within the client's request and the bound on one fetch
+	ctx, cancel := context.WithTimeout(c.Ctx.Request.Context(), fetchResourceTimeout)
+	defer cancel()
+	driver, release, err := gateway.Drivers().Acquire(ctx, platform)
 	if err != nil {
 		logs.Error("Failed to connect driver:", err)
 		c.JSONResponse(nil, err)
 		return
 	}
-	defer driver.Disconnect(ctx)
 
 	// Test the resource
-	var result interface{}
-	if (platform.Type == "REST" && resource.Type == "rest_endpoint") || (platform.Type == "InfluxDB" && resource.Type == "influxdb_query") {
-		// For REST, log the constructed URL
-		if platform.Type == "REST" {
-			var details model.RESTResourceDetails
-			if err := json.Unmarshal([]byte(resource.Details), &details); err != nil {
-				logs.Error("Failed to parse REST resource details: %v", err)
-				c.JSONResponse(nil, fmt.Errorf("invalid resource details: %w", err))
-				return
-			}
-			var config model.RESTMetadata
-			if err := json.Unmarshal([]byte(platform.Metadata), &config); err != nil {
-				logs.Error("Failed to parse REST platform metadata: %v", err)
-				c.JSONResponse(nil, fmt.Errorf("invalid platform metadata: %w", err))
-				return
-			}
-			baseURL, err := url.Parse(config.BaseEndpoint)
-			if err != nil {
-				logs.Error("Invalid base URL %s: %v", config.BaseEndpoint, err)
-				c.JSONResponse(nil, fmt.Errorf("invalid base URL: %w", err))
+	result, err := driver.FetchData(ctx, resource.Details)
+	release(err)
+	if err != nil {
+		logs.Error("Failed to test resource %s: %v", resource.Name, err)
+		c.testFailed(resource, result, err)
+		return
+	}
+	logs.Debug("Test result for resource %s: %v", resource.Name, result)
+
+	// Show the points the result maps to, so the mapping can be adjusted against real data
+	response := map[string]interface{}{
+		"resource_id":   resource.ID,
+		"name":          resource.Name,
+		"type":          resource.Type,
+		"platform_id":   platform.ID,
+		"platform_type": platform.Type,
+		"result":        result,
+	}
+	if points, err := drivers.ResourcePoints(resource, result, time.Now(), false); err != nil {
+		response["points_error"] = err.Error()
+	} else {
+		response["points"] = points
+	}
+
+	logs.Info("Resource %d tested successfully", resource.ID)
+	c.JSONResponse(response, nil)
+}
+
+// testFailed reports a failed resource test, with whatever the driver returned alongside the
+// error, such as the body of an HTTP error or of a response the extraction rules did not match.
+func (c *ResourceController) testFailed(resource *model.Resource, result interface{}, err error) {
+	response := map[string]interface{}{
+		"error":       fmt.Sprintf("test failed: %v", err),
+		"code":        400,
+		"resource_id": resource.ID,
+	}
+	if result != nil {
+		response["result"] = result
+	}
+	c.Data["json"] = response
+	c.ServeJSON()
+}
+
+// streamKeepAlive is how often an idle event stream sends a comment to keep proxies from closing it
+const streamKeepAlive = 15 * time.Second
+
+// Stream pushes a resource's value changes as server-sent events (API)
+func (c *ResourceController) Stream() {
+	logs.Info("Received request to stream resource %s", c.Ctx.Input.Param(":id"))
+	id, err := strconv.Atoi(c.Ctx.Input.Param(":id"))
+	if err != nil {
+		logs.Error("Invalid resource ID:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+
+	q := dal.Q
+	resource, err := q.Resource.Where(q.Resource.ID.Eq(uint(id))).First()
+	if err != nil {
+		logs.Error("Failed to find resource:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+	platform, err := q.Platform.Where(q.Platform.ID.Eq(resource.PlatformID)).First()
+	if err != nil {
+		logs.Error("Failed to find platform:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+
+	changes, unsubscribe, err := gateway.Subscriptions().Subscribe(platform, resource)
+	if err != nil {
+		logs.Error("Failed to subscribe to resource:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+	defer unsubscribe()
+
+	c.EnableRender = false
+	w := c.Ctx.ResponseWriter
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-c.Ctx.Request.Context().Done():
+			logs.Info("Stream for resource %d closed by client", id)
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case change, ok := <-changes:
+			if !ok {
+				fmt.Fprint(w, "event: end\ndata: {}\n\n")
+				w.Flush()
 				return
 			}
-			pathURL, err := url.Parse(details.Path)
+			payload, err := json.Marshal(change)
 			if err != nil {
-				logs.Error("Invalid path %s: %v", details.Path, err)
-				c.JSONResponse(nil, fmt.Errorf("invalid path: %w", err))
-				return
-			}
-			fullURL := baseURL.ResolveReference(pathURL)
-			query := fullURL.Query()
-			for key, value := range details.QueryParams {
-				query.Set(key, value)
+				logs.Error("Failed to encode change:", err)
+				continue
 			}
-			fullURL.RawQuery = query.Encode()
-			logs.Info("Testing REST resource %s: %s %s", resource.Name, details.Method, fullURL.String())
+			fmt.Fprintf(w, "data: %s\n\n", payload)
 		}
+		w.Flush()
+	}
+}
 
-		result, err = driver.FetchData(ctx, resource.Details)
-		if err != nil {
-			logs.Error("Failed to test resource %s: %v", resource.Name, err)
-			c.JSONResponse(map[string]interface{}{
-				"resource_id": resource.ID,
-				"error":       err.Error(),
-			}, fmt.Errorf("test failed: %w", err))
-			return
-		}
-		logs.Debug("Test result for resource %s: %v", resource.Name, result)
-	} else {
-		err = fmt.Errorf("incompatible resource type %s for platform type %s", resource.Type, platform.Type)
-		logs.Error("Validation failed: %v", err)
+// writeTimeout bounds a write, including the read of the value it replaces
+const writeTimeout = 15 * time.Second
+
+// Results recorded in the audit entry of a write
+const (
+	writePending   = "pending"
+	writeSucceeded = "success"
+	writeFailed    = "failed"
+)
+
+// writeRequest is the body of a write: a value for setpoints, or a request body for REST actions
+type writeRequest struct {
+	Value interface{} `json:"value"`
+	Body  *string     `json:"body"`
+}
+
+// writeAudit is the metadata of the user interaction recorded for every write
+type writeAudit struct {
+	DeviceID     uint        `json:"device_id"`
+	PlatformID   uint        `json:"platform_id"`
+	ResourceID   uint        `json:"resource_id"`
+	ResourceName string      `json:"resource_name"`
+	ResourceType string      `json:"resource_type"`
+	ApiKeyID     uint        `json:"api_key_id,omitempty"`
+	ClientIP     string      `json:"client_ip"`
+	Value        interface{} `json:"value,omitempty"`
+	Body         *string     `json:"body,omitempty"`
+	Result       string      `json:"result"`
+	OldValue     interface{} `json:"old_value,omitempty"`
+	NewValue     interface{} `json:"new_value,omitempty"`
+	Error        string      `json:"error,omitempty"`
+}
+
+// Write writes a value to a writable resource of a device's platform (API). The write is
+// recorded as a user interaction before it is sent and updated with its outcome; a write that
+// cannot be recorded is refused.
+func (c *ResourceController) Write() {
+	logs.Info("Received POST request to write resource %s of device %s", c.Ctx.Input.Param(":id"), c.Ctx.Input.Param(":device_id"))
+	userID, err := c.authorizeControl()
+	if err != nil {
+		logs.Error("Write refused: %v", err)
+		c.Ctx.Output.SetStatus(403)
+		c.JSONResponse(nil, err)
+		return
+	}
+	deviceID, err := strconv.Atoi(c.Ctx.Input.Param(":device_id"))
+	if err != nil {
+		logs.Error("Invalid device ID:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+	platformID, err := strconv.Atoi(c.Ctx.Input.Param(":platform_id"))
+	if err != nil {
+		logs.Error("Invalid platform ID:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+	id, err := strconv.Atoi(c.Ctx.Input.Param(":id"))
+	if err != nil {
+		logs.Error("Invalid resource ID:", err)
 		c.JSONResponse(nil, err)
 		return
 	}
 
-	logs.Info("Resource %d tested successfully", resource.ID)
+	q := dal.Q
+	dp, err := q.DevicePlatform.Where(
+		q.DevicePlatform.PlatformID.Eq(uint(platformID)),
+		q.DevicePlatform.DeviceID.Eq(uint(deviceID)),
+	).First()
+	if err != nil {
+		logs.Error("Failed to find DevicePlatform:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+	platform, err := q.Platform.Where(q.Platform.ID.Eq(uint(platformID))).First()
+	if err != nil {
+		logs.Error("Failed to find platform:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+	resource, err := q.Resource.Where(q.Resource.ID.Eq(uint(id)), q.Resource.PlatformID.Eq(uint(platformID))).First()
+	if err != nil {
+		logs.Error("Failed to find resource:", err)
+		c.JSONResponse(nil, errors.New("resource not found for platform"))
+		return
+	}
+	if !resource.Writable {
+		c.JSONResponse(nil, fmt.Errorf("resource %s is not writable", resource.Name))
+		return
+	}
+	if err := drivers.CheckWritable(resource.Type); err != nil {
+		c.JSONResponse(nil, err)
+		return
+	}
+
+	// Keep numbers exact, so large integers reach 64-bit registers unchanged
+	var req writeRequest
+	decoder := json.NewDecoder(bytes.NewReader(c.Ctx.Input.RequestBody))
+	decoder.UseNumber()
+	if err := decoder.Decode(&req); err != nil {
+		logs.Error("Failed to decode write request:", err)
+		c.JSONResponse(nil, fmt.Errorf("invalid write request: %w", err))
+		return
+	}
+
+	details, err := drivers.PrepareResourceDetails(platform.Type, resource.Type, resource.Details, dp.DeviceAlias, nil)
+	if err != nil {
+		logs.Error("Failed to prepare details for resource %s: %v", resource.Name, err)
+		c.JSONResponse(nil, err)
+		return
+	}
+
+	// Not tied to the client's request: a write that has started runs to completion and is
+	// audited even if the client disconnects
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	driver, release, err := gateway.Drivers().Acquire(ctx, platform)
+	if err != nil {
+		logs.Error("Failed to connect driver:", err)
+		c.JSONResponse(nil, err)
+		return
+	}
+	writer, ok := driver.(drivers.Writer)
+	if !ok {
+		release(nil)
+		c.JSONResponse(nil, fmt.Errorf("platform type %s does not support writes", platform.Type))
+		return
+	}
+
+	audit := writeAudit{
+		DeviceID:     uint(deviceID),
+		PlatformID:   platform.ID,
+		ResourceID:   resource.ID,
+		ResourceName: resource.Name,
+		ResourceType: resource.Type,
+		ClientIP:     c.Ctx.Input.IP(),
+		Value:        req.Value,
+		Body:         req.Body,
+		Result:       writePending,
+	}
+	if apiKeyID, ok := c.Ctx.Input.GetData("api_key_id").(uint); ok {
+		audit.ApiKeyID = apiKeyID
+	}
+	metadata, _ := json.Marshal(audit)
+	interaction := &model.UserInteraction{UserID: userID, Action: "resource_write", Metadata: string(metadata), Occurred: time.Now()}
+	if err := q.UserInteraction.Create(interaction); err != nil {
+		release(nil)
+		logs.Error("Failed to record write of resource %d, write refused: %v", resource.ID, err)
+		c.JSONResponse(nil, fmt.Errorf("failed to record audit entry, write refused: %w", err))
+		return
+	}
+
+	result, err := writer.WriteData(ctx, details, drivers.WriteValue{Value: req.Value, Body: req.Body})
+	release(err)
+
+	audit.Result = writeSucceeded
+	if result != nil {
+		audit.OldValue, audit.NewValue = result.OldValue, result.NewValue
+	}
+	if err != nil {
+		audit.Result = writeFailed
+		audit.Error = err.Error()
+	}
+	metadata, _ = json.Marshal(audit)
+	if _, updateErr := q.UserInteraction.Where(q.UserInteraction.ID.Eq(interaction.ID)).Update(q.UserInteraction.Metadata, string(metadata)); updateErr != nil {
+		logs.Error("Failed to record outcome of write %d (%s): %v", interaction.ID, audit.Result, updateErr)
+	}
+
+	if err != nil {
+		logs.Error("Failed to write resource %s: %v", resource.Name, err)
+		response := map[string]interface{}{
+			"error":       fmt.Sprintf("write failed: %v", err),
+			"code":        400,
+			"resource_id": resource.ID,
+			"audit_id":    interaction.ID,
+		}
+		if result != nil {
+			response["result"] = result
+		}
+		c.Data["json"] = response
+		c.ServeJSON()
+		return
+	}
+
+	logs.Info("User %d wrote resource %d: %v -> %v", userID, resource.ID, result.OldValue, result.NewValue)
 	c.JSONResponse(map[string]interface{}{
-		"resource_id":   resource.ID,
-		"name":          resource.Name,
-		"type":          resource.Type,
-		"platform_id":   platform.ID,
-		"platform_type": platform.Type,
-		"result":        result,
+		"resource_id": resource.ID,
+		"audit_id":    interaction.ID,
+		"result":      result,
 	}, nil)
 }
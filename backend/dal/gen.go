@@ -16,16 +16,18 @@ import (
 )
 
 var (
-	Q               = new(Query)
-	ApiKey          *apiKey
-	Device          *device
-	DevicePlatform  *devicePlatform
-	Platform        *platform
-	Resource        *resource
-	Site            *site
-	User            *user
-	UserInteraction *userInteraction
-	ValueStream     *valueStream
+	Q                   = new(Query)
+	ApiKey              *apiKey
+	Device              *device
+	DevicePlatform      *devicePlatform
+	Platform            *platform
+	PlatformStateChange *platformStateChange
+	Resource            *resource
+	SensorData          *sensorData
+	Site                *site
+	User                *user
+	UserInteraction     *userInteraction
+	ValueStream         *valueStream
 )
 
 func SetDefault(db *gorm.DB, opts ...gen.DOOption) {
@@ -34,7 +36,9 @@ func SetDefault(db *gorm.DB, opts ...gen.DOOption) {
 	Device = &Q.Device
 	DevicePlatform = &Q.DevicePlatform
 	Platform = &Q.Platform
+	PlatformStateChange = &Q.PlatformStateChange
 	Resource = &Q.Resource
+	SensorData = &Q.SensorData
 	Site = &Q.Site
 	User = &Q.User
 	UserInteraction = &Q.UserInteraction
@@ -43,47 +47,53 @@ func SetDefault(db *gorm.DB, opts ...gen.DOOption) {
 
 func Use(db *gorm.DB, opts ...gen.DOOption) *Query {
 	return &Query{
-		db:              db,
-		ApiKey:          newApiKey(db, opts...),
-		Device:          newDevice(db, opts...),
-		DevicePlatform:  newDevicePlatform(db, opts...),
-		Platform:        newPlatform(db, opts...),
-		Resource:        newResource(db, opts...),
-		Site:            newSite(db, opts...),
-		User:            newUser(db, opts...),
-		UserInteraction: newUserInteraction(db, opts...),
-		ValueStream:     newValueStream(db, opts...),
+		db:                  db,
+		ApiKey:              newApiKey(db, opts...),
+		Device:              newDevice(db, opts...),
+		DevicePlatform:      newDevicePlatform(db, opts...),
+		Platform:            newPlatform(db, opts...),
+		PlatformStateChange: newPlatformStateChange(db, opts...),
+		Resource:            newResource(db, opts...),
+		SensorData:          newSensorData(db, opts...),
+		Site:                newSite(db, opts...),
+		User:                newUser(db, opts...),
+		UserInteraction:     newUserInteraction(db, opts...),
+		ValueStream:         newValueStream(db, opts...),
 	}
 }
 
 type Query struct {
 	db *gorm.DB
 
-	ApiKey          apiKey
-	Device          device
-	DevicePlatform  devicePlatform
-	Platform        platform
-	Resource        resource
-	Site            site
-	User            user
-	UserInteraction userInteraction
-	ValueStream     valueStream
+	ApiKey              apiKey
+	Device              device
+	DevicePlatform      devicePlatform
+	Platform            platform
+	PlatformStateChange platformStateChange
+	Resource            resource
+	SensorData          sensorData
+	Site                site
+	User                user
+	UserInteraction     userInteraction
+	ValueStream         valueStream
 }
 
 func (q *Query) Available() bool { return q.db != nil }
 
 func (q *Query) clone(db *gorm.DB) *Query {
 	return &Query{
-		db:              db,
-		ApiKey:          q.ApiKey.clone(db),
-		Device:          q.Device.clone(db),
-		DevicePlatform:  q.DevicePlatform.clone(db),
-		Platform:        q.Platform.clone(db),
-		Resource:        q.Resource.clone(db),
-		Site:            q.Site.clone(db),
-		User:            q.User.clone(db),
-		UserInteraction: q.UserInteraction.clone(db),
-		ValueStream:     q.ValueStream.clone(db),
+		db:                  db,
+		ApiKey:              q.ApiKey.clone(db),
+		Device:              q.Device.clone(db),
+		DevicePlatform:      q.DevicePlatform.clone(db),
+		Platform:            q.Platform.clone(db),
+		PlatformStateChange: q.PlatformStateChange.clone(db),
+		Resource:            q.Resource.clone(db),
+		SensorData:          q.SensorData.clone(db),
+		Site:                q.Site.clone(db),
+		User:                q.User.clone(db),
+		UserInteraction:     q.UserInteraction.clone(db),
+		ValueStream:         q.ValueStream.clone(db),
 	}
 }
 
@@ -97,42 +107,48 @@ func (q *Query) WriteDB() *Query {
 
 func (q *Query) ReplaceDB(db *gorm.DB) *Query {
 	return &Query{
-		db:              db,
-		ApiKey:          q.ApiKey.replaceDB(db),
-		Device:          q.Device.replaceDB(db),
-		DevicePlatform:  q.DevicePlatform.replaceDB(db),
-		Platform:        q.Platform.replaceDB(db),
-		Resource:        q.Resource.replaceDB(db),
-		Site:            q.Site.replaceDB(db),
-		User:            q.User.replaceDB(db),
-		UserInteraction: q.UserInteraction.replaceDB(db),
-		ValueStream:     q.ValueStream.replaceDB(db),
+		db:                  db,
+		ApiKey:              q.ApiKey.replaceDB(db),
+		Device:              q.Device.replaceDB(db),
+		DevicePlatform:      q.DevicePlatform.replaceDB(db),
+		Platform:            q.Platform.replaceDB(db),
+		PlatformStateChange: q.PlatformStateChange.replaceDB(db),
+		Resource:            q.Resource.replaceDB(db),
+		SensorData:          q.SensorData.replaceDB(db),
+		Site:                q.Site.replaceDB(db),
+		User:                q.User.replaceDB(db),
+		UserInteraction:     q.UserInteraction.replaceDB(db),
+		ValueStream:         q.ValueStream.replaceDB(db),
 	}
 }
 
 type queryCtx struct {
-	ApiKey          IApiKeyDo
-	Device          IDeviceDo
-	DevicePlatform  IDevicePlatformDo
-	Platform        IPlatformDo
-	Resource        IResourceDo
-	Site            ISiteDo
-	User            IUserDo
-	UserInteraction IUserInteractionDo
-	ValueStream     IValueStreamDo
+	ApiKey              IApiKeyDo
+	Device              IDeviceDo
+	DevicePlatform      IDevicePlatformDo
+	Platform            IPlatformDo
+	PlatformStateChange IPlatformStateChangeDo
+	Resource            IResourceDo
+	SensorData          ISensorDataDo
+	Site                ISiteDo
+	User                IUserDo
+	UserInteraction     IUserInteractionDo
+	ValueStream         IValueStreamDo
 }
 
 func (q *Query) WithContext(ctx context.Context) *queryCtx {
 	return &queryCtx{
-		ApiKey:          q.ApiKey.WithContext(ctx),
-		Device:          q.Device.WithContext(ctx),
-		DevicePlatform:  q.DevicePlatform.WithContext(ctx),
-		Platform:        q.Platform.WithContext(ctx),
-		Resource:        q.Resource.WithContext(ctx),
-		Site:            q.Site.WithContext(ctx),
-		User:            q.User.WithContext(ctx),
-		UserInteraction: q.UserInteraction.WithContext(ctx),
-		ValueStream:     q.ValueStream.WithContext(ctx),
+		ApiKey:              q.ApiKey.WithContext(ctx),
+		Device:              q.Device.WithContext(ctx),
+		DevicePlatform:      q.DevicePlatform.WithContext(ctx),
+		Platform:            q.Platform.WithContext(ctx),
+		PlatformStateChange: q.PlatformStateChange.WithContext(ctx),
+		Resource:            q.Resource.WithContext(ctx),
+		SensorData:          q.SensorData.WithContext(ctx),
+		Site:                q.Site.WithContext(ctx),
+		User:                q.User.WithContext(ctx),
+		UserInteraction:     q.UserInteraction.WithContext(ctx),
+		ValueStream:         q.ValueStream.WithContext(ctx),
 	}
 }
 
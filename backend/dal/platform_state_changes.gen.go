@@ -0,0 +1,407 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package dal
+
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"app/model"
+)
+
+func newPlatformStateChange(db *gorm.DB, opts ...gen.DOOption) platformStateChange {
+	_platformStateChange := platformStateChange{}
+
+	_platformStateChange.platformStateChangeDo.UseDB(db, opts...)
+	_platformStateChange.platformStateChangeDo.UseModel(&model.PlatformStateChange{})
+
+	tableName := _platformStateChange.platformStateChangeDo.TableName()
+	_platformStateChange.ALL = field.NewAsterisk(tableName)
+	_platformStateChange.ID = field.NewUint(tableName, "id")
+	_platformStateChange.PlatformID = field.NewUint(tableName, "platform_id")
+	_platformStateChange.FromState = field.NewString(tableName, "from_state")
+	_platformStateChange.ToState = field.NewString(tableName, "to_state")
+	_platformStateChange.Error = field.NewString(tableName, "error")
+	_platformStateChange.LatencyMs = field.NewFloat64(tableName, "latency_ms")
+	_platformStateChange.ChangedAt = field.NewTime(tableName, "changed_at")
+
+	_platformStateChange.fillFieldMap()
+
+	return _platformStateChange
+}
+
+type platformStateChange struct {
+	platformStateChangeDo
+
+	ALL        field.Asterisk
+	ID         field.Uint
+	PlatformID field.Uint
+	FromState  field.String
+	ToState    field.String
+	Error      field.String
+	LatencyMs  field.Float64
+	ChangedAt  field.Time
+
+	fieldMap map[string]field.Expr
+}
+
+func (p platformStateChange) Table(newTableName string) *platformStateChange {
+	p.platformStateChangeDo.UseTable(newTableName)
+	return p.updateTableName(newTableName)
+}
+
+func (p platformStateChange) As(alias string) *platformStateChange {
+	p.platformStateChangeDo.DO = *(p.platformStateChangeDo.As(alias).(*gen.DO))
+	return p.updateTableName(alias)
+}
+
+func (p *platformStateChange) updateTableName(table string) *platformStateChange {
+	p.ALL = field.NewAsterisk(table)
+	p.ID = field.NewUint(table, "id")
+	p.PlatformID = field.NewUint(table, "platform_id")
+	p.FromState = field.NewString(table, "from_state")
+	p.ToState = field.NewString(table, "to_state")
+	p.Error = field.NewString(table, "error")
+	p.LatencyMs = field.NewFloat64(table, "latency_ms")
+	p.ChangedAt = field.NewTime(table, "changed_at")
+
+	p.fillFieldMap()
+
+	return p
+}
+
+func (p *platformStateChange) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := p.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (p *platformStateChange) fillFieldMap() {
+	p.fieldMap = make(map[string]field.Expr, 7)
+	p.fieldMap["id"] = p.ID
+	p.fieldMap["platform_id"] = p.PlatformID
+	p.fieldMap["from_state"] = p.FromState
+	p.fieldMap["to_state"] = p.ToState
+	p.fieldMap["error"] = p.Error
+	p.fieldMap["latency_ms"] = p.LatencyMs
+	p.fieldMap["changed_at"] = p.ChangedAt
+}
+
+func (p platformStateChange) clone(db *gorm.DB) platformStateChange {
+	p.platformStateChangeDo.ReplaceConnPool(db.Statement.ConnPool)
+	return p
+}
+
+func (p platformStateChange) replaceDB(db *gorm.DB) platformStateChange {
+	p.platformStateChangeDo.ReplaceDB(db)
+	return p
+}
+
+type platformStateChangeDo struct{ gen.DO }
+
+type IPlatformStateChangeDo interface {
+	gen.SubQuery
+	Debug() IPlatformStateChangeDo
+	WithContext(ctx context.Context) IPlatformStateChangeDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() IPlatformStateChangeDo
+	WriteDB() IPlatformStateChangeDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) IPlatformStateChangeDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) IPlatformStateChangeDo
+	Not(conds ...gen.Condition) IPlatformStateChangeDo
+	Or(conds ...gen.Condition) IPlatformStateChangeDo
+	Select(conds ...field.Expr) IPlatformStateChangeDo
+	Where(conds ...gen.Condition) IPlatformStateChangeDo
+	Order(conds ...field.Expr) IPlatformStateChangeDo
+	Distinct(cols ...field.Expr) IPlatformStateChangeDo
+	Omit(cols ...field.Expr) IPlatformStateChangeDo
+	Join(table schema.Tabler, on ...field.Expr) IPlatformStateChangeDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) IPlatformStateChangeDo
+	RightJoin(table schema.Tabler, on ...field.Expr) IPlatformStateChangeDo
+	Group(cols ...field.Expr) IPlatformStateChangeDo
+	Having(conds ...gen.Condition) IPlatformStateChangeDo
+	Limit(limit int) IPlatformStateChangeDo
+	Offset(offset int) IPlatformStateChangeDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) IPlatformStateChangeDo
+	Unscoped() IPlatformStateChangeDo
+	Create(values ...*model.PlatformStateChange) error
+	CreateInBatches(values []*model.PlatformStateChange, batchSize int) error
+	Save(values ...*model.PlatformStateChange) error
+	First() (*model.PlatformStateChange, error)
+	Take() (*model.PlatformStateChange, error)
+	Last() (*model.PlatformStateChange, error)
+	Find() ([]*model.PlatformStateChange, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.PlatformStateChange, err error)
+	FindInBatches(result *[]*model.PlatformStateChange, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.PlatformStateChange) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) IPlatformStateChangeDo
+	Assign(attrs ...field.AssignExpr) IPlatformStateChangeDo
+	Joins(fields ...field.RelationField) IPlatformStateChangeDo
+	Preload(fields ...field.RelationField) IPlatformStateChangeDo
+	FirstOrInit() (*model.PlatformStateChange, error)
+	FirstOrCreate() (*model.PlatformStateChange, error)
+	FindByPage(offset int, limit int) (result []*model.PlatformStateChange, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Rows() (*sql.Rows, error)
+	Row() *sql.Row
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) IPlatformStateChangeDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (p platformStateChangeDo) Debug() IPlatformStateChangeDo {
+	return p.withDO(p.DO.Debug())
+}
+
+func (p platformStateChangeDo) WithContext(ctx context.Context) IPlatformStateChangeDo {
+	return p.withDO(p.DO.WithContext(ctx))
+}
+
+func (p platformStateChangeDo) ReadDB() IPlatformStateChangeDo {
+	return p.Clauses(dbresolver.Read)
+}
+
+func (p platformStateChangeDo) WriteDB() IPlatformStateChangeDo {
+	return p.Clauses(dbresolver.Write)
+}
+
+func (p platformStateChangeDo) Session(config *gorm.Session) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Session(config))
+}
+
+func (p platformStateChangeDo) Clauses(conds ...clause.Expression) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Clauses(conds...))
+}
+
+func (p platformStateChangeDo) Returning(value interface{}, columns ...string) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Returning(value, columns...))
+}
+
+func (p platformStateChangeDo) Not(conds ...gen.Condition) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Not(conds...))
+}
+
+func (p platformStateChangeDo) Or(conds ...gen.Condition) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Or(conds...))
+}
+
+func (p platformStateChangeDo) Select(conds ...field.Expr) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Select(conds...))
+}
+
+func (p platformStateChangeDo) Where(conds ...gen.Condition) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Where(conds...))
+}
+
+func (p platformStateChangeDo) Order(conds ...field.Expr) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Order(conds...))
+}
+
+func (p platformStateChangeDo) Distinct(cols ...field.Expr) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Distinct(cols...))
+}
+
+func (p platformStateChangeDo) Omit(cols ...field.Expr) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Omit(cols...))
+}
+
+func (p platformStateChangeDo) Join(table schema.Tabler, on ...field.Expr) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Join(table, on...))
+}
+
+func (p platformStateChangeDo) LeftJoin(table schema.Tabler, on ...field.Expr) IPlatformStateChangeDo {
+	return p.withDO(p.DO.LeftJoin(table, on...))
+}
+
+func (p platformStateChangeDo) RightJoin(table schema.Tabler, on ...field.Expr) IPlatformStateChangeDo {
+	return p.withDO(p.DO.RightJoin(table, on...))
+}
+
+func (p platformStateChangeDo) Group(cols ...field.Expr) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Group(cols...))
+}
+
+func (p platformStateChangeDo) Having(conds ...gen.Condition) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Having(conds...))
+}
+
+func (p platformStateChangeDo) Limit(limit int) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Limit(limit))
+}
+
+func (p platformStateChangeDo) Offset(offset int) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Offset(offset))
+}
+
+func (p platformStateChangeDo) Scopes(funcs ...func(gen.Dao) gen.Dao) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Scopes(funcs...))
+}
+
+func (p platformStateChangeDo) Unscoped() IPlatformStateChangeDo {
+	return p.withDO(p.DO.Unscoped())
+}
+
+func (p platformStateChangeDo) Create(values ...*model.PlatformStateChange) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return p.DO.Create(values)
+}
+
+func (p platformStateChangeDo) CreateInBatches(values []*model.PlatformStateChange, batchSize int) error {
+	return p.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (p platformStateChangeDo) Save(values ...*model.PlatformStateChange) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return p.DO.Save(values)
+}
+
+func (p platformStateChangeDo) First() (*model.PlatformStateChange, error) {
+	if result, err := p.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.PlatformStateChange), nil
+	}
+}
+
+func (p platformStateChangeDo) Take() (*model.PlatformStateChange, error) {
+	if result, err := p.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.PlatformStateChange), nil
+	}
+}
+
+func (p platformStateChangeDo) Last() (*model.PlatformStateChange, error) {
+	if result, err := p.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.PlatformStateChange), nil
+	}
+}
+
+func (p platformStateChangeDo) Find() ([]*model.PlatformStateChange, error) {
+	result, err := p.DO.Find()
+	return result.([]*model.PlatformStateChange), err
+}
+
+func (p platformStateChangeDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.PlatformStateChange, err error) {
+	buf := make([]*model.PlatformStateChange, 0, batchSize)
+	err = p.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (p platformStateChangeDo) FindInBatches(result *[]*model.PlatformStateChange, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return p.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (p platformStateChangeDo) Attrs(attrs ...field.AssignExpr) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Attrs(attrs...))
+}
+
+func (p platformStateChangeDo) Assign(attrs ...field.AssignExpr) IPlatformStateChangeDo {
+	return p.withDO(p.DO.Assign(attrs...))
+}
+
+func (p platformStateChangeDo) Joins(fields ...field.RelationField) IPlatformStateChangeDo {
+	for _, _f := range fields {
+		p = *p.withDO(p.DO.Joins(_f))
+	}
+	return &p
+}
+
+func (p platformStateChangeDo) Preload(fields ...field.RelationField) IPlatformStateChangeDo {
+	for _, _f := range fields {
+		p = *p.withDO(p.DO.Preload(_f))
+	}
+	return &p
+}
+
+func (p platformStateChangeDo) FirstOrInit() (*model.PlatformStateChange, error) {
+	if result, err := p.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.PlatformStateChange), nil
+	}
+}
+
+func (p platformStateChangeDo) FirstOrCreate() (*model.PlatformStateChange, error) {
+	if result, err := p.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.PlatformStateChange), nil
+	}
+}
+
+func (p platformStateChangeDo) FindByPage(offset int, limit int) (result []*model.PlatformStateChange, count int64, err error) {
+	result, err = p.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = p.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (p platformStateChangeDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = p.Count()
+	if err != nil {
+		return
+	}
+
+	err = p.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (p platformStateChangeDo) Scan(result interface{}) (err error) {
+	return p.DO.Scan(result)
+}
+
+func (p platformStateChangeDo) Delete(models ...*model.PlatformStateChange) (result gen.ResultInfo, err error) {
+	return p.DO.Delete(models)
+}
+
+func (p *platformStateChangeDo) withDO(do gen.Dao) *platformStateChangeDo {
+	p.DO = *do.(*gen.DO)
+	return p
+}
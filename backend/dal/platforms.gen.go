@@ -37,6 +37,7 @@ func newPlatform(db *gorm.DB, opts ...gen.DOOption) platform {
 	_platform.Type = field.NewString(tableName, "type")
 	_platform.ConnectionState = field.NewString(tableName, "connection_state")
 	_platform.LastConnected = field.NewTime(tableName, "last_connected")
+	_platform.LastError = field.NewString(tableName, "last_error")
 	_platform.OrganizationID = field.NewInt(tableName, "organization_id")
 	_platform.IsActive = field.NewBool(tableName, "is_active")
 	_platform.Metadata = field.NewString(tableName, "metadata")
@@ -116,6 +117,7 @@ type platform struct {
 	Type            field.String
 	ConnectionState field.String
 	LastConnected   field.Time
+	LastError       field.String
 	OrganizationID  field.Int
 	IsActive        field.Bool
 	Metadata        field.String
@@ -146,6 +148,7 @@ func (p *platform) updateTableName(table string) *platform {
 	p.Type = field.NewString(table, "type")
 	p.ConnectionState = field.NewString(table, "connection_state")
 	p.LastConnected = field.NewTime(table, "last_connected")
+	p.LastError = field.NewString(table, "last_error")
 	p.OrganizationID = field.NewInt(table, "organization_id")
 	p.IsActive = field.NewBool(table, "is_active")
 	p.Metadata = field.NewString(table, "metadata")
@@ -165,7 +168,7 @@ func (p *platform) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (p *platform) fillFieldMap() {
-	p.fieldMap = make(map[string]field.Expr, 13)
+	p.fieldMap = make(map[string]field.Expr, 14)
 	p.fieldMap["id"] = p.ID
 	p.fieldMap["created_at"] = p.CreatedAt
 	p.fieldMap["updated_at"] = p.UpdatedAt
@@ -174,6 +177,7 @@ func (p *platform) fillFieldMap() {
 	p.fieldMap["type"] = p.Type
 	p.fieldMap["connection_state"] = p.ConnectionState
 	p.fieldMap["last_connected"] = p.LastConnected
+	p.fieldMap["last_error"] = p.LastError
 	p.fieldMap["organization_id"] = p.OrganizationID
 	p.fieldMap["is_active"] = p.IsActive
 	p.fieldMap["metadata"] = p.Metadata
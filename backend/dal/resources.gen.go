@@ -38,6 +38,7 @@ func newResource(db *gorm.DB, opts ...gen.DOOption) resource {
 	_resource.Type = field.NewString(tableName, "type")
 	_resource.Details = field.NewString(tableName, "details")
 	_resource.Metadata = field.NewString(tableName, "metadata")
+	_resource.Writable = field.NewBool(tableName, "writable")
 
 	_resource.fillFieldMap()
 
@@ -57,6 +58,7 @@ type resource struct {
 	Type       field.String
 	Details    field.String
 	Metadata   field.String
+	Writable   field.Bool
 
 	fieldMap map[string]field.Expr
 }
@@ -82,6 +84,7 @@ func (r *resource) updateTableName(table string) *resource {
 	r.Type = field.NewString(table, "type")
 	r.Details = field.NewString(table, "details")
 	r.Metadata = field.NewString(table, "metadata")
+	r.Writable = field.NewBool(table, "writable")
 
 	r.fillFieldMap()
 
@@ -98,7 +101,7 @@ func (r *resource) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (r *resource) fillFieldMap() {
-	r.fieldMap = make(map[string]field.Expr, 9)
+	r.fieldMap = make(map[string]field.Expr, 10)
 	r.fieldMap["id"] = r.ID
 	r.fieldMap["created_at"] = r.CreatedAt
 	r.fieldMap["updated_at"] = r.UpdatedAt
@@ -108,6 +111,7 @@ func (r *resource) fillFieldMap() {
 	r.fieldMap["type"] = r.Type
 	r.fieldMap["details"] = r.Details
 	r.fieldMap["metadata"] = r.Metadata
+	r.fieldMap["writable"] = r.Writable
 }
 
 func (r resource) clone(db *gorm.DB) resource {
@@ -0,0 +1,411 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package dal
+
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"app/model"
+)
+
+func newSensorData(db *gorm.DB, opts ...gen.DOOption) sensorData {
+	_sensorData := sensorData{}
+
+	_sensorData.sensorDataDo.UseDB(db, opts...)
+	_sensorData.sensorDataDo.UseModel(&model.SensorData{})
+
+	tableName := _sensorData.sensorDataDo.TableName()
+	_sensorData.ALL = field.NewAsterisk(tableName)
+	_sensorData.DeviceID = field.NewUint(tableName, "device_id")
+	_sensorData.ResourceID = field.NewUint(tableName, "resource_id")
+	_sensorData.Timestamp = field.NewTime(tableName, "timestamp")
+	_sensorData.NumericValue = field.NewFloat64(tableName, "numeric_value")
+	_sensorData.StringValue = field.NewString(tableName, "string_value")
+	_sensorData.BoolValue = field.NewBool(tableName, "bool_value")
+	_sensorData.Quality = field.NewString(tableName, "quality")
+	_sensorData.CreatedAt = field.NewTime(tableName, "created_at")
+
+	_sensorData.fillFieldMap()
+
+	return _sensorData
+}
+
+type sensorData struct {
+	sensorDataDo
+
+	ALL          field.Asterisk
+	DeviceID     field.Uint
+	ResourceID   field.Uint
+	Timestamp    field.Time
+	NumericValue field.Float64
+	StringValue  field.String
+	BoolValue    field.Bool
+	Quality      field.String
+	CreatedAt    field.Time
+
+	fieldMap map[string]field.Expr
+}
+
+func (s sensorData) Table(newTableName string) *sensorData {
+	s.sensorDataDo.UseTable(newTableName)
+	return s.updateTableName(newTableName)
+}
+
+func (s sensorData) As(alias string) *sensorData {
+	s.sensorDataDo.DO = *(s.sensorDataDo.As(alias).(*gen.DO))
+	return s.updateTableName(alias)
+}
+
+func (s *sensorData) updateTableName(table string) *sensorData {
+	s.ALL = field.NewAsterisk(table)
+	s.DeviceID = field.NewUint(table, "device_id")
+	s.ResourceID = field.NewUint(table, "resource_id")
+	s.Timestamp = field.NewTime(table, "timestamp")
+	s.NumericValue = field.NewFloat64(table, "numeric_value")
+	s.StringValue = field.NewString(table, "string_value")
+	s.BoolValue = field.NewBool(table, "bool_value")
+	s.Quality = field.NewString(table, "quality")
+	s.CreatedAt = field.NewTime(table, "created_at")
+
+	s.fillFieldMap()
+
+	return s
+}
+
+func (s *sensorData) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := s.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (s *sensorData) fillFieldMap() {
+	s.fieldMap = make(map[string]field.Expr, 8)
+	s.fieldMap["device_id"] = s.DeviceID
+	s.fieldMap["resource_id"] = s.ResourceID
+	s.fieldMap["timestamp"] = s.Timestamp
+	s.fieldMap["numeric_value"] = s.NumericValue
+	s.fieldMap["string_value"] = s.StringValue
+	s.fieldMap["bool_value"] = s.BoolValue
+	s.fieldMap["quality"] = s.Quality
+	s.fieldMap["created_at"] = s.CreatedAt
+}
+
+func (s sensorData) clone(db *gorm.DB) sensorData {
+	s.sensorDataDo.ReplaceConnPool(db.Statement.ConnPool)
+	return s
+}
+
+func (s sensorData) replaceDB(db *gorm.DB) sensorData {
+	s.sensorDataDo.ReplaceDB(db)
+	return s
+}
+
+type sensorDataDo struct{ gen.DO }
+
+type ISensorDataDo interface {
+	gen.SubQuery
+	Debug() ISensorDataDo
+	WithContext(ctx context.Context) ISensorDataDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() ISensorDataDo
+	WriteDB() ISensorDataDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) ISensorDataDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) ISensorDataDo
+	Not(conds ...gen.Condition) ISensorDataDo
+	Or(conds ...gen.Condition) ISensorDataDo
+	Select(conds ...field.Expr) ISensorDataDo
+	Where(conds ...gen.Condition) ISensorDataDo
+	Order(conds ...field.Expr) ISensorDataDo
+	Distinct(cols ...field.Expr) ISensorDataDo
+	Omit(cols ...field.Expr) ISensorDataDo
+	Join(table schema.Tabler, on ...field.Expr) ISensorDataDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) ISensorDataDo
+	RightJoin(table schema.Tabler, on ...field.Expr) ISensorDataDo
+	Group(cols ...field.Expr) ISensorDataDo
+	Having(conds ...gen.Condition) ISensorDataDo
+	Limit(limit int) ISensorDataDo
+	Offset(offset int) ISensorDataDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) ISensorDataDo
+	Unscoped() ISensorDataDo
+	Create(values ...*model.SensorData) error
+	CreateInBatches(values []*model.SensorData, batchSize int) error
+	Save(values ...*model.SensorData) error
+	First() (*model.SensorData, error)
+	Take() (*model.SensorData, error)
+	Last() (*model.SensorData, error)
+	Find() ([]*model.SensorData, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.SensorData, err error)
+	FindInBatches(result *[]*model.SensorData, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.SensorData) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) ISensorDataDo
+	Assign(attrs ...field.AssignExpr) ISensorDataDo
+	Joins(fields ...field.RelationField) ISensorDataDo
+	Preload(fields ...field.RelationField) ISensorDataDo
+	FirstOrInit() (*model.SensorData, error)
+	FirstOrCreate() (*model.SensorData, error)
+	FindByPage(offset int, limit int) (result []*model.SensorData, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Rows() (*sql.Rows, error)
+	Row() *sql.Row
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) ISensorDataDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (s sensorDataDo) Debug() ISensorDataDo {
+	return s.withDO(s.DO.Debug())
+}
+
+func (s sensorDataDo) WithContext(ctx context.Context) ISensorDataDo {
+	return s.withDO(s.DO.WithContext(ctx))
+}
+
+func (s sensorDataDo) ReadDB() ISensorDataDo {
+	return s.Clauses(dbresolver.Read)
+}
+
+func (s sensorDataDo) WriteDB() ISensorDataDo {
+	return s.Clauses(dbresolver.Write)
+}
+
+func (s sensorDataDo) Session(config *gorm.Session) ISensorDataDo {
+	return s.withDO(s.DO.Session(config))
+}
+
+func (s sensorDataDo) Clauses(conds ...clause.Expression) ISensorDataDo {
+	return s.withDO(s.DO.Clauses(conds...))
+}
+
+func (s sensorDataDo) Returning(value interface{}, columns ...string) ISensorDataDo {
+	return s.withDO(s.DO.Returning(value, columns...))
+}
+
+func (s sensorDataDo) Not(conds ...gen.Condition) ISensorDataDo {
+	return s.withDO(s.DO.Not(conds...))
+}
+
+func (s sensorDataDo) Or(conds ...gen.Condition) ISensorDataDo {
+	return s.withDO(s.DO.Or(conds...))
+}
+
+func (s sensorDataDo) Select(conds ...field.Expr) ISensorDataDo {
+	return s.withDO(s.DO.Select(conds...))
+}
+
+func (s sensorDataDo) Where(conds ...gen.Condition) ISensorDataDo {
+	return s.withDO(s.DO.Where(conds...))
+}
+
+func (s sensorDataDo) Order(conds ...field.Expr) ISensorDataDo {
+	return s.withDO(s.DO.Order(conds...))
+}
+
+func (s sensorDataDo) Distinct(cols ...field.Expr) ISensorDataDo {
+	return s.withDO(s.DO.Distinct(cols...))
+}
+
+func (s sensorDataDo) Omit(cols ...field.Expr) ISensorDataDo {
+	return s.withDO(s.DO.Omit(cols...))
+}
+
+func (s sensorDataDo) Join(table schema.Tabler, on ...field.Expr) ISensorDataDo {
+	return s.withDO(s.DO.Join(table, on...))
+}
+
+func (s sensorDataDo) LeftJoin(table schema.Tabler, on ...field.Expr) ISensorDataDo {
+	return s.withDO(s.DO.LeftJoin(table, on...))
+}
+
+func (s sensorDataDo) RightJoin(table schema.Tabler, on ...field.Expr) ISensorDataDo {
+	return s.withDO(s.DO.RightJoin(table, on...))
+}
+
+func (s sensorDataDo) Group(cols ...field.Expr) ISensorDataDo {
+	return s.withDO(s.DO.Group(cols...))
+}
+
+func (s sensorDataDo) Having(conds ...gen.Condition) ISensorDataDo {
+	return s.withDO(s.DO.Having(conds...))
+}
+
+func (s sensorDataDo) Limit(limit int) ISensorDataDo {
+	return s.withDO(s.DO.Limit(limit))
+}
+
+func (s sensorDataDo) Offset(offset int) ISensorDataDo {
+	return s.withDO(s.DO.Offset(offset))
+}
+
+func (s sensorDataDo) Scopes(funcs ...func(gen.Dao) gen.Dao) ISensorDataDo {
+	return s.withDO(s.DO.Scopes(funcs...))
+}
+
+func (s sensorDataDo) Unscoped() ISensorDataDo {
+	return s.withDO(s.DO.Unscoped())
+}
+
+func (s sensorDataDo) Create(values ...*model.SensorData) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return s.DO.Create(values)
+}
+
+func (s sensorDataDo) CreateInBatches(values []*model.SensorData, batchSize int) error {
+	return s.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (s sensorDataDo) Save(values ...*model.SensorData) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return s.DO.Save(values)
+}
+
+func (s sensorDataDo) First() (*model.SensorData, error) {
+	if result, err := s.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.SensorData), nil
+	}
+}
+
+func (s sensorDataDo) Take() (*model.SensorData, error) {
+	if result, err := s.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.SensorData), nil
+	}
+}
+
+func (s sensorDataDo) Last() (*model.SensorData, error) {
+	if result, err := s.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.SensorData), nil
+	}
+}
+
+func (s sensorDataDo) Find() ([]*model.SensorData, error) {
+	result, err := s.DO.Find()
+	return result.([]*model.SensorData), err
+}
+
+func (s sensorDataDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.SensorData, err error) {
+	buf := make([]*model.SensorData, 0, batchSize)
+	err = s.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (s sensorDataDo) FindInBatches(result *[]*model.SensorData, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return s.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (s sensorDataDo) Attrs(attrs ...field.AssignExpr) ISensorDataDo {
+	return s.withDO(s.DO.Attrs(attrs...))
+}
+
+func (s sensorDataDo) Assign(attrs ...field.AssignExpr) ISensorDataDo {
+	return s.withDO(s.DO.Assign(attrs...))
+}
+
+func (s sensorDataDo) Joins(fields ...field.RelationField) ISensorDataDo {
+	for _, _f := range fields {
+		s = *s.withDO(s.DO.Joins(_f))
+	}
+	return &s
+}
+
+func (s sensorDataDo) Preload(fields ...field.RelationField) ISensorDataDo {
+	for _, _f := range fields {
+		s = *s.withDO(s.DO.Preload(_f))
+	}
+	return &s
+}
+
+func (s sensorDataDo) FirstOrInit() (*model.SensorData, error) {
+	if result, err := s.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.SensorData), nil
+	}
+}
+
+func (s sensorDataDo) FirstOrCreate() (*model.SensorData, error) {
+	if result, err := s.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.SensorData), nil
+	}
+}
+
+func (s sensorDataDo) FindByPage(offset int, limit int) (result []*model.SensorData, count int64, err error) {
+	result, err = s.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = s.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (s sensorDataDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = s.Count()
+	if err != nil {
+		return
+	}
+
+	err = s.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (s sensorDataDo) Scan(result interface{}) (err error) {
+	return s.DO.Scan(result)
+}
+
+func (s sensorDataDo) Delete(models ...*model.SensorData) (result gen.ResultInfo, err error) {
+	return s.DO.Delete(models)
+}
+
+func (s *sensorDataDo) withDO(do gen.Dao) *sensorDataDo {
+	s.DO = *do.(*gen.DO)
+	return s
+}
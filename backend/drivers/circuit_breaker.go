@@ -0,0 +1,116 @@
+package drivers
+
+import (
+	"app/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	circuitBreakerKey      = "circuit_breaker" // Metadata key every platform type accepts
+	circuitDefaultCoolDown = 30 * time.Second
+	circuitDefaultHalfOpen = 1
+)
+
+// CircuitBreakerPolicy is the parsed circuit breaker configuration of a platform.
+type CircuitBreakerPolicy struct {
+	FailureThreshold int           // Failed requests in a row that open the circuit
+	CoolDown         time.Duration // How long the circuit stays open
+	HalfOpenRequests int           // Trial requests let through after the cool-down
+}
+
+// ParseCircuitBreaker returns the circuit breaker configured in platform metadata, or nil when
+// there is none.
+func ParseCircuitBreaker(metadata string) (*CircuitBreakerPolicy, error) {
+	var settings struct {
+		CircuitBreaker *model.CircuitBreaker `json:"circuit_breaker"`
+	}
+	if err := json.Unmarshal([]byte(metadata), &settings); err != nil || settings.CircuitBreaker == nil {
+		return nil, nil
+	}
+	return newCircuitBreakerPolicy(settings.CircuitBreaker)
+}
+
+// newCircuitBreakerPolicy parses circuit breaker settings, using the defaults for those left
+// empty.
+func newCircuitBreakerPolicy(config *model.CircuitBreaker) (*CircuitBreakerPolicy, error) {
+	if config.FailureThreshold < 1 {
+		return nil, errors.New("circuit_breaker.failure_threshold must be at least 1")
+	}
+	policy := &CircuitBreakerPolicy{
+		FailureThreshold: config.FailureThreshold,
+		CoolDown:         circuitDefaultCoolDown,
+		HalfOpenRequests: circuitDefaultHalfOpen,
+	}
+	if config.CoolDown != "" {
+		d, err := time.ParseDuration(config.CoolDown)
+		if err != nil || d <= 0 {
+			return nil, errors.New(`circuit_breaker.cool_down must be a positive duration such as "30s"`)
+		}
+		policy.CoolDown = d
+	}
+	if config.HalfOpenRequests < 0 {
+		return nil, errors.New("circuit_breaker.half_open_requests cannot be negative")
+	}
+	if config.HalfOpenRequests > 0 {
+		policy.HalfOpenRequests = config.HalfOpenRequests
+	}
+	return policy, nil
+}
+
+// splitCircuitBreaker removes the circuit breaker settings from platform metadata, so the
+// driver's schema and validator only see their own settings, and returns them validated with
+// the defaults filled in.
+func splitCircuitBreaker(metadata string) (string, *model.CircuitBreaker, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(metadata), &fields); err != nil {
+		return metadata, nil, nil // Not an object, left to the schema and validator
+	}
+	raw, ok := fields[circuitBreakerKey]
+	if !ok {
+		return metadata, nil, nil
+	}
+	delete(fields, circuitBreakerKey)
+	rest, err := json.Marshal(fields)
+	if err != nil {
+		return "", nil, err
+	}
+	if string(raw) == "null" {
+		return string(rest), nil, nil
+	}
+
+	var config model.CircuitBreaker
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return "", nil, fmt.Errorf("invalid circuit_breaker: %w", err)
+	}
+	policy, err := newCircuitBreakerPolicy(&config)
+	if err != nil {
+		return "", nil, err
+	}
+	config.CoolDown = policy.CoolDown.String()
+	config.HalfOpenRequests = policy.HalfOpenRequests
+	return string(rest), &config, nil
+}
+
+// withCircuitBreaker adds circuit breaker settings back to sanitized platform metadata.
+func withCircuitBreaker(metadata string, config *model.CircuitBreaker) (string, error) {
+	if config == nil {
+		return metadata, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(metadata), &fields); err != nil {
+		return "", errors.New("failed to serialize sanitized metadata")
+	}
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return "", errors.New("failed to serialize sanitized metadata")
+	}
+	fields[circuitBreakerKey] = raw
+	serialized, err := json.Marshal(fields)
+	if err != nil {
+		return "", errors.New("failed to serialize sanitized metadata")
+	}
+	return string(serialized), nil
+}
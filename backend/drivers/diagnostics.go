@@ -0,0 +1,263 @@
+package drivers
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Connection test stages, in the order they run.
+const (
+	StageDNS  = "dns"  // Resolve the platform's host name
+	StageTCP  = "tcp"  // Open a TCP connection to the platform
+	StageTLS  = "tls"  // Complete the TLS handshake, for endpoints that start with one
+	StageAuth = "auth" // Speak the platform's protocol with the configured credentials
+)
+
+// Locator is implemented by drivers that talk to a network endpoint, so connection tests can
+// check name resolution, reachability and TLS before the protocol itself.
+type Locator interface {
+	// Endpoint returns the address the driver connects to, or nil when it does not connect
+	// over TCP, e.g. through a Unix socket.
+	Endpoint() (*Endpoint, error)
+}
+
+// Endpoint is the network address of a platform.
+type Endpoint struct {
+	Host  string
+	Port  string
+	TLS   *tls.Config // Set when the connection starts with a TLS handshake
+	Proxy *url.URL    // Set when connections go through an HTTP proxy
+}
+
+// Identifier is implemented by drivers that can report what software the platform runs.
+type Identifier interface {
+	// Identify returns the product and version the platform reports about itself.
+	Identify(ctx context.Context) (*ServerInfo, error)
+}
+
+// ServerInfo describes the software answering on a platform's endpoint.
+type ServerInfo struct {
+	Product string `json:"product,omitempty"`
+	Version string `json:"version,omitempty"`
+	Vendor  string `json:"vendor,omitempty"`
+}
+
+// CertificateInfo summarizes the certificate a platform presented during the TLS handshake.
+type CertificateInfo struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	DNSNames  []string  `json:"dns_names,omitempty"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+// StageResult is the outcome of one connection test stage.
+type StageResult struct {
+	Stage     string  `json:"stage"`
+	Success   bool    `json:"success"`
+	LatencyMs float64 `json:"latency_ms"`
+	Detail    string  `json:"detail,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Diagnostics is the result of a connection test. Stages stop at the first failure, which is
+// named by FailedStage.
+type Diagnostics struct {
+	Success     bool             `json:"success"`
+	FailedStage string           `json:"failed_stage,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	AuthFailed  bool             `json:"auth_failed,omitempty"` // The platform rejected the configured credentials
+	LatencyMs   float64          `json:"latency_ms"`
+	Stages      []StageResult    `json:"stages"`
+	Server      *ServerInfo      `json:"server,omitempty"`
+	Certificate *CertificateInfo `json:"certificate,omitempty"`
+	TestedAt    time.Time        `json:"tested_at"`
+}
+
+// Diagnose tests a driver's connection stage by stage. Drivers that are not a Locator only run
+// the auth stage, which calls ValidateConfig.
+func Diagnose(ctx context.Context, driver PlatformDriver) *Diagnostics {
+	diag := &Diagnostics{Stages: []StageResult{}, TestedAt: time.Now()}
+	start := time.Now()
+	defer func() { diag.LatencyMs = milliseconds(time.Since(start)) }()
+
+	if locator, ok := driver.(Locator); ok {
+		endpoint, err := locator.Endpoint()
+		if err != nil {
+			diag.fail(StageDNS, 0, err)
+			return diag
+		}
+		if endpoint != nil && !diag.checkEndpoint(ctx, endpoint) {
+			return diag
+		}
+	}
+
+	stageStart := time.Now()
+	if err := driver.ValidateConfig(ctx); err != nil {
+		diag.fail(StageAuth, time.Since(stageStart), err)
+		diag.AuthFailed = errors.Is(err, ErrAuthFailed)
+		return diag
+	}
+	diag.pass(StageAuth, time.Since(stageStart), "")
+	diag.Success = true
+
+	// Identification is informational, so a platform that will not say is still reachable
+	if identifier, ok := driver.(Identifier); ok {
+		if info, err := identifier.Identify(ctx); err == nil {
+			diag.Server = info
+		}
+	}
+	return diag
+}
+
+// checkEndpoint runs the DNS, TCP and TLS stages and reports whether they all passed. Behind a
+// proxy, the DNS and TCP stages check the proxy, and TLS endpoints are reached through a
+// CONNECT tunnel as the drivers reach them.
+func (diag *Diagnostics) checkEndpoint(ctx context.Context, endpoint *Endpoint) bool {
+	host, port := endpoint.Host, endpoint.Port
+	if endpoint.Proxy != nil {
+		host, port = endpoint.Proxy.Hostname(), endpoint.Proxy.Port()
+		if port == "" {
+			port = map[string]string{"http": "80", "https": "443"}[endpoint.Proxy.Scheme]
+		}
+	}
+
+	stageStart := time.Now()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		diag.fail(StageDNS, time.Since(stageStart), err)
+		return false
+	}
+	diag.pass(StageDNS, time.Since(stageStart), fmt.Sprintf("%s resolved to %v", host, addrs))
+
+	stageStart = time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		diag.fail(StageTCP, time.Since(stageStart), err)
+		return false
+	}
+	defer conn.Close()
+	detail := "connected to " + conn.RemoteAddr().String()
+	if endpoint.Proxy != nil {
+		// Plain HTTP requests are forwarded by the proxy, so only TLS endpoints need a tunnel
+		detail = "connected to proxy " + conn.RemoteAddr().String()
+		if endpoint.TLS != nil {
+			tunnel, err := proxyConnect(ctx, conn, endpoint.Proxy, net.JoinHostPort(endpoint.Host, endpoint.Port))
+			if err != nil {
+				diag.fail(StageTCP, time.Since(stageStart), err)
+				return false
+			}
+			conn = tunnel
+			detail += ", tunneled to " + net.JoinHostPort(endpoint.Host, endpoint.Port)
+		}
+	}
+	diag.pass(StageTCP, time.Since(stageStart), detail)
+
+	if endpoint.TLS == nil {
+		return true
+	}
+	config := endpoint.TLS.Clone()
+	if config.ServerName == "" {
+		config.ServerName = endpoint.Host
+	}
+	stageStart = time.Now()
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		diag.fail(StageTLS, time.Since(stageStart), err)
+		return false
+	}
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) > 0 {
+		diag.Certificate = certificateInfo(state.PeerCertificates[0])
+	}
+	detail = tls.VersionName(state.Version) + " " + tls.CipherSuiteName(state.CipherSuite)
+	if config.InsecureSkipVerify {
+		detail += ", certificate not verified (insecure_skip_verify)"
+	}
+	diag.pass(StageTLS, time.Since(stageStart), detail)
+	return true
+}
+
+// proxyConnect opens a tunnel to target through an HTTP proxy, over an HTTPS connection for
+// HTTPS proxies.
+func proxyConnect(ctx context.Context, conn net.Conn, proxy *url.URL, target string) (net.Conn, error) {
+	if proxy.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxy.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return nil, fmt.Errorf("proxy TLS handshake failed: %w", err)
+		}
+		conn = tlsConn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{Method: http.MethodConnect, URL: &url.URL{Opaque: target}, Host: target, Header: http.Header{}}
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(proxy.User.Username()+":"+password)))
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("proxy CONNECT failed: %w", err)
+	}
+	reader := bufio.NewReader(conn)
+	// A successful answer has no body, and the connection is dropped after a refusal
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, fmt.Errorf("proxy CONNECT failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("proxy refused tunnel to %s: %s", target, resp.Status)
+	}
+	if reader.Buffered() > 0 {
+		return nil, errors.New("proxy sent data before the tunnel was established")
+	}
+	return conn, nil
+}
+
+func (diag *Diagnostics) pass(stage string, latency time.Duration, detail string) {
+	diag.Stages = append(diag.Stages, StageResult{Stage: stage, Success: true, LatencyMs: milliseconds(latency), Detail: detail})
+}
+
+func (diag *Diagnostics) fail(stage string, latency time.Duration, err error) {
+	diag.Stages = append(diag.Stages, StageResult{Stage: stage, LatencyMs: milliseconds(latency), Error: err.Error()})
+	diag.FailedStage = stage
+	diag.Error = err.Error()
+}
+
+func certificateInfo(cert *x509.Certificate) *CertificateInfo {
+	return &CertificateInfo{
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		DNSNames:  cert.DNSNames,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+	}
+}
+
+// milliseconds converts a duration to fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// urlEndpoint builds an Endpoint from a URL, using defaultPort when the URL has none.
+func urlEndpoint(host, port, defaultPort string, tlsConfig *tls.Config) (*Endpoint, error) {
+	if host == "" {
+		return nil, fmt.Errorf("no host in endpoint")
+	}
+	if port == "" {
+		port = defaultPort
+	}
+	return &Endpoint{Host: host, Port: port, TLS: tlsConfig}, nil
+}
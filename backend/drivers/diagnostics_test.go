@@ -0,0 +1,230 @@
+package drivers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newDiagnosticsRESTDriver(t *testing.T, baseURL string) *RESTDriver {
+	driver, err := NewRESTDriver(fmt.Sprintf(`{"base_endpoint": %q, "auth": {"type": "none"}, "timeout": 2}`, baseURL))
+	if err != nil {
+		t.Fatalf("NewRESTDriver failed: %v", err)
+	}
+	return driver
+}
+
+func stageNames(diag *Diagnostics) []string {
+	names := make([]string, len(diag.Stages))
+	for i, stage := range diag.Stages {
+		names[i] = stage.Stage
+	}
+	return names
+}
+
+func TestDiagnose_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "plantapi/2.4.1")
+	}))
+	defer server.Close()
+
+	diag := Diagnose(context.Background(), newDiagnosticsRESTDriver(t, server.URL))
+	if !diag.Success {
+		t.Fatalf("Expected success, failed at %s: %s", diag.FailedStage, diag.Error)
+	}
+	if got := fmt.Sprint(stageNames(diag)); got != "[dns tcp auth]" {
+		t.Errorf("Expected stages [dns tcp auth], got %s", got)
+	}
+	if diag.Server == nil || diag.Server.Product != "plantapi" || diag.Server.Version != "2.4.1" {
+		t.Errorf("Expected server plantapi 2.4.1, got %+v", diag.Server)
+	}
+}
+
+func TestDiagnose_FailedStages(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	closedURL := "http://" + listener.Addr().String()
+	listener.Close()
+
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer unauthorized.Close()
+
+	// The test server's certificate is not signed by a trusted CA
+	untrusted := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer untrusted.Close()
+
+	tests := []struct {
+		name          string
+		baseURL       string
+		expectedStage string
+		authFailed    bool
+	}{
+		{"unresolvable host", "http://iotgo-diagnostics.invalid", StageDNS, false},
+		{"closed port", closedURL, StageTCP, false},
+		{"untrusted certificate", untrusted.URL, StageTLS, false},
+		{"rejected credentials", unauthorized.URL, StageAuth, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diag := Diagnose(context.Background(), newDiagnosticsRESTDriver(t, tt.baseURL))
+			if diag.Success {
+				t.Fatal("Expected the connection test to fail")
+			}
+			if diag.AuthFailed != tt.authFailed {
+				t.Errorf("Expected auth_failed %v, got %v", tt.authFailed, diag.AuthFailed)
+			}
+			if diag.FailedStage != tt.expectedStage {
+				t.Errorf("Expected failure at %s, got %s (%s)", tt.expectedStage, diag.FailedStage, diag.Error)
+			}
+			last := diag.Stages[len(diag.Stages)-1]
+			if last.Stage != tt.expectedStage || last.Success || last.Error == "" {
+				t.Errorf("Expected the last stage to be the failed %s stage, got %+v", tt.expectedStage, last)
+			}
+		})
+	}
+}
+
+func TestDiagnose_MutualTLSThroughProxy(t *testing.T) {
+	clientCert, clientKey := newTestClientCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM([]byte(clientCert))
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "plantapi/2.4.1")
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	serverCA := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	var tunnels atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		tunnels.Add(1)
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	defer proxy.Close()
+
+	newDriver := func(settings map[string]interface{}) *RESTDriver {
+		metadata, _ := json.Marshal(map[string]interface{}{
+			"base_endpoint": server.URL,
+			"auth":          map[string]string{"type": "none"},
+			"timeout":       2,
+			"tls":           settings,
+			"proxy_url":     proxy.URL,
+		})
+		validated, err := validateRESTMetadata(string(metadata))
+		if err != nil {
+			t.Fatalf("Expected valid metadata, got %v", err)
+		}
+		driver, err := NewRESTDriver(validated)
+		if err != nil {
+			t.Fatalf("NewRESTDriver failed: %v", err)
+		}
+		return driver
+	}
+
+	// The test certificate is issued for example.com, so the server name is overridden
+	diag := Diagnose(context.Background(), newDriver(map[string]interface{}{
+		"ca_cert": serverCA, "certificate": clientCert, "private_key": clientKey, "server_name": "example.com",
+	}))
+	if !diag.Success {
+		t.Fatalf("Expected success, failed at %s: %s", diag.FailedStage, diag.Error)
+	}
+	if got := fmt.Sprint(stageNames(diag)); got != "[dns tcp tls auth]" {
+		t.Errorf("Expected stages [dns tcp tls auth], got %s", got)
+	}
+	if !strings.Contains(diag.Stages[1].Detail, "tunneled") || tunnels.Load() < 2 {
+		t.Errorf("Expected the test and the driver to go through the proxy, got %q and %d tunnels", diag.Stages[1].Detail, tunnels.Load())
+	}
+
+	// Skipping verification works without the CA bundle but is reported
+	diag = Diagnose(context.Background(), newDriver(map[string]interface{}{
+		"certificate": clientCert, "private_key": clientKey, "insecure_skip_verify": true,
+	}))
+	if !diag.Success || !strings.Contains(diag.Stages[2].Detail, "not verified") {
+		t.Errorf("Expected an unverified success, got %+v", diag)
+	}
+
+	// Without the CA bundle the server is not trusted
+	diag = Diagnose(context.Background(), newDriver(map[string]interface{}{
+		"certificate": clientCert, "private_key": clientKey,
+	}))
+	if diag.FailedStage != StageTLS {
+		t.Errorf("Expected failure at tls, got %q (%s)", diag.FailedStage, diag.Error)
+	}
+
+	for _, metadata := range []string{
+		`{"base_endpoint": "https://api.example.com", "tls": {"ca_cert": "not a certificate"}}`,
+		`{"base_endpoint": "https://api.example.com", "tls": {"certificate": "` + strings.ReplaceAll(clientCert, "\n", `\n`) + `"}}`,
+		`{"base_endpoint": "https://api.example.com", "proxy_url": "socks5://proxy:1080"}`,
+	} {
+		if _, err := validateRESTMetadata(metadata); err == nil {
+			t.Errorf("Expected an error for metadata %s", metadata)
+		}
+	}
+}
+
+// newTestClientCertificate returns a self-signed client certificate and its key in PEM.
+func newTestClientCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gateway"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate failed: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey failed: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
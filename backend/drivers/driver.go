@@ -3,7 +3,7 @@ package drivers
 import (
 	"context"
 	"errors"
-	"fmt"
+	"time"
 )
 
 // PlatformDriver defines the interface for all platform drivers.
@@ -24,19 +24,112 @@ type PlatformDriver interface {
 	TestResource(ctx context.Context, resourceDetails string) (interface{}, error)
 }
 
+// Browser is implemented by drivers that can enumerate the platform's address space.
+type Browser interface {
+	// Browse walks the address space starting at a node and returns one page of the nodes found.
+	Browse(ctx context.Context, req BrowseRequest) (*BrowseResult, error)
+}
+
+// BrowseRequest defines where to start browsing and how much of the address space to return.
+type BrowseRequest struct {
+	NodeID string // Starting node, empty for the platform's default root
+	Depth  int    // Number of levels below the starting node to walk
+	Limit  int    // Page size
+	Offset int    // Page offset
+}
+
+// BrowseNode describes a single node discovered while browsing.
+type BrowseNode struct {
+	NodeID       string   `json:"node_id"`
+	ParentNodeID string   `json:"parent_node_id"`
+	Depth        int      `json:"depth"`
+	NodeClass    string   `json:"node_class"`
+	BrowseName   string   `json:"browse_name"`
+	DisplayName  string   `json:"display_name"`
+	DataType     string   `json:"data_type,omitempty"`
+	AccessLevel  []string `json:"access_level,omitempty"`
+}
+
+// BrowseResult is one page of browsed nodes.
+type BrowseResult struct {
+	Nodes     []BrowseNode `json:"nodes"`
+	Total     int          `json:"total"`
+	Limit     int          `json:"limit"`
+	Offset    int          `json:"offset"`
+	Truncated bool         `json:"truncated"` // True when the walk stopped at the node cap
+}
+
+// Subscriber is implemented by drivers that can push value changes instead of being polled.
+type Subscriber interface {
+	// Subscribe streams value changes for a resource until ctx is cancelled, then closes the channel.
+	Subscribe(ctx context.Context, resourceDetails string) (<-chan DataChange, error)
+}
+
+// DataChange is a single update delivered by a Subscriber.
+type DataChange struct {
+	Data       interface{} `json:"data,omitempty"`  // Same shape FetchData returns for the resource
+	Error      string      `json:"error,omitempty"` // Set when the platform reported a problem instead of a value
+	ReceivedAt time.Time   `json:"received_at"`
+}
+
+// Writer is implemented by drivers that can change values on the platform or trigger actions,
+// such as PLC setpoints, OPC UA node writes and REST calls.
+type Writer interface {
+	// WriteData writes a value to a resource, or sends an action's request body.
+	WriteData(ctx context.Context, resourceDetails string, value WriteValue) (*WriteResult, error)
+}
+
+// WriteValue is what a write sends: a typed value for setpoints, or a request body for actions.
+type WriteValue struct {
+	Value interface{} // Converted to the resource's data type; a list for resources holding several values
+	Body  *string     // Request body replacing the resource's own, for REST actions
+}
+
+// WriteResult describes a completed write.
+type WriteResult struct {
+	OldValue interface{} `json:"old_value"`          // Value before the write, nil when it cannot be read without side effects
+	NewValue interface{} `json:"new_value"`          // Value written, after conversion to the resource's data type
+	Response interface{} `json:"response,omitempty"` // The platform's answer, for actions
+}
+
+// Sink is implemented by drivers whose platform can store the samples the gateway collects
+// from other platforms, such as time series databases.
+type Sink interface {
+	// WritePoints stores points in a bucket, an empty bucket meaning the platform's default.
+	WritePoints(ctx context.Context, bucket string, points []SinkPoint) error
+}
+
+// SinkPoint is one sample written to a Sink.
+type SinkPoint struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      map[string]interface{}
+	Time        time.Time
+}
+
+// BatchFetcher is implemented by drivers that can combine the reads of several resources.
+type BatchFetcher interface {
+	// FetchBatch fetches several resources at once and returns one result per resource, in order.
+	FetchBatch(ctx context.Context, resourceDetails []string) []BatchResult
+}
+
+// BatchResult is the outcome of fetching one resource in a batch.
+type BatchResult struct {
+	Data interface{}
+	Err  error
+}
+
 // ErrNotImplemented is returned when a driver does not implement a method.
 var ErrNotImplemented = errors.New("method not implemented for this platform type")
 
+// ErrAuthFailed is wrapped by drivers when the platform rejects the configured credentials.
+var ErrAuthFailed = errors.New("authentication failed")
+
 // GetDriver returns the appropriate PlatformDriver based on the platform type and metadata.
 func GetDriver(platformType string, metadata string) (PlatformDriver, error) {
-	switch platformType {
-	case "REST":
-		return NewRESTDriver(metadata)
-	// case "OPCUA":
-	// 	return NewOPCUADriver(metadata)
-	case "InfluxDB":
-		return NewInfluxDBDriver(metadata)
-	default:
-		return nil, fmt.Errorf("unsupported platform type: %s", platformType)
+	r, err := Lookup(platformType)
+	if err != nil {
+		return nil, err
 	}
+	return r.New(metadata)
 }
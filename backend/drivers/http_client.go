@@ -0,0 +1,289 @@
+package drivers
+
+import (
+	"app/model"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/logs"
+)
+
+const (
+	retryDefaultInitialBackoff = 200 * time.Millisecond
+	retryDefaultMaxBackoff     = 5 * time.Second
+	retryMaxRetries            = 10
+	retryDrainSize             = 64 << 10 // Bytes of a failed response read so its connection is reused
+)
+
+// retryDefaultStatus lists the status codes retried when the metadata names none.
+var retryDefaultStatus = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// httpSettings holds the TLS, proxy and retry configuration of a driver that speaks HTTP.
+type httpSettings struct {
+	tls   *tls.Config  // Nil for the system defaults
+	proxy *url.URL     // Nil for the environment's proxy settings
+	retry *retryPolicy // Nil when failed requests are not retried
+}
+
+// newHTTPSettings builds the TLS configuration, proxy URL and retry policy of platform metadata.
+func newHTTPSettings(settings *model.HTTPTLS, proxyURL string, retry *model.HTTPRetry) (*httpSettings, error) {
+	s := &httpSettings{}
+	if settings != nil {
+		tlsConfig, err := clientTLSConfig(settings)
+		if err != nil {
+			return nil, err
+		}
+		s.tls = tlsConfig
+	}
+	if proxyURL != "" {
+		proxy, err := url.Parse(proxyURL)
+		if err != nil || (proxy.Scheme != "http" && proxy.Scheme != "https") || proxy.Host == "" {
+			return nil, errors.New("proxy_url must be a valid HTTP/HTTPS URL")
+		}
+		s.proxy = proxy
+	}
+	if retry != nil && retry.MaxRetries != 0 {
+		policy, err := newRetryPolicy(retry)
+		if err != nil {
+			return nil, err
+		}
+		s.retry = policy
+	}
+	return s, nil
+}
+
+// validateHTTPSettings sanitizes the TLS, proxy and retry settings of platform metadata,
+// checks that the certificates and keys parse and fills in the retry defaults.
+func validateHTTPSettings(settings *model.HTTPTLS, proxyURL *string, retry *model.HTTPRetry) error {
+	if settings != nil {
+		settings.CACert = strings.TrimSpace(settings.CACert)
+		settings.Certificate = strings.TrimSpace(settings.Certificate)
+		settings.PrivateKey = strings.TrimSpace(settings.PrivateKey)
+		settings.ServerName = strings.TrimSpace(settings.ServerName)
+	}
+	*proxyURL = strings.TrimSpace(*proxyURL)
+	if retry != nil {
+		if _, err := newRetryPolicy(retry); err != nil {
+			return err
+		}
+		retry.InitialBackoff = defaultString(retry.InitialBackoff, retryDefaultInitialBackoff.String())
+		retry.MaxBackoff = defaultString(retry.MaxBackoff, retryDefaultMaxBackoff.String())
+		if len(retry.RetryableStatus) == 0 {
+			retry.RetryableStatus = slices.Clone(retryDefaultStatus)
+		}
+	}
+	_, err := newHTTPSettings(settings, *proxyURL, retry)
+	return err
+}
+
+// clientTLSConfig builds the TLS configuration for connections to a platform.
+func clientTLSConfig(settings *model.HTTPTLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ServerName: settings.ServerName, InsecureSkipVerify: settings.InsecureSkipVerify}
+	if settings.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(settings.CACert)) {
+			return nil, errors.New("tls ca_cert contains no valid PEM certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if settings.Certificate != "" || settings.PrivateKey != "" {
+		cert, err := tls.X509KeyPair([]byte(settings.Certificate), []byte(settings.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid tls client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// client returns an HTTP client that uses the settings. Requests for which idempotent returns
+// true are retried according to the retry policy; the timeout covers all attempts.
+func (s *httpSettings) client(timeout time.Duration, idempotent func(*http.Request) bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if s.tls != nil {
+		transport.TLSClientConfig = s.tls
+		if s.tls.InsecureSkipVerify {
+			logs.Warn("TLS certificate verification is disabled (insecure_skip_verify)")
+		}
+	}
+	if s.proxy != nil {
+		transport.Proxy = http.ProxyURL(s.proxy)
+	}
+	if s.retry != nil {
+		return &http.Client{Timeout: timeout, Transport: &retryTransport{next: transport, policy: s.retry, idempotent: idempotent}}
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// endpoint returns the Endpoint of an HTTP or HTTPS URL reached with the settings.
+func (s *httpSettings) endpoint(u *url.URL) (*Endpoint, error) {
+	var endpoint *Endpoint
+	var err error
+	if u.Scheme == "https" {
+		tlsConfig := s.tls
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		endpoint, err = urlEndpoint(u.Hostname(), u.Port(), "443", tlsConfig)
+	} else {
+		endpoint, err = urlEndpoint(u.Hostname(), u.Port(), "80", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	endpoint.Proxy = s.proxy
+	return endpoint, nil
+}
+
+// retryPolicy is the parsed retry configuration of a platform.
+type retryPolicy struct {
+	maxRetries int
+	initial    time.Duration
+	max        time.Duration
+	status     map[int]bool
+}
+
+// newRetryPolicy parses retry settings, using the defaults for those left empty.
+func newRetryPolicy(config *model.HTTPRetry) (*retryPolicy, error) {
+	if config.MaxRetries < 0 || config.MaxRetries > retryMaxRetries {
+		return nil, fmt.Errorf("retry.max_retries must be between 0 and %d", retryMaxRetries)
+	}
+	policy := &retryPolicy{
+		maxRetries: config.MaxRetries,
+		initial:    retryDefaultInitialBackoff,
+		max:        retryDefaultMaxBackoff,
+		status:     make(map[int]bool),
+	}
+	for _, setting := range []struct {
+		name  string
+		value string
+		into  *time.Duration
+	}{
+		{"initial_backoff", config.InitialBackoff, &policy.initial},
+		{"max_backoff", config.MaxBackoff, &policy.max},
+	} {
+		if setting.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(setting.value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("retry.%s must be a positive duration such as \"500ms\"", setting.name)
+		}
+		*setting.into = d
+	}
+	if policy.initial > policy.max {
+		return nil, errors.New("retry.initial_backoff cannot exceed retry.max_backoff")
+	}
+	statuses := config.RetryableStatus
+	if len(statuses) == 0 {
+		statuses = retryDefaultStatus
+	}
+	for _, code := range statuses {
+		if code < 400 || code > 599 {
+			return nil, fmt.Errorf("retry.retryable_status: %d is not a 4xx or 5xx status code", code)
+		}
+		policy.status[code] = true
+	}
+	return policy, nil
+}
+
+// retryable reports whether an attempt that ended with resp or err is worth repeating.
+// Rejected certificates and requests the caller gave up on are final.
+func (p *retryPolicy) retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		var certErr *tls.CertificateVerificationError
+		return !errors.As(err, &certErr)
+	}
+	return p.status[resp.StatusCode]
+}
+
+// backoff returns the wait after a number of failed retries: the initial backoff doubled for
+// each, capped at the maximum, with up to half of it replaced by random jitter. A Retry-After
+// header in seconds lengthens the wait up to the maximum.
+func (p *retryPolicy) backoff(retries int, resp *http.Response) time.Duration {
+	wait := p.initial
+	for i := 0; i < retries && wait < p.max; i++ {
+		wait *= 2
+	}
+	wait = min(wait, p.max)
+	wait = wait/2 + rand.N(wait/2+1)
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			wait = max(wait, min(time.Duration(seconds)*time.Second, p.max))
+		}
+	}
+	return wait
+}
+
+// retryTransport repeats idempotent requests that fail with a network error or a retryable
+// status code, waiting between attempts as the retry policy says.
+type retryTransport struct {
+	next       http.RoundTripper
+	policy     *retryPolicy
+	idempotent func(*http.Request) bool
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A body that cannot be read again rules out a second attempt
+	if !t.idempotent(req) || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return t.next.RoundTrip(req)
+	}
+	ctx := req.Context()
+	for retries := 0; ; retries++ {
+		attempt := req
+		if retries > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attempt = req.Clone(ctx)
+			attempt.Body = body
+		}
+		resp, err := t.next.RoundTrip(attempt)
+		if retries >= t.policy.maxRetries || !t.policy.retryable(ctx, resp, err) {
+			return resp, err
+		}
+
+		wait := t.policy.backoff(retries, resp)
+		var reason string
+		if resp != nil {
+			reason = resp.Status
+			io.Copy(io.Discard, io.LimitReader(resp.Body, retryDrainSize))
+			resp.Body.Close()
+		} else {
+			reason = err.Error()
+		}
+		logs.Debug("Retrying %s %s%s in %s after %s (retry %d of %d)", req.Method, req.URL.Host, req.URL.Path, wait, reason, retries+1, t.policy.maxRetries)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// idempotentMethod reports whether a request's method can be repeated without changing the
+// outcome, as defined by RFC 9110.
+func idempotentMethod(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -6,14 +6,48 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	ihttp "github.com/influxdata/influxdb-client-go/v2/api/http"
 )
 
+func init() {
+	Register(Registration{
+		Type:             "InfluxDB",
+		Label:            "InfluxDB",
+		New:              func(metadata string) (PlatformDriver, error) { return NewInfluxDBDriver(metadata) },
+		MetadataSchema:   mustLoadSchema("influxdb_metadata.json"),
+		ValidateMetadata: validateInfluxDBMetadata,
+		ResourceTypes: []ResourceType{{
+			Type:            "influxdb_query",
+			Label:           "InfluxDB Query",
+			DetailsSchema:   mustLoadSchema("influxdb_query.json"),
+			ValidateDetails: validateInfluxDBResourceDetails,
+			PrepareDetails:  prepareInfluxDBResourceDetails,
+			Points:          PointMapping{Items: "$", Value: "value", Timestamp: "time"},
+		}, {
+			Type:            "influxdb_flux",
+			Label:           "InfluxDB Flux Script",
+			DetailsSchema:   mustLoadSchema("influxdb_flux.json"),
+			ValidateDetails: validateInfluxDBFluxDetails,
+			PrepareDetails:  prepareInfluxDBFluxDetails,
+			Points:          PointMapping{Items: "$", Value: "_value", Timestamp: "_time"},
+		}},
+	})
+}
+
 // InfluxDBDriver implements the PlatformDriver interface for InfluxDB platforms.
 type InfluxDBDriver struct {
-	client influxdb2.Client
+	client influxdb2.Client // Nil for InfluxDB 1.x
+	v1     *influxQLClient  // Set for InfluxDB 1.x, which is queried with InfluxQL
+	url    string
 	org    string
+	bucket string // Default bucket, written to by WritePoints
+	http   *httpSettings
 }
 
 // NewInfluxDBDriver creates a new InfluxDBDriver instance from platform metadata.
@@ -25,6 +59,22 @@ func NewInfluxDBDriver(metadata string) (*InfluxDBDriver, error) {
 	if config.URL == "" {
 		return nil, errors.New("missing url in metadata")
 	}
+	if config.Timeout == 0 {
+		config.Timeout = 10
+	}
+	settings, err := newHTTPSettings(config.TLS, config.ProxyURL, config.Retry)
+	if err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(config.Timeout) * time.Second
+
+	if config.Version == influxVersion1 {
+		v1, err := newInfluxQLClient(config, settings.client(timeout, idempotentMethod))
+		if err != nil {
+			return nil, err
+		}
+		return &InfluxDBDriver{v1: v1, url: config.URL, http: settings}, nil
+	}
 	if config.Token == "" {
 		return nil, errors.New("missing token in metadata")
 	}
@@ -34,22 +84,143 @@ func NewInfluxDBDriver(metadata string) (*InfluxDBDriver, error) {
 	if config.Bucket == "" {
 		return nil, errors.New("missing bucket in metadata")
 	}
-	if config.Timeout == 0 {
-		config.Timeout = 10
-	}
 
-	// Create InfluxDB client with timeout
+	// Create InfluxDB client with timeout, TLS, proxy and retry settings
 	client := influxdb2.NewClientWithOptions(config.URL, config.Token, influxdb2.DefaultOptions().
-		SetHTTPRequestTimeout(uint(config.Timeout)))
+		SetHTTPClient(settings.client(timeout, influxIdempotent)))
 
 	return &InfluxDBDriver{
 		client: client,
+		url:    config.URL,
 		org:    config.Org,
+		bucket: config.Bucket,
+		http:   settings,
 	}, nil
 }
 
+// influxIdempotent reports whether an InfluxDB API request can be retried. Flux queries are
+// sent with POST but only read.
+func influxIdempotent(req *http.Request) bool {
+	return idempotentMethod(req) || strings.HasSuffix(req.URL.Path, "/api/v2/query")
+}
+
+// validateInfluxDBMetadata validates and sanitizes InfluxDB platform metadata.
+func validateInfluxDBMetadata(metadataJSON string) (string, error) {
+	var metadata model.InfluxDBMetadata
+	if err := json.Unmarshal([]byte(metadataJSON), &metadata); err != nil {
+		return "", errors.New("invalid metadata JSON")
+	}
+
+	if metadata.URL == "" {
+		return "", errors.New("url is required for InfluxDB platforms")
+	}
+	parsedURL, err := url.Parse(metadata.URL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return "", errors.New("url must be a valid HTTP/HTTPS URL")
+	}
+	metadata.URL = parsedURL.String()
+
+	switch metadata.Version = strings.TrimSpace(metadata.Version); metadata.Version {
+	case "", "2":
+		if metadata.Token == "" {
+			return "", errors.New("token is required for InfluxDB platforms")
+		}
+		if metadata.Org == "" {
+			return "", errors.New("org is required for InfluxDB platforms")
+		}
+		if metadata.Bucket == "" {
+			return "", errors.New("bucket is required for InfluxDB platforms")
+		}
+	case influxVersion1:
+		if err := validateInfluxQLMetadata(&metadata); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("invalid version %q: must be 1 or 2", metadata.Version)
+	}
+	if metadata.Timeout == 0 {
+		metadata.Timeout = 10
+	}
+
+	metadata.Token = strings.TrimSpace(metadata.Token)
+	metadata.Org = strings.TrimSpace(metadata.Org)
+	metadata.Bucket = strings.TrimSpace(metadata.Bucket)
+
+	if err := validateHTTPSettings(metadata.TLS, &metadata.ProxyURL, metadata.Retry); err != nil {
+		return "", err
+	}
+	if metadata.Sink != nil {
+		if err := NormalizeTelemetrySink(metadata.Sink); err != nil {
+			return "", err
+		}
+	}
+
+	serialized, err := json.Marshal(metadata)
+	if err != nil {
+		return "", errors.New("failed to serialize sanitized metadata")
+	}
+	return string(serialized), nil
+}
+
+// validateInfluxDBResourceDetails validates and sanitizes InfluxDB query details.
+func validateInfluxDBResourceDetails(detailsJSON string) (string, error) {
+	var details model.InfluxDBResourceDetails
+	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
+		return "", errors.New("invalid details JSON")
+	}
+	if err := NormalizeInfluxQuery(&details); err != nil {
+		return "", err
+	}
+
+	// Re-serialize sanitized details
+	serialized, err := json.Marshal(details)
+	if err != nil {
+		return "", errors.New("failed to serialize sanitized details")
+	}
+	return string(serialized), nil
+}
+
+// prepareInfluxDBResourceDetails applies the range, aggregation, field and tag query parameters
+// and uses the device alias as the measurement.
+func prepareInfluxDBResourceDetails(detailsJSON, deviceAlias string, params url.Values) (string, error) {
+	var details model.InfluxDBResourceDetails
+	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
+		return "", fmt.Errorf("invalid resource details: %w", err)
+	}
+	applyInfluxQueryParams(&details, params)
+	if deviceAlias != "" {
+		details.Measurement = deviceAlias
+	}
+	if err := NormalizeInfluxQuery(&details); err != nil {
+		return "", err
+	}
+
+	serialized, err := json.Marshal(details)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize InfluxDB details: %w", err)
+	}
+	return string(serialized), nil
+}
+
+// isInfluxTimeRange reports whether a value is a negative Flux duration, e.g. "-1h".
+func isInfluxTimeRange(timeRange string) bool {
+	return strings.HasPrefix(timeRange, "-") && fluxDurationPattern.MatchString(timeRange[1:])
+}
+
+// fluxString quotes a value as a Flux string literal, escaping quotes, backslashes and
+// interpolation.
+func fluxString(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "${", `\${`)
+	return `"` + value + `"`
+}
+
 // ValidateConfig checks if the InfluxDB configuration is valid by performing a health check.
 func (d *InfluxDBDriver) ValidateConfig(ctx context.Context) error {
+	if d.v1 != nil {
+		return d.v1.validate(ctx)
+	}
 	health, err := d.client.Health(ctx)
 	if err != nil {
 		return fmt.Errorf("health check failed: %w", err)
@@ -57,35 +228,79 @@ func (d *InfluxDBDriver) ValidateConfig(ctx context.Context) error {
 	if health.Status != "pass" {
 		return fmt.Errorf("influxDB health check failed: %s", *health.Message)
 	}
+
+	// The health endpoint is public, so look up the organization to check the token
+	if _, err := d.client.OrganizationsAPI().FindOrganizationByName(ctx, d.org); err != nil {
+		var httpErr *ihttp.Error
+		if errors.As(err, &httpErr) && (httpErr.StatusCode == http.StatusUnauthorized || httpErr.StatusCode == http.StatusForbidden) {
+			return fmt.Errorf("token rejected: %w", ErrAuthFailed)
+		}
+		return fmt.Errorf("failed to find organization %s: %w", d.org, err)
+	}
 	return nil
 }
 
+// Endpoint returns the host and port of the InfluxDB URL.
+func (d *InfluxDBDriver) Endpoint() (*Endpoint, error) {
+	u, err := url.Parse(d.url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	return d.http.endpoint(u)
+}
+
+// Identify reports the server name and version from the health endpoint.
+func (d *InfluxDBDriver) Identify(ctx context.Context) (*ServerInfo, error) {
+	if d.v1 != nil {
+		return d.v1.identify(ctx)
+	}
+	health, err := d.client.Health(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("health check failed: %w", err)
+	}
+	info := &ServerInfo{Product: health.Name}
+	if health.Version != nil {
+		info.Version = *health.Version
+	}
+	return info, nil
+}
+
 // Connect is a no-op for InfluxDB since the client manages connections.
 func (d *InfluxDBDriver) Connect(ctx context.Context) error {
 	return nil
 }
 
-// FetchData sends a Flux query to InfluxDB to retrieve device data.
+// FetchData sends a Flux query to InfluxDB to retrieve device data. influxdb_flux resources run
+// their own script; influxdb_query resources fill in the fixed template, or an InfluxQL query
+// on InfluxDB 1.x.
 func (d *InfluxDBDriver) FetchData(ctx context.Context, resourceDetails string) (interface{}, error) {
+	var script model.InfluxDBFluxDetails
+	if err := json.Unmarshal([]byte(resourceDetails), &script); err != nil {
+		return nil, fmt.Errorf("invalid resource details: %w", err)
+	}
+	if script.Flux != "" {
+		if d.v1 != nil {
+			return nil, errors.New("influxdb_flux resources require InfluxDB 2.x, this platform is queried with InfluxQL")
+		}
+		return d.fetchFlux(ctx, script)
+	}
+
 	var details model.InfluxDBResourceDetails
 	if err := json.Unmarshal([]byte(resourceDetails), &details); err != nil {
 		return nil, fmt.Errorf("invalid resource details: %w", err)
 	}
-
-	if details.Bucket == "" {
-		return nil, errors.New("bucket is required in resource details")
+	if err := NormalizeInfluxQuery(&details); err != nil {
+		return nil, err
 	}
-	if details.Measurement == "" || details.Field == "" || details.TimeRange == "" {
-		return nil, errors.New("measurement, field, and time_range are required in resource details")
+	if d.v1 != nil {
+		return d.v1.fetch(ctx, &details)
 	}
 
-	// Construct Flux query
-	fluxQuery := fmt.Sprintf(
-		`from(bucket: "%s")
-		|> range(start: %s)
-		|> filter(fn: (r) => r._measurement == "%s")
-		|> filter(fn: (r) => r._field == "%s")`,
-		details.Bucket, details.TimeRange, details.Measurement, details.Field)
+	// Construct Flux query; the measurement may be a device alias, so every name is quoted
+	fluxQuery, err := buildInfluxQuery(&details)
+	if err != nil {
+		return nil, err
+	}
 
 	// Get query API
 	queryAPI := d.client.QueryAPI(d.org)
@@ -97,16 +312,26 @@ func (d *InfluxDBDriver) FetchData(ctx context.Context, resourceDetails string)
 	}
 	defer result.Close()
 
-	// Parse query results
+	// Parse query results; tags tell series apart when filters leave several
 	data := []map[string]interface{}{}
 	for result.Next() {
 		record := result.Record()
-		data = append(data, map[string]interface{}{
+		row := map[string]interface{}{
 			"time":        record.Time(),
 			"value":       record.Value(),
 			"field":       record.Field(),
 			"measurement": record.Measurement(),
-		})
+		}
+		tags := map[string]interface{}{}
+		for column, value := range record.Values() {
+			if !strings.HasPrefix(column, "_") && column != "result" && column != "table" {
+				tags[column] = value
+			}
+		}
+		if len(tags) > 0 {
+			row["tags"] = tags
+		}
+		data = append(data, row)
 	}
 	if result.Err() != nil {
 		return nil, fmt.Errorf("query error: %w", result.Err())
@@ -122,6 +347,8 @@ func (d *InfluxDBDriver) TestResource(ctx context.Context, resourceDetails strin
 
 // Disconnect closes the InfluxDB client.
 func (d *InfluxDBDriver) Disconnect(ctx context.Context) error {
-	d.client.Close()
+	if d.client != nil {
+		d.client.Close()
+	}
 	return nil
 }
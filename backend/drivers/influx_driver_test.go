@@ -0,0 +1,341 @@
+package drivers
+
+import (
+	"app/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// influxQuery is the body of a Flux query request.
+type influxQuery struct {
+	Query  string                 `json:"query"`
+	Params map[string]interface{} `json:"params"`
+}
+
+// startTestInfluxServer answers Flux queries with an annotated CSV result and records the
+// last query it received.
+func startTestInfluxServer(t *testing.T, csv string) (*httptest.Server, *influxQuery) {
+	t.Helper()
+	received := &influxQuery{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/query" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(received)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Write([]byte(csv))
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func newTestInfluxDBDriver(t *testing.T, serverURL string) *InfluxDBDriver {
+	t.Helper()
+	metadataJSON, _ := json.Marshal(model.InfluxDBMetadata{URL: serverURL, Token: "token", Org: "plant", Bucket: "telemetry", Timeout: 5})
+	driver, err := NewInfluxDBDriver(string(metadataJSON))
+	if err != nil {
+		t.Fatalf("Failed to create InfluxDBDriver: %v", err)
+	}
+	t.Cleanup(func() { driver.Disconnect(context.Background()) })
+	return driver
+}
+
+func TestInfluxDBDriver_FetchDataQuotesNames(t *testing.T) {
+	server, received := startTestInfluxServer(t, "#datatype,string,long,dateTime:RFC3339,double,string,string\n"+
+		"#group,false,false,false,false,true,true\n"+
+		"#default,_result,,,,,\n"+
+		",result,table,_time,_value,_field,_measurement\n"+
+		",,0,2024-05-01T12:00:00Z,20.5,temp,boiler\n\n")
+	driver := newTestInfluxDBDriver(t, server.URL)
+
+	// A device alias trying to end the string and add to the query stays a string
+	details, err := PrepareResourceDetails("InfluxDB", "influxdb_query",
+		`{"bucket": "telemetry", "measurement": "boiler", "field": "temp", "time_range": "-1h"}`, `x") |> drop(columns: ["${a}"]) //\`, nil)
+	if err != nil {
+		t.Fatalf("Failed to prepare details: %v", err)
+	}
+	result, err := driver.FetchData(context.Background(), details)
+	if err != nil {
+		t.Fatalf("Failed to fetch data: %v", err)
+	}
+	want := `r._measurement == "x\") |> drop(columns: [\"\${a}\"]) //\\")`
+	if !strings.Contains(received.Query, want) {
+		t.Errorf("Expected quoted measurement %s in query:\n%s", want, received.Query)
+	}
+	if rows := result.([]map[string]interface{}); len(rows) != 1 || rows[0]["value"] != 20.5 {
+		t.Errorf("Unexpected result: %v", result)
+	}
+
+	for _, timeRange := range []string{"-1h) |> yield()", "-1x", "1h"} {
+		if _, err := validateInfluxDBResourceDetails(`{"bucket": "b", "measurement": "m", "field": "f", "time_range": "` + timeRange + `"}`); err == nil {
+			t.Errorf("Expected time_range %q to be rejected", timeRange)
+		}
+	}
+}
+
+func TestInfluxDBDriver_FluxParams(t *testing.T) {
+	server, received := startTestInfluxServer(t, "#datatype,string,long,dateTime:RFC3339,double,double\n"+
+		"#group,false,false,false,false,false\n"+
+		"#default,_result,,,,\n"+
+		",result,table,_time,temp,humidity\n"+
+		",,0,2024-05-01T12:00:00Z,20.5,40\n"+
+		",,0,2024-05-01T12:05:00Z,21,41\n\n")
+	driver := newTestInfluxDBDriver(t, server.URL)
+
+	flux := `from(bucket: "telemetry")
+  |> range(start: time(v: params.start), stop: time(v: params.stop))
+  |> filter(fn: (r) => r.device == params.device_alias)
+  |> aggregateWindow(every: duration(v: params.every), fn: mean)
+  |> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")`
+	raw, _ := json.Marshal(model.InfluxDBFluxDetails{Flux: flux})
+	validated, err := ValidateResourceDetails("influxdb_flux", string(raw))
+	if err != nil {
+		t.Fatalf("Expected valid details, got %v", err)
+	}
+	details, err := PrepareResourceDetails("InfluxDB", "influxdb_flux", validated, "pump-1", url.Values{"every": {"5m"}})
+	if err != nil {
+		t.Fatalf("Failed to prepare details: %v", err)
+	}
+
+	before := time.Now().Add(-time.Hour - time.Second)
+	result, err := driver.FetchData(context.Background(), details)
+	if err != nil {
+		t.Fatalf("Failed to fetch data: %v", err)
+	}
+	if received.Query != flux {
+		t.Errorf("Expected the script to be sent unchanged, got:\n%s", received.Query)
+	}
+	if received.Params["device_alias"] != "pump-1" || received.Params["every"] != "5m" {
+		t.Errorf("Unexpected params: %v", received.Params)
+	}
+	start, err := time.Parse(time.RFC3339Nano, received.Params["start"].(string))
+	if err != nil || start.Before(before) || start.After(time.Now().Add(-time.Hour)) {
+		t.Errorf("Expected params.start an hour ago, got %v", received.Params["start"])
+	}
+
+	rows := result.([]map[string]interface{})
+	if len(rows) != 2 || rows[1]["temp"] != 21.0 || rows[1]["humidity"] != 41.0 {
+		t.Errorf("Unexpected rows: %v", rows)
+	}
+	if _, ok := rows[0]["result"]; ok {
+		t.Errorf("Expected the result and table columns to be dropped, got %v", rows[0])
+	}
+
+	for _, details := range []string{
+		`{"flux": "from(bucket: params.bucket)"}`,
+		`{"flux": "from(bucket: \"b\")", "every": "5 minutes"}`,
+		`{"flux": "from(bucket: \"b\")", "time_range": "1h"}`,
+	} {
+		if _, err := ValidateResourceDetails("influxdb_flux", details); err == nil {
+			t.Errorf("Expected %s to be rejected", details)
+		}
+	}
+}
+
+func TestInfluxDBDriver_AggregatedQuery(t *testing.T) {
+	server, received := startTestInfluxServer(t, "#datatype,string,long,dateTime:RFC3339,double,string,string,string\n"+
+		"#group,false,false,false,false,true,true,true\n"+
+		"#default,_result,,,,,,\n"+
+		",result,table,_time,_value,_field,_measurement,site\n"+
+		",,0,2024-05-01T01:00:00Z,20.5,temp,boiler,north\n\n")
+	driver := newTestInfluxDBDriver(t, server.URL)
+
+	validated, err := ValidateResourceDetails("influxdb_query", `{"bucket": "telemetry", "measurement": "boiler", "field": "temp",
+		"fields": ["temp", "pressure"], "tags": {"site": "north"}, "start": "2024-05-01T02:00:00+02:00", "stop": "2024-05-08T00:00:00Z", "every": "1h"}`)
+	if err != nil {
+		t.Fatalf("Expected valid details, got %v", err)
+	}
+	details, err := PrepareResourceDetails("InfluxDB", "influxdb_query", validated, "", url.Values{"fn": {"max"}, "fill": {"previous"}, "tag.line": {"2"}})
+	if err != nil {
+		t.Fatalf("Failed to prepare details: %v", err)
+	}
+	result, err := driver.FetchData(context.Background(), details)
+	if err != nil {
+		t.Fatalf("Failed to fetch data: %v", err)
+	}
+
+	want := `from(bucket: "telemetry")
+	|> range(start: 2024-05-01T00:00:00Z, stop: 2024-05-08T00:00:00Z)
+	|> filter(fn: (r) => r._measurement == "boiler")
+	|> filter(fn: (r) => r._field == "temp" or r._field == "pressure")
+	|> filter(fn: (r) => r["line"] == "2")
+	|> filter(fn: (r) => r["site"] == "north")
+	|> aggregateWindow(every: 1h, fn: max, createEmpty: true)
+	|> fill(usePrevious: true)`
+	if received.Query != want {
+		t.Errorf("Unexpected query:\n%s\nwant:\n%s", received.Query, want)
+	}
+	rows := result.([]map[string]interface{})
+	if len(rows) != 1 || rows[0]["tags"].(map[string]interface{})["site"] != "north" {
+		t.Errorf("Expected the site tag in the result, got %v", result)
+	}
+
+	for _, details := range []string{
+		`{"bucket": "b", "measurement": "m", "time_range": "-1h"}`,
+		`{"bucket": "b", "measurement": "m", "field": "f"}`,
+		`{"bucket": "b", "measurement": "m", "field": "f", "start": "yesterday"}`,
+		`{"bucket": "b", "measurement": "m", "field": "f", "start": "2024-05-02T00:00:00Z", "stop": "2024-05-01T00:00:00Z"}`,
+		`{"bucket": "b", "measurement": "m", "field": "f", "start": "-1d", "fn": "max"}`,
+		`{"bucket": "b", "measurement": "m", "field": "f", "start": "-1d", "every": "1m", "fn": "spread"}`,
+		`{"bucket": "b", "measurement": "m", "field": "f", "start": "-1d", "every": "1m) |> yield("}`,
+	} {
+		if _, err := ValidateResourceDetails("influxdb_query", details); err == nil {
+			t.Errorf("Expected %s to be rejected", details)
+		}
+	}
+}
+
+func TestInfluxDBDriver_InfluxQL(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "reader" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "authorization failed"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/ping":
+			w.Header().Set("X-Influxdb-Version", "1.8.10")
+			w.WriteHeader(http.StatusNoContent)
+		case "/query":
+			query = r.URL.Query()
+			w.Header().Set("Content-Type", "application/json")
+			if strings.HasPrefix(query.Get("q"), "SHOW") {
+				w.Write([]byte(`{"results": [{"statement_id": 0, "series": [{"columns": ["name", "duration", "default"], "values": [["autogen", "0s", true]]}]}]}`))
+				return
+			}
+			w.Write([]byte(`{"results": [{"statement_id": 0, "series": [{"name": "boiler", "tags": {"line": "", "site": "north"},
+				"columns": ["time", "temp", "pressure"], "values": [["2024-05-01T00:00:00Z", 20.5, null], ["2024-05-01T01:00:00Z", 21, 1.2]]}]}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	metadata, err := ValidateMetadata("InfluxDB", `{"url": "`+server.URL+`", "version": "1", "username": "reader", "password": "secret", "database": "plant", "retention_policy": "hourly"}`)
+	if err != nil {
+		t.Fatalf("ValidateMetadata failed: %v", err)
+	}
+	driver, err := NewInfluxDBDriver(metadata)
+	if err != nil {
+		t.Fatalf("Failed to create InfluxDBDriver: %v", err)
+	}
+	defer driver.Disconnect(context.Background())
+	if err := driver.ValidateConfig(context.Background()); err != nil {
+		t.Fatalf("ValidateConfig failed: %v", err)
+	}
+	if info, err := driver.Identify(context.Background()); err != nil || info.Version != "1.8.10" {
+		t.Errorf("Expected version 1.8.10, got %v (%v)", info, err)
+	}
+
+	details, err := PrepareResourceDetails("InfluxDB", "influxdb_query", `{"bucket": "plant", "measurement": "boiler", "fields": ["temp", "pressure"],
+		"tags": {"site": "north's"}, "start": "2024-05-01T00:00:00Z", "stop": "-1h", "every": "1h", "fn": "max"}`, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to prepare details: %v", err)
+	}
+	result, err := driver.FetchData(context.Background(), details)
+	if err != nil {
+		t.Fatalf("Failed to fetch data: %v", err)
+	}
+	want := `SELECT MAX("temp") AS "temp", MAX("pressure") AS "pressure" FROM "plant"."hourly"."boiler" ` +
+		`WHERE time >= '2024-05-01T00:00:00Z' AND time < now() - 1h AND "site" = 'north\'s' GROUP BY time(1h), * fill(none)`
+	if query.Get("q") != want || query.Get("db") != "plant" {
+		t.Errorf("Unexpected query %q on %q, want:\n%s", query.Get("q"), query.Get("db"), want)
+	}
+
+	// Rows have the shape of the Flux path's, without the null left by the missing pressure
+	rows := result.([]map[string]interface{})
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 rows, got %v", rows)
+	}
+	first := rows[0]
+	if first["field"] != "temp" || first["value"] != 20.5 || first["measurement"] != "boiler" ||
+		!first["time"].(time.Time).Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected row: %v", first)
+	}
+	if tags := first["tags"].(map[string]interface{}); len(tags) != 1 || tags["site"] != "north" {
+		t.Errorf("Expected only the site tag, got %v", tags)
+	}
+	if rows[2]["field"] != "pressure" || rows[2]["value"] != 1.2 {
+		t.Errorf("Unexpected row: %v", rows[2])
+	}
+
+	if _, err := driver.FetchData(context.Background(), `{"flux": "from(bucket: \"plant\")"}`); err == nil {
+		t.Error("Expected influxdb_flux to be rejected on InfluxDB 1.x")
+	}
+	if _, err := driver.FetchData(context.Background(), `{"bucket": "plant", "measurement": "boiler", "field": "temp", "start": "-1mo"}`); err == nil {
+		t.Error("Expected a month duration to be rejected by InfluxQL")
+	}
+
+	denied, _ := json.Marshal(model.InfluxDBMetadata{URL: server.URL, Version: "1", Username: "reader", Password: "wrong", Database: "plant"})
+	driver, err = NewInfluxDBDriver(string(denied))
+	if err != nil {
+		t.Fatalf("Failed to create InfluxDBDriver: %v", err)
+	}
+	if err := driver.ValidateConfig(context.Background()); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Expected ErrAuthFailed, got %v", err)
+	}
+
+	for _, metadata := range []string{
+		`{"url": "http://localhost:8086", "version": "1"}`,
+		`{"url": "http://localhost:8086", "version": "1", "database": "plant", "password": "secret"}`,
+		`{"url": "http://localhost:8086", "version": "3", "database": "plant"}`,
+		`{"url": "http://localhost:8086", "database": "plant"}`,
+	} {
+		if _, err := ValidateMetadata("InfluxDB", metadata); err == nil {
+			t.Errorf("Expected %s to be rejected", metadata)
+		}
+	}
+}
+
+func TestInfluxDBDriver_WritePoints(t *testing.T) {
+	var path string
+	var params url.Values
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, params = r.URL.Path, r.URL.Query()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	points := []SinkPoint{
+		{Measurement: "telemetry", Tags: map[string]string{"device": "Press 1", "site": "North"}, Fields: map[string]interface{}{"numeric_value": 21.5}, Time: at},
+		{Measurement: "telemetry", Tags: map[string]string{"device": "Press 1"}, Fields: map[string]interface{}{"string_value": "running"}, Time: at},
+	}
+	want := "telemetry,device=Press\\ 1,site=North numeric_value=21.5 1714564800000000000\n" +
+		"telemetry,device=Press\\ 1 string_value=\"running\" 1714564800000000000"
+
+	driver := newTestInfluxDBDriver(t, server.URL)
+	if err := driver.WritePoints(context.Background(), "", points); err != nil {
+		t.Fatalf("WritePoints failed: %v", err)
+	}
+	if path != "/api/v2/write" || params.Get("bucket") != "telemetry" || params.Get("org") != "plant" {
+		t.Errorf("Unexpected write to %s?%s", path, params.Encode())
+	}
+	if strings.TrimSpace(string(body)) != want {
+		t.Errorf("Unexpected line protocol:\n%s\nwant:\n%s", body, want)
+	}
+
+	metadata, _ := json.Marshal(model.InfluxDBMetadata{URL: server.URL, Version: "1", Database: "plant", RetentionPolicy: "hourly"})
+	v1, err := NewInfluxDBDriver(string(metadata))
+	if err != nil {
+		t.Fatalf("Failed to create InfluxDBDriver: %v", err)
+	}
+	if err := v1.WritePoints(context.Background(), "", points); err != nil {
+		t.Fatalf("WritePoints failed: %v", err)
+	}
+	if path != "/write" || params.Get("db") != "plant" || params.Get("rp") != "hourly" || string(body) != want {
+		t.Errorf("Unexpected write to %s?%s:\n%s", path, params.Encode(), body)
+	}
+}
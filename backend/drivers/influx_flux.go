@@ -0,0 +1,154 @@
+package drivers
+
+import (
+	"app/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	fluxDefaultTimeRange = "-1h"
+	fluxDefaultEvery     = "1m"
+)
+
+// fluxParameters lists the members of the Flux params object passed to influxdb_flux scripts.
+var fluxParameters = []string{"device_alias", "start", "stop", "every"}
+
+var (
+	fluxParamPattern    = regexp.MustCompile(`\bparams\.([A-Za-z_][A-Za-z0-9_]*)`)
+	fluxDurationPattern = regexp.MustCompile(`^([0-9]+(ns|us|µs|ms|s|mo|m|h|d|w|y))+$`)
+)
+
+// validateInfluxDBFluxDetails validates and sanitizes Flux script details.
+func validateInfluxDBFluxDetails(detailsJSON string) (string, error) {
+	var details model.InfluxDBFluxDetails
+	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
+		return "", errors.New("invalid details JSON")
+	}
+
+	details.Flux = strings.TrimSpace(details.Flux)
+	if err := checkFluxParameters(details.Flux); err != nil {
+		return "", err
+	}
+	details.TimeRange = strings.TrimSpace(details.TimeRange)
+	if details.TimeRange == "" {
+		details.TimeRange = fluxDefaultTimeRange
+	}
+	if _, err := ParseRelativeDuration(details.TimeRange); err != nil {
+		return "", err
+	}
+	details.Every = strings.TrimSpace(details.Every)
+	if details.Every == "" {
+		details.Every = fluxDefaultEvery
+	}
+	if !fluxDurationPattern.MatchString(details.Every) {
+		return "", fmt.Errorf("every must be a Flux duration such as '1m' or '1h30m', got %q", details.Every)
+	}
+	// The alias comes from the device-platform association at fetch time
+	details.DeviceAlias = ""
+
+	serialized, err := json.Marshal(details)
+	if err != nil {
+		return "", errors.New("failed to serialize sanitized details")
+	}
+	return string(serialized), nil
+}
+
+// checkFluxParameters checks a script is present and only reads declared parameters, so a typo
+// fails when the resource is saved rather than when it is queried.
+func checkFluxParameters(flux string) error {
+	if flux == "" {
+		return errors.New("flux is required for influxdb_flux")
+	}
+	for _, match := range fluxParamPattern.FindAllStringSubmatch(flux, -1) {
+		known := false
+		for _, name := range fluxParameters {
+			known = known || match[1] == name
+		}
+		if !known {
+			return fmt.Errorf("unknown parameter params.%s, must be one of %s", match[1], strings.Join(fluxParameters, ", "))
+		}
+	}
+	return nil
+}
+
+// prepareInfluxDBFluxDetails sets the device alias and applies the time_range and every query
+// parameters. They reach the script through params, never spliced into it.
+func prepareInfluxDBFluxDetails(detailsJSON, deviceAlias string, params url.Values) (string, error) {
+	var details model.InfluxDBFluxDetails
+	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
+		return "", fmt.Errorf("invalid resource details: %w", err)
+	}
+	details.DeviceAlias = deviceAlias
+	if timeRange := params.Get("time_range"); timeRange != "" {
+		if _, err := ParseRelativeDuration(timeRange); err != nil {
+			return "", err
+		}
+		details.TimeRange = timeRange
+	}
+	if every := params.Get("every"); every != "" {
+		if !fluxDurationPattern.MatchString(every) {
+			return "", fmt.Errorf("invalid every %q, must be a Flux duration such as '1m'", every)
+		}
+		details.Every = every
+	}
+
+	serialized, err := json.Marshal(details)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize InfluxDB details: %w", err)
+	}
+	return string(serialized), nil
+}
+
+// fetchFlux runs a Flux script with its parameters and returns one row per record, holding
+// every column of the record. Pivoted and multi-field results keep their shape.
+func (d *InfluxDBDriver) fetchFlux(ctx context.Context, details model.InfluxDBFluxDetails) (interface{}, error) {
+	if err := checkFluxParameters(details.Flux); err != nil {
+		return nil, err
+	}
+	timeRange, every := details.TimeRange, details.Every
+	if timeRange == "" {
+		timeRange = fluxDefaultTimeRange
+	}
+	if every == "" {
+		every = fluxDefaultEvery
+	}
+	window, err := ParseRelativeDuration(timeRange)
+	if err != nil {
+		return nil, err
+	}
+	stop := time.Now().UTC()
+	params := map[string]interface{}{
+		"device_alias": details.DeviceAlias,
+		"start":        stop.Add(window),
+		"stop":         stop,
+		"every":        every,
+	}
+
+	result, err := d.client.QueryAPI(d.org).QueryWithParams(ctx, details.Flux, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer result.Close()
+
+	rows := []map[string]interface{}{}
+	for result.Next() {
+		row := make(map[string]interface{}, len(result.Record().Values()))
+		for column, value := range result.Record().Values() {
+			if column != "result" && column != "table" {
+				row[column] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("query error: %w", result.Err())
+	}
+	return rows, nil
+}
@@ -0,0 +1,219 @@
+package drivers
+
+import (
+	"app/model"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// influxAggregates lists the aggregate functions influxdb_query resources may apply per window.
+var influxAggregates = []string{"mean", "min", "max", "sum", "count", "first", "last", "median"}
+
+// influxFillModes lists how windows without records are filled.
+var influxFillModes = []string{"none", "null", "previous"}
+
+// NormalizeInfluxQuery validates influxdb_query details and fills in their defaults. A single
+// field is kept in Field and several in Fields.
+func NormalizeInfluxQuery(details *model.InfluxDBResourceDetails) error {
+	details.Bucket = strings.TrimSpace(details.Bucket)
+	details.Measurement = strings.TrimSpace(details.Measurement)
+	if details.Bucket == "" {
+		return errors.New("bucket is required for influxdb_query")
+	}
+	if details.Measurement == "" {
+		return errors.New("measurement is required for influxdb_query")
+	}
+
+	var fields []string
+	for _, field := range append([]string{details.Field}, details.Fields...) {
+		if field = strings.TrimSpace(field); field != "" && !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	switch len(fields) {
+	case 0:
+		return errors.New("field or fields is required for influxdb_query")
+	case 1:
+		details.Field, details.Fields = fields[0], nil
+	default:
+		details.Field, details.Fields = "", fields
+	}
+
+	tags := make(map[string]string, len(details.Tags))
+	for key, value := range details.Tags {
+		if key = strings.TrimSpace(key); key == "" {
+			return errors.New("tag names must not be empty")
+		}
+		tags[key] = strings.TrimSpace(value)
+	}
+	details.Tags = tags
+	if len(tags) == 0 {
+		details.Tags = nil
+	}
+
+	details.TimeRange = strings.TrimSpace(details.TimeRange)
+	details.Start = strings.TrimSpace(details.Start)
+	details.Stop = strings.TrimSpace(details.Stop)
+	if details.Start == "" && details.TimeRange == "" {
+		return errors.New("start or time_range is required for influxdb_query")
+	}
+	if details.TimeRange != "" && !isInfluxTimeRange(details.TimeRange) {
+		return errors.New("time_range must be a negative duration (e.g., '-1h', '-30m', '-1d')")
+	}
+	start, err := fluxTime("start", influxStart(details))
+	if err != nil {
+		return err
+	}
+	if details.Stop != "" {
+		stop, err := fluxTime("stop", details.Stop)
+		if err != nil {
+			return err
+		}
+		if !start.relative && !stop.relative && !start.at.Before(stop.at) {
+			return errors.New("start must be before stop")
+		}
+	}
+
+	details.Every = strings.TrimSpace(details.Every)
+	details.Fn = strings.ToLower(strings.TrimSpace(details.Fn))
+	details.Fill = strings.ToLower(strings.TrimSpace(details.Fill))
+	if details.Every == "" {
+		if details.Fn != "" || details.Fill != "" {
+			return errors.New("fn and fill require an aggregate window in every")
+		}
+		return nil
+	}
+	if !fluxDurationPattern.MatchString(details.Every) {
+		return fmt.Errorf("every must be a Flux duration such as '1m' or '1h30m', got %q", details.Every)
+	}
+	if details.Fn == "" {
+		details.Fn = "mean"
+	}
+	if !slices.Contains(influxAggregates, details.Fn) {
+		return fmt.Errorf("invalid fn %q: must be one of %s", details.Fn, strings.Join(influxAggregates, ", "))
+	}
+	if details.Fill == "" {
+		details.Fill = "none"
+	}
+	if !slices.Contains(influxFillModes, details.Fill) {
+		return fmt.Errorf("invalid fill %q: must be one of %s", details.Fill, strings.Join(influxFillModes, ", "))
+	}
+	return nil
+}
+
+// influxStart returns the start of the queried range; Start takes precedence over TimeRange.
+func influxStart(details *model.InfluxDBResourceDetails) string {
+	if details.Start != "" {
+		return details.Start
+	}
+	return details.TimeRange
+}
+
+// fluxTimeValue is a parsed range bound: a negative duration relative to now or a point in time.
+type fluxTimeValue struct {
+	literal  string // Flux literal for the bound
+	relative bool
+	at       time.Time
+}
+
+// fluxTime parses an RFC 3339 time or a negative Flux duration. Times are reformatted, so the
+// literal never carries anything but the time itself.
+func fluxTime(name, value string) (fluxTimeValue, error) {
+	if isInfluxTimeRange(value) {
+		return fluxTimeValue{literal: value, relative: true}, nil
+	}
+	at, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return fluxTimeValue{}, fmt.Errorf("%s must be an RFC 3339 time or a negative duration (e.g., '2024-05-01T00:00:00Z', '-1h'), got %q", name, value)
+	}
+	return fluxTimeValue{literal: at.UTC().Format(time.RFC3339Nano), at: at}, nil
+}
+
+// applyInfluxQueryParams overrides details with the query parameters of a data request:
+// start, stop, time_range, every, fn, fill, field (comma separated) and tag.<name>.
+func applyInfluxQueryParams(details *model.InfluxDBResourceDetails, params url.Values) {
+	if timeRange := params.Get("time_range"); timeRange != "" {
+		details.TimeRange, details.Start = timeRange, ""
+	}
+	if start := params.Get("start"); start != "" {
+		details.Start = start
+	}
+	if stop := params.Get("stop"); stop != "" {
+		details.Stop = stop
+	}
+	if every := params.Get("every"); every != "" {
+		details.Every = every
+	}
+	if fn := params.Get("fn"); fn != "" {
+		details.Fn = fn
+	}
+	if fill := params.Get("fill"); fill != "" {
+		details.Fill = fill
+	}
+	if field := params.Get("field"); field != "" {
+		details.Field, details.Fields = "", strings.Split(field, ",")
+	}
+	for key, values := range params {
+		if name, ok := strings.CutPrefix(key, "tag."); ok && len(values) > 0 {
+			if details.Tags == nil {
+				details.Tags = make(map[string]string)
+			}
+			details.Tags[name] = values[0]
+		}
+	}
+}
+
+// buildInfluxQuery renders normalized influxdb_query details as a Flux query. Names and tag
+// values are quoted as Flux strings and range bounds are validated literals.
+func buildInfluxQuery(details *model.InfluxDBResourceDetails) (string, error) {
+	start, err := fluxTime("start", influxStart(details))
+	if err != nil {
+		return "", err
+	}
+	bounds := "start: " + start.literal
+	if details.Stop != "" {
+		stop, err := fluxTime("stop", details.Stop)
+		if err != nil {
+			return "", err
+		}
+		bounds += ", stop: " + stop.literal
+	}
+
+	var query strings.Builder
+	fmt.Fprintf(&query, "from(bucket: %s)\n", fluxString(details.Bucket))
+	fmt.Fprintf(&query, "\t|> range(%s)\n", bounds)
+	fmt.Fprintf(&query, "\t|> filter(fn: (r) => r._measurement == %s)\n", fluxString(details.Measurement))
+
+	fields := details.Fields
+	if details.Field != "" {
+		fields = []string{details.Field}
+	}
+	conditions := make([]string, len(fields))
+	for i, field := range fields {
+		conditions[i] = "r._field == " + fluxString(field)
+	}
+	fmt.Fprintf(&query, "\t|> filter(fn: (r) => %s)\n", strings.Join(conditions, " or "))
+
+	names := make([]string, 0, len(details.Tags))
+	for name := range details.Tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&query, "\t|> filter(fn: (r) => r[%s] == %s)\n", fluxString(name), fluxString(details.Tags[name]))
+	}
+
+	if details.Every != "" {
+		// Fn and Fill were checked against fixed lists and Every against the duration pattern
+		fmt.Fprintf(&query, "\t|> aggregateWindow(every: %s, fn: %s, createEmpty: %t)\n", details.Every, details.Fn, details.Fill != "none")
+		if details.Fill == "previous" {
+			query.WriteString("\t|> fill(usePrevious: true)\n")
+		}
+	}
+	return strings.TrimSuffix(query.String(), "\n"), nil
+}
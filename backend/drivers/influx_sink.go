@@ -0,0 +1,134 @@
+package drivers
+
+import (
+	"app/model"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+const (
+	sinkDefaultMeasurement   = "telemetry"
+	sinkDefaultBatchSize     = 500
+	sinkDefaultFlushInterval = 5 * time.Second
+	sinkDefaultBufferSize    = 10000
+	sinkMaxBatchSize         = 10000
+	sinkMaxBufferSize        = 1000000
+	influxWriteErrorSize     = 4 << 10 // Bytes of a rejected write's body kept for the error
+)
+
+// SinkTagSources lists the names a telemetry sink can tag samples with.
+var SinkTagSources = []string{"device", "site", "value_stream", "resource", "platform", "quality"}
+
+// NormalizeTelemetrySink checks the sink settings of platform metadata and fills in their
+// defaults. Tags default to one tag per source, named after it.
+func NormalizeTelemetrySink(sink *model.TelemetrySink) error {
+	sink.Bucket = strings.TrimSpace(sink.Bucket)
+	sink.Measurement = strings.TrimSpace(sink.Measurement)
+	if sink.Measurement == "" {
+		sink.Measurement = sinkDefaultMeasurement
+	}
+	if len(sink.Tags) == 0 {
+		sink.Tags = make(map[string]string, len(SinkTagSources))
+		for _, source := range SinkTagSources {
+			sink.Tags[source] = source
+		}
+	}
+	for source, key := range sink.Tags {
+		if !slices.Contains(SinkTagSources, source) {
+			return fmt.Errorf("unknown sink tag source %q, must be one of %s", source, strings.Join(SinkTagSources, ", "))
+		}
+		if key = strings.TrimSpace(key); key == "" || strings.HasPrefix(key, "_") {
+			return fmt.Errorf("sink tag key for %s must not be empty or start with an underscore", source)
+		}
+		sink.Tags[source] = key
+	}
+
+	if sink.BatchSize == 0 {
+		sink.BatchSize = sinkDefaultBatchSize
+	}
+	if sink.BatchSize < 0 || sink.BatchSize > sinkMaxBatchSize {
+		return fmt.Errorf("sink batch_size must be between 1 and %d", sinkMaxBatchSize)
+	}
+	if sink.BufferSize == 0 {
+		sink.BufferSize = sinkDefaultBufferSize
+	}
+	if sink.BufferSize < sink.BatchSize || sink.BufferSize > sinkMaxBufferSize {
+		return fmt.Errorf("sink buffer_size must be between batch_size and %d", sinkMaxBufferSize)
+	}
+	if sink.FlushInterval == "" {
+		sink.FlushInterval = sinkDefaultFlushInterval.String()
+	}
+	if interval, err := time.ParseDuration(sink.FlushInterval); err != nil || interval < time.Second {
+		return errors.New("sink flush_interval must be a duration of at least 1s, such as '5s'")
+	}
+	return nil
+}
+
+// WritePoints writes points as line protocol to a bucket, or on InfluxDB 1.x to a database
+// or "database/retention_policy". An empty bucket is the platform's.
+func (d *InfluxDBDriver) WritePoints(ctx context.Context, bucket string, points []SinkPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+	lines := make([]string, len(points))
+	for i, point := range points {
+		line := write.PointToLineProtocol(write.NewPoint(point.Measurement, point.Tags, point.Fields, point.Time), time.Nanosecond)
+		lines[i] = strings.TrimSuffix(line, "\n")
+	}
+
+	if d.v1 != nil {
+		if bucket == "" {
+			bucket = d.v1.database
+		}
+		return d.v1.write(ctx, bucket, lines)
+	}
+	if bucket == "" {
+		bucket = d.bucket
+	}
+	if err := d.client.WriteAPIBlocking(d.org, bucket).WriteRecord(ctx, lines...); err != nil {
+		return fmt.Errorf("failed to write points: %w", err)
+	}
+	return nil
+}
+
+// write sends line protocol to the /write API of InfluxDB 1.x.
+func (c *influxQLClient) write(ctx context.Context, bucket string, lines []string) error {
+	database, retentionPolicy := c.source(bucket)
+	params := url.Values{"db": {database}, "precision": {"ns"}}
+	if retentionPolicy != "" {
+		params.Set("rp", retentionPolicy)
+	}
+	target := c.url.JoinPath("write")
+	target.RawQuery = params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to write points: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("credentials rejected: %w", ErrAuthFailed)
+	}
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, influxWriteErrorSize))
+		return fmt.Errorf("write failed with status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package drivers
+
+import (
+	"app/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// influxVersion1 is the metadata version of InfluxDB 1.x platforms.
+const influxVersion1 = "1"
+
+// influxQLAggregates maps the fn of influxdb_query details to InfluxQL functions.
+var influxQLAggregates = map[string]string{
+	"mean": "MEAN", "min": "MIN", "max": "MAX", "sum": "SUM",
+	"count": "COUNT", "first": "FIRST", "last": "LAST", "median": "MEDIAN",
+}
+
+var fluxDurationUnitPattern = regexp.MustCompile(`([0-9]+)(ns|us|µs|ms|s|mo|m|h|d|w|y)`)
+
+// influxQLClient queries InfluxDB 1.x through its /query API.
+type influxQLClient struct {
+	client          *http.Client
+	url             *url.URL
+	username        string
+	password        string
+	database        string
+	retentionPolicy string
+}
+
+// influxQLResponse is the body of a /query response.
+type influxQLResponse struct {
+	Results []struct {
+		Series []influxQLSeries `json:"series"`
+		Error  string           `json:"error"`
+	} `json:"results"`
+	Error string `json:"error"`
+}
+
+// influxQLSeries is one series of a statement's result.
+type influxQLSeries struct {
+	Name    string            `json:"name"`
+	Tags    map[string]string `json:"tags"`
+	Columns []string          `json:"columns"`
+	Values  [][]interface{}   `json:"values"`
+}
+
+// newInfluxQLClient creates the client of an InfluxDB 1.x platform.
+func newInfluxQLClient(config model.InfluxDBMetadata, client *http.Client) (*influxQLClient, error) {
+	if config.Database == "" {
+		return nil, errors.New("missing database in metadata")
+	}
+	base, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	return &influxQLClient{
+		client:          client,
+		url:             base,
+		username:        config.Username,
+		password:        config.Password,
+		database:        config.Database,
+		retentionPolicy: config.RetentionPolicy,
+	}, nil
+}
+
+// validateInfluxQLMetadata validates and sanitizes the InfluxDB 1.x settings of platform metadata.
+func validateInfluxQLMetadata(metadata *model.InfluxDBMetadata) error {
+	metadata.Database = strings.TrimSpace(metadata.Database)
+	metadata.RetentionPolicy = strings.TrimSpace(metadata.RetentionPolicy)
+	metadata.Username = strings.TrimSpace(metadata.Username)
+	if metadata.Database == "" {
+		return errors.New("database is required for InfluxDB 1.x platforms")
+	}
+	if metadata.Password != "" && metadata.Username == "" {
+		return errors.New("username is required with a password")
+	}
+	return nil
+}
+
+// request sends an authenticated GET request to an API path. Queries use GET, so they can be
+// retried.
+func (c *influxQLClient) request(ctx context.Context, path string, params url.Values) (*http.Response, error) {
+	target := c.url.JoinPath(path)
+	target.RawQuery = params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	return c.client.Do(req)
+}
+
+// query runs an InfluxQL statement against a database and returns the series of its result.
+func (c *influxQLClient) query(ctx context.Context, database, statement string) ([]influxQLSeries, error) {
+	resp, err := c.request(ctx, "query", url.Values{"db": {database}, "q": {statement}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("credentials rejected: %w", ErrAuthFailed)
+	}
+
+	var body influxQLResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		if resp.StatusCode >= 400 {
+			return nil, fmt.Errorf("HTTP error: %s", resp.Status)
+		}
+		return nil, fmt.Errorf("invalid query response: %w", err)
+	}
+	if body.Error != "" {
+		return nil, fmt.Errorf("query error: %s", body.Error)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("HTTP error: %s", resp.Status)
+	}
+	var series []influxQLSeries
+	for _, result := range body.Results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("query error: %s", result.Error)
+		}
+		series = append(series, result.Series...)
+	}
+	return series, nil
+}
+
+// ping checks that the server is up and returns the headers of its answer.
+func (c *influxQLClient) ping(ctx context.Context) (http.Header, error) {
+	resp, err := c.request(ctx, "ping", nil)
+	if err != nil {
+		return nil, fmt.Errorf("ping failed: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		// Servers with ping-auth-enabled check credentials here as well
+		return nil, fmt.Errorf("credentials rejected: %w", ErrAuthFailed)
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ping failed with status: %s", resp.Status)
+	}
+	return resp.Header, nil
+}
+
+// validate pings the server, then lists the retention policies of the database, which checks
+// the credentials and that the database exists. The ping endpoint is usually public.
+func (c *influxQLClient) validate(ctx context.Context) error {
+	if _, err := c.ping(ctx); err != nil {
+		return err
+	}
+	_, err := c.query(ctx, c.database, "SHOW RETENTION POLICIES ON "+influxQLIdentifier(c.database))
+	return err
+}
+
+// identify reports the version and build the ping endpoint answers with.
+func (c *influxQLClient) identify(ctx context.Context) (*ServerInfo, error) {
+	header, err := c.ping(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info := &ServerInfo{Product: "influxdb", Version: header.Get("X-Influxdb-Version")}
+	if build := header.Get("X-Influxdb-Build"); build != "" {
+		info.Product += " " + strings.ToLower(build)
+	}
+	return info, nil
+}
+
+// source returns the database and retention policy a bucket names, as the 1.x compatibility
+// API of InfluxDB 2.x reads them: "telegraf/autogen", or "telegraf" for its default retention
+// policy. The platform's retention policy applies to its own database.
+func (c *influxQLClient) source(bucket string) (string, string) {
+	database, retentionPolicy, ok := strings.Cut(bucket, "/")
+	if !ok && database == c.database {
+		retentionPolicy = c.retentionPolicy
+	}
+	return database, retentionPolicy
+}
+
+// fetch runs normalized influxdb_query details as an InfluxQL query and returns the rows the
+// Flux path returns: one per field and record, with time, value, field, measurement and tags.
+func (c *influxQLClient) fetch(ctx context.Context, details *model.InfluxDBResourceDetails) (interface{}, error) {
+	database, retentionPolicy := c.source(details.Bucket)
+	statement, err := buildInfluxQLQuery(details, database, retentionPolicy)
+	if err != nil {
+		return nil, err
+	}
+	series, err := c.query(ctx, database, statement)
+	if err != nil {
+		return nil, err
+	}
+
+	// Series without a value in a window or record come back as null; only fill null keeps them
+	data := []map[string]interface{}{}
+	for _, s := range series {
+		tags := map[string]interface{}{}
+		for name, value := range s.Tags {
+			if value != "" {
+				tags[name] = value
+			}
+		}
+		timeColumn := -1
+		for i, column := range s.Columns {
+			if column == "time" {
+				timeColumn = i
+			}
+		}
+		for i, field := range s.Columns {
+			if i == timeColumn {
+				continue
+			}
+			for _, values := range s.Values {
+				if i >= len(values) || (values[i] == nil && details.Fill != "null") {
+					continue
+				}
+				row := map[string]interface{}{
+					"value":       values[i],
+					"field":       field,
+					"measurement": s.Name,
+				}
+				if timeColumn >= 0 && timeColumn < len(values) {
+					timestamp, _ := values[timeColumn].(string)
+					at, err := time.Parse(time.RFC3339Nano, timestamp)
+					if err != nil {
+						return nil, fmt.Errorf("invalid time %v in query result", values[timeColumn])
+					}
+					row["time"] = at
+				}
+				if len(tags) > 0 {
+					row["tags"] = tags
+				}
+				data = append(data, row)
+			}
+		}
+	}
+	return data, nil
+}
+
+// buildInfluxQLQuery renders normalized influxdb_query details as an InfluxQL query. Names are
+// quoted identifiers, tag values quoted strings and range bounds validated literals. Grouping
+// by every tag returns each series with its tags, as Flux tables carry them.
+func buildInfluxQLQuery(details *model.InfluxDBResourceDetails, database, retentionPolicy string) (string, error) {
+	fields := details.Fields
+	if details.Field != "" {
+		fields = []string{details.Field}
+	}
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = influxQLIdentifier(field)
+		if details.Every != "" {
+			// Fn was checked against the list of aggregates
+			columns[i] = fmt.Sprintf("%s(%s) AS %s", influxQLAggregates[details.Fn], columns[i], columns[i])
+		}
+	}
+	rp := ""
+	if retentionPolicy != "" {
+		rp = influxQLIdentifier(retentionPolicy)
+	}
+
+	start, err := influxQLTime("start", influxStart(details))
+	if err != nil {
+		return "", err
+	}
+	conditions := []string{"time >= " + start}
+	if details.Stop != "" {
+		stop, err := influxQLTime("stop", details.Stop)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, "time < "+stop)
+	}
+	names := make([]string, 0, len(details.Tags))
+	for name := range details.Tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		conditions = append(conditions, influxQLIdentifier(name)+" = "+influxQLString(details.Tags[name]))
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s.%s.%s WHERE %s", strings.Join(columns, ", "),
+		influxQLIdentifier(database), rp, influxQLIdentifier(details.Measurement), strings.Join(conditions, " AND "))
+	if details.Every == "" {
+		return query + " GROUP BY *", nil
+	}
+	every, err := influxQLDuration(details.Every)
+	if err != nil {
+		return "", err
+	}
+	// Fill was checked against the fill modes, which are also InfluxQL's
+	return fmt.Sprintf("%s GROUP BY time(%s), * fill(%s)", query, every, details.Fill), nil
+}
+
+// influxQLTime renders a range bound as an InfluxQL time: an RFC 3339 string or an offset
+// from now().
+func influxQLTime(name, value string) (string, error) {
+	bound, err := fluxTime(name, value)
+	if err != nil {
+		return "", err
+	}
+	if !bound.relative {
+		return influxQLString(bound.literal), nil
+	}
+	duration, err := influxQLDuration(strings.TrimPrefix(bound.literal, "-"))
+	if err != nil {
+		return "", err
+	}
+	return "now() - " + duration, nil
+}
+
+// influxQLDuration converts a Flux duration to an InfluxQL duration literal. InfluxQL has no
+// calendar units, so months and years are rejected.
+func influxQLDuration(value string) (string, error) {
+	if !fluxDurationPattern.MatchString(value) {
+		return "", fmt.Errorf("invalid duration %q", value)
+	}
+	var duration strings.Builder
+	for _, part := range fluxDurationUnitPattern.FindAllStringSubmatch(value, -1) {
+		unit := part[2]
+		switch unit {
+		case "us", "µs":
+			unit = "u"
+		case "mo", "y":
+			return "", fmt.Errorf("duration %q uses %s, which InfluxDB 1.x does not support; use d or w", value, unit)
+		}
+		duration.WriteString(part[1] + unit)
+	}
+	return duration.String(), nil
+}
+
+// influxQLIdentifier quotes a name as an InfluxQL identifier.
+func influxQLIdentifier(name string) string {
+	name = strings.ReplaceAll(name, `\`, `\\`)
+	return `"` + strings.ReplaceAll(name, `"`, `\"`) + `"`
+}
+
+// influxQLString quotes a value as an InfluxQL string literal.
+func influxQLString(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return `'` + strings.ReplaceAll(value, `'`, `\'`) + `'`
+}
@@ -0,0 +1,86 @@
+package drivers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// extractJSONPath returns the value at a dotted path such as "data.readings[0].value"
+// in a decoded JSON document. A leading "$" is accepted and an empty path returns the document.
+func extractJSONPath(doc interface{}, path string) (interface{}, error) {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return doc, nil
+	}
+
+	current := doc
+	for _, segment := range strings.Split(path, ".") {
+		name, indexes, err := splitJSONPathSegment(segment)
+		if err != nil {
+			return nil, err
+		}
+		if name != "" {
+			object, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("json path %q: %q is not an object", path, name)
+			}
+			if current, ok = object[name]; !ok {
+				return nil, fmt.Errorf("json path %q: key %q not found", path, name)
+			}
+		}
+		for _, index := range indexes {
+			array, ok := current.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("json path %q: %q is not an array", path, segment)
+			}
+			if index < 0 || index >= len(array) {
+				return nil, fmt.Errorf("json path %q: index %d out of range", path, index)
+			}
+			current = array[index]
+		}
+	}
+	return current, nil
+}
+
+// splitJSONPathSegment splits "readings[0][1]" into its key and array indexes.
+func splitJSONPathSegment(segment string) (string, []int, error) {
+	name := segment
+	var indexes []int
+	if open := strings.Index(segment, "["); open >= 0 {
+		name = segment[:open]
+		rest := segment[open:]
+		for rest != "" {
+			end := strings.Index(rest, "]")
+			if rest[0] != '[' || end < 0 {
+				return "", nil, fmt.Errorf("invalid json path segment %q", segment)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid array index in json path segment %q", segment)
+			}
+			indexes = append(indexes, index)
+			rest = rest[end+1:]
+		}
+	}
+	if name == "" && len(indexes) == 0 {
+		return "", nil, fmt.Errorf("empty json path segment")
+	}
+	return name, indexes, nil
+}
+
+// ValidateJSONPath checks that a path is well formed without evaluating it.
+func ValidateJSONPath(path string) error {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return nil
+	}
+	for _, segment := range strings.Split(path, ".") {
+		if _, _, err := splitJSONPathSegment(segment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package drivers
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExtractJSONPath(t *testing.T) {
+	var doc interface{}
+	json.Unmarshal([]byte(`{"data": {"readings": [{"value": 1.5}, {"value": [7, 8]}]}, "name": "press"}`), &doc)
+
+	tests := []struct {
+		path    string
+		want    interface{}
+		wantErr bool
+	}{
+		{"name", "press", false},
+		{"$.name", "press", false},
+		{"data.readings[0].value", 1.5, false},
+		{"data.readings[1].value[1]", 8.0, false},
+		{"data.readings[2].value", nil, true},
+		{"data.missing", nil, true},
+		{"name.first", nil, true},
+		{"data.readings[x]", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := extractJSONPath(doc, tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("extractJSONPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("extractJSONPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
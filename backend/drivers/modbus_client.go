@@ -0,0 +1,283 @@
+package drivers
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Modbus function codes for the read and write functions supported by the driver.
+const (
+	modbusReadCoils              byte = 0x01
+	modbusReadDiscreteInputs     byte = 0x02
+	modbusReadHoldingRegisters   byte = 0x03
+	modbusReadInputRegisters     byte = 0x04
+	modbusWriteSingleCoil        byte = 0x05
+	modbusWriteSingleRegister    byte = 0x06
+	modbusWriteMultipleCoils     byte = 0x0F
+	modbusWriteMultipleRegisters byte = 0x10
+)
+
+// Limits on the quantity read or written by a single request, from the Modbus application
+// protocol spec.
+const (
+	modbusMaxBits           = 2000
+	modbusMaxRegisters      = 125
+	modbusMaxWriteBits      = 1968
+	modbusMaxWriteRegisters = 123
+)
+
+// modbusExceptions names the standard Modbus exception codes.
+var modbusExceptions = map[byte]string{
+	0x01: "illegal function",
+	0x02: "illegal data address",
+	0x03: "illegal data value",
+	0x04: "server device failure",
+	0x05: "acknowledge",
+	0x06: "server device busy",
+	0x08: "memory parity error",
+	0x0A: "gateway path unavailable",
+	0x0B: "gateway target device failed to respond",
+}
+
+// modbusClient is a minimal Modbus TCP client for the read function codes and the writes of
+// coils and holding registers.
+// Requests are serialized over a single connection. A connection that failed or got out of
+// step with the device is closed, and the next request opens a new one.
+type modbusClient struct {
+	address string
+	unitID  byte
+	timeout time.Duration
+
+	mu            sync.Mutex
+	conn          net.Conn // Nil once broken, until the next request reconnects
+	closed        bool
+	transactionID uint16
+}
+
+// dialModbus opens a TCP connection to a Modbus server.
+func dialModbus(ctx context.Context, address string, unitID byte, timeout time.Duration) (*modbusClient, error) {
+	c := &modbusClient{address: address, unitID: unitID, timeout: timeout}
+	if err := c.dial(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// dial opens the connection. The caller holds c.mu, or is the only user of c.
+func (c *modbusClient) dial(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	return nil
+}
+
+// broken closes a connection after an I/O or framing error, since a late or partial response
+// would be read as the answer to the next request. The caller holds c.mu.
+func (c *modbusClient) broken(err error) error {
+	c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// readBits reads coils or discrete inputs.
+func (c *modbusClient) readBits(ctx context.Context, functionCode byte, address, quantity uint16) ([]bool, error) {
+	data, err := c.read(ctx, functionCode, address, quantity)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < int(quantity+7)/8 {
+		return nil, fmt.Errorf("short response: %d bytes for %d bits", len(data), quantity)
+	}
+	bits := make([]bool, quantity)
+	for i := range bits {
+		bits[i] = data[i/8]&(1<<(i%8)) != 0
+	}
+	return bits, nil
+}
+
+// readRegisters reads holding or input registers.
+func (c *modbusClient) readRegisters(ctx context.Context, functionCode byte, address, quantity uint16) ([]uint16, error) {
+	data, err := c.read(ctx, functionCode, address, quantity)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != int(quantity)*2 {
+		return nil, fmt.Errorf("short response: %d bytes for %d registers", len(data), quantity)
+	}
+	registers := make([]uint16, quantity)
+	for i := range registers {
+		registers[i] = binary.BigEndian.Uint16(data[i*2:])
+	}
+	return registers, nil
+}
+
+// writeBits writes coils, with the single coil function when there is only one.
+func (c *modbusClient) writeBits(ctx context.Context, address uint16, bits []bool) error {
+	if len(bits) == 0 || len(bits) > modbusMaxWriteBits {
+		return fmt.Errorf("cannot write %d coils in one request", len(bits))
+	}
+	if len(bits) == 1 {
+		pdu := make([]byte, 5)
+		pdu[0] = modbusWriteSingleCoil
+		binary.BigEndian.PutUint16(pdu[1:], address)
+		if bits[0] {
+			binary.BigEndian.PutUint16(pdu[3:], 0xFF00)
+		}
+		return c.write(ctx, pdu)
+	}
+
+	data := make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		if bit {
+			data[i/8] |= 1 << (i % 8)
+		}
+	}
+	pdu := make([]byte, 6, 6+len(data))
+	pdu[0] = modbusWriteMultipleCoils
+	binary.BigEndian.PutUint16(pdu[1:], address)
+	binary.BigEndian.PutUint16(pdu[3:], uint16(len(bits)))
+	pdu[5] = byte(len(data))
+	return c.write(ctx, append(pdu, data...))
+}
+
+// writeRegisters writes holding registers, with the single register function when there is
+// only one.
+func (c *modbusClient) writeRegisters(ctx context.Context, address uint16, registers []uint16) error {
+	if len(registers) == 0 || len(registers) > modbusMaxWriteRegisters {
+		return fmt.Errorf("cannot write %d registers in one request", len(registers))
+	}
+	if len(registers) == 1 {
+		pdu := make([]byte, 5)
+		pdu[0] = modbusWriteSingleRegister
+		binary.BigEndian.PutUint16(pdu[1:], address)
+		binary.BigEndian.PutUint16(pdu[3:], registers[0])
+		return c.write(ctx, pdu)
+	}
+
+	pdu := make([]byte, 6, 6+len(registers)*2)
+	pdu[0] = modbusWriteMultipleRegisters
+	binary.BigEndian.PutUint16(pdu[1:], address)
+	binary.BigEndian.PutUint16(pdu[3:], uint16(len(registers)))
+	pdu[5] = byte(len(registers) * 2)
+	for _, register := range registers {
+		pdu = binary.BigEndian.AppendUint16(pdu, register)
+	}
+	return c.write(ctx, pdu)
+}
+
+// write sends a write request. The device confirms by echoing the address and the value or
+// quantity.
+func (c *modbusClient) write(ctx context.Context, pdu []byte) error {
+	resp, err := c.request(ctx, pdu)
+	if err != nil {
+		return err
+	}
+	if len(resp) != 5 || string(resp[1:5]) != string(pdu[1:5]) {
+		return errors.New("malformed response: write not confirmed")
+	}
+	return nil
+}
+
+// read sends a read request and returns the data bytes following the byte count.
+func (c *modbusClient) read(ctx context.Context, functionCode byte, address, quantity uint16) ([]byte, error) {
+	pdu := make([]byte, 5)
+	pdu[0] = functionCode
+	binary.BigEndian.PutUint16(pdu[1:], address)
+	binary.BigEndian.PutUint16(pdu[3:], quantity)
+
+	resp, err := c.request(ctx, pdu)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < 2 || int(resp[1]) != len(resp)-2 {
+		return nil, errors.New("malformed response: byte count mismatch")
+	}
+	return resp[2:], nil
+}
+
+// request sends one PDU wrapped in an MBAP header and returns the response PDU.
+func (c *modbusClient) request(ctx context.Context, pdu []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, errors.New("connection closed")
+	}
+	if c.conn == nil {
+		if err := c.dial(ctx); err != nil {
+			return nil, fmt.Errorf("failed to reconnect: %w", err)
+		}
+	}
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, c.broken(err)
+	}
+
+	c.transactionID++
+	frame := make([]byte, 7+len(pdu))
+	binary.BigEndian.PutUint16(frame[0:], c.transactionID)
+	binary.BigEndian.PutUint16(frame[2:], 0) // Protocol identifier
+	binary.BigEndian.PutUint16(frame[4:], uint16(len(pdu)+1))
+	frame[6] = c.unitID
+	copy(frame[7:], pdu)
+	if _, err := c.conn.Write(frame); err != nil {
+		return nil, c.broken(fmt.Errorf("failed to send request: %w", err))
+	}
+
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return nil, c.broken(fmt.Errorf("failed to read response: %w", err))
+	}
+	if id := binary.BigEndian.Uint16(header[0:]); id != c.transactionID {
+		return nil, c.broken(fmt.Errorf("unexpected transaction id %d, expected %d", id, c.transactionID))
+	}
+	length := binary.BigEndian.Uint16(header[4:])
+	if length < 2 || length > 254 {
+		return nil, c.broken(fmt.Errorf("invalid response length %d", length))
+	}
+	resp := make([]byte, length-1)
+	if _, err := io.ReadFull(c.conn, resp); err != nil {
+		return nil, c.broken(fmt.Errorf("failed to read response: %w", err))
+	}
+
+	if resp[0] == pdu[0]|0x80 {
+		code := byte(0)
+		if len(resp) > 1 {
+			code = resp[1]
+		}
+		name, ok := modbusExceptions[code]
+		if !ok {
+			name = "unknown exception"
+		}
+		return nil, fmt.Errorf("modbus exception %d: %s", code, name)
+	}
+	if resp[0] != pdu[0] {
+		return nil, c.broken(fmt.Errorf("unexpected function code %d in response", resp[0]))
+	}
+	return resp, nil
+}
+
+// close closes the connection. Requests fail from then on.
+func (c *modbusClient) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
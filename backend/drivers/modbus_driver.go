@@ -0,0 +1,593 @@
+package drivers
+
+import (
+	"app/model"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// modbusFunctionCodes maps function names accepted in resource details to Modbus function codes.
+var modbusFunctionCodes = map[string]byte{
+	"coils":             modbusReadCoils,
+	"discrete_inputs":   modbusReadDiscreteInputs,
+	"holding_registers": modbusReadHoldingRegisters,
+	"input_registers":   modbusReadInputRegisters,
+}
+
+// modbusDataTypeWidths is the number of registers each register data type occupies.
+var modbusDataTypeWidths = map[string]int{
+	"int16":   1,
+	"uint16":  1,
+	"scaled":  1,
+	"int32":   2,
+	"uint32":  2,
+	"float32": 2,
+	"int64":   4,
+	"uint64":  4,
+	"float64": 4,
+}
+
+func init() {
+	Register(Registration{
+		Type:             "ModbusTCP",
+		Label:            "Modbus TCP",
+		New:              func(metadata string) (PlatformDriver, error) { return NewModbusTCPDriver(metadata) },
+		MetadataSchema:   mustLoadSchema("modbus_tcp_metadata.json"),
+		ValidateMetadata: validateModbusTCPMetadata,
+		ResourceTypes: []ResourceType{{
+			Type:            "modbus_register",
+			Label:           "Modbus Register",
+			DetailsSchema:   mustLoadSchema("modbus_register.json"),
+			ValidateDetails: validateModbusRegisterDetails,
+			Points:          PointMapping{Value: "value", UnitPath: "unit"},
+			Writable:        true, // Coils and holding registers only
+			// Register maps address the device directly and need no substitution
+		}},
+	})
+}
+
+// ModbusTCPDriver implements the PlatformDriver interface for Modbus TCP devices.
+type ModbusTCPDriver struct {
+	config  model.ModbusTCPMetadata
+	address string
+	timeout time.Duration
+	client  *modbusClient
+}
+
+// NewModbusTCPDriver creates a new ModbusTCPDriver instance from platform metadata.
+func NewModbusTCPDriver(metadata string) (*ModbusTCPDriver, error) {
+	var config model.ModbusTCPMetadata
+	if err := json.Unmarshal([]byte(metadata), &config); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	if config.Host == "" {
+		return nil, errors.New("missing host in metadata")
+	}
+	if config.Port == 0 {
+		config.Port = 502
+	}
+	if config.Port < 1 || config.Port > 65535 {
+		return nil, fmt.Errorf("invalid port %d", config.Port)
+	}
+	if config.ByteOrder != "" && config.ByteOrder != "big" && config.ByteOrder != "little" {
+		return nil, fmt.Errorf("invalid byte_order %q: must be big or little", config.ByteOrder)
+	}
+	if config.WordOrder != "" && config.WordOrder != "high_first" && config.WordOrder != "low_first" {
+		return nil, fmt.Errorf("invalid word_order %q: must be high_first or low_first", config.WordOrder)
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 // Default timeout
+	}
+
+	return &ModbusTCPDriver{
+		config:  config,
+		address: net.JoinHostPort(config.Host, strconv.Itoa(config.Port)),
+		timeout: time.Duration(config.Timeout) * time.Second,
+	}, nil
+}
+
+// NormalizeModbusRegister validates register details and fills in their defaults.
+func NormalizeModbusRegister(details *model.ModbusRegisterDetails) error {
+	functionCode, ok := modbusFunctionCodes[details.FunctionCode]
+	if !ok {
+		return fmt.Errorf("invalid function_code %q: must be coils, discrete_inputs, holding_registers or input_registers", details.FunctionCode)
+	}
+	if details.Count == 0 {
+		details.Count = 1
+	}
+
+	if functionCode == modbusReadCoils || functionCode == modbusReadDiscreteInputs {
+		if details.DataType == "" {
+			details.DataType = "bool"
+		}
+		if details.DataType != "bool" {
+			return fmt.Errorf("data_type must be bool for %s", details.FunctionCode)
+		}
+	} else {
+		if details.DataType == "" {
+			details.DataType = "uint16"
+		}
+		if _, ok := modbusDataTypeWidths[details.DataType]; !ok {
+			return fmt.Errorf("invalid data_type %q for %s", details.DataType, details.FunctionCode)
+		}
+		if details.DataType == "scaled" && details.Scale == 0 {
+			return errors.New("scale is required for scaled data_type")
+		}
+	}
+
+	quantity, limit := modbusQuantity(functionCode, details)
+	if quantity > limit {
+		return fmt.Errorf("register span of %d exceeds the limit of %d per request", quantity, limit)
+	}
+	if int(details.Address)+quantity > 65536 {
+		return errors.New("register span exceeds the address space")
+	}
+	return nil
+}
+
+// modbusQuantity returns how many bits or registers a resource reads and the per-request limit.
+func modbusQuantity(functionCode byte, details *model.ModbusRegisterDetails) (int, int) {
+	if functionCode == modbusReadCoils || functionCode == modbusReadDiscreteInputs {
+		return int(details.Count), modbusMaxBits
+	}
+	return int(details.Count) * modbusDataTypeWidths[details.DataType], modbusMaxRegisters
+}
+
+// validateModbusTCPMetadata validates and sanitizes Modbus TCP platform metadata.
+func validateModbusTCPMetadata(metadataJSON string) (string, error) {
+	var metadata model.ModbusTCPMetadata
+	if err := json.Unmarshal([]byte(metadataJSON), &metadata); err != nil {
+		return "", errors.New("invalid metadata JSON")
+	}
+
+	metadata.Host = strings.TrimSpace(metadata.Host)
+	if metadata.Host == "" {
+		return "", errors.New("host is required for ModbusTCP platforms")
+	}
+	if metadata.Port == 0 {
+		metadata.Port = 502
+	}
+	if metadata.Timeout == 0 {
+		metadata.Timeout = 10
+	}
+	if metadata.ByteOrder == "" {
+		metadata.ByteOrder = "big"
+	}
+	if metadata.WordOrder == "" {
+		metadata.WordOrder = "high_first"
+	}
+
+	serialized, err := json.Marshal(metadata)
+	if err != nil {
+		return "", errors.New("failed to serialize sanitized metadata")
+	}
+
+	// Let the constructor check the port and byte/word order
+	if _, err := NewModbusTCPDriver(string(serialized)); err != nil {
+		return "", err
+	}
+	return string(serialized), nil
+}
+
+// validateModbusRegisterDetails validates and sanitizes Modbus register map details.
+func validateModbusRegisterDetails(detailsJSON string) (string, error) {
+	var details model.ModbusRegisterDetails
+	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
+		return "", errors.New("invalid details JSON")
+	}
+
+	details.FunctionCode = strings.TrimSpace(details.FunctionCode)
+	details.DataType = strings.TrimSpace(details.DataType)
+	details.Unit = strings.TrimSpace(details.Unit)
+	if err := NormalizeModbusRegister(&details); err != nil {
+		return "", err
+	}
+
+	// Re-serialize sanitized details
+	serialized, err := json.Marshal(details)
+	if err != nil {
+		return "", errors.New("failed to serialize sanitized details")
+	}
+	return string(serialized), nil
+}
+
+// Connect opens the TCP connection to the Modbus device.
+func (d *ModbusTCPDriver) Connect(ctx context.Context) error {
+	client, err := dialModbus(ctx, d.address, d.config.UnitID, d.timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Modbus device: %w", err)
+	}
+	d.client = client
+	return nil
+}
+
+// FetchData reads and decodes a single register map resource.
+func (d *ModbusTCPDriver) FetchData(ctx context.Context, resourceDetails string) (interface{}, error) {
+	result := d.FetchBatch(ctx, []string{resourceDetails})[0]
+	return result.Data, result.Err
+}
+
+// modbusRead is one resource waiting to be served by a batched request.
+type modbusRead struct {
+	index        int
+	details      model.ModbusRegisterDetails
+	functionCode byte
+	quantity     int
+}
+
+// FetchBatch reads several register map resources, merging resources of the same function
+// whose address ranges touch or overlap into a single request.
+func (d *ModbusTCPDriver) FetchBatch(ctx context.Context, resourceDetails []string) []BatchResult {
+	results := make([]BatchResult, len(resourceDetails))
+	if d.client == nil {
+		for i := range results {
+			results[i].Err = errors.New("not connected to Modbus device")
+		}
+		return results
+	}
+
+	var reads []modbusRead
+	for i, raw := range resourceDetails {
+		var details model.ModbusRegisterDetails
+		if err := json.Unmarshal([]byte(raw), &details); err != nil {
+			results[i].Err = fmt.Errorf("invalid resource details: %w", err)
+			continue
+		}
+		if err := NormalizeModbusRegister(&details); err != nil {
+			results[i].Err = err
+			continue
+		}
+		functionCode := modbusFunctionCodes[details.FunctionCode]
+		quantity, _ := modbusQuantity(functionCode, &details)
+		reads = append(reads, modbusRead{index: i, details: details, functionCode: functionCode, quantity: quantity})
+	}
+	sort.Slice(reads, func(i, j int) bool {
+		if reads[i].functionCode != reads[j].functionCode {
+			return reads[i].functionCode < reads[j].functionCode
+		}
+		return reads[i].details.Address < reads[j].details.Address
+	})
+
+	for start := 0; start < len(reads); {
+		first := reads[start]
+		blockStart := int(first.details.Address)
+		blockEnd := blockStart + first.quantity
+		_, limit := modbusQuantity(first.functionCode, &first.details)
+
+		end := start + 1
+		for ; end < len(reads); end++ {
+			next := reads[end]
+			nextEnd := int(next.details.Address) + next.quantity
+			if next.functionCode != first.functionCode || int(next.details.Address) > blockEnd || max(blockEnd, nextEnd)-blockStart > limit {
+				break
+			}
+			blockEnd = max(blockEnd, nextEnd)
+		}
+
+		d.readBlock(ctx, first.functionCode, uint16(blockStart), uint16(blockEnd-blockStart), reads[start:end], results)
+		start = end
+	}
+	return results
+}
+
+// readBlock performs one request and hands each resource its slice of the response.
+func (d *ModbusTCPDriver) readBlock(ctx context.Context, functionCode byte, address, quantity uint16, reads []modbusRead, results []BatchResult) {
+	if functionCode == modbusReadCoils || functionCode == modbusReadDiscreteInputs {
+		bits, err := d.client.readBits(ctx, functionCode, address, quantity)
+		for _, read := range reads {
+			if err != nil {
+				results[read.index].Err = fmt.Errorf("failed to read %s: %w", read.details.FunctionCode, err)
+				continue
+			}
+			offset := int(read.details.Address - address)
+			raw := bits[offset : offset+read.quantity]
+			values := make([]interface{}, len(raw))
+			for i, bit := range raw {
+				values[i] = bit
+			}
+			results[read.index].Data = modbusResult(read.details, values, raw)
+		}
+		return
+	}
+
+	registers, err := d.client.readRegisters(ctx, functionCode, address, quantity)
+	for _, read := range reads {
+		if err != nil {
+			results[read.index].Err = fmt.Errorf("failed to read %s: %w", read.details.FunctionCode, err)
+			continue
+		}
+		offset := int(read.details.Address - address)
+		raw := registers[offset : offset+read.quantity]
+		results[read.index].Data = modbusResult(read.details, d.decodeRegisters(read.details, raw), raw)
+	}
+}
+
+// decodeRegisters converts raw registers into engineering values for the resource's data type.
+func (d *ModbusTCPDriver) decodeRegisters(details model.ModbusRegisterDetails, registers []uint16) []interface{} {
+	width := modbusDataTypeWidths[details.DataType]
+	values := make([]interface{}, details.Count)
+	for i := range values {
+		data := d.registerBytes(registers[i*width : (i+1)*width])
+
+		var value interface{}
+		switch details.DataType {
+		case "int16", "scaled":
+			value = int16(binary.BigEndian.Uint16(data))
+		case "uint16":
+			value = binary.BigEndian.Uint16(data)
+		case "int32":
+			value = int32(binary.BigEndian.Uint32(data))
+		case "uint32":
+			value = binary.BigEndian.Uint32(data)
+		case "float32":
+			value = float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+		case "int64":
+			value = int64(binary.BigEndian.Uint64(data))
+		case "uint64":
+			value = binary.BigEndian.Uint64(data)
+		case "float64":
+			value = math.Float64frombits(binary.BigEndian.Uint64(data))
+		}
+		if details.Scale != 0 || details.Offset != 0 {
+			value = scaleModbusValue(value, details.Scale, details.Offset)
+		}
+		values[i] = value
+	}
+	return values
+}
+
+// registerBytes lays out the registers of one value as big-endian bytes, undoing the
+// device's byte and word order.
+func (d *ModbusTCPDriver) registerBytes(registers []uint16) []byte {
+	data := make([]byte, 0, len(registers)*2)
+	for i := range registers {
+		register := registers[i]
+		if d.config.WordOrder == "low_first" {
+			register = registers[len(registers)-1-i]
+		}
+		if d.config.ByteOrder == "little" {
+			register = register<<8 | register>>8
+		}
+		data = binary.BigEndian.AppendUint16(data, register)
+	}
+	return data
+}
+
+// WriteData writes coils or holding registers. A resource holding several values takes a list
+// of as many values; scale and offset are reversed before the values are encoded. The current
+// values are read first, so a device that cannot be read is not written to.
+func (d *ModbusTCPDriver) WriteData(ctx context.Context, resourceDetails string, value WriteValue) (*WriteResult, error) {
+	if d.client == nil {
+		return nil, errors.New("not connected to Modbus device")
+	}
+	if value.Body != nil || value.Value == nil {
+		return nil, errors.New("modbus_register writes require a value")
+	}
+
+	var details model.ModbusRegisterDetails
+	if err := json.Unmarshal([]byte(resourceDetails), &details); err != nil {
+		return nil, fmt.Errorf("invalid resource details: %w", err)
+	}
+	if err := NormalizeModbusRegister(&details); err != nil {
+		return nil, err
+	}
+	functionCode := modbusFunctionCodes[details.FunctionCode]
+	if functionCode != modbusReadCoils && functionCode != modbusReadHoldingRegisters {
+		return nil, fmt.Errorf("%s cannot be written, only coils and holding_registers", details.FunctionCode)
+	}
+	quantity, _ := modbusQuantity(functionCode, &details)
+	limit := modbusMaxWriteRegisters
+	if functionCode == modbusReadCoils {
+		limit = modbusMaxWriteBits
+	}
+	if quantity > limit {
+		return nil, fmt.Errorf("register span of %d exceeds the limit of %d per write request", quantity, limit)
+	}
+
+	values, ok := value.Value.([]interface{})
+	if !ok {
+		values = []interface{}{value.Value}
+	}
+	if len(values) != int(details.Count) {
+		return nil, fmt.Errorf("expected %d values, got %d", details.Count, len(values))
+	}
+
+	current, err := d.FetchData(ctx, resourceDetails)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current value: %w", err)
+	}
+	result := &WriteResult{OldValue: current.(map[string]interface{})["value"]}
+
+	if functionCode == modbusReadCoils {
+		bits := make([]bool, len(values))
+		written := make([]interface{}, len(values))
+		for i, v := range values {
+			if bits[i], err = writeBool(v); err != nil {
+				return nil, fmt.Errorf("value %d: %w", i, err)
+			}
+			written[i] = bits[i]
+		}
+		if err := d.client.writeBits(ctx, details.Address, bits); err != nil {
+			return nil, fmt.Errorf("failed to write coils: %w", err)
+		}
+		result.NewValue = modbusResult(details, written, bits)["value"]
+		return result, nil
+	}
+
+	registers, err := d.encodeRegisters(details, values)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.client.writeRegisters(ctx, details.Address, registers); err != nil {
+		return nil, fmt.Errorf("failed to write holding_registers: %w", err)
+	}
+	// Report what the registers now hold, after rounding to the data type
+	result.NewValue = modbusResult(details, d.decodeRegisters(details, registers), registers)["value"]
+	return result, nil
+}
+
+// encodeRegisters converts engineering values into raw registers for the resource's data type,
+// the inverse of decodeRegisters.
+func (d *ModbusTCPDriver) encodeRegisters(details model.ModbusRegisterDetails, values []interface{}) ([]uint16, error) {
+	registers := make([]uint16, 0, len(values)*modbusDataTypeWidths[details.DataType])
+	for i, value := range values {
+		data, err := encodeModbusValue(details, value)
+		if err != nil {
+			return nil, fmt.Errorf("value %d: %w", i, err)
+		}
+		registers = append(registers, d.registerWords(data)...)
+	}
+	return registers, nil
+}
+
+// encodeModbusValue reverses scale and offset and encodes one value as big-endian bytes.
+// Integer data types round the unscaled value.
+func encodeModbusValue(details model.ModbusRegisterDetails, value interface{}) ([]byte, error) {
+	if details.Scale != 0 || details.Offset != 0 {
+		f, err := writeFloat(value)
+		if err != nil {
+			return nil, err
+		}
+		scale := details.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		raw := (f - details.Offset) / scale
+		if details.DataType != "float32" && details.DataType != "float64" {
+			raw = math.Round(raw)
+		}
+		value = raw
+	}
+
+	switch details.DataType {
+	case "int16", "scaled":
+		i, err := writeInt(value, math.MinInt16, math.MaxInt16)
+		return binary.BigEndian.AppendUint16(nil, uint16(i)), err
+	case "uint16":
+		u, err := writeUint(value, math.MaxUint16)
+		return binary.BigEndian.AppendUint16(nil, uint16(u)), err
+	case "int32":
+		i, err := writeInt(value, math.MinInt32, math.MaxInt32)
+		return binary.BigEndian.AppendUint32(nil, uint32(i)), err
+	case "uint32":
+		u, err := writeUint(value, math.MaxUint32)
+		return binary.BigEndian.AppendUint32(nil, uint32(u)), err
+	case "float32":
+		f, err := writeFloat(value)
+		if err == nil && math.Abs(f) > math.MaxFloat32 {
+			err = fmt.Errorf("value %v is out of range for float32", f)
+		}
+		return binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(f))), err
+	case "int64":
+		i, err := writeInt(value, math.MinInt64, math.MaxInt64)
+		return binary.BigEndian.AppendUint64(nil, uint64(i)), err
+	case "uint64":
+		u, err := writeUint(value, math.MaxUint64)
+		return binary.BigEndian.AppendUint64(nil, u), err
+	case "float64":
+		f, err := writeFloat(value)
+		return binary.BigEndian.AppendUint64(nil, math.Float64bits(f)), err
+	}
+	return nil, fmt.Errorf("invalid data_type %q", details.DataType)
+}
+
+// registerWords splits the big-endian bytes of one value into registers in the device's byte
+// and word order, the inverse of registerBytes.
+func (d *ModbusTCPDriver) registerWords(data []byte) []uint16 {
+	registers := make([]uint16, len(data)/2)
+	for i := range registers {
+		register := binary.BigEndian.Uint16(data[i*2:])
+		if d.config.ByteOrder == "little" {
+			register = register<<8 | register>>8
+		}
+		if d.config.WordOrder == "low_first" {
+			registers[len(registers)-1-i] = register
+		} else {
+			registers[i] = register
+		}
+	}
+	return registers
+}
+
+// scaleModbusValue applies value*scale+offset, treating an unset scale as 1.
+func scaleModbusValue(value interface{}, scale, offset float64) float64 {
+	if scale == 0 {
+		scale = 1
+	}
+	var raw float64
+	switch v := value.(type) {
+	case int16:
+		raw = float64(v)
+	case uint16:
+		raw = float64(v)
+	case int32:
+		raw = float64(v)
+	case uint32:
+		raw = float64(v)
+	case int64:
+		raw = float64(v)
+	case uint64:
+		raw = float64(v)
+	case float64:
+		raw = v
+	}
+	return raw*scale + offset
+}
+
+// modbusResult builds the driver's result shape; single values are not wrapped in a list.
+func modbusResult(details model.ModbusRegisterDetails, values []interface{}, raw interface{}) map[string]interface{} {
+	result := map[string]interface{}{
+		"function_code": details.FunctionCode,
+		"address":       details.Address,
+		"data_type":     details.DataType,
+		"raw":           raw,
+		"value":         values,
+	}
+	if len(values) == 1 {
+		result["value"] = values[0]
+	}
+	if details.Unit != "" {
+		result["unit"] = details.Unit
+	}
+	return result
+}
+
+// Endpoint returns the host and port of the Modbus device.
+func (d *ModbusTCPDriver) Endpoint() (*Endpoint, error) {
+	return urlEndpoint(d.config.Host, strconv.Itoa(d.config.Port), "502", nil)
+}
+
+// Disconnect closes the connection to the Modbus device.
+func (d *ModbusTCPDriver) Disconnect(ctx context.Context) error {
+	if d.client == nil {
+		return nil
+	}
+	err := d.client.close()
+	d.client = nil
+	return err
+}
+
+// ValidateConfig checks the Modbus device accepts connections.
+func (d *ModbusTCPDriver) ValidateConfig(ctx context.Context) error {
+	if d.client != nil {
+		return nil
+	}
+	if err := d.Connect(ctx); err != nil {
+		return err
+	}
+	return d.Disconnect(ctx)
+}
+
+// TestResource tests a Modbus register map resource.
+func (d *ModbusTCPDriver) TestResource(ctx context.Context, resourceDetails string) (interface{}, error) {
+	return d.FetchData(ctx, resourceDetails)
+}
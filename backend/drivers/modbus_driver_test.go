@@ -0,0 +1,355 @@
+package drivers
+
+import (
+	"app/model"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// testModbusServer serves read and write requests from an in-memory register map.
+type testModbusServer struct {
+	coils    []bool
+	holding  []uint16
+	input    []uint16
+	requests atomic.Int32
+	listener net.Listener
+}
+
+func startTestModbusServer(t *testing.T) *testModbusServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &testModbusServer{
+		coils:    make([]bool, 64),
+		holding:  make([]uint16, 256),
+		input:    make([]uint16, 256),
+		listener: listener,
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *testModbusServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *testModbusServer) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		header := make([]byte, 7)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		pdu := make([]byte, binary.BigEndian.Uint16(header[4:])-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+		s.requests.Add(1)
+
+		resp := s.handle(pdu)
+		frame := append(header[:4:4], 0, 0, header[6])
+		binary.BigEndian.PutUint16(frame[4:], uint16(len(resp)+1))
+		conn.Write(append(frame, resp...))
+	}
+}
+
+func (s *testModbusServer) handle(pdu []byte) []byte {
+	functionCode := pdu[0]
+	address := int(binary.BigEndian.Uint16(pdu[1:]))
+	quantity := int(binary.BigEndian.Uint16(pdu[3:]))
+
+	switch functionCode {
+	case modbusReadCoils:
+		if address+quantity > len(s.coils) {
+			return []byte{functionCode | 0x80, 0x02}
+		}
+		data := make([]byte, (quantity+7)/8)
+		for i := 0; i < quantity; i++ {
+			if s.coils[address+i] {
+				data[i/8] |= 1 << (i % 8)
+			}
+		}
+		return append([]byte{functionCode, byte(len(data))}, data...)
+	case modbusReadHoldingRegisters, modbusReadInputRegisters:
+		registers := s.holding
+		if functionCode == modbusReadInputRegisters {
+			registers = s.input
+		}
+		if address+quantity > len(registers) {
+			return []byte{functionCode | 0x80, 0x02}
+		}
+		resp := []byte{functionCode, byte(quantity * 2)}
+		for _, register := range registers[address : address+quantity] {
+			resp = binary.BigEndian.AppendUint16(resp, register)
+		}
+		return resp
+	case modbusWriteSingleCoil:
+		if address >= len(s.coils) {
+			return []byte{functionCode | 0x80, 0x02}
+		}
+		s.coils[address] = quantity == 0xFF00
+		return pdu
+	case modbusWriteSingleRegister:
+		if address >= len(s.holding) {
+			return []byte{functionCode | 0x80, 0x02}
+		}
+		s.holding[address] = uint16(quantity)
+		return pdu
+	case modbusWriteMultipleCoils:
+		if address+quantity > len(s.coils) {
+			return []byte{functionCode | 0x80, 0x02}
+		}
+		for i := 0; i < quantity; i++ {
+			s.coils[address+i] = pdu[6+i/8]&(1<<(i%8)) != 0
+		}
+		return pdu[:5]
+	case modbusWriteMultipleRegisters:
+		if address+quantity > len(s.holding) {
+			return []byte{functionCode | 0x80, 0x02}
+		}
+		for i := 0; i < quantity; i++ {
+			s.holding[address+i] = binary.BigEndian.Uint16(pdu[6+i*2:])
+		}
+		return pdu[:5]
+	default:
+		return []byte{functionCode | 0x80, 0x01}
+	}
+}
+
+func newTestModbusDriver(t *testing.T, metadata model.ModbusTCPMetadata) *ModbusTCPDriver {
+	t.Helper()
+	metadataJSON, _ := json.Marshal(metadata)
+	driver, err := NewModbusTCPDriver(string(metadataJSON))
+	if err != nil {
+		t.Fatalf("Failed to create ModbusTCPDriver: %v", err)
+	}
+	if err := driver.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { driver.Disconnect(context.Background()) })
+	return driver
+}
+
+func TestNormalizeModbusRegister(t *testing.T) {
+	valid := model.ModbusRegisterDetails{FunctionCode: "holding_registers", Address: 10}
+	if err := NormalizeModbusRegister(&valid); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if valid.Count != 1 || valid.DataType != "uint16" {
+		t.Errorf("Expected defaults count 1 and uint16, got %d and %s", valid.Count, valid.DataType)
+	}
+
+	tests := []model.ModbusRegisterDetails{
+		{FunctionCode: "write_registers"},
+		{FunctionCode: "coils", DataType: "int16"},
+		{FunctionCode: "input_registers", DataType: "bool"},
+		{FunctionCode: "input_registers", DataType: "scaled"},
+		{FunctionCode: "holding_registers", DataType: "float32", Count: 63},
+		{FunctionCode: "holding_registers", Address: 65535, DataType: "uint32"},
+	}
+	for _, details := range tests {
+		if err := NormalizeModbusRegister(&details); err == nil {
+			t.Errorf("Expected error for %+v, got nil", details)
+		}
+	}
+}
+
+func TestModbusTCPDriver_DecodeTypes(t *testing.T) {
+	server := startTestModbusServer(t)
+	server.holding[0] = 0xFF38 // -200
+	bits := math.Float32bits(230.5)
+	server.holding[1], server.holding[2] = uint16(bits>>16), uint16(bits)
+	server.holding[3], server.holding[4] = 0x0001, 0x86A0 // 100000
+	server.input[0] = 1234
+	server.coils[2] = true
+
+	driver := newTestModbusDriver(t, model.ModbusTCPMetadata{Host: "127.0.0.1", Port: server.port(), UnitID: 1, Timeout: 2})
+	ctx := context.Background()
+
+	tests := []struct {
+		details string
+		want    interface{}
+	}{
+		{`{"function_code": "holding_registers", "address": 0, "data_type": "int16"}`, int16(-200)},
+		{`{"function_code": "holding_registers", "address": 1, "data_type": "float32"}`, 230.5},
+		{`{"function_code": "holding_registers", "address": 3, "data_type": "uint32"}`, uint32(100000)},
+		{`{"function_code": "input_registers", "address": 0, "data_type": "scaled", "scale": 0.5, "offset": -100}`, 517.0},
+		{`{"function_code": "coils", "address": 2}`, true},
+	}
+	for _, tt := range tests {
+		result, err := driver.FetchData(ctx, tt.details)
+		if err != nil {
+			t.Errorf("FetchData(%s) failed: %v", tt.details, err)
+			continue
+		}
+		if value := result.(map[string]interface{})["value"]; value != tt.want {
+			t.Errorf("FetchData(%s) = %v (%T), want %v (%T)", tt.details, value, value, tt.want, tt.want)
+		}
+	}
+
+	_, err := driver.FetchData(ctx, `{"function_code": "holding_registers", "address": 250, "count": 10}`)
+	if err == nil || !strings.Contains(err.Error(), "illegal data address") {
+		t.Errorf("Expected illegal data address exception, got %v", err)
+	}
+}
+
+func TestModbusTCPDriver_ByteAndWordOrder(t *testing.T) {
+	server := startTestModbusServer(t)
+	// 0x12345678 stored low word first with swapped bytes
+	server.holding[0], server.holding[1] = 0x7856, 0x3412
+
+	driver := newTestModbusDriver(t, model.ModbusTCPMetadata{Host: "127.0.0.1", Port: server.port(), Timeout: 2, ByteOrder: "little", WordOrder: "low_first"})
+	result, err := driver.FetchData(context.Background(), `{"function_code": "holding_registers", "address": 0, "data_type": "uint32"}`)
+	if err != nil {
+		t.Fatalf("Failed to fetch data: %v", err)
+	}
+	if value := result.(map[string]interface{})["value"]; value != uint32(0x12345678) {
+		t.Errorf("Expected 0x12345678, got %#x", value)
+	}
+}
+
+func TestModbusTCPDriver_WriteData(t *testing.T) {
+	server := startTestModbusServer(t)
+	server.holding[0] = 500 // 50.0 scaled by 0.1
+	driver := newTestModbusDriver(t, model.ModbusTCPMetadata{Host: "127.0.0.1", Port: server.port(), Timeout: 2, WordOrder: "low_first"})
+	ctx := context.Background()
+
+	tests := []struct {
+		details  string
+		value    interface{}
+		oldValue interface{}
+		newValue interface{}
+		raw      string
+	}{
+		{`{"function_code": "holding_registers", "address": 0, "data_type": "int16", "scale": 0.1}`, json.Number("21.56"), 50.0, 21.6, "[216]"},
+		{`{"function_code": "holding_registers", "address": 2, "data_type": "uint32"}`, json.Number("305419896"), uint32(0), uint32(0x12345678), "[22136 4660]"},
+		{`{"function_code": "holding_registers", "address": 4, "count": 2}`, []interface{}{1.0, 2.0}, "[0 0]", "[1 2]", "[1 2]"},
+		{`{"function_code": "coils", "address": 3}`, true, false, true, "[true]"},
+		{`{"function_code": "coils", "address": 8, "count": 3}`, []interface{}{true, false, true}, "[false false false]", "[true false true]", "[true false true]"},
+	}
+	for _, tt := range tests {
+		result, err := driver.WriteData(ctx, tt.details, WriteValue{Value: tt.value})
+		if err != nil {
+			t.Errorf("WriteData(%s) failed: %v", tt.details, err)
+			continue
+		}
+		if fmt.Sprint(result.OldValue) != fmt.Sprint(tt.oldValue) || fmt.Sprint(result.NewValue) != fmt.Sprint(tt.newValue) {
+			t.Errorf("WriteData(%s) = %v -> %v, want %v -> %v", tt.details, result.OldValue, result.NewValue, tt.oldValue, tt.newValue)
+		}
+		data, err := driver.FetchData(ctx, tt.details)
+		if err != nil {
+			t.Errorf("FetchData(%s) failed: %v", tt.details, err)
+			continue
+		}
+		if raw := fmt.Sprint(data.(map[string]interface{})["raw"]); raw != tt.raw {
+			t.Errorf("FetchData(%s) raw = %s, want %s", tt.details, raw, tt.raw)
+		}
+	}
+
+	for _, tt := range []struct {
+		details string
+		value   interface{}
+	}{
+		{`{"function_code": "input_registers", "address": 0}`, 1.0},
+		{`{"function_code": "holding_registers", "address": 0, "data_type": "int16"}`, 40000.0},
+		{`{"function_code": "holding_registers", "address": 0}`, 1.5},
+		{`{"function_code": "holding_registers", "address": 0, "count": 2}`, 1.0},
+	} {
+		if _, err := driver.WriteData(ctx, tt.details, WriteValue{Value: tt.value}); err == nil {
+			t.Errorf("WriteData(%s, %v) succeeded, expected an error", tt.details, tt.value)
+		}
+	}
+}
+
+func TestModbusTCPDriver_FetchBatchMergesContiguousReads(t *testing.T) {
+	server := startTestModbusServer(t)
+	for i := range server.holding {
+		server.holding[i] = uint16(i)
+	}
+	driver := newTestModbusDriver(t, model.ModbusTCPMetadata{Host: "127.0.0.1", Port: server.port(), Timeout: 2})
+
+	details := []string{
+		`{"function_code": "holding_registers", "address": 12, "count": 2}`,
+		`{"function_code": "holding_registers", "address": 10, "count": 2}`,
+		`{"function_code": "holding_registers", "address": 13}`,
+		`{"function_code": "holding_registers", "address": 100}`,
+		`{"function_code": "input_registers", "address": 10}`,
+		`{"function_code": "coils", "data_type": "int16"}`,
+	}
+	results := driver.FetchBatch(context.Background(), details)
+
+	// Addresses 10-13 share one request; 100 and the input register need their own
+	if got := server.requests.Load(); got != 3 {
+		t.Errorf("Expected 3 requests, got %d", got)
+	}
+	for i, want := range []interface{}{[]interface{}{uint16(12), uint16(13)}, []interface{}{uint16(10), uint16(11)}, uint16(13), uint16(100), uint16(0)} {
+		if results[i].Err != nil {
+			t.Errorf("Resource %d failed: %v", i, results[i].Err)
+			continue
+		}
+		if got := results[i].Data.(map[string]interface{})["value"]; fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Resource %d = %v, want %v", i, got, want)
+		}
+	}
+	if results[5].Err == nil {
+		t.Errorf("Expected error for invalid resource, got nil")
+	}
+}
+
+func TestModbusTCPDriver_ReconnectsAfterBrokenResponse(t *testing.T) {
+	server := startTestModbusServer(t)
+	server.holding[5] = 42
+	driver := newTestModbusDriver(t, model.ModbusTCPMetadata{Host: "127.0.0.1", Port: server.port(), Timeout: 2})
+
+	// A device answering with another transaction's response leaves the stream out of step
+	device, conn := net.Pipe()
+	driver.client.conn.Close()
+	driver.client.conn = conn
+	go func() {
+		defer device.Close()
+		request := make([]byte, 12)
+		if _, err := io.ReadFull(device, request); err != nil {
+			return
+		}
+		binary.BigEndian.PutUint16(request[0:], binary.BigEndian.Uint16(request[0:])+1)
+		device.Write(request)
+	}()
+	details := `{"function_code": "holding_registers", "address": 5}`
+	if _, err := driver.FetchData(context.Background(), details); err == nil || !strings.Contains(err.Error(), "transaction id") {
+		t.Fatalf("Expected a transaction id mismatch, got %v", err)
+	}
+
+	data, err := driver.FetchData(context.Background(), details)
+	if err != nil {
+		t.Fatalf("Expected the next read to reconnect, got %v", err)
+	}
+	if value := data.(map[string]interface{})["value"]; value != uint16(42) {
+		t.Errorf("Expected 42, got %v", value)
+	}
+
+	// A closed client does not reconnect
+	client := driver.client
+	driver.Disconnect(context.Background())
+	if _, err := client.readRegisters(context.Background(), modbusReadHoldingRegisters, 5, 1); err == nil {
+		t.Error("Expected reads to fail after disconnecting")
+	}
+}
@@ -0,0 +1,658 @@
+package drivers
+
+import (
+	"app/model"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/logs"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/eclipse/paho.mqtt.golang/packets"
+)
+
+// mqttSchemes lists the broker URL schemes supported by the paho client.
+var mqttSchemes = map[string]bool{"tcp": true, "mqtt": true, "ssl": true, "tls": true, "mqtts": true, "ws": true, "wss": true}
+
+func init() {
+	Register(Registration{
+		Type:             "MQTT",
+		Label:            "MQTT Broker",
+		New:              func(metadata string) (PlatformDriver, error) { return NewMQTTDriver(metadata) },
+		MetadataSchema:   mustLoadSchema("mqtt_metadata.json"),
+		ValidateMetadata: validateMQTTMetadata,
+		ResourceTypes: []ResourceType{{
+			Type:            "mqtt_topic",
+			Label:           "MQTT Topic",
+			DetailsSchema:   mustLoadSchema("mqtt_topic.json"),
+			ValidateDetails: validateMQTTResourceDetails,
+			PrepareDetails:  prepareMQTTResourceDetails,
+			Points:          PointMapping{Value: "value", Timestamp: "received_at"},
+		}},
+	})
+}
+
+// mqttCacheMaxTopics caps the topics a last-value cache holds. Wildcard filters can match any
+// number of topics, so the oldest message is dropped when a new topic arrives at the cap.
+const mqttCacheMaxTopics = 10000
+
+// MQTTDriver implements the PlatformDriver interface for MQTT brokers.
+type MQTTDriver struct {
+	config    model.MQTTMetadata
+	timeout   time.Duration
+	tlsConfig *tls.Config
+
+	mu         sync.Mutex
+	client     mqtt.Client
+	cache      *mqttCache                   // Held while connected
+	subscribed map[string]*mqttSubscription // Topic filters subscribed on the current connection
+}
+
+// mqttSubscription is a topic filter subscribed, or being subscribed, on a connection. done is
+// closed once the broker acknowledged it or that failed with err.
+type mqttSubscription struct {
+	done chan struct{}
+	err  error
+}
+
+// NewMQTTDriver creates a new MQTTDriver instance from platform metadata.
+func NewMQTTDriver(metadata string) (*MQTTDriver, error) {
+	var config model.MQTTMetadata
+	if err := json.Unmarshal([]byte(metadata), &config); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	if config.BrokerURL == "" {
+		return nil, errors.New("missing broker_url in metadata")
+	}
+	brokerURL, err := url.Parse(config.BrokerURL)
+	if err != nil || !mqttSchemes[brokerURL.Scheme] || brokerURL.Host == "" {
+		return nil, fmt.Errorf("invalid broker_url %q: expected tcp://, ssl://, ws:// or wss:// URL", config.BrokerURL)
+	}
+	if config.QoS > 2 {
+		return nil, fmt.Errorf("invalid qos %d: must be 0, 1 or 2", config.QoS)
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 // Default timeout
+	}
+
+	var tlsConfig *tls.Config
+	if config.TLS != nil {
+		if tlsConfig, err = mqttTLSConfig(config.TLS); err != nil {
+			return nil, err
+		}
+	}
+
+	return &MQTTDriver{
+		config:    config,
+		timeout:   time.Duration(config.Timeout) * time.Second,
+		tlsConfig: tlsConfig,
+	}, nil
+}
+
+// mqttTLSConfig builds the TLS configuration for a broker connection.
+func mqttTLSConfig(settings *model.MQTTTLS) (*tls.Config, error) {
+	return clientTLSConfig(&model.HTTPTLS{
+		CACert:             settings.CACert,
+		Certificate:        settings.Certificate,
+		PrivateKey:         settings.PrivateKey,
+		InsecureSkipVerify: settings.InsecureSkipVerify,
+	})
+}
+
+// validateMQTTMetadata validates and sanitizes MQTT platform metadata.
+func validateMQTTMetadata(metadataJSON string) (string, error) {
+	var metadata model.MQTTMetadata
+	if err := json.Unmarshal([]byte(metadataJSON), &metadata); err != nil {
+		return "", errors.New("invalid metadata JSON")
+	}
+
+	metadata.BrokerURL = strings.TrimSpace(metadata.BrokerURL)
+	if metadata.BrokerURL == "" {
+		return "", errors.New("broker_url is required for MQTT platforms")
+	}
+	metadata.ClientID = strings.TrimSpace(metadata.ClientID)
+	metadata.Username = strings.TrimSpace(metadata.Username)
+	if metadata.Password != "" && metadata.Username == "" {
+		return "", errors.New("username is required when password is set")
+	}
+	if metadata.Timeout == 0 {
+		metadata.Timeout = 10
+	}
+	if metadata.TLS != nil {
+		metadata.TLS.CACert = strings.TrimSpace(metadata.TLS.CACert)
+		metadata.TLS.Certificate = strings.TrimSpace(metadata.TLS.Certificate)
+		metadata.TLS.PrivateKey = strings.TrimSpace(metadata.TLS.PrivateKey)
+	}
+
+	serialized, err := json.Marshal(metadata)
+	if err != nil {
+		return "", errors.New("failed to serialize sanitized metadata")
+	}
+
+	// Let the constructor check the broker URL, QoS and TLS material
+	if _, err := NewMQTTDriver(string(serialized)); err != nil {
+		return "", err
+	}
+	return string(serialized), nil
+}
+
+// validateMQTTResourceDetails validates and sanitizes MQTT topic details.
+func validateMQTTResourceDetails(detailsJSON string) (string, error) {
+	var details model.MQTTResourceDetails
+	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
+		return "", errors.New("invalid details JSON")
+	}
+
+	details.Topic = strings.TrimSpace(details.Topic)
+	if details.Topic == "" {
+		return "", errors.New("topic is required for mqtt_topic")
+	}
+	if err := ValidateMQTTTopicFilter(details.Topic); err != nil {
+		return "", err
+	}
+	details.JSONPath = strings.TrimSpace(details.JSONPath)
+	if err := ValidateJSONPath(details.JSONPath); err != nil {
+		return "", fmt.Errorf("json_path is invalid: %v", err)
+	}
+
+	// Re-serialize sanitized details
+	serialized, err := json.Marshal(details)
+	if err != nil {
+		return "", errors.New("failed to serialize sanitized details")
+	}
+	return string(serialized), nil
+}
+
+// prepareMQTTResourceDetails substitutes the device alias into the topic,
+// e.g. "plant/:device_alias/telemetry".
+func prepareMQTTResourceDetails(detailsJSON, deviceAlias string, params url.Values) (string, error) {
+	var details model.MQTTResourceDetails
+	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
+		return "", fmt.Errorf("invalid resource details: %w", err)
+	}
+	if deviceAlias != "" {
+		details.Topic = strings.ReplaceAll(details.Topic, ":device_alias", deviceAlias)
+	}
+
+	serialized, err := json.Marshal(details)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize MQTT details: %w", err)
+	}
+	return string(serialized), nil
+}
+
+// Connect establishes a session with the MQTT broker. Brokers drop the older session when a
+// client ID connects twice, and the pool, subscriptions and health checks each open their own
+// connection, so a configured client ID gets a random suffix per connection.
+func (d *MQTTDriver) Connect(ctx context.Context) error {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	clientID := "iotgo-" + hex.EncodeToString(suffix)
+	if d.config.ClientID != "" {
+		clientID = d.config.ClientID + "-" + hex.EncodeToString(suffix)
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(d.config.BrokerURL).
+		SetClientID(clientID).
+		SetConnectTimeout(d.timeout).
+		SetAutoReconnect(true).
+		SetOrderMatters(false).
+		SetOnConnectHandler(d.resubscribe)
+	if d.config.Username != "" {
+		opts.SetUsername(d.config.Username)
+		opts.SetPassword(d.config.Password)
+	}
+	if d.tlsConfig != nil {
+		opts.SetTLSConfig(d.tlsConfig)
+	}
+
+	d.mu.Lock()
+	if d.cache == nil {
+		d.cache = acquireMQTTCache(d.cacheKey())
+	}
+	d.subscribed = make(map[string]*mqttSubscription)
+	d.mu.Unlock()
+
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if err := waitMQTTToken(ctx, token, d.timeout); err != nil {
+		client.Disconnect(0)
+		d.mu.Lock()
+		if d.client == nil {
+			d.releaseCache()
+		}
+		d.mu.Unlock()
+		if errors.Is(err, packets.ErrorRefusedBadUsernameOrPassword) || errors.Is(err, packets.ErrorRefusedNotAuthorised) {
+			return fmt.Errorf("failed to connect to MQTT broker: %w: %w", ErrAuthFailed, err)
+		}
+		return fmt.Errorf("failed to connect to MQTT broker: %w", err)
+	}
+	d.mu.Lock()
+	previous := d.client
+	d.client = client
+	d.mu.Unlock()
+	if previous != nil {
+		previous.Disconnect(250)
+	}
+	return nil
+}
+
+// cacheKey names the last-value cache of the broker and username.
+func (d *MQTTDriver) cacheKey() string {
+	return d.config.Username + "@" + d.config.BrokerURL
+}
+
+// releaseCache lets go of the last-value cache. The caller holds d.mu.
+func (d *MQTTDriver) releaseCache() {
+	if d.cache != nil {
+		releaseMQTTCache(d.cacheKey())
+		d.cache = nil
+	}
+}
+
+// connection returns the client and cache of the current connection.
+func (d *MQTTDriver) connection() (mqtt.Client, *mqttCache, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.client == nil {
+		return nil, nil, errors.New("not connected to MQTT broker")
+	}
+	return d.client, d.cache, nil
+}
+
+// Endpoint returns the host and port of the broker URL.
+func (d *MQTTDriver) Endpoint() (*Endpoint, error) {
+	u, err := url.Parse(d.config.BrokerURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid broker_url: %w", err)
+	}
+	tlsConfig := d.tlsConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	switch u.Scheme {
+	case "ssl", "tls", "mqtts":
+		return urlEndpoint(u.Hostname(), u.Port(), "8883", tlsConfig)
+	case "wss":
+		return urlEndpoint(u.Hostname(), u.Port(), "443", tlsConfig)
+	case "ws":
+		return urlEndpoint(u.Hostname(), u.Port(), "80", nil)
+	default:
+		return urlEndpoint(u.Hostname(), u.Port(), "1883", nil)
+	}
+}
+
+// resubscribe restores topic subscriptions after the client reconnects with a clean session.
+func (d *MQTTDriver) resubscribe(client mqtt.Client) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cache == nil {
+		return
+	}
+	for filter := range d.subscribed {
+		client.Subscribe(filter, d.config.QoS, d.cache.store)
+	}
+}
+
+// subscribe makes sure the connection receives messages for a topic filter. The request is
+// sent and acknowledged without holding d.mu, so other fetches on the connection go on;
+// callers asking for a filter that is being subscribed wait for that request.
+func (d *MQTTDriver) subscribe(ctx context.Context, filter string) error {
+	d.mu.Lock()
+	if d.client == nil {
+		d.mu.Unlock()
+		return errors.New("not connected to MQTT broker")
+	}
+	sub, ok := d.subscribed[filter]
+	if ok {
+		d.mu.Unlock()
+		select {
+		case <-sub.done:
+			return sub.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	sub = &mqttSubscription{done: make(chan struct{})}
+	client, store, subscribed := d.client, d.cache.store, d.subscribed
+	subscribed[filter] = sub
+	d.mu.Unlock()
+
+	err := waitMQTTToken(ctx, client.Subscribe(filter, d.config.QoS, store), d.timeout)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err != nil {
+		// The next fetch tries again
+		sub.err = fmt.Errorf("failed to subscribe to %s: %w", filter, err)
+		if subscribed[filter] == sub {
+			delete(subscribed, filter)
+		}
+	}
+	close(sub.done)
+	return sub.err
+}
+
+// FetchData returns the last message received on the resource's topic. Retained messages
+// arrive right after subscribing; otherwise it waits up to the timeout for the next message.
+func (d *MQTTDriver) FetchData(ctx context.Context, resourceDetails string) (interface{}, error) {
+	_, cache, err := d.connection()
+	if err != nil {
+		return nil, err
+	}
+	details, err := parseMQTTResourceDetails(resourceDetails)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.subscribe(ctx, details.Topic); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(d.timeout)
+	defer timer.Stop()
+	for {
+		msg, ok, changed := cache.latest(details.Topic)
+		if ok {
+			return mqttMessageResult(msg, details.JSONPath)
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return nil, fmt.Errorf("no message received on %s within %s", details.Topic, d.timeout)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Subscribe streams every message received on the resource's topic.
+func (d *MQTTDriver) Subscribe(ctx context.Context, resourceDetails string) (<-chan DataChange, error) {
+	_, cache, err := d.connection()
+	if err != nil {
+		return nil, err
+	}
+	details, err := parseMQTTResourceDetails(resourceDetails)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, stop := cache.listen(details.Topic)
+	if err := d.subscribe(ctx, details.Topic); err != nil {
+		stop()
+		return nil, err
+	}
+
+	changes := make(chan DataChange)
+	go func() {
+		defer close(changes)
+		defer stop()
+		for {
+			var msg mqttMessage
+			select {
+			case <-ctx.Done():
+				return
+			case msg = <-messages:
+			}
+
+			change := DataChange{ReceivedAt: msg.ReceivedAt}
+			if data, err := mqttMessageResult(msg, details.JSONPath); err != nil {
+				change.Error = err.Error()
+			} else {
+				change.Data = data
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case changes <- change:
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// Disconnect closes the MQTT session. The last driver of a broker to disconnect drops its
+// last-value cache.
+func (d *MQTTDriver) Disconnect(ctx context.Context) error {
+	d.mu.Lock()
+	client := d.client
+	d.client = nil
+	d.subscribed = nil
+	d.releaseCache()
+	d.mu.Unlock()
+	if client != nil {
+		client.Disconnect(250)
+	}
+	return nil
+}
+
+// ValidateConfig checks the broker is reachable with the configured credentials.
+func (d *MQTTDriver) ValidateConfig(ctx context.Context) error {
+	if _, _, err := d.connection(); err == nil {
+		return nil
+	}
+	if err := d.Connect(ctx); err != nil {
+		return err
+	}
+	return d.Disconnect(ctx)
+}
+
+// TestResource tests an MQTT resource configuration.
+func (d *MQTTDriver) TestResource(ctx context.Context, resourceDetails string) (interface{}, error) {
+	return d.FetchData(ctx, resourceDetails)
+}
+
+// parseMQTTResourceDetails decodes and checks MQTT resource details.
+func parseMQTTResourceDetails(resourceDetails string) (model.MQTTResourceDetails, error) {
+	var details model.MQTTResourceDetails
+	if err := json.Unmarshal([]byte(resourceDetails), &details); err != nil {
+		return details, fmt.Errorf("invalid resource details: %w", err)
+	}
+	if err := ValidateMQTTTopicFilter(details.Topic); err != nil {
+		return details, err
+	}
+	return details, nil
+}
+
+// ValidateMQTTTopicFilter checks a topic filter follows the MQTT wildcard rules.
+func ValidateMQTTTopicFilter(filter string) error {
+	if filter == "" {
+		return errors.New("topic is required in resource details")
+	}
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if strings.Contains(level, "#") && (level != "#" || i != len(levels)-1) {
+			return fmt.Errorf("invalid topic %q: # must be the last level on its own", filter)
+		}
+		if strings.Contains(level, "+") && level != "+" {
+			return fmt.Errorf("invalid topic %q: + must occupy a whole level", filter)
+		}
+	}
+	return nil
+}
+
+// mqttTopicMatches reports whether a topic name matches a topic filter.
+func mqttTopicMatches(filter, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) || (level != "+" && level != topicLevels[i]) {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
+
+// mqttMessageResult converts a cached message into the driver's result shape, decoding
+// JSON payloads and extracting the configured JSON path into "value".
+func mqttMessageResult(msg mqttMessage, jsonPath string) (map[string]interface{}, error) {
+	var payload interface{}
+	isJSON := json.Unmarshal(msg.Payload, &payload) == nil
+	if !isJSON {
+		payload = string(msg.Payload)
+	}
+
+	value := payload
+	if jsonPath != "" {
+		if !isJSON {
+			return nil, fmt.Errorf("payload on %s is not JSON, cannot apply json_path", msg.Topic)
+		}
+		extracted, err := extractJSONPath(payload, jsonPath)
+		if err != nil {
+			return nil, err
+		}
+		value = extracted
+	}
+
+	return map[string]interface{}{
+		"topic":       msg.Topic,
+		"value":       value,
+		"payload":     payload,
+		"retained":    msg.Retained,
+		"qos":         msg.QoS,
+		"received_at": msg.ReceivedAt,
+	}, nil
+}
+
+// waitMQTTToken waits for a paho token to complete within the timeout.
+func waitMQTTToken(ctx context.Context, token mqtt.Token, timeout time.Duration) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-time.After(timeout):
+		return errors.New("timed out waiting for broker")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// mqttMessage is a message held in the last-value cache.
+type mqttMessage struct {
+	Topic      string
+	Payload    []byte
+	Retained   bool
+	QoS        byte
+	ReceivedAt time.Time
+}
+
+// mqttCache keeps the last message per topic for one broker and credential pair.
+// It is shared by every driver connected with them, so a long-lived connection
+// (e.g. an open stream) keeps values available to short-lived FetchData calls.
+type mqttCache struct {
+	mu        sync.Mutex
+	messages  map[string]mqttMessage
+	changed   chan struct{} // Closed and replaced whenever a message is stored
+	listeners map[chan mqttMessage]string
+	users     int // Connected drivers, guarded by mqttCaches
+}
+
+var mqttCaches = struct {
+	sync.Mutex
+	byBroker map[string]*mqttCache
+}{byBroker: make(map[string]*mqttCache)}
+
+// acquireMQTTCache returns the last-value cache for a broker and username, creating it for
+// the first connected driver.
+func acquireMQTTCache(key string) *mqttCache {
+	mqttCaches.Lock()
+	defer mqttCaches.Unlock()
+	cache, ok := mqttCaches.byBroker[key]
+	if !ok {
+		cache = &mqttCache{
+			messages:  make(map[string]mqttMessage),
+			changed:   make(chan struct{}),
+			listeners: make(map[chan mqttMessage]string),
+		}
+		mqttCaches.byBroker[key] = cache
+	}
+	cache.users++
+	return cache
+}
+
+// releaseMQTTCache drops the cache once no connected driver uses it.
+func releaseMQTTCache(key string) {
+	mqttCaches.Lock()
+	defer mqttCaches.Unlock()
+	cache, ok := mqttCaches.byBroker[key]
+	if !ok {
+		return
+	}
+	if cache.users--; cache.users <= 0 {
+		delete(mqttCaches.byBroker, key)
+	}
+}
+
+// store is the paho message handler for every subscribed topic filter.
+func (c *mqttCache) store(_ mqtt.Client, m mqtt.Message) {
+	msg := mqttMessage{
+		Topic:      m.Topic(),
+		Payload:    m.Payload(),
+		Retained:   m.Retained(),
+		QoS:        m.Qos(),
+		ReceivedAt: time.Now(),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.messages[msg.Topic]; !ok && len(c.messages) >= mqttCacheMaxTopics {
+		oldest := ""
+		for topic, cached := range c.messages {
+			if oldest == "" || cached.ReceivedAt.Before(c.messages[oldest].ReceivedAt) {
+				oldest = topic
+			}
+		}
+		delete(c.messages, oldest)
+	}
+	c.messages[msg.Topic] = msg
+	close(c.changed)
+	c.changed = make(chan struct{})
+	for listener, filter := range c.listeners {
+		if !mqttTopicMatches(filter, msg.Topic) {
+			continue
+		}
+		select {
+		case listener <- msg:
+		default:
+			logs.Warn("Dropping MQTT message on %s for slow listener", msg.Topic)
+		}
+	}
+}
+
+// latest returns the most recent cached message matching a filter, plus a channel
+// that is closed when the next message is stored.
+func (c *mqttCache) latest(filter string) (mqttMessage, bool, <-chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var found mqttMessage
+	ok := false
+	for topic, msg := range c.messages {
+		if mqttTopicMatches(filter, topic) && (!ok || msg.ReceivedAt.After(found.ReceivedAt)) {
+			found, ok = msg, true
+		}
+	}
+	return found, ok, c.changed
+}
+
+// listen registers a channel receiving every new message matching a filter.
+func (c *mqttCache) listen(filter string) (<-chan mqttMessage, func()) {
+	listener := make(chan mqttMessage, 64)
+	c.mu.Lock()
+	c.listeners[listener] = filter
+	c.mu.Unlock()
+	return listener, func() {
+		c.mu.Lock()
+		delete(c.listeners, listener)
+		c.mu.Unlock()
+	}
+}
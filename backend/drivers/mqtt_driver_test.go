@@ -0,0 +1,281 @@
+package drivers
+
+import (
+	"app/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	mqttserver "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+// startTestMQTTBroker starts an embedded broker that accepts any client.
+func startTestMQTTBroker(t *testing.T) (string, *mqttserver.Server) {
+	t.Helper()
+	broker := mqttserver.New(&mqttserver.Options{InlineClient: true})
+	if err := broker.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatalf("Failed to add auth hook: %v", err)
+	}
+	address := fmt.Sprintf("127.0.0.1:%d", freePort(t))
+	if err := broker.AddListener(listeners.NewTCP(listeners.Config{ID: "test", Address: address})); err != nil {
+		t.Fatalf("Failed to add listener: %v", err)
+	}
+	go broker.Serve()
+	t.Cleanup(func() { broker.Close() })
+	return "tcp://" + address, broker
+}
+
+func newTestMQTTDriver(t *testing.T, metadata model.MQTTMetadata) *MQTTDriver {
+	t.Helper()
+	metadataJSON, _ := json.Marshal(metadata)
+	driver, err := NewMQTTDriver(string(metadataJSON))
+	if err != nil {
+		t.Fatalf("Failed to create MQTTDriver: %v", err)
+	}
+	if err := driver.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { driver.Disconnect(context.Background()) })
+	return driver
+}
+
+func TestNewMQTTDriver_InvalidMetadata(t *testing.T) {
+	tests := []string{
+		`{}`,
+		`{"broker_url": "http://broker:1883"}`,
+		`{"broker_url": "tcp://broker:1883", "qos": 3}`,
+		`{"broker_url": "ssl://broker:8883", "tls": {"ca_cert": "not a certificate"}}`,
+	}
+	for _, metadata := range tests {
+		if _, err := NewMQTTDriver(metadata); err == nil {
+			t.Errorf("Expected error for metadata %s, got nil", metadata)
+		}
+	}
+}
+
+func TestMQTTDriver_FetchRetainedMessage(t *testing.T) {
+	brokerURL, broker := startTestMQTTBroker(t)
+	payload := `{"device": "press-1", "readings": [{"name": "temperature", "value": 71.5}]}`
+	if err := broker.Publish("plant/press-1/telemetry", []byte(payload), true, 0); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	driver := newTestMQTTDriver(t, model.MQTTMetadata{BrokerURL: brokerURL, QoS: 1, Timeout: 2})
+	ctx := context.Background()
+
+	result, err := driver.FetchData(ctx, `{"topic": "plant/press-1/telemetry", "json_path": "readings[0].value"}`)
+	if err != nil {
+		t.Fatalf("Failed to fetch data: %v", err)
+	}
+	data := result.(map[string]interface{})
+	if data["value"] != 71.5 || data["retained"] != true {
+		t.Errorf("Unexpected result: %v", data)
+	}
+
+	// Wildcard filters return the newest message on any matching topic
+	result, err = driver.FetchData(ctx, `{"topic": "plant/+/telemetry", "json_path": "$.device"}`)
+	if err != nil {
+		t.Fatalf("Failed to fetch wildcard data: %v", err)
+	}
+	if value := result.(map[string]interface{})["value"]; value != "press-1" {
+		t.Errorf("Expected value press-1, got %v", value)
+	}
+
+	if _, err := driver.FetchData(ctx, `{"topic": "plant/press-1/telemetry", "json_path": "readings[3].value"}`); err == nil {
+		t.Errorf("Expected error for out of range json path, got nil")
+	}
+	if _, err := driver.FetchData(ctx, `{"topic": "plant/unknown/telemetry"}`); err == nil {
+		t.Errorf("Expected error for topic without messages, got nil")
+	}
+}
+
+func TestMQTTDriver_Subscribe(t *testing.T) {
+	brokerURL, broker := startTestMQTTBroker(t)
+	driver := newTestMQTTDriver(t, model.MQTTMetadata{BrokerURL: brokerURL, Timeout: 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := driver.Subscribe(ctx, `{"topic": "plant/+/status"}`)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	if err := broker.Publish("plant/press-2/status", []byte("running"), false, 0); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	select {
+	case change := <-changes:
+		data := change.Data.(map[string]interface{})
+		if data["value"] != "running" || data["topic"] != "plant/press-2/status" {
+			t.Errorf("Unexpected change: %v", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for message")
+	}
+
+	// The streamed message is also served from the last-value cache
+	result, err := driver.FetchData(context.Background(), `{"topic": "plant/press-2/status"}`)
+	if err != nil {
+		t.Fatalf("Failed to fetch cached data: %v", err)
+	}
+	if value := result.(map[string]interface{})["value"]; value != "running" {
+		t.Errorf("Expected cached value running, got %v", value)
+	}
+}
+
+// slowSubscribeHook holds back SUBSCRIBE packets for the filter until release is closed.
+type slowSubscribeHook struct {
+	mqttserver.HookBase
+	filter  string
+	release chan struct{}
+}
+
+func (h *slowSubscribeHook) ID() string { return "slow-subscribe" }
+
+func (h *slowSubscribeHook) Provides(b byte) bool { return b == mqttserver.OnSubscribe }
+
+func (h *slowSubscribeHook) OnSubscribe(cl *mqttserver.Client, pk packets.Packet) packets.Packet {
+	for _, sub := range pk.Filters {
+		if sub.Filter == h.filter {
+			<-h.release
+		}
+	}
+	return pk
+}
+
+func TestMQTTDriver_SlowSubscribeDoesNotBlockFetches(t *testing.T) {
+	brokerURL, broker := startTestMQTTBroker(t)
+	hook := &slowSubscribeHook{filter: "plant/slow/#", release: make(chan struct{})}
+	if err := broker.AddHook(hook, nil); err != nil {
+		t.Fatalf("Failed to add hook: %v", err)
+	}
+	if err := broker.Publish("plant/press-1/status", []byte("running"), true, 0); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	driver := newTestMQTTDriver(t, model.MQTTMetadata{BrokerURL: brokerURL, Timeout: 5})
+	if _, err := driver.FetchData(context.Background(), `{"topic": "plant/press-1/status"}`); err != nil {
+		t.Fatalf("Failed to fetch data: %v", err)
+	}
+
+	slow := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := driver.FetchData(context.Background(), `{"topic": "plant/slow/#"}`)
+			slow <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond) // Let the SUBSCRIBE reach the broker
+
+	// Fetches of subscribed topics go on while the broker holds back the SUBSCRIBE
+	started := time.Now()
+	if _, err := driver.FetchData(context.Background(), `{"topic": "plant/press-1/status"}`); err != nil {
+		t.Fatalf("Failed to fetch data: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Expected the fetch not to wait for the pending SUBSCRIBE, took %s", elapsed)
+	}
+
+	if err := broker.Publish("plant/slow/level", []byte("3.2"), true, 0); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	close(hook.release)
+	for i := 0; i < 2; i++ {
+		if err := <-slow; err != nil {
+			t.Errorf("Expected the pending subscription to serve both fetches, got %v", err)
+		}
+	}
+}
+
+func TestMQTTDriver_SharedClientIDAndCache(t *testing.T) {
+	brokerURL, broker := startTestMQTTBroker(t)
+	metadataJSON, _ := json.Marshal(model.MQTTMetadata{BrokerURL: brokerURL, ClientID: "gateway", Timeout: 2})
+	var connected []*MQTTDriver
+	for i := 0; i < 2; i++ {
+		driver, err := NewMQTTDriver(string(metadataJSON))
+		if err != nil {
+			t.Fatalf("Failed to create MQTTDriver: %v", err)
+		}
+		if err := driver.Connect(context.Background()); err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		connected = append(connected, driver)
+	}
+
+	// Each connection has its own client ID, so the broker keeps both sessions
+	sessions := 0
+	for id, client := range broker.Clients.GetAll() {
+		if strings.HasPrefix(id, "gateway-") && !client.Closed() {
+			sessions++
+		}
+	}
+	if sessions != 2 {
+		t.Errorf("Expected 2 sessions with the client ID prefix, got %d", sessions)
+	}
+
+	key := connected[0].cacheKey()
+	connected[0].Disconnect(context.Background())
+	mqttCaches.Lock()
+	_, kept := mqttCaches.byBroker[key]
+	mqttCaches.Unlock()
+	if !kept {
+		t.Error("Expected the cache to stay while a driver is connected")
+	}
+	connected[1].Disconnect(context.Background())
+	mqttCaches.Lock()
+	_, kept = mqttCaches.byBroker[key]
+	mqttCaches.Unlock()
+	if kept {
+		t.Error("Expected the last disconnect to drop the cache")
+	}
+}
+
+func TestMQTTCache_CapsTopics(t *testing.T) {
+	cache := acquireMQTTCache("capped@tcp://127.0.0.1:1")
+	defer releaseMQTTCache("capped@tcp://127.0.0.1:1")
+	for i := 0; i <= mqttCacheMaxTopics; i++ {
+		cache.store(nil, &testMQTTMessage{topic: fmt.Sprintf("plant/%d", i)})
+	}
+	if len(cache.messages) != mqttCacheMaxTopics {
+		t.Errorf("Expected %d cached topics, got %d", mqttCacheMaxTopics, len(cache.messages))
+	}
+	if _, ok := cache.messages["plant/0"]; ok {
+		t.Error("Expected the oldest topic to be dropped")
+	}
+}
+
+// testMQTTMessage is a received message handed to the cache directly.
+type testMQTTMessage struct {
+	mqtt.Message
+	topic string
+}
+
+func (m *testMQTTMessage) Topic() string   { return m.topic }
+func (m *testMQTTMessage) Payload() []byte { return []byte("1") }
+func (m *testMQTTMessage) Retained() bool  { return false }
+func (m *testMQTTMessage) Qos() byte       { return 0 }
+
+func TestMQTTTopicMatches(t *testing.T) {
+	tests := []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"plant/press-1/temp", "plant/press-1/temp", true},
+		{"plant/+/temp", "plant/press-1/temp", true},
+		{"plant/+/temp", "plant/press-1/pressure", false},
+		{"plant/#", "plant/press-1/temp", true},
+		{"plant/#", "plant", true},
+		{"plant/+", "plant/press-1/temp", false},
+	}
+	for _, tt := range tests {
+		if got := mqttTopicMatches(tt.filter, tt.topic); got != tt.want {
+			t.Errorf("mqttTopicMatches(%q, %q) = %v, want %v", tt.filter, tt.topic, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gopcua/opcua/id"
+	"github.com/gopcua/opcua/ua"
+)
+
+const (
+	defaultBrowseDepth = 1
+	maxBrowseDepth     = 5
+	defaultBrowseLimit = 100
+	maxBrowseLimit     = 1000
+	maxBrowseNodes     = 5000 // Upper bound on nodes collected in a single walk
+)
+
+// Browse walks the OPC UA address space along hierarchical references, starting
+// at the ObjectsFolder unless another node is given.
+func (d *OPCUADriver) Browse(ctx context.Context, req BrowseRequest) (*BrowseResult, error) {
+	if d.client == nil {
+		return nil, errors.New("not connected to OPCUA server")
+	}
+
+	start := ua.NewNumericNodeID(0, id.ObjectsFolder)
+	if req.NodeID != "" {
+		nodeID, err := ua.ParseNodeID(req.NodeID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node ID: %w", err)
+		}
+		start = nodeID
+	}
+	if req.Depth <= 0 {
+		req.Depth = defaultBrowseDepth
+	}
+	if req.Depth > maxBrowseDepth {
+		req.Depth = maxBrowseDepth
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultBrowseLimit
+	}
+	if req.Limit > maxBrowseLimit {
+		req.Limit = maxBrowseLimit
+	}
+	if req.Offset < 0 {
+		req.Offset = 0
+	}
+
+	type pending struct {
+		nodeID *ua.NodeID
+		depth  int
+	}
+	queue := []pending{{nodeID: start, depth: 1}}
+	visited := map[string]bool{start.String(): true}
+	nodes := []BrowseNode{}
+	truncated := false
+
+walk:
+	for len(queue) > 0 {
+		item := queue[0]
+		queue = queue[1:]
+
+		refs, err := d.browseReferences(ctx, item.nodeID)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			nodeID := ref.NodeID.NodeID
+			key := nodeID.String()
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+
+			if len(nodes) >= maxBrowseNodes {
+				truncated = true
+				break walk
+			}
+			node := BrowseNode{
+				NodeID:       key,
+				ParentNodeID: item.nodeID.String(),
+				Depth:        item.depth,
+				NodeClass:    nodeClassName(ref.NodeClass),
+			}
+			if ref.BrowseName != nil {
+				node.BrowseName = ref.BrowseName.Name
+			}
+			if ref.DisplayName != nil {
+				node.DisplayName = ref.DisplayName.Text
+			}
+			nodes = append(nodes, node)
+
+			if item.depth < req.Depth && (ref.NodeClass == ua.NodeClassObject || ref.NodeClass == ua.NodeClassVariable) {
+				queue = append(queue, pending{nodeID: nodeID, depth: item.depth + 1})
+			}
+		}
+	}
+
+	total := len(nodes)
+	from := min(req.Offset, total)
+	to := min(req.Offset+req.Limit, total)
+	page := nodes[from:to]
+	if err := d.describeVariables(ctx, page); err != nil {
+		return nil, err
+	}
+
+	return &BrowseResult{
+		Nodes:     page,
+		Total:     total,
+		Limit:     req.Limit,
+		Offset:    req.Offset,
+		Truncated: truncated,
+	}, nil
+}
+
+// browseReferences returns all forward hierarchical references of a node,
+// following continuation points until the server has returned everything.
+func (d *OPCUADriver) browseReferences(ctx context.Context, nodeID *ua.NodeID) ([]*ua.ReferenceDescription, error) {
+	resp, err := d.client.Browse(ctx, &ua.BrowseRequest{
+		NodesToBrowse: []*ua.BrowseDescription{{
+			NodeID:          nodeID,
+			BrowseDirection: ua.BrowseDirectionForward,
+			ReferenceTypeID: ua.NewNumericNodeID(0, id.HierarchicalReferences),
+			IncludeSubtypes: true,
+			ResultMask:      uint32(ua.BrowseResultMaskAll),
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to browse %s: %w", nodeID, err)
+	}
+	if len(resp.Results) == 0 {
+		return nil, nil
+	}
+
+	result := resp.Results[0]
+	if result.StatusCode != ua.StatusOK {
+		return nil, fmt.Errorf("failed to browse %s: %w", nodeID, result.StatusCode)
+	}
+	refs := result.References
+	for len(result.ContinuationPoint) > 0 {
+		next, err := d.client.BrowseNext(ctx, &ua.BrowseNextRequest{
+			ContinuationPoints: [][]byte{result.ContinuationPoint},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to continue browsing %s: %w", nodeID, err)
+		}
+		if len(next.Results) == 0 {
+			break
+		}
+		result = next.Results[0]
+		refs = append(refs, result.References...)
+	}
+	return refs, nil
+}
+
+// describeVariables reads the data type and access level of the variable nodes in a page.
+func (d *OPCUADriver) describeVariables(ctx context.Context, nodes []BrowseNode) error {
+	var reads []*ua.ReadValueID
+	var indexes []int
+	for i, node := range nodes {
+		if node.NodeClass != nodeClassName(ua.NodeClassVariable) {
+			continue
+		}
+		nodeID, err := ua.ParseNodeID(node.NodeID)
+		if err != nil {
+			continue
+		}
+		reads = append(reads,
+			&ua.ReadValueID{NodeID: nodeID, AttributeID: ua.AttributeIDDataType},
+			&ua.ReadValueID{NodeID: nodeID, AttributeID: ua.AttributeIDAccessLevel},
+		)
+		indexes = append(indexes, i)
+	}
+	if len(reads) == 0 {
+		return nil
+	}
+
+	resp, err := d.client.Read(ctx, &ua.ReadRequest{NodesToRead: reads})
+	if err != nil {
+		return fmt.Errorf("failed to read variable attributes: %w", err)
+	}
+	for n, i := range indexes {
+		if 2*n+1 >= len(resp.Results) {
+			break
+		}
+		if dataType := resp.Results[2*n]; dataType.Status == ua.StatusOK && dataType.Value != nil {
+			switch typeID := dataType.Value.Value().(type) {
+			case *ua.NodeID:
+				nodes[i].DataType = dataTypeName(typeID)
+			case *ua.ExpandedNodeID:
+				nodes[i].DataType = dataTypeName(typeID.NodeID)
+			}
+		}
+		if access := resp.Results[2*n+1]; access.Status == ua.StatusOK && access.Value != nil {
+			if level, ok := access.Value.Value().(byte); ok {
+				nodes[i].AccessLevel = accessLevelNames(ua.AccessLevelType(level))
+			}
+		}
+	}
+	return nil
+}
+
+// nodeClassName returns the OPC UA name of a node class, e.g. "Variable".
+func nodeClassName(class ua.NodeClass) string {
+	switch class {
+	case ua.NodeClassObject:
+		return "Object"
+	case ua.NodeClassVariable:
+		return "Variable"
+	case ua.NodeClassMethod:
+		return "Method"
+	case ua.NodeClassObjectType:
+		return "ObjectType"
+	case ua.NodeClassVariableType:
+		return "VariableType"
+	case ua.NodeClassReferenceType:
+		return "ReferenceType"
+	case ua.NodeClassDataType:
+		return "DataType"
+	case ua.NodeClassView:
+		return "View"
+	default:
+		return "Unspecified"
+	}
+}
+
+// dataTypeName resolves standard data types (namespace 0) to their names.
+func dataTypeName(typeID *ua.NodeID) string {
+	if typeID.Namespace() == 0 && typeID.IntID() != 0 {
+		if name := id.Name(typeID.IntID()); name != "" {
+			return name
+		}
+	}
+	return typeID.String()
+}
+
+// accessLevelNames lists the access level flags set on a variable.
+func accessLevelNames(level ua.AccessLevelType) []string {
+	names := []string{}
+	flags := []struct {
+		flag ua.AccessLevelType
+		name string
+	}{
+		{ua.AccessLevelTypeCurrentRead, "read"},
+		{ua.AccessLevelTypeCurrentWrite, "write"},
+		{ua.AccessLevelTypeHistoryRead, "history_read"},
+		{ua.AccessLevelTypeHistoryWrite, "history_write"},
+	}
+	for _, f := range flags {
+		if level&f.flag != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
@@ -1,38 +1,72 @@
 package drivers
 
 import (
+	"app/model"
 	"context"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"math"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/id"
 	"github.com/gopcua/opcua/ua"
 )
 
-// OPCUADriver implements the PlatformDriver interface for OPCUA platforms.
-type OPCUADriver struct {
-	client *opcua.Client
-	config OPCUAMetadata
+// opcuaSecurityPolicies maps the supported security policy names to their URIs.
+var opcuaSecurityPolicies = map[string]string{
+	"None":                  ua.SecurityPolicyURINone,
+	"Basic256Sha256":        ua.SecurityPolicyURIBasic256Sha256,
+	"Aes128_Sha256_RsaOaep": ua.SecurityPolicyURIAes128Sha256RsaOaep,
+	"Aes256_Sha256_RsaPss":  ua.SecurityPolicyURIAes256Sha256RsaPss,
 }
 
-// OPCUAMetadata defines the configuration for OPCUA platforms.
-type OPCUAMetadata struct {
-	Endpoint string `json:"endpoint"`
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Timeout  int    `json:"timeout"`
+// opcuaSecurityModes maps the supported message security mode names to their values.
+var opcuaSecurityModes = map[string]ua.MessageSecurityMode{
+	"None":           ua.MessageSecurityModeNone,
+	"Sign":           ua.MessageSecurityModeSign,
+	"SignAndEncrypt": ua.MessageSecurityModeSignAndEncrypt,
 }
 
-// OPCUAResourceDetails defines the details for an OPCUA resource.
-type OPCUAResourceDetails struct {
-	NodeID string `json:"node_id"`
+func init() {
+	Register(Registration{
+		Type:             "OPCUA",
+		Label:            "OPC UA",
+		New:              func(metadata string) (PlatformDriver, error) { return NewOPCUADriver(metadata) },
+		MetadataSchema:   mustLoadSchema("opcua_metadata.json"),
+		ValidateMetadata: validateOPCUAMetadata,
+		ResourceTypes: []ResourceType{{
+			Type:            "opcua_node",
+			Label:           "OPC UA Node",
+			DetailsSchema:   mustLoadSchema("opcua_node.json"),
+			ValidateDetails: validateOPCUAResourceDetails,
+			PrepareDetails:  prepareOPCUAResourceDetails,
+			Points:          PointMapping{Value: "value", Timestamp: "source_timestamp", Quality: "quality"},
+			Writable:        true,
+		}},
+	})
+}
+
+// OPCUADriver implements the PlatformDriver interface for OPCUA platforms.
+type OPCUADriver struct {
+	client    *opcua.Client
+	config    model.OPCUAMetadata
+	policyURI string
+	mode      ua.MessageSecurityMode
+	cert      []byte // DER encoded client certificate
+	key       *rsa.PrivateKey
+	trust     *OPCUATrustStore
 }
 
 // NewOPCUADriver creates a new OPCUADriver instance from platform metadata.
 func NewOPCUADriver(metadata string) (*OPCUADriver, error) {
-	var config OPCUAMetadata
+	var config model.OPCUAMetadata
 	if err := json.Unmarshal([]byte(metadata), &config); err != nil {
 		return nil, fmt.Errorf("invalid metadata: %w", err)
 	}
@@ -42,38 +76,264 @@ func NewOPCUADriver(metadata string) (*OPCUADriver, error) {
 	if config.Timeout == 0 {
 		config.Timeout = 10
 	}
+	if config.SecurityPolicy == "" {
+		config.SecurityPolicy = "None"
+	}
+	if config.SecurityMode == "" {
+		config.SecurityMode = "None"
+	}
 
-	opts := []opcua.Option{
-		opcua.SecurityMode(ua.MessageSecurityModeNone),
-		opcua.AutoReconnect(true),
-		opcua.ReconnectInterval(time.Second * time.Duration(config.Timeout)),
+	policyURI, ok := opcuaSecurityPolicies[config.SecurityPolicy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported security_policy: %s", config.SecurityPolicy)
+	}
+	mode, ok := opcuaSecurityModes[config.SecurityMode]
+	if !ok {
+		return nil, fmt.Errorf("unsupported security_mode: %s", config.SecurityMode)
+	}
+	if (policyURI == ua.SecurityPolicyURINone) != (mode == ua.MessageSecurityModeNone) {
+		return nil, errors.New("security_policy None requires security_mode None and vice versa")
+	}
+
+	driver := &OPCUADriver{
+		config:    config,
+		policyURI: policyURI,
+		mode:      mode,
+		trust:     DefaultOPCUATrustStore(),
+	}
+
+	if mode != ua.MessageSecurityModeNone {
+		if config.Certificate == "" || config.PrivateKey == "" {
+			return nil, errors.New("certificate and private_key are required for secure sessions")
+		}
+		cert, key, err := parseOPCUAKeyPair(config.Certificate, config.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		driver.cert = cert
+		driver.key = key
+	}
+
+	return driver, nil
+}
+
+// parseOPCUAKeyPair decodes a PEM client certificate and RSA private key.
+func parseOPCUAKeyPair(certPEM, keyPEM string) ([]byte, *rsa.PrivateKey, error) {
+	certBlock, _ := pem.Decode([]byte(certPEM))
+	if certBlock == nil || certBlock.Type != "CERTIFICATE" {
+		return nil, nil, errors.New("certificate must be a PEM encoded CERTIFICATE block")
+	}
+	if _, err := x509.ParseCertificate(certBlock.Bytes); err != nil {
+		return nil, nil, fmt.Errorf("invalid certificate: %w", err)
+	}
+
+	keyBlock, _ := pem.Decode([]byte(keyPEM))
+	if keyBlock == nil {
+		return nil, nil, errors.New("private_key must be PEM encoded")
+	}
+	var key *rsa.PrivateKey
+	switch keyBlock.Type {
+	case "RSA PRIVATE KEY":
+		k, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid private_key: %w", err)
+		}
+		key = k
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid private_key: %w", err)
+		}
+		rsaKey, ok := k.(*rsa.PrivateKey)
+		if !ok {
+			return nil, nil, errors.New("private_key must be an RSA key")
+		}
+		key = rsaKey
+	default:
+		return nil, nil, fmt.Errorf("unexpected PEM block type %s for private_key", keyBlock.Type)
+	}
+	return certBlock.Bytes, key, nil
+}
+
+// validateOPCUAMetadata validates and sanitizes OPC UA platform metadata.
+func validateOPCUAMetadata(metadataJSON string) (string, error) {
+	var metadata model.OPCUAMetadata
+	if err := json.Unmarshal([]byte(metadataJSON), &metadata); err != nil {
+		return "", errors.New("invalid metadata JSON")
 	}
-	if config.Username != "" && config.Password != "" {
-		opts = append(opts, opcua.AuthUsername(config.Username, config.Password))
+
+	metadata.Endpoint = strings.TrimSpace(metadata.Endpoint)
+	if metadata.Endpoint == "" {
+		return "", errors.New("endpoint is required for OPCUA platforms")
+	}
+	parsedURL, err := url.Parse(metadata.Endpoint)
+	if err != nil || parsedURL.Scheme != "opc.tcp" || parsedURL.Host == "" {
+		return "", errors.New("endpoint must be a valid opc.tcp:// URL")
 	}
 
-	client, err := opcua.NewClient(config.Endpoint, opts...)
+	if metadata.SecurityPolicy == "" {
+		metadata.SecurityPolicy = "None"
+	}
+	if metadata.SecurityMode == "" {
+		metadata.SecurityMode = "None"
+	}
+	if metadata.Timeout == 0 {
+		metadata.Timeout = 10
+	}
+	metadata.Username = strings.TrimSpace(metadata.Username)
+	if metadata.Password != "" && metadata.Username == "" {
+		return "", errors.New("username is required when password is set")
+	}
+	metadata.Certificate = strings.TrimSpace(metadata.Certificate)
+	metadata.PrivateKey = strings.TrimSpace(metadata.PrivateKey)
+
+	serialized, err := json.Marshal(metadata)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create OPCUA client: %w", err)
+		return "", errors.New("failed to serialize sanitized metadata")
 	}
 
-	return &OPCUADriver{
-		client: client,
-		config: config,
-	}, nil
+	// Let the constructor check the security policy, mode and key pair
+	if _, err := NewOPCUADriver(string(serialized)); err != nil {
+		return "", err
+	}
+	return string(serialized), nil
+}
+
+// validateOPCUAResourceDetails validates and sanitizes OPC UA node details.
+func validateOPCUAResourceDetails(detailsJSON string) (string, error) {
+	var details model.OPCUAResourceDetails
+	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
+		return "", errors.New("invalid details JSON")
+	}
+
+	details.NodeID = strings.TrimSpace(details.NodeID)
+	if details.NodeID == "" {
+		return "", errors.New("node_id is required for opcua_node")
+	}
+	// Node IDs containing the alias placeholder can only be parsed once substituted
+	if !strings.Contains(details.NodeID, ":device_alias") {
+		if _, err := ua.ParseNodeID(details.NodeID); err != nil {
+			return "", fmt.Errorf("node_id is not a valid OPC UA node ID: %v", err)
+		}
+	}
+
+	// Validate monitored item settings used by subscriptions
+	if details.SamplingInterval < 0 {
+		return "", errors.New("sampling_interval must not be negative")
+	}
+	if details.QueueSize > maxOPCUAQueueSize {
+		return "", fmt.Errorf("queue_size must be between 0 and %d", maxOPCUAQueueSize)
+	}
+	switch details.DeadbandType {
+	case "", "None":
+		details.DeadbandType = ""
+		details.DeadbandValue = 0
+	case "Absolute", "Percent":
+		if details.DeadbandValue < 0 {
+			return "", errors.New("deadband_value must not be negative")
+		}
+		if details.DeadbandType == "Percent" && details.DeadbandValue > 100 {
+			return "", errors.New("deadband_value must be between 0 and 100 for Percent deadband")
+		}
+	default:
+		return "", errors.New("deadband_type must be None, Absolute or Percent")
+	}
+
+	// Re-serialize sanitized details
+	serialized, err := json.Marshal(details)
+	if err != nil {
+		return "", errors.New("failed to serialize sanitized details")
+	}
+	return string(serialized), nil
+}
+
+// prepareOPCUAResourceDetails substitutes the device alias into the node ID,
+// e.g. "ns=2;s=:device_alias.Temperature".
+func prepareOPCUAResourceDetails(detailsJSON, deviceAlias string, params url.Values) (string, error) {
+	var details model.OPCUAResourceDetails
+	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
+		return "", fmt.Errorf("invalid resource details: %w", err)
+	}
+	if deviceAlias != "" {
+		details.NodeID = strings.ReplaceAll(details.NodeID, ":device_alias", deviceAlias)
+	}
+
+	serialized, err := json.Marshal(details)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize OPCUA details: %w", err)
+	}
+	return string(serialized), nil
 }
 
 // Connect establishes a connection to the OPCUA server.
 func (d *OPCUADriver) Connect(ctx context.Context) error {
-	if err := d.client.Connect(ctx); err != nil {
-		return fmt.Errorf("failed to connect to OPCUA server: %w", err)
+	client, err := d.dial(ctx)
+	if err != nil {
+		return err
 	}
+	d.client = client
 	return nil
 }
 
+// dial opens a session with the server, checking its certificate against the trust store
+// when the session is secured.
+func (d *OPCUADriver) dial(ctx context.Context) (*opcua.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(d.config.Timeout)*time.Second)
+	defer cancel()
+
+	endpoints, err := opcua.GetEndpoints(ctx, d.config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OPCUA endpoints: %w", err)
+	}
+	ep, err := opcua.SelectEndpoint(endpoints, d.policyURI, d.mode)
+	if err != nil {
+		return nil, fmt.Errorf("server does not offer %s/%s: %w", d.config.SecurityPolicy, d.config.SecurityMode, err)
+	}
+	// Some servers advertise a hostname that is not resolvable from the gateway,
+	// so keep the configured address and only take the security settings.
+	ep.EndpointURL = d.config.Endpoint
+
+	opts := []opcua.Option{
+		opcua.AutoReconnect(true),
+		opcua.ReconnectInterval(time.Second * time.Duration(d.config.Timeout)),
+		opcua.RequestTimeout(time.Second * time.Duration(d.config.Timeout)),
+	}
+	if d.mode != ua.MessageSecurityModeNone {
+		if err := d.trust.Verify(ep.ServerCertificate); err != nil {
+			return nil, err
+		}
+		opts = append(opts, opcua.Certificate(d.cert), opcua.PrivateKey(d.key))
+	}
+
+	authType := ua.UserTokenTypeAnonymous
+	if d.config.Username != "" {
+		authType = ua.UserTokenTypeUserName
+		opts = append(opts, opcua.AuthUsername(d.config.Username, d.config.Password))
+	} else {
+		opts = append(opts, opcua.AuthAnonymous())
+	}
+	opts = append(opts, opcua.SecurityFromEndpoint(ep, authType))
+
+	client, err := opcua.NewClient(ep.EndpointURL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OPCUA client: %w", err)
+	}
+	if err := client.Connect(ctx); err != nil {
+		if errors.Is(err, ua.StatusBadUserAccessDenied) || errors.Is(err, ua.StatusBadIdentityTokenInvalid) || errors.Is(err, ua.StatusBadIdentityTokenRejected) {
+			return nil, fmt.Errorf("failed to connect to OPCUA server: %w: %w", ErrAuthFailed, err)
+		}
+		return nil, fmt.Errorf("failed to connect to OPCUA server: %w", err)
+	}
+	return client, nil
+}
+
 // FetchData retrieves data from an OPCUA node.
 func (d *OPCUADriver) FetchData(ctx context.Context, resourceDetails string) (interface{}, error) {
-	var details OPCUAResourceDetails
+	if d.client == nil {
+		return nil, errors.New("not connected to OPCUA server")
+	}
+
+	var details model.OPCUAResourceDetails
 	if err := json.Unmarshal([]byte(resourceDetails), &details); err != nil {
 		return nil, fmt.Errorf("invalid resource details: %w", err)
 	}
@@ -91,6 +351,7 @@ func (d *OPCUADriver) FetchData(ctx context.Context, resourceDetails string) (in
 		NodesToRead: []*ua.ReadValueID{
 			{NodeID: nodeID, AttributeID: ua.AttributeIDValue},
 		},
+		TimestampsToReturn: ua.TimestampsToReturnBoth,
 	}
 	resp, err := d.client.Read(ctx, req)
 	if err != nil {
@@ -101,25 +362,228 @@ func (d *OPCUADriver) FetchData(ctx context.Context, resourceDetails string) (in
 		return nil, errors.New("no data returned")
 	}
 
+	return opcuaDataValue(details.NodeID, resp.Results[0]), nil
+}
+
+// WriteData writes a value to an OPCUA node. The value is converted to the data type of the
+// node's current value, which is read first and reported as the old value.
+func (d *OPCUADriver) WriteData(ctx context.Context, resourceDetails string, value WriteValue) (*WriteResult, error) {
+	if d.client == nil {
+		return nil, errors.New("not connected to OPCUA server")
+	}
+	if value.Body != nil || value.Value == nil {
+		return nil, errors.New("opcua_node writes require a value")
+	}
+
+	var details model.OPCUAResourceDetails
+	if err := json.Unmarshal([]byte(resourceDetails), &details); err != nil {
+		return nil, fmt.Errorf("invalid resource details: %w", err)
+	}
+	nodeID, err := ua.ParseNodeID(details.NodeID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node ID: %w", err)
+	}
+
+	read, err := d.client.Read(ctx, &ua.ReadRequest{
+		NodesToRead: []*ua.ReadValueID{{NodeID: nodeID, AttributeID: ua.AttributeIDValue}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node: %w", err)
+	}
+	if len(read.Results) == 0 {
+		return nil, errors.New("no data returned")
+	}
+	result := &WriteResult{}
+	current := read.Results[0].Value
+	if current != nil {
+		result.OldValue = current.Value()
+	}
+
+	variant, err := opcuaWriteVariant(current, value.Value)
+	if err != nil {
+		return nil, err
+	}
+	result.NewValue = variant.Value()
+
+	resp, err := d.client.Write(ctx, &ua.WriteRequest{
+		NodesToWrite: []*ua.WriteValue{{
+			NodeID:      nodeID,
+			AttributeID: ua.AttributeIDValue,
+			Value:       &ua.DataValue{EncodingMask: ua.DataValueValue, Value: variant},
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write node: %w", err)
+	}
+	if len(resp.Results) == 0 {
+		return nil, errors.New("no write result returned")
+	}
+	if status := resp.Results[0]; status != ua.StatusOK {
+		return result, fmt.Errorf("write rejected: %w", status)
+	}
+	return result, nil
+}
+
+// opcuaWriteVariant converts a written value to the data type of the node's current value. Nodes
+// without a value take the type that matches the JSON value.
+func opcuaWriteVariant(current *ua.Variant, value interface{}) (*ua.Variant, error) {
+	typeID := ua.TypeIDNull
+	if current != nil {
+		typeID = current.Type()
+	}
+	if typeID == ua.TypeIDNull {
+		switch value.(type) {
+		case bool:
+			typeID = ua.TypeIDBoolean
+		case string:
+			typeID = ua.TypeIDString
+		default:
+			typeID = ua.TypeIDDouble
+		}
+	}
+
+	var converted interface{}
+	var err error
+	switch typeID {
+	case ua.TypeIDBoolean:
+		converted, err = writeBool(value)
+	case ua.TypeIDSByte:
+		var i int64
+		i, err = writeInt(value, math.MinInt8, math.MaxInt8)
+		converted = int8(i)
+	case ua.TypeIDByte:
+		var u uint64
+		u, err = writeUint(value, math.MaxUint8)
+		converted = uint8(u)
+	case ua.TypeIDInt16:
+		var i int64
+		i, err = writeInt(value, math.MinInt16, math.MaxInt16)
+		converted = int16(i)
+	case ua.TypeIDUint16:
+		var u uint64
+		u, err = writeUint(value, math.MaxUint16)
+		converted = uint16(u)
+	case ua.TypeIDInt32:
+		var i int64
+		i, err = writeInt(value, math.MinInt32, math.MaxInt32)
+		converted = int32(i)
+	case ua.TypeIDUint32:
+		var u uint64
+		u, err = writeUint(value, math.MaxUint32)
+		converted = uint32(u)
+	case ua.TypeIDInt64:
+		converted, err = writeInt(value, math.MinInt64, math.MaxInt64)
+	case ua.TypeIDUint64:
+		converted, err = writeUint(value, math.MaxUint64)
+	case ua.TypeIDFloat:
+		var f float64
+		f, err = writeFloat(value)
+		converted = float32(f)
+	case ua.TypeIDDouble:
+		converted, err = writeFloat(value)
+	case ua.TypeIDString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %T", value)
+		}
+		converted = s
+	default:
+		return nil, fmt.Errorf("writing nodes of data type %s is not supported", typeID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ua.NewVariant(converted)
+}
+
+// opcuaDataValue converts a read or monitored data value into the driver's result shape.
+func opcuaDataValue(nodeID string, result *ua.DataValue) map[string]interface{} {
+	var value interface{}
+	if result.Value != nil {
+		value = result.Value.Value()
+	}
 	return map[string]interface{}{
-		"node_id": details.NodeID,
-		"value":   resp.Results[0].Value,
-		"status":  resp.Results[0].Status,
-	}, nil
+		"node_id":          nodeID,
+		"value":            value,
+		"status":           result.Status.Error(),
+		"quality":          opcuaQuality(result.Status),
+		"source_timestamp": result.SourceTimestamp,
+		"server_timestamp": result.ServerTimestamp,
+	}
+}
+
+// opcuaQuality maps the severity bits of a status code to a sample quality.
+func opcuaQuality(status ua.StatusCode) string {
+	switch uint32(status) >> 30 {
+	case 0:
+		return model.QualityGood
+	case 1:
+		return model.QualityUncertain
+	default:
+		return model.QualityBad
+	}
+}
+
+// Endpoint returns the host and port of the opc.tcp endpoint.
+func (d *OPCUADriver) Endpoint() (*Endpoint, error) {
+	u, err := url.Parse(d.config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	// OPC UA secures sessions itself, so there is no TLS handshake to check
+	return urlEndpoint(u.Hostname(), u.Port(), "4840", nil)
+}
+
+// Identify reads the server's build information.
+func (d *OPCUADriver) Identify(ctx context.Context) (*ServerInfo, error) {
+	if d.client == nil {
+		if err := d.Connect(ctx); err != nil {
+			return nil, err
+		}
+		defer d.Disconnect(ctx)
+	}
+
+	nodes := []uint32{
+		id.Server_ServerStatus_BuildInfo_ProductName,
+		id.Server_ServerStatus_BuildInfo_SoftwareVersion,
+		id.Server_ServerStatus_BuildInfo_ManufacturerName,
+	}
+	req := &ua.ReadRequest{TimestampsToReturn: ua.TimestampsToReturnNeither}
+	for _, n := range nodes {
+		req.NodesToRead = append(req.NodesToRead, &ua.ReadValueID{NodeID: ua.NewNumericNodeID(0, n), AttributeID: ua.AttributeIDValue})
+	}
+	resp, err := d.client.Read(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build info: %w", err)
+	}
+
+	values := make([]string, len(nodes))
+	for i, result := range resp.Results {
+		if i < len(values) && result.Status == ua.StatusOK && result.Value != nil {
+			values[i], _ = result.Value.Value().(string)
+		}
+	}
+	return &ServerInfo{Product: values[0], Version: values[1], Vendor: values[2]}, nil
 }
 
 // Disconnect closes the OPCUA connection.
 func (d *OPCUADriver) Disconnect(ctx context.Context) error {
-	return d.client.Close(ctx)
+	if d.client == nil {
+		return nil
+	}
+	err := d.client.Close(ctx)
+	d.client = nil
+	return err
 }
 
 // ValidateConfig checks if the OPCUA configuration is valid.
 func (d *OPCUADriver) ValidateConfig(ctx context.Context) error {
-	if err := d.Connect(ctx); err != nil {
+	// A session of its own, so a connected driver is checked against the current trust store
+	client, err := d.dial(ctx)
+	if err != nil {
 		return err
 	}
-	defer d.Disconnect(ctx)
-	return nil
+	return client.Close(ctx)
 }
 
 // TestResource tests an OPCUA resource by reading the node.
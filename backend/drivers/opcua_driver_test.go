@@ -0,0 +1,430 @@
+package drivers
+
+import (
+	"app/model"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopcua/opcua/id"
+	"github.com/gopcua/opcua/server"
+	"github.com/gopcua/opcua/ua"
+	"github.com/gopcua/opcua/uapolicy"
+	"github.com/gopcua/opcua/uasc"
+)
+
+// generateTestCertificate creates a self-signed OPC UA application certificate.
+func generateTestCertificate(t *testing.T, appURI string) ([]byte, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	uri, _ := url.Parse(appURI)
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: appURI},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageContentCommitment | x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageDataEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		URIs:                  []*url.URL{uri},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return der, key
+}
+
+// freePort returns a TCP port that is free on localhost.
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// startTestOPCUAServer starts a gopcua server exposing a single Temperature variable.
+func startTestOPCUAServer(t *testing.T) (endpoint string, serverCert []byte, ns *server.NodeNameSpace) {
+	t.Helper()
+	cert, key := generateTestCertificate(t, "urn:iotgo:test:server")
+	endpoint, ns = serveTestOPCUA(t, cert, key)
+	return endpoint, cert, ns
+}
+
+// serveTestOPCUA starts a gopcua server with the given application certificate. The server
+// also advertises its endpoints on the proxy ports.
+func serveTestOPCUA(t *testing.T, cert []byte, key *rsa.PrivateKey, proxyPorts ...int) (endpoint string, ns *server.NodeNameSpace) {
+	t.Helper()
+	port := freePort(t)
+
+	opts := []server.Option{server.EndPoint("localhost", port)}
+	for _, proxyPort := range proxyPorts {
+		opts = append(opts, server.EndPoint("localhost", proxyPort))
+	}
+	s := server.New(append(opts,
+		server.Certificate(cert),
+		server.PrivateKey(key),
+		server.EnableSecurity("None", ua.MessageSecurityModeNone),
+		server.EnableSecurity("Basic256Sha256", ua.MessageSecurityModeSignAndEncrypt),
+		server.EnableAuthMode(ua.UserTokenTypeAnonymous),
+	)...)
+
+	ns = server.NewNodeNameSpace(s, "IoTGo")
+	rootNS, _ := s.Namespace(0)
+	rootNS.Objects().AddRef(ns.Objects(), id.HasComponent, true)
+	node := ns.AddNewVariableStringNode("Temperature", 21.5)
+	ns.Objects().AddRef(node, id.HasComponent, true)
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start OPCUA server: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return fmt.Sprintf("opc.tcp://localhost:%d", port), ns
+}
+
+// startTestOPCUAProxy forwards connections to a gopcua test server and decrypts the
+// asymmetric OpenSecureChannel request on the way. The gopcua server only learns the
+// security mode from that request, so it cannot decrypt it itself; its response and every
+// message after it are signed and encrypted end to end.
+func startTestOPCUAProxy(t *testing.T, port int, endpoint string, serverKey *rsa.PrivateKey) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	target := strings.TrimPrefix(endpoint, "opc.tcp://")
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go proxyTestOPCUA(conn, target, serverKey)
+		}
+	}()
+	return fmt.Sprintf("opc.tcp://localhost:%d", port)
+}
+
+func proxyTestOPCUA(conn net.Conn, target string, serverKey *rsa.PrivateKey) {
+	defer conn.Close()
+	backend, err := net.Dial("tcp", target)
+	if err != nil {
+		return
+	}
+	defer backend.Close()
+	go func() {
+		io.Copy(conn, backend)
+		conn.Close()
+	}()
+
+	for {
+		header := make([]byte, 8)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		frame := make([]byte, binary.LittleEndian.Uint32(header[4:]))
+		copy(frame, header)
+		if _, err := io.ReadFull(conn, frame[len(header):]); err != nil {
+			return
+		}
+		if string(frame[:3]) == "OPN" {
+			if frame, err = decryptTestOPCUAOpen(frame, serverKey); err != nil {
+				return
+			}
+		}
+		if _, err := backend.Write(frame); err != nil {
+			return
+		}
+	}
+}
+
+// decryptTestOPCUAOpen verifies and decrypts a secured OpenSecureChannel request, keeping its
+// security header so the server still sets up the channel's security from it.
+func decryptTestOPCUAOpen(frame []byte, serverKey *rsa.PrivateKey) ([]byte, error) {
+	chunk := new(uasc.MessageChunk)
+	if _, err := chunk.Decode(frame); err != nil {
+		return nil, err
+	}
+	if chunk.SecurityPolicyURI == ua.SecurityPolicyURINone {
+		return frame, nil
+	}
+	cert, err := x509.ParseCertificate(chunk.SenderCertificate)
+	if err != nil {
+		return nil, err
+	}
+	algo, err := uapolicy.Asymmetric(chunk.SecurityPolicyURI, serverKey, cert.PublicKey.(*rsa.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	header := frame[: len(frame)-len(chunk.Data) : len(frame)-len(chunk.Data)]
+	plain, err := algo.Decrypt(chunk.Data)
+	if err != nil {
+		return nil, err
+	}
+	signed := len(plain) - algo.RemoteSignatureLength()
+	if err := algo.VerifySignature(append(header, plain[:signed]...), plain[signed:]); err != nil {
+		return nil, err
+	}
+	plain = plain[:signed]
+	plain = plain[:len(plain)-int(plain[len(plain)-1])-1] // Padding and its size byte
+
+	out := append(header, plain...)
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)))
+	return out, nil
+}
+
+func newTestOPCUADriver(t *testing.T, metadata model.OPCUAMetadata, trust *OPCUATrustStore) *OPCUADriver {
+	t.Helper()
+	metadataJSON, _ := json.Marshal(metadata)
+	driver, err := NewOPCUADriver(string(metadataJSON))
+	if err != nil {
+		t.Fatalf("Failed to create OPCUADriver: %v", err)
+	}
+	driver.trust = trust
+	return driver
+}
+
+func TestOPCUADriver_NewRejectsInvalidSecurity(t *testing.T) {
+	tests := []model.OPCUAMetadata{
+		{Endpoint: "opc.tcp://localhost:4840", SecurityPolicy: "Basic128Rsa15", SecurityMode: "Sign"},
+		{Endpoint: "opc.tcp://localhost:4840", SecurityPolicy: "Basic256Sha256", SecurityMode: "None"},
+		{Endpoint: "opc.tcp://localhost:4840", SecurityPolicy: "Basic256Sha256", SecurityMode: "SignAndEncrypt"},
+	}
+	for _, metadata := range tests {
+		metadataJSON, _ := json.Marshal(metadata)
+		if _, err := NewOPCUADriver(string(metadataJSON)); err == nil {
+			t.Errorf("Expected error for policy %s mode %s, got nil", metadata.SecurityPolicy, metadata.SecurityMode)
+		}
+	}
+}
+
+func TestOPCUADriver_ServerCertificateTrust(t *testing.T) {
+	serverCert, serverKey := generateTestCertificate(t, "urn:iotgo:test:server")
+	proxyPort := freePort(t)
+	endpoint, _ := serveTestOPCUA(t, serverCert, serverKey, proxyPort)
+	endpoint = startTestOPCUAProxy(t, proxyPort, endpoint, serverKey)
+	clientCert, clientKey := generateTestCertificate(t, "urn:iotgo:test:client")
+	trust := NewOPCUATrustStore(t.TempDir())
+
+	metadata := model.OPCUAMetadata{
+		Endpoint:       endpoint,
+		SecurityPolicy: "Basic256Sha256",
+		SecurityMode:   "SignAndEncrypt",
+		Certificate:    string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientCert})),
+		PrivateKey:     string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(clientKey)})),
+		Timeout:        2,
+	}
+	ctx := context.Background()
+
+	// The server certificate is not trusted yet, so the session must be refused
+	driver := newTestOPCUADriver(t, metadata, trust)
+	if err := driver.Connect(ctx); !errors.Is(err, ErrUntrustedCertificate) {
+		t.Fatalf("Expected ErrUntrustedCertificate, got %v", err)
+	}
+
+	// Once trusted the driver opens an encrypted session
+	if _, err := trust.Add(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: serverCert})); err != nil {
+		t.Fatalf("Failed to trust server certificate: %v", err)
+	}
+	if err := driver.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	result, err := driver.FetchData(ctx, `{"node_id": "ns=1;s=Temperature"}`)
+	if err != nil {
+		t.Fatalf("Failed to fetch data: %v", err)
+	}
+	if value := result.(map[string]interface{})["value"]; value != 21.5 {
+		t.Errorf("Expected value 21.5, got %v", value)
+	}
+	defer driver.Disconnect(ctx)
+
+	certs, err := trust.List()
+	if err != nil || len(certs) != 1 {
+		t.Fatalf("Expected 1 trusted certificate, got %d (%v)", len(certs), err)
+	}
+	if err := trust.Remove(certs[0].Thumbprint); err != nil {
+		t.Fatalf("Failed to remove certificate: %v", err)
+	}
+	if err := trust.Verify(serverCert); !errors.Is(err, ErrUntrustedCertificate) {
+		t.Errorf("Expected ErrUntrustedCertificate after removal, got %v", err)
+	}
+	// Validating a connected driver checks the certificate again, without closing its session
+	if err := driver.ValidateConfig(ctx); !errors.Is(err, ErrUntrustedCertificate) {
+		t.Errorf("Expected ErrUntrustedCertificate from a connected driver, got %v", err)
+	}
+	if _, err := driver.FetchData(ctx, `{"node_id": "ns=1;s=Temperature"}`); err != nil {
+		t.Errorf("Expected the session to stay open, got %v", err)
+	}
+}
+
+func TestOPCUADriver_InsecureSession(t *testing.T) {
+	endpoint, _, _ := startTestOPCUAServer(t)
+	driver := newTestOPCUADriver(t, model.OPCUAMetadata{Endpoint: endpoint, Timeout: 5}, NewOPCUATrustStore(t.TempDir()))
+
+	ctx := context.Background()
+	if err := driver.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer driver.Disconnect(ctx)
+
+	result, err := driver.FetchData(ctx, `{"node_id": "ns=1;s=Temperature"}`)
+	if err != nil {
+		t.Fatalf("Failed to fetch data: %v", err)
+	}
+	if value := result.(map[string]interface{})["value"]; value != 21.5 {
+		t.Errorf("Expected value 21.5, got %v", value)
+	}
+}
+
+func TestOPCUADriver_WriteData(t *testing.T) {
+	endpoint, _, _ := startTestOPCUAServer(t)
+	driver := newTestOPCUADriver(t, model.OPCUAMetadata{Endpoint: endpoint, Timeout: 5}, NewOPCUATrustStore(t.TempDir()))
+
+	ctx := context.Background()
+	if err := driver.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer driver.Disconnect(ctx)
+
+	details := `{"node_id": "ns=1;s=Temperature"}`
+	result, err := driver.WriteData(ctx, details, WriteValue{Value: json.Number("23")})
+	if err != nil {
+		t.Fatalf("Failed to write data: %v", err)
+	}
+	if result.OldValue != 21.5 || result.NewValue != 23.0 {
+		t.Errorf("Unexpected write result: %+v", result)
+	}
+
+	data, err := driver.FetchData(ctx, details)
+	if err != nil {
+		t.Fatalf("Failed to fetch data: %v", err)
+	}
+	if value := data.(map[string]interface{})["value"]; value != 23.0 {
+		t.Errorf("Expected written value 23, got %v", value)
+	}
+
+	// The node holds a Double, so strings are rejected before anything is sent
+	if _, err := driver.WriteData(ctx, details, WriteValue{Value: "hot"}); err == nil {
+		t.Errorf("Expected error writing a string to a Double node")
+	}
+}
+
+func TestOPCUADriver_Browse(t *testing.T) {
+	endpoint, _, _ := startTestOPCUAServer(t)
+	driver := newTestOPCUADriver(t, model.OPCUAMetadata{Endpoint: endpoint, Timeout: 5}, NewOPCUATrustStore(t.TempDir()))
+
+	ctx := context.Background()
+	if err := driver.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer driver.Disconnect(ctx)
+
+	result, err := driver.Browse(ctx, BrowseRequest{Depth: 2, Limit: maxBrowseLimit})
+	if err != nil {
+		t.Fatalf("Failed to browse: %v", err)
+	}
+
+	var found *BrowseNode
+	for i, node := range result.Nodes {
+		if node.NodeID == "ns=1;s=Temperature" {
+			found = &result.Nodes[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("Expected Temperature node in %d browsed nodes", result.Total)
+	}
+	if found.NodeClass != "Variable" || found.BrowseName != "Temperature" || found.Depth != 2 {
+		t.Errorf("Unexpected node description: %+v", *found)
+	}
+	// The gopcua test server reports a placeholder data type, so only its presence is checked
+	if found.DataType == "" {
+		t.Errorf("Expected data type for variable node")
+	}
+
+	page, err := driver.Browse(ctx, BrowseRequest{Depth: 2, Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("Failed to browse page: %v", err)
+	}
+	if len(page.Nodes) != 1 || page.Total != result.Total || page.Nodes[0].NodeID != result.Nodes[1].NodeID {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+}
+
+func TestOPCUADriver_Subscribe(t *testing.T) {
+	endpoint, _, ns := startTestOPCUAServer(t)
+	driver := newTestOPCUADriver(t, model.OPCUAMetadata{Endpoint: endpoint, Timeout: 5}, NewOPCUATrustStore(t.TempDir()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := driver.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer driver.Disconnect(context.Background())
+
+	if _, err := driver.Subscribe(ctx, `{"node_id": "ns=1;s=Temperature", "deadband_type": "Relative"}`); err == nil {
+		t.Errorf("Expected error for unsupported deadband type, got nil")
+	}
+	if _, err := driver.Subscribe(ctx, `{"node_id": "ns=1;s=Temperature", "queue_size": 4294967295}`); err == nil {
+		t.Errorf("Expected error for an oversized queue, got nil")
+	}
+	if _, err := ValidateResourceDetails("opcua_node", `{"node_id": "ns=1;s=Temperature", "queue_size": 1001}`); err == nil {
+		t.Errorf("Expected queue_size above %d to be rejected", maxOPCUAQueueSize)
+	}
+
+	changes, err := driver.Subscribe(ctx, `{"node_id": "ns=1;s=Temperature", "sampling_interval": 100, "queue_size": 5}`)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	expectValue := func(want float64) {
+		t.Helper()
+		select {
+		case change := <-changes:
+			if change.Error != "" {
+				t.Fatalf("Unexpected subscription error: %s", change.Error)
+			}
+			if value := change.Data.(map[string]interface{})["value"]; value != want {
+				t.Fatalf("Expected value %v, got %v", want, value)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for value %v", want)
+		}
+	}
+
+	// The server reports the current value as soon as the item is monitored
+	expectValue(21.5)
+
+	nodeID := ua.NewStringNodeID(ns.ID(), "Temperature")
+	ns.Node(nodeID).SetAttribute(ua.AttributeIDValue, server.DataValueFromValue(23.0))
+	ns.ChangeNotification(nodeID)
+	expectValue(23.0)
+
+	// Cancelling the context must close the channel
+	cancel()
+	for range changes {
+	}
+}
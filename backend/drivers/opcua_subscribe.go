@@ -0,0 +1,135 @@
+package drivers
+
+import (
+	"app/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/ua"
+)
+
+const (
+	defaultOPCUASamplingInterval = 1000.0 // Milliseconds
+	defaultOPCUAQueueSize        = 10
+	maxOPCUAQueueSize            = 1000
+	opcuaChannelBuffer           = 16 // Publish results and changes buffered while the consumer is busy
+)
+
+// opcuaDeadbandTypes maps deadband names accepted in resource details to OPC UA deadband types.
+var opcuaDeadbandTypes = map[string]ua.DeadbandType{
+	"":         ua.DeadbandTypeNone,
+	"None":     ua.DeadbandTypeNone,
+	"Absolute": ua.DeadbandTypeAbsolute,
+	"Percent":  ua.DeadbandTypePercent,
+}
+
+// Subscribe creates an OPC UA subscription with a single monitored item for the resource's node.
+// Changes are delivered until ctx is cancelled, after which the subscription is deleted on the server.
+func (d *OPCUADriver) Subscribe(ctx context.Context, resourceDetails string) (<-chan DataChange, error) {
+	if d.client == nil {
+		return nil, errors.New("not connected to OPCUA server")
+	}
+
+	var details model.OPCUAResourceDetails
+	if err := json.Unmarshal([]byte(resourceDetails), &details); err != nil {
+		return nil, fmt.Errorf("invalid resource details: %w", err)
+	}
+	if details.NodeID == "" {
+		return nil, errors.New("node_id is required in resource details")
+	}
+	nodeID, err := ua.ParseNodeID(details.NodeID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node ID: %w", err)
+	}
+	deadband, ok := opcuaDeadbandTypes[details.DeadbandType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported deadband type: %s", details.DeadbandType)
+	}
+
+	interval := details.SamplingInterval
+	if interval <= 0 {
+		interval = defaultOPCUASamplingInterval
+	}
+	queueSize := details.QueueSize
+	if queueSize == 0 {
+		queueSize = defaultOPCUAQueueSize
+	}
+	if queueSize > maxOPCUAQueueSize {
+		return nil, fmt.Errorf("queue_size must not exceed %d", maxOPCUAQueueSize)
+	}
+
+	// The server-side queue size only sets what the server keeps between publishes
+	notifyCh := make(chan *opcua.PublishNotificationData, opcuaChannelBuffer)
+	sub, err := d.client.Subscribe(ctx, &opcua.SubscriptionParameters{
+		Interval: time.Duration(interval * float64(time.Millisecond)),
+	}, notifyCh)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	req := opcua.NewMonitoredItemCreateRequestWithDefaults(nodeID, ua.AttributeIDValue, sub.SubscriptionID)
+	req.RequestedParameters.SamplingInterval = interval
+	req.RequestedParameters.QueueSize = queueSize
+	if deadband != ua.DeadbandTypeNone {
+		req.RequestedParameters.Filter = ua.NewExtensionObject(&ua.DataChangeFilter{
+			Trigger:       ua.DataChangeTriggerStatusValue,
+			DeadbandType:  uint32(deadband),
+			DeadbandValue: details.DeadbandValue,
+		})
+	}
+
+	resp, err := sub.Monitor(ctx, ua.TimestampsToReturnBoth, req)
+	if err == nil && (len(resp.Results) == 0 || resp.Results[0].StatusCode != ua.StatusOK) {
+		err = errors.New("no monitored item created")
+		if len(resp.Results) > 0 {
+			err = resp.Results[0].StatusCode
+		}
+	}
+	if err != nil {
+		sub.Cancel(context.Background())
+		return nil, fmt.Errorf("failed to monitor node %s: %w", details.NodeID, err)
+	}
+
+	changes := make(chan DataChange, opcuaChannelBuffer)
+	go func() {
+		defer close(changes)
+		defer sub.Cancel(context.Background())
+
+		for {
+			var msg *opcua.PublishNotificationData
+			select {
+			case <-ctx.Done():
+				return
+			case msg = <-notifyCh:
+			}
+
+			var batch []DataChange
+			switch {
+			case msg.Error != nil:
+				batch = append(batch, DataChange{Error: msg.Error.Error(), ReceivedAt: time.Now()})
+			default:
+				notification, ok := msg.Value.(*ua.DataChangeNotification)
+				if !ok {
+					continue
+				}
+				for _, item := range notification.MonitoredItems {
+					batch = append(batch, DataChange{Data: opcuaDataValue(details.NodeID, item.Value), ReceivedAt: time.Now()})
+				}
+			}
+
+			for _, change := range batch {
+				select {
+				case <-ctx.Done():
+					return
+				case changes <- change:
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
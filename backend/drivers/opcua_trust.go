@@ -0,0 +1,194 @@
+package drivers
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUntrustedCertificate is returned when an OPC UA server presents a certificate
+// that is not in the trust store.
+var ErrUntrustedCertificate = errors.New("server certificate is not trusted")
+
+// TrustedCertificate describes a server certificate held in the OPC UA trust store.
+type TrustedCertificate struct {
+	Thumbprint     string    `json:"thumbprint"` // SHA-1 thumbprint, hex encoded
+	Subject        string    `json:"subject"`
+	Issuer         string    `json:"issuer"`
+	ApplicationURI string    `json:"application_uri"`
+	NotBefore      time.Time `json:"not_before"`
+	NotAfter       time.Time `json:"not_after"`
+}
+
+// OPCUATrustStore keeps the server certificates that OPC UA drivers accept when
+// opening signed or encrypted sessions. Certificates are stored as DER files named
+// after their thumbprint.
+type OPCUATrustStore struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+var (
+	defaultTrustStore     *OPCUATrustStore
+	defaultTrustStoreOnce sync.Once
+)
+
+// NewOPCUATrustStore creates a trust store backed by the given directory.
+func NewOPCUATrustStore(dir string) *OPCUATrustStore {
+	return &OPCUATrustStore{dir: dir}
+}
+
+// DefaultOPCUATrustStore returns the trust store shared by all OPC UA drivers.
+// The directory is taken from OPCUA_TRUST_DIR and defaults to pki/opcua/trusted.
+func DefaultOPCUATrustStore() *OPCUATrustStore {
+	defaultTrustStoreOnce.Do(func() {
+		dir := os.Getenv("OPCUA_TRUST_DIR")
+		if dir == "" {
+			dir = filepath.Join("pki", "opcua", "trusted")
+		}
+		defaultTrustStore = NewOPCUATrustStore(dir)
+	})
+	return defaultTrustStore
+}
+
+// List returns all certificates in the trust store.
+func (s *OPCUATrustStore) List() ([]TrustedCertificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []TrustedCertificate{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trust store: %w", err)
+	}
+
+	certs := []TrustedCertificate{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".der" {
+			continue
+		}
+		der, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+		info, err := describeCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate %s: %w", entry.Name(), err)
+		}
+		certs = append(certs, *info)
+	}
+	return certs, nil
+}
+
+// Add stores a PEM or DER encoded certificate in the trust store.
+func (s *OPCUATrustStore) Add(cert []byte) (*TrustedCertificate, error) {
+	der, err := decodeCertificate(cert)
+	if err != nil {
+		return nil, err
+	}
+	info, err := describeCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create trust store: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, info.Thumbprint+".der"), der, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write certificate: %w", err)
+	}
+	return info, nil
+}
+
+// Remove deletes the certificate with the given thumbprint from the trust store.
+func (s *OPCUATrustStore) Remove(thumbprint string) error {
+	thumbprint = strings.ToLower(strings.TrimSpace(thumbprint))
+	if _, err := hex.DecodeString(thumbprint); err != nil || len(thumbprint) != sha1.Size*2 {
+		return errors.New("invalid thumbprint")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(filepath.Join(s.dir, thumbprint+".der"))
+	if errors.Is(err, os.ErrNotExist) {
+		return errors.New("certificate not found")
+	}
+	return err
+}
+
+// Verify checks that a DER encoded server certificate is in the trust store and
+// currently valid.
+func (s *OPCUATrustStore) Verify(der []byte) error {
+	info, err := describeCertificate(der)
+	if err != nil {
+		return fmt.Errorf("invalid server certificate: %w", err)
+	}
+
+	s.mu.RLock()
+	trusted, err := os.ReadFile(filepath.Join(s.dir, info.Thumbprint+".der"))
+	s.mu.RUnlock()
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: thumbprint %s, subject %q", ErrUntrustedCertificate, info.Thumbprint, info.Subject)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read trust store: %w", err)
+	}
+	if string(trusted) != string(der) {
+		return fmt.Errorf("%w: thumbprint %s does not match stored certificate", ErrUntrustedCertificate, info.Thumbprint)
+	}
+
+	now := time.Now()
+	if now.Before(info.NotBefore) || now.After(info.NotAfter) {
+		return fmt.Errorf("server certificate %s is outside its validity period (%s - %s)",
+			info.Thumbprint, info.NotBefore.Format(time.RFC3339), info.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// decodeCertificate accepts a PEM or DER encoded certificate and returns the DER bytes.
+func decodeCertificate(data []byte) ([]byte, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("unexpected PEM block type %s", block.Type)
+		}
+		return block.Bytes, nil
+	}
+	if _, err := x509.ParseCertificate(data); err != nil {
+		return nil, errors.New("certificate must be PEM or DER encoded")
+	}
+	return data, nil
+}
+
+// describeCertificate parses a DER encoded certificate into a TrustedCertificate.
+func describeCertificate(der []byte) (*TrustedCertificate, error) {
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum(der)
+	info := &TrustedCertificate{
+		Thumbprint: hex.EncodeToString(sum[:]),
+		Subject:    cert.Subject.String(),
+		Issuer:     cert.Issuer.String(),
+		NotBefore:  cert.NotBefore,
+		NotAfter:   cert.NotAfter,
+	}
+	if len(cert.URIs) > 0 {
+		info.ApplicationURI = cert.URIs[0].String()
+	}
+	return info, nil
+}
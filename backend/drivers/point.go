@@ -0,0 +1,257 @@
+package drivers
+
+import (
+	"app/model"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Point is one timestamped value in the shape shared by every platform type.
+type Point struct {
+	Timestamp  time.Time   `json:"timestamp"`
+	Value      interface{} `json:"value"`
+	Unit       string      `json:"unit,omitempty"`
+	Quality    string      `json:"quality"`       // model.QualityGood, QualityUncertain or QualityBad
+	ResourceID uint        `json:"resource_id"`   // Resource the point was read from, set by the caller
+	Resource   string      `json:"resource"`      // Name of that resource
+	Raw        interface{} `json:"raw,omitempty"` // Part of the driver result the point came from, when asked for
+}
+
+// PointMapping locates points in a driver result with JSON paths such as "body.readings[0]".
+// When Items is set, each element of the array it selects gives one point and the other paths
+// are relative to the element; otherwise the whole result gives one point. "$" selects the
+// element itself.
+type PointMapping struct {
+	Items     string `json:"items,omitempty"`
+	Value     string `json:"value,omitempty"`     // The whole element when empty
+	Timestamp string `json:"timestamp,omitempty"` // RFC 3339 string or Unix time in seconds or milliseconds
+	Quality   string `json:"quality,omitempty"`
+	UnitPath  string `json:"unit_path,omitempty"`
+	Unit      string `json:"unit,omitempty"` // Fixed unit, used when UnitPath is empty or finds nothing
+}
+
+// Points extracts points from a driver result. Points without a timestamp are stamped with
+// fetchedAt and points without a quality are Good. A value path that matches nothing is an
+// error; the optional paths fall back to these defaults.
+func (m PointMapping) Points(data interface{}, fetchedAt time.Time, includeRaw bool) ([]Point, error) {
+	doc, err := jsonDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	elements := []interface{}{doc}
+	if m.Items != "" {
+		items, err := extractJSONPath(doc, m.Items)
+		if err != nil {
+			return nil, fmt.Errorf("items: %w", err)
+		}
+		var ok bool
+		if elements, ok = items.([]interface{}); !ok {
+			return nil, fmt.Errorf("items: json path %q is not an array", m.Items)
+		}
+	}
+
+	points := make([]Point, 0, len(elements))
+	for i, element := range elements {
+		value, err := extractJSONPath(element, m.Value)
+		if err != nil {
+			if m.Items != "" {
+				return nil, fmt.Errorf("item %d: value: %w", i, err)
+			}
+			return nil, fmt.Errorf("value: %w", err)
+		}
+		point := Point{
+			Timestamp: fetchedAt,
+			Value:     value,
+			Unit:      m.Unit,
+			Quality:   model.QualityGood,
+		}
+		if t, ok := pointTime(optionalJSONPath(element, m.Timestamp)); ok {
+			point.Timestamp = t
+		}
+		if quality := optionalJSONPath(element, m.Quality); quality != nil {
+			point.Quality = pointQuality(quality)
+		}
+		if unit, ok := optionalJSONPath(element, m.UnitPath).(string); ok && unit != "" {
+			point.Unit = unit
+		}
+		if includeRaw {
+			point.Raw = element
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+// Merge returns the mapping with the fields set in override replacing its own.
+func (m PointMapping) Merge(override *PointMapping) PointMapping {
+	if override == nil {
+		return m
+	}
+	for _, f := range []struct{ dst, src *string }{
+		{&m.Items, &override.Items},
+		{&m.Value, &override.Value},
+		{&m.Timestamp, &override.Timestamp},
+		{&m.Quality, &override.Quality},
+		{&m.UnitPath, &override.UnitPath},
+		{&m.Unit, &override.Unit},
+	} {
+		if *f.src != "" {
+			*f.dst = *f.src
+		}
+	}
+	return m
+}
+
+// Validate checks that the mapping's paths are well formed.
+func (m PointMapping) Validate() error {
+	for _, f := range []struct{ name, path string }{
+		{"items", m.Items},
+		{"value", m.Value},
+		{"timestamp", m.Timestamp},
+		{"quality", m.Quality},
+		{"unit_path", m.UnitPath},
+	} {
+		if err := ValidateJSONPath(f.path); err != nil {
+			return fmt.Errorf("points.%s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// ParsePointMapping reads the "points" mapping of resource metadata. It returns nil when the
+// metadata has none.
+func ParsePointMapping(metadata string) (*PointMapping, error) {
+	if metadata == "" {
+		return nil, nil
+	}
+	var config struct {
+		Points *PointMapping `json:"points"`
+	}
+	if err := json.Unmarshal([]byte(metadata), &config); err != nil {
+		return nil, fmt.Errorf("invalid metadata JSON: %w", err)
+	}
+	if config.Points == nil {
+		return nil, nil
+	}
+	if err := config.Points.Validate(); err != nil {
+		return nil, err
+	}
+	return config.Points, nil
+}
+
+// ResourcePointMapping returns the mapping for a resource: its type's default mapping for its
+// details, with the resource metadata's "points" mapping applied.
+func ResourcePointMapping(resource *model.Resource) (PointMapping, error) {
+	rt, _, err := LookupResourceType(resource.Type)
+	if err != nil {
+		return PointMapping{}, err
+	}
+	override, err := ParsePointMapping(resource.Metadata)
+	if err != nil {
+		return PointMapping{}, err
+	}
+	mapping := rt.Points
+	if rt.DetailsPoints != nil {
+		mapping = mapping.Merge(rt.DetailsPoints(resource.Details))
+	}
+	return mapping.Merge(override), nil
+}
+
+// ResourcePoints extracts the points of a resource's fetch result with the resource's mapping
+// and marks them with the resource.
+func ResourcePoints(resource *model.Resource, data interface{}, fetchedAt time.Time, includeRaw bool) ([]Point, error) {
+	mapping, err := ResourcePointMapping(resource)
+	if err != nil {
+		return nil, err
+	}
+	points, err := mapping.Points(data, fetchedAt, includeRaw)
+	if err != nil {
+		return nil, err
+	}
+	for i := range points {
+		points[i].ResourceID = resource.ID
+		points[i].Resource = resource.Name
+	}
+	return points, nil
+}
+
+// jsonDocument converts a driver result to its decoded JSON form, so paths see the same
+// objects, arrays, strings and numbers the API returns. Numbers keep their precision.
+func jsonDocument(data interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode result: %w", err)
+	}
+	return doc, nil
+}
+
+// optionalJSONPath returns the value at a path, or nil when the path is empty or matches nothing.
+func optionalJSONPath(doc interface{}, path string) interface{} {
+	if path == "" {
+		return nil
+	}
+	value, err := extractJSONPath(doc, path)
+	if err != nil {
+		return nil
+	}
+	return value
+}
+
+// pointTime reads a timestamp given as an RFC 3339 string or as Unix seconds, or milliseconds
+// for values too large to be seconds. The zero time counts as no timestamp.
+func pointTime(value interface{}) (time.Time, bool) {
+	var t time.Time
+	switch v := value.(type) {
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, false
+		}
+		t = parsed
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		if f > 1e12 {
+			t = time.UnixMilli(int64(f))
+		} else {
+			t = time.Unix(0, int64(f*float64(time.Second)))
+		}
+	default:
+		return time.Time{}, false
+	}
+	if t.IsZero() {
+		return time.Time{}, false
+	}
+	return t.UTC(), true
+}
+
+// pointQuality maps a reported quality to Good, Uncertain or Bad. Booleans read as good or
+// bad; anything unrecognised is Uncertain.
+func pointQuality(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return model.QualityGood
+		}
+		return model.QualityBad
+	case string:
+		for _, quality := range []string{model.QualityGood, model.QualityUncertain, model.QualityBad} {
+			if strings.EqualFold(v, quality) {
+				return quality
+			}
+		}
+	}
+	return model.QualityUncertain
+}
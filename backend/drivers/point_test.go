@@ -0,0 +1,146 @@
+package drivers
+
+import (
+	"app/model"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestResourcePoints_DefaultMappings(t *testing.T) {
+	fetchedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	source := fetchedAt.Add(-time.Minute)
+
+	tests := []struct {
+		name         string
+		resourceType string
+		data         interface{}
+		count        int
+		value        interface{}
+		timestamp    time.Time
+		quality      string
+		unit         string
+	}{
+		{
+			name:         "REST body",
+			resourceType: "rest_endpoint",
+			data:         map[string]interface{}{"status_code": 200, "headers": map[string]string{}, "body": "running"},
+			count:        1, value: "running", timestamp: fetchedAt, quality: model.QualityGood,
+		},
+		{
+			name:         "InfluxDB records",
+			resourceType: "influxdb_query",
+			data: []map[string]interface{}{
+				{"time": source, "value": 20.5, "field": "temp", "measurement": "boiler"},
+				{"time": source.Add(time.Second), "value": 21.0, "field": "temp", "measurement": "boiler"},
+			},
+			count: 2, value: json.Number("20.5"), timestamp: source, quality: model.QualityGood,
+		},
+		{
+			name:         "OPC UA value",
+			resourceType: "opcua_node",
+			data:         map[string]interface{}{"node_id": "ns=2;s=Temp", "value": 7, "status": "", "quality": model.QualityBad, "source_timestamp": source, "server_timestamp": fetchedAt},
+			count:        1, value: json.Number("7"), timestamp: source, quality: model.QualityBad,
+		},
+		{
+			name:         "OPC UA value without source timestamp",
+			resourceType: "opcua_node",
+			data:         map[string]interface{}{"value": true, "quality": model.QualityGood, "source_timestamp": time.Time{}},
+			count:        1, value: true, timestamp: fetchedAt, quality: model.QualityGood,
+		},
+		{
+			name:         "Modbus register with unit",
+			resourceType: "modbus_register",
+			data:         map[string]interface{}{"function_code": 3, "address": 10, "value": 1.5, "unit": "bar"},
+			count:        1, value: json.Number("1.5"), timestamp: fetchedAt, quality: model.QualityGood, unit: "bar",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := &model.Resource{Model: model.Model{ID: 9}, Name: "reading", Type: tt.resourceType}
+			points, err := ResourcePoints(resource, tt.data, fetchedAt, false)
+			if err != nil {
+				t.Fatalf("ResourcePoints failed: %v", err)
+			}
+			if len(points) != tt.count {
+				t.Fatalf("Expected %d points, got %d", tt.count, len(points))
+			}
+			p := points[0]
+			if p.Value != tt.value {
+				t.Errorf("Expected value %#v, got %#v", tt.value, p.Value)
+			}
+			if !p.Timestamp.Equal(tt.timestamp) {
+				t.Errorf("Expected timestamp %s, got %s", tt.timestamp, p.Timestamp)
+			}
+			if p.Quality != tt.quality || p.Unit != tt.unit {
+				t.Errorf("Expected quality %q and unit %q, got %q and %q", tt.quality, tt.unit, p.Quality, p.Unit)
+			}
+			if p.ResourceID != 9 || p.Resource != "reading" || p.Raw != nil {
+				t.Errorf("Unexpected point source %+v", p)
+			}
+		})
+	}
+}
+
+func TestResourcePoints_MetadataMapping(t *testing.T) {
+	fetchedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	resource := &model.Resource{
+		Name:     "meters",
+		Type:     "rest_endpoint",
+		Metadata: `{"points": {"items": "body.readings", "value": "kwh", "timestamp": "ts", "quality": "ok", "unit": "kWh"}}`,
+	}
+	data := map[string]interface{}{
+		"status_code": 200,
+		"body": map[string]interface{}{
+			"readings": []interface{}{
+				map[string]interface{}{"kwh": 10.0, "ts": 1714564800, "ok": true},
+				map[string]interface{}{"kwh": 11.0, "ts": 1714564860000.0, "ok": false},
+			},
+		},
+	}
+
+	points, err := ResourcePoints(resource, data, fetchedAt, true)
+	if err != nil {
+		t.Fatalf("ResourcePoints failed: %v", err)
+	}
+	if len(points) != 2 {
+		t.Fatalf("Expected 2 points, got %d", len(points))
+	}
+	if !points[0].Timestamp.Equal(time.Unix(1714564800, 0)) || points[0].Quality != model.QualityGood || points[0].Unit != "kWh" {
+		t.Errorf("Unexpected first point %+v", points[0])
+	}
+	// Millisecond timestamps and boolean qualities
+	if !points[1].Timestamp.Equal(time.Unix(1714564860, 0)) || points[1].Quality != model.QualityBad {
+		t.Errorf("Unexpected second point %+v", points[1])
+	}
+	if raw, ok := points[1].Raw.(map[string]interface{}); !ok || raw["kwh"] != json.Number("11") {
+		t.Errorf("Expected the reading as raw payload, got %#v", points[1].Raw)
+	}
+
+	resource.Metadata = `{"points": {"items": "body.missing"}}`
+	if _, err := ResourcePoints(resource, data, fetchedAt, false); err == nil {
+		t.Error("Expected an error for a missing items path")
+	}
+	resource.Metadata = `{"points": {"value": "body.missing"}}`
+	if _, err := ResourcePoints(resource, data, fetchedAt, false); err == nil {
+		t.Error("Expected an error for a missing value path")
+	}
+}
+
+func TestParsePointMapping(t *testing.T) {
+	if mapping, err := ParsePointMapping(`{"poll_interval": "1m"}`); err != nil || mapping != nil {
+		t.Errorf("Expected no mapping, got %+v, %v", mapping, err)
+	}
+	if _, err := ParsePointMapping(`{"points": {"value": "data[x]"}}`); err == nil {
+		t.Error("Expected an error for a malformed path")
+	}
+	mapping, err := ParsePointMapping(`{"points": {"value": "data.temp"}}`)
+	if err != nil {
+		t.Fatalf("ParsePointMapping failed: %v", err)
+	}
+	merged := PointMapping{Value: "value", Timestamp: "time"}.Merge(mapping)
+	if merged.Value != "data.temp" || merged.Timestamp != "time" {
+		t.Errorf("Unexpected merged mapping %+v", merged)
+	}
+}
@@ -0,0 +1,213 @@
+package drivers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// Registration describes a platform type: how to build its driver and how to check the
+// configuration stored for it. Drivers register themselves from an init function.
+type Registration struct {
+	Type  string // Value stored in Platform.Type, e.g. "REST"
+	Label string // Human readable name shown in forms
+
+	// New creates a driver from platform metadata.
+	New func(metadata string) (PlatformDriver, error)
+
+	// MetadataSchema describes the platform metadata. Metadata is checked against it before
+	// ValidateMetadata runs.
+	MetadataSchema *Schema
+
+	// ValidateMetadata checks platform metadata and returns it sanitized, with defaults applied.
+	ValidateMetadata func(metadata string) (string, error)
+
+	// ResourceTypes lists the resources the platform type can fetch.
+	ResourceTypes []ResourceType
+}
+
+// ResourceType describes one kind of resource served by a platform type.
+type ResourceType struct {
+	Type  string // Value stored in Resource.Type, e.g. "rest_endpoint"
+	Label string // Human readable name shown in forms
+
+	// DetailsSchema describes the resource details. Details are checked against it before
+	// ValidateDetails runs.
+	DetailsSchema *Schema
+
+	// ValidateDetails checks resource details and returns them sanitized, with defaults applied.
+	ValidateDetails func(details string) (string, error)
+
+	// PrepareDetails applies the device alias and the request's query parameters to stored
+	// details before they are fetched. Nil means the stored details are used as they are.
+	PrepareDetails func(details string, deviceAlias string, params url.Values) (string, error)
+
+	// Points is the default mapping from fetch results to points. Resources can override parts
+	// of it with a "points" object in their metadata.
+	Points PointMapping
+
+	// DetailsPoints returns changes to Points for particular details, for resource types whose
+	// results depend on how they are configured. Nil, or a nil result, keeps Points.
+	DetailsPoints func(details string) *PointMapping
+
+	// Writable allows resources of this type to be marked writable. The platform type's
+	// driver must implement Writer.
+	Writable bool
+}
+
+var (
+	registryMu    sync.RWMutex
+	registrations = make(map[string]*Registration)
+	resourceTypes = make(map[string]*registeredResourceType)
+)
+
+// registeredResourceType remembers which platform type owns a resource type.
+type registeredResourceType struct {
+	ResourceType
+	platformType string
+}
+
+// Register makes a platform type available. It panics if the platform type or one of its
+// resource types is already registered, or if a required hook is missing.
+func Register(r Registration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if r.Type == "" || r.New == nil || r.ValidateMetadata == nil {
+		panic("drivers: Register requires a type, New and ValidateMetadata")
+	}
+	if _, dup := registrations[r.Type]; dup {
+		panic("drivers: Register called twice for platform type " + r.Type)
+	}
+	for _, rt := range r.ResourceTypes {
+		if rt.Type == "" || rt.ValidateDetails == nil {
+			panic("drivers: resource types of " + r.Type + " require a type and ValidateDetails")
+		}
+		if owner, dup := resourceTypes[rt.Type]; dup {
+			panic("drivers: resource type " + rt.Type + " is already registered by " + owner.platformType)
+		}
+		resourceTypes[rt.Type] = &registeredResourceType{ResourceType: rt, platformType: r.Type}
+	}
+	registrations[r.Type] = &r
+}
+
+// Lookup returns the registration of a platform type.
+func Lookup(platformType string) (*Registration, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	r, ok := registrations[platformType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported platform type: %s", platformType)
+	}
+	return r, nil
+}
+
+// Registrations returns every registered platform type, sorted by type.
+func Registrations() []Registration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	list := make([]Registration, 0, len(registrations))
+	for _, r := range registrations {
+		list = append(list, *r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Type < list[j].Type })
+	return list
+}
+
+// LookupResourceType returns a registered resource type and the platform type that serves it.
+func LookupResourceType(resourceType string) (*ResourceType, string, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	rt, ok := resourceTypes[resourceType]
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported resource type: %s", resourceType)
+	}
+	return &rt.ResourceType, rt.platformType, nil
+}
+
+// ValidateMetadata validates platform metadata against the platform type's schema and
+// validator and returns the sanitized metadata. Schema violations are returned as a
+// *ValidationError. The circuit breaker settings every platform type accepts are validated
+// here and kept in the result.
+func ValidateMetadata(platformType, metadata string) (string, error) {
+	r, err := Lookup(platformType)
+	if err != nil {
+		return "", err
+	}
+	if !json.Valid([]byte(metadata)) {
+		return "", errors.New("invalid metadata JSON")
+	}
+	metadata, breaker, err := splitCircuitBreaker(metadata)
+	if err != nil {
+		return "", err
+	}
+	if r.MetadataSchema != nil {
+		if err := r.MetadataSchema.Validate(metadata); err != nil {
+			return "", err
+		}
+	}
+	sanitized, err := r.ValidateMetadata(metadata)
+	if err != nil {
+		return "", err
+	}
+	return withCircuitBreaker(sanitized, breaker)
+}
+
+// ValidateResourceDetails validates resource details against the resource type's schema and
+// validator and returns the sanitized details. Schema violations are returned as a
+// *ValidationError.
+func ValidateResourceDetails(resourceType, details string) (string, error) {
+	rt, _, err := LookupResourceType(resourceType)
+	if err != nil {
+		return "", err
+	}
+	if !json.Valid([]byte(details)) {
+		return "", errors.New("invalid details JSON")
+	}
+	if rt.DetailsSchema != nil {
+		if err := rt.DetailsSchema.Validate(details); err != nil {
+			return "", err
+		}
+	}
+	return rt.ValidateDetails(details)
+}
+
+// CheckWritable reports whether resources of a type can be marked writable.
+func CheckWritable(resourceType string) error {
+	rt, _, err := LookupResourceType(resourceType)
+	if err != nil {
+		return err
+	}
+	if !rt.Writable {
+		return fmt.Errorf("resource type %s does not support writes", resourceType)
+	}
+	return nil
+}
+
+// CheckResourceType reports whether a platform type can fetch a resource type.
+func CheckResourceType(platformType, resourceType string) error {
+	_, owner, err := LookupResourceType(resourceType)
+	if err != nil || owner != platformType {
+		return fmt.Errorf("incompatible resource type %s for platform type %s", resourceType, platformType)
+	}
+	return nil
+}
+
+// PrepareResourceDetails applies the device alias and query parameter overrides of a
+// resource type to stored details, ready to be passed to FetchData.
+func PrepareResourceDetails(platformType, resourceType, details, deviceAlias string, params url.Values) (string, error) {
+	if err := CheckResourceType(platformType, resourceType); err != nil {
+		return "", err
+	}
+	rt, _, _ := LookupResourceType(resourceType)
+	if rt.PrepareDetails == nil {
+		return details, nil
+	}
+	return rt.PrepareDetails(details, deviceAlias, params)
+}
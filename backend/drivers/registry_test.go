@@ -0,0 +1,87 @@
+package drivers
+
+import (
+	"app/model"
+	"encoding/json"
+	"net/url"
+	"testing"
+)
+
+func TestRegistry_BuiltinTypes(t *testing.T) {
+	want := map[string]string{
+		"REST":      "rest_endpoint",
+		"InfluxDB":  "influxdb_query",
+		"OPCUA":     "opcua_node",
+		"MQTT":      "mqtt_topic",
+		"ModbusTCP": "modbus_register",
+		"SQL":       "sql_query",
+	}
+	for platformType, resourceType := range want {
+		if _, err := Lookup(platformType); err != nil {
+			t.Errorf("Lookup(%s) failed: %v", platformType, err)
+		}
+		if err := CheckResourceType(platformType, resourceType); err != nil {
+			t.Errorf("CheckResourceType(%s, %s) failed: %v", platformType, resourceType, err)
+		}
+	}
+	if err := CheckResourceType("REST", "influxdb_query"); err == nil {
+		t.Error("Expected influxdb_query to be incompatible with REST")
+	}
+	if _, err := GetDriver("SDK", "{}"); err == nil {
+		t.Error("Expected error for unregistered platform type")
+	}
+	if _, err := ValidateResourceDetails("unknown", "{}"); err == nil {
+		t.Error("Expected error for unregistered resource type")
+	}
+}
+
+func TestRegistry_ValidateMetadataSanitizes(t *testing.T) {
+	sanitized, err := ValidateMetadata("REST", `{"base_endpoint": "https://api.example.com"}`)
+	if err != nil {
+		t.Fatalf("ValidateMetadata failed: %v", err)
+	}
+	var metadata model.RESTMetadata
+	if err := json.Unmarshal([]byte(sanitized), &metadata); err != nil {
+		t.Fatalf("Sanitized metadata is not valid JSON: %v", err)
+	}
+	if metadata.Auth.Type != "none" {
+		t.Errorf("Expected auth type none, got %q", metadata.Auth.Type)
+	}
+
+	if _, err := ValidateMetadata("REST", `{"base_endpoint": "https://api.example.com", "auth": {"type": "bearer"}}`); err == nil {
+		t.Error("Expected error for bearer auth without a token")
+	}
+}
+
+func TestRegistry_PrepareResourceDetails(t *testing.T) {
+	params := url.Values{"page": {"2"}, "limit": {"50"}}
+
+	prepared, err := PrepareResourceDetails("REST", "rest_endpoint", `{"method": "GET", "path": "/devices/:device_alias"}`, "pump 1", params)
+	if err != nil {
+		t.Fatalf("PrepareResourceDetails failed: %v", err)
+	}
+	var rest model.RESTResourceDetails
+	json.Unmarshal([]byte(prepared), &rest)
+	if rest.Path != "/devices/pump%201" || rest.QueryParams["page"] != "2" {
+		t.Errorf("Unexpected REST details: %+v", rest)
+	}
+
+	prepared, err = PrepareResourceDetails("SQL", "sql_query", `{"query": "SELECT 1"}`, "pump-1", params)
+	if err != nil {
+		t.Fatalf("PrepareResourceDetails failed: %v", err)
+	}
+	var query model.SQLResourceDetails
+	json.Unmarshal([]byte(prepared), &query)
+	if query.DeviceAlias != "pump-1" || query.Limit != 50 {
+		t.Errorf("Unexpected SQL details: %+v", query)
+	}
+
+	details := `{"function_code": "coils", "address": 3}`
+	if prepared, err := PrepareResourceDetails("ModbusTCP", "modbus_register", details, "pump-1", params); err != nil || prepared != details {
+		t.Errorf("Expected Modbus details unchanged, got %s, %v", prepared, err)
+	}
+
+	if _, err := PrepareResourceDetails("InfluxDB", "influxdb_query", `{}`, "", url.Values{"time_range": {"1h"}}); err == nil {
+		t.Error("Expected error for a positive time_range")
+	}
+}
@@ -0,0 +1,326 @@
+package drivers
+
+import (
+	"app/model"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	restAPIKeyHeader      = "X-API-Key" // Default API key header
+	restAPIKeyParam       = "api_key"   // Default API key query parameter
+	oauth2ExpirySkew      = time.Minute // Tokens are renewed this long before they expire
+	oauth2DefaultLifetime = time.Hour   // Lifetime assumed for tokens without expires_in
+	oauth2MaxResponseSize = 1 << 20
+)
+
+// restTemplatePlaceholder matches the {{name}} placeholders of auth header templates.
+var restTemplatePlaceholder = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// restTemplateValues lists the placeholders each auth type fills in header templates.
+var restTemplateValues = map[string][]string{
+	"none":    {},
+	"api_key": {"api_key"},
+	"bearer":  {"token"},
+	"basic":   {"username", "password"},
+	"oauth2":  {"token"},
+}
+
+// validateRESTAuth validates and sanitizes the authentication settings of REST metadata.
+func validateRESTAuth(auth *model.RESTAuth) error {
+	if auth.Type == "" {
+		auth.Type = "none"
+	}
+
+	switch auth.Type {
+	case "api_key":
+		if auth.APIKey == nil || *auth.APIKey == "" {
+			return errors.New("auth.api_key is required for api_key authentication")
+		}
+		*auth.APIKey = strings.TrimSpace(*auth.APIKey)
+		auth.APIKeyName = strings.TrimSpace(auth.APIKeyName)
+		switch auth.APIKeyIn {
+		case "", "header":
+			auth.APIKeyIn = "header"
+			if auth.APIKeyName == "" {
+				auth.APIKeyName = restAPIKeyHeader
+			}
+		case "query":
+			if auth.APIKeyName == "" {
+				auth.APIKeyName = restAPIKeyParam
+			}
+		default:
+			return errors.New("auth.api_key_in must be header or query")
+		}
+	case "bearer":
+		if auth.BearerToken == nil || *auth.BearerToken == "" {
+			return errors.New("auth.bearer_token is required for bearer authentication")
+		}
+		*auth.BearerToken = strings.TrimSpace(*auth.BearerToken)
+	case "basic":
+		if auth.BasicAuth == nil || auth.BasicAuth.Username == "" || auth.BasicAuth.Password == "" {
+			return errors.New("auth.basic_auth.username and auth.basic_auth.password are required for basic authentication")
+		}
+		auth.BasicAuth.Username = strings.TrimSpace(auth.BasicAuth.Username)
+		auth.BasicAuth.Password = strings.TrimSpace(auth.BasicAuth.Password)
+	case "oauth2":
+		if err := validateRESTOAuth2(auth.OAuth2); err != nil {
+			return err
+		}
+	case "none":
+		*auth = model.RESTAuth{Type: "none", Headers: auth.Headers} // Clear other auth fields
+	default:
+		return errors.New("auth.type must be none, api_key, bearer, basic, or oauth2")
+	}
+
+	// Header templates may only use the values the auth type has
+	names := make([]string, 0, len(auth.Headers))
+	for name := range auth.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			return errors.New("auth.headers names cannot be empty")
+		}
+		for _, match := range restTemplatePlaceholder.FindAllStringSubmatch(auth.Headers[name], -1) {
+			if !slices.Contains(restTemplateValues[auth.Type], match[1]) {
+				return fmt.Errorf("auth.headers.%s: {{%s}} is not available for %s authentication", name, match[1], auth.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// validateRESTOAuth2 validates and sanitizes client credentials settings.
+func validateRESTOAuth2(config *model.RESTOAuth2) error {
+	if config == nil {
+		return errors.New("auth.oauth2 is required for oauth2 authentication")
+	}
+	tokenURL, err := url.Parse(strings.TrimSpace(config.TokenURL))
+	if err != nil || (tokenURL.Scheme != "http" && tokenURL.Scheme != "https") || tokenURL.Host == "" {
+		return errors.New("auth.oauth2.token_url must be a valid HTTP/HTTPS URL")
+	}
+	config.TokenURL = tokenURL.String()
+	config.ClientID = strings.TrimSpace(config.ClientID)
+	config.ClientSecret = strings.TrimSpace(config.ClientSecret)
+	if config.ClientID == "" || config.ClientSecret == "" {
+		return errors.New("auth.oauth2.client_id and auth.oauth2.client_secret are required for oauth2 authentication")
+	}
+	scopes := config.Scopes[:0]
+	for _, scope := range config.Scopes {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	config.Scopes = scopes
+	config.Audience = strings.TrimSpace(config.Audience)
+	switch config.AuthStyle {
+	case "":
+		config.AuthStyle = "header"
+	case "header", "body":
+	default:
+		return errors.New("auth.oauth2.auth_style must be header or body")
+	}
+	return nil
+}
+
+// authorize adds the platform's credentials to a request. Header templates, when configured,
+// replace the header the auth type would send.
+func (d *RESTDriver) authorize(ctx context.Context, req *http.Request) error {
+	values := map[string]string{}
+	var header, value string
+	switch d.Auth.Type {
+	case "api_key":
+		if d.Auth.APIKey == nil {
+			return errors.New("API key missing for api_key authentication")
+		}
+		values["api_key"] = *d.Auth.APIKey
+		if d.Auth.APIKeyIn == "query" {
+			query := req.URL.Query()
+			query.Set(defaultString(d.Auth.APIKeyName, restAPIKeyParam), *d.Auth.APIKey)
+			req.URL.RawQuery = query.Encode()
+		} else {
+			header, value = defaultString(d.Auth.APIKeyName, restAPIKeyHeader), *d.Auth.APIKey
+		}
+	case "bearer":
+		if d.Auth.BearerToken == nil {
+			return errors.New("bearer token missing for bearer authentication")
+		}
+		values["token"] = *d.Auth.BearerToken
+		header, value = "Authorization", "Bearer "+*d.Auth.BearerToken
+	case "basic":
+		if d.Auth.BasicAuth == nil {
+			return errors.New("basic auth configuration missing")
+		}
+		values["username"], values["password"] = d.Auth.BasicAuth.Username, d.Auth.BasicAuth.Password
+		auth := base64.StdEncoding.EncodeToString([]byte(d.Auth.BasicAuth.Username + ":" + d.Auth.BasicAuth.Password))
+		header, value = "Authorization", "Basic "+auth
+	case "oauth2":
+		if d.tokens == nil {
+			return errors.New("oauth2 configuration missing")
+		}
+		token, tokenType, err := d.tokens.Token(ctx, d.client)
+		if err != nil {
+			return err
+		}
+		values["token"] = token
+		header, value = "Authorization", tokenType+" "+token
+	}
+
+	if len(d.Auth.Headers) > 0 {
+		for name, template := range d.Auth.Headers {
+			req.Header.Set(name, restTemplatePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+				return values[restTemplatePlaceholder.FindStringSubmatch(placeholder)[1]]
+			}))
+		}
+	} else if header != "" {
+		req.Header.Set(header, value)
+	}
+	return nil
+}
+
+// redactURL returns a request URL as it may be logged or returned in errors: without an API
+// key placed in the query, or a password in the user info.
+func (d *RESTDriver) redactURL(u *url.URL) string {
+	redacted := *u
+	if d.Auth.Type == "api_key" && d.Auth.APIKeyIn == "query" {
+		query := redacted.Query()
+		if name := defaultString(d.Auth.APIKeyName, restAPIKeyParam); query.Has(name) {
+			query.Set(name, "xxxxx")
+			redacted.RawQuery = query.Encode()
+		}
+	}
+	return redacted.Redacted()
+}
+
+// oauth2Tokens fetches client credentials tokens and caches them until shortly before they
+// expire. Requests waiting for a token share the one being fetched.
+type oauth2Tokens struct {
+	config model.RESTOAuth2
+
+	mu          sync.Mutex
+	accessToken string
+	tokenType   string
+	renewAt     time.Time
+}
+
+// oauth2TokenResponse is a token endpoint's answer, or its error as defined by RFC 6749.
+type oauth2TokenResponse struct {
+	AccessToken      string      `json:"access_token"`
+	TokenType        string      `json:"token_type"`
+	ExpiresIn        json.Number `json:"expires_in"` // Some providers send it as a string
+	Error            string      `json:"error"`
+	ErrorDescription string      `json:"error_description"`
+}
+
+// Token returns the cached token and its type, fetching a new one with client when there is
+// none or it is about to expire.
+func (t *oauth2Tokens) Token(ctx context.Context, client *http.Client) (string, string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.accessToken != "" && time.Now().Before(t.renewAt) {
+		return t.accessToken, t.tokenType, nil
+	}
+	if err := t.fetch(ctx, client); err != nil {
+		return "", "", err
+	}
+	return t.accessToken, t.tokenType, nil
+}
+
+// Invalidate drops the cached token, for example after the API rejected it.
+func (t *oauth2Tokens) Invalidate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.accessToken = ""
+}
+
+// fetch requests a token with the client credentials grant.
+func (t *oauth2Tokens) fetch(ctx context.Context, client *http.Client) error {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(t.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(t.config.Scopes, " "))
+	}
+	if t.config.Audience != "" {
+		form.Set("audience", t.config.Audience)
+	}
+	if t.config.AuthStyle == "body" {
+		form.Set("client_id", t.config.ClientID)
+		form.Set("client_secret", t.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if t.config.AuthStyle != "body" {
+		req.SetBasicAuth(url.QueryEscape(t.config.ClientID), url.QueryEscape(t.config.ClientSecret))
+	}
+
+	issuedAt := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oauth2MaxResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var token oauth2TokenResponse
+	decodeErr := json.Unmarshal(body, &token)
+	if resp.StatusCode >= 400 {
+		reason := resp.Status
+		if token.Error != "" {
+			reason += ": " + token.Error
+			if token.ErrorDescription != "" {
+				reason += ": " + token.ErrorDescription
+			}
+		}
+		if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			return fmt.Errorf("token request failed: %s: %w", reason, ErrAuthFailed)
+		}
+		return fmt.Errorf("token request failed: %s", reason)
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("invalid token response: %w", decodeErr)
+	}
+	if token.AccessToken == "" {
+		return errors.New("token response has no access_token")
+	}
+
+	lifetime := oauth2DefaultLifetime
+	if seconds, err := token.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		lifetime = time.Duration(seconds) * time.Second
+	}
+	t.accessToken = token.AccessToken
+	t.tokenType = token.TokenType
+	if t.tokenType == "" || strings.EqualFold(t.tokenType, "bearer") {
+		t.tokenType = "Bearer"
+	}
+	t.renewAt = issuedAt.Add(lifetime - min(oauth2ExpirySkew, lifetime/2))
+	return nil
+}
+
+// defaultString returns s, or def when s is empty.
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -2,9 +2,7 @@ package drivers
 
 import (
 	"app/model"
-	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,18 +10,41 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/beego/beego/logs"
 )
 
+func init() {
+	Register(Registration{
+		Type:             "REST",
+		Label:            "REST API",
+		New:              func(metadata string) (PlatformDriver, error) { return NewRESTDriver(metadata) },
+		MetadataSchema:   mustLoadSchema("rest_metadata.json"),
+		ValidateMetadata: validateRESTMetadata,
+		ResourceTypes: []ResourceType{{
+			Type:            "rest_endpoint",
+			Label:           "REST Endpoint",
+			DetailsSchema:   mustLoadSchema("rest_endpoint.json"),
+			ValidateDetails: validateRESTResourceDetails,
+			PrepareDetails:  prepareRESTResourceDetails,
+			Points:          PointMapping{Value: "body"},
+			DetailsPoints:   restDetailsPoints,
+			Writable:        true,
+		}},
+	})
+}
+
 // RESTDriver implements the PlatformDriver interface for REST-based platforms.
 type RESTDriver struct {
 	BaseURL string
 	Auth    model.RESTAuth
 	Timeout time.Duration
 	client  *http.Client
+	http    *httpSettings
+	tokens  *oauth2Tokens // Cached OAuth2 tokens, for oauth2 authentication
 }
 
 // NewRESTDriver creates a new RESTDriver instance from platform metadata.
@@ -38,12 +59,249 @@ func NewRESTDriver(metadata string) (*RESTDriver, error) {
 	if config.Timeout == 0 {
 		config.Timeout = 10 // Default timeout
 	}
-	return &RESTDriver{
+	settings, err := newHTTPSettings(config.TLS, config.ProxyURL, config.Retry)
+	if err != nil {
+		return nil, err
+	}
+	driver := &RESTDriver{
 		BaseURL: config.BaseEndpoint,
 		Auth:    config.Auth,
 		Timeout: time.Duration(config.Timeout) * time.Second,
-		client:  &http.Client{Timeout: time.Duration(config.Timeout) * time.Second},
-	}, nil
+		client:  settings.client(time.Duration(config.Timeout)*time.Second, idempotentMethod),
+		http:    settings,
+	}
+	if config.Auth.Type == "oauth2" && config.Auth.OAuth2 != nil {
+		driver.tokens = &oauth2Tokens{config: *config.Auth.OAuth2}
+	}
+	return driver, nil
+}
+
+// validateRESTMetadata validates and sanitizes REST platform metadata.
+func validateRESTMetadata(metadataJSON string) (string, error) {
+	var metadata model.RESTMetadata
+	if err := json.Unmarshal([]byte(metadataJSON), &metadata); err != nil {
+		return "", errors.New("invalid metadata JSON")
+	}
+
+	// Validate and sanitize base_endpoint
+	if metadata.BaseEndpoint == "" {
+		return "", errors.New("base_endpoint is required for REST platforms")
+	}
+	parsedURL, err := url.Parse(metadata.BaseEndpoint)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return "", errors.New("base_endpoint must be a valid HTTP/HTTPS URL")
+	}
+	// Sanitize by reconstructing the URL to prevent malicious input
+	metadata.BaseEndpoint = parsedURL.String()
+
+	// Validate authentication
+	if err := validateRESTAuth(&metadata.Auth); err != nil {
+		return "", err
+	}
+
+	// Validate TLS material, proxy and retry policy
+	if err := validateHTTPSettings(metadata.TLS, &metadata.ProxyURL, metadata.Retry); err != nil {
+		return "", err
+	}
+
+	// Re-serialize sanitized metadata
+	serialized, err := json.Marshal(metadata)
+	if err != nil {
+		return "", errors.New("failed to serialize sanitized metadata")
+	}
+	return string(serialized), nil
+}
+
+// validateRESTResourceDetails validates and sanitizes REST endpoint details.
+func validateRESTResourceDetails(detailsJSON string) (string, error) {
+	var details model.RESTResourceDetails
+	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
+		return "", errors.New("invalid details JSON")
+	}
+
+	// Validate method
+	validMethods := map[string]bool{"GET": true, "POST": true, "PUT": true, "DELETE": true}
+	if details.Method == "" {
+		return "", errors.New("method is required for rest_endpoint")
+	}
+	if !validMethods[strings.ToUpper(details.Method)] {
+		return "", errors.New("method must be GET, POST, PUT, or DELETE")
+	}
+	details.Method = strings.ToUpper(details.Method)
+
+	// Validate and sanitize path
+	if details.Path == "" {
+		return "", errors.New("path is required for rest_endpoint")
+	}
+	cleanPath := path.Clean("/" + strings.TrimPrefix(details.Path, "/"))
+	if cleanPath == "/" {
+		return "", errors.New("path must not be empty")
+	}
+	details.Path = cleanPath
+
+	// Validate headers and query parameters
+	if details.Headers == nil {
+		details.Headers = make(map[string]string)
+	}
+	if details.QueryParams == nil {
+		details.QueryParams = make(map[string]string)
+	}
+	for key, value := range details.Headers {
+		if strings.TrimSpace(key) == "" {
+			return "", errors.New("header keys must not be empty")
+		}
+		details.Headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	for key, value := range details.QueryParams {
+		if strings.TrimSpace(key) == "" {
+			return "", errors.New("query parameter keys must not be empty")
+		}
+		details.QueryParams[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	// Validate body (optional, must be valid JSON if provided)
+	if details.Body != "" {
+		var body interface{}
+		if err := json.Unmarshal([]byte(details.Body), &body); err != nil {
+			return "", errors.New("body must be valid JSON")
+		}
+	}
+
+	// Validate extraction rules
+	if rules := details.Extract; rules != nil {
+		for _, f := range []struct{ name, path string }{
+			{"items", rules.Items},
+			{"value", rules.Value},
+			{"timestamp", rules.Timestamp},
+		} {
+			if err := ValidateJSONPath(f.path); err != nil {
+				return "", fmt.Errorf("extract.%s: %w", f.name, err)
+			}
+		}
+		for name, path := range rules.Fields {
+			if strings.TrimSpace(name) == "" {
+				return "", errors.New("extract field names must not be empty")
+			}
+			if strings.TrimSpace(path) == "" {
+				return "", fmt.Errorf("extract field %q needs a path", name)
+			}
+			if err := ValidateJSONPath(path); err != nil {
+				return "", fmt.Errorf("extract field %q: %w", name, err)
+			}
+		}
+	}
+
+	// Validate pagination and apply its defaults
+	if details.Pagination != nil {
+		if err := validateRESTPagination(details.Pagination); err != nil {
+			return "", err
+		}
+	}
+
+	// Re-serialize sanitized details
+	serialized, err := json.Marshal(details)
+	if err != nil {
+		return "", errors.New("failed to serialize sanitized details")
+	}
+	return string(serialized), nil
+}
+
+// prepareRESTResourceDetails merges the request's query parameters into the endpoint and
+// places the device alias in the path or, failing that, in the query string.
+func prepareRESTResourceDetails(detailsJSON, deviceAlias string, params url.Values) (string, error) {
+	var details model.RESTResourceDetails
+	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
+		return "", fmt.Errorf("invalid resource details: %w", err)
+	}
+	if details.QueryParams == nil {
+		details.QueryParams = make(map[string]string)
+	}
+	for key, values := range params {
+		details.QueryParams[key] = values[0]
+	}
+	if deviceAlias != "" {
+		if strings.Contains(details.Path, ":device_alias") {
+			details.Path = strings.ReplaceAll(details.Path, ":device_alias", url.PathEscape(deviceAlias))
+		} else {
+			details.QueryParams["device_alias"] = deviceAlias
+		}
+	}
+
+	serialized, err := json.Marshal(details)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize REST details: %w", err)
+	}
+	return string(serialized), nil
+}
+
+// extractRESTRecords applies extraction rules to a decoded response body. Each record holds the
+// value, the timestamp when the rules and the body have one, and the named fields; fields the
+// body lacks are null. Without a value path the record's value is its fields, or the whole
+// element when there are none.
+func extractRESTRecords(body interface{}, rules model.RESTExtraction) ([]map[string]interface{}, error) {
+	elements := []interface{}{body}
+	if rules.Items != "" {
+		items, err := extractJSONPath(body, rules.Items)
+		if err != nil {
+			return nil, fmt.Errorf("items: %w", err)
+		}
+		var ok bool
+		if elements, ok = items.([]interface{}); !ok {
+			return nil, fmt.Errorf("items: json path %q is not an array", rules.Items)
+		}
+	}
+
+	records := make([]map[string]interface{}, 0, len(elements))
+	for i, element := range elements {
+		record := make(map[string]interface{})
+		if len(rules.Fields) > 0 {
+			fields := make(map[string]interface{}, len(rules.Fields))
+			for name, path := range rules.Fields {
+				fields[name], _ = extractJSONPath(element, path)
+			}
+			record["fields"] = fields
+		}
+
+		switch {
+		case rules.Value != "":
+			value, err := extractJSONPath(element, rules.Value)
+			if err != nil {
+				if rules.Items != "" {
+					return nil, fmt.Errorf("item %d: value: %w", i, err)
+				}
+				return nil, fmt.Errorf("value: %w", err)
+			}
+			record["value"] = value
+		case len(rules.Fields) > 0:
+			record["value"] = record["fields"]
+		default:
+			record["value"] = element
+		}
+
+		if rules.Timestamp != "" {
+			if timestamp, err := extractJSONPath(element, rules.Timestamp); err == nil {
+				record["timestamp"] = timestamp
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// restDetailsPoints reads points from the extracted records of endpoints with extraction rules,
+// and one point per merged item from paginated endpoints without them.
+func restDetailsPoints(detailsJSON string) *PointMapping {
+	var details model.RESTResourceDetails
+	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
+		return nil
+	}
+	switch {
+	case details.Extract != nil:
+		return &PointMapping{Items: "extracted", Value: "value", Timestamp: "timestamp"}
+	case details.Pagination != nil:
+		return &PointMapping{Items: "body", Value: "$"}
+	}
+	return nil
 }
 
 // Connect is a no-op for REST since it's stateless.
@@ -51,7 +309,8 @@ func (d *RESTDriver) Connect(ctx context.Context) error {
 	return nil
 }
 
-// FetchData sends an HTTP request to the REST endpoint and returns the response.
+// FetchData sends an HTTP request to the REST endpoint and returns the response. Paginated
+// endpoints are requested page by page and their items merged into one body.
 func (d *RESTDriver) FetchData(ctx context.Context, resourceDetails string) (interface{}, error) {
 	var details model.RESTResourceDetails
 	if err := json.Unmarshal([]byte(resourceDetails), &details); err != nil {
@@ -62,7 +321,80 @@ func (d *RESTDriver) FetchData(ctx context.Context, resourceDetails string) (int
 		return nil, errors.New("method and path are required in resource details")
 	}
 
-	// Construct URL with query parameters
+	fullURL, err := d.resourceURL(details)
+	if err != nil {
+		return nil, err
+	}
+
+	var responseData map[string]interface{}
+	if details.Pagination != nil {
+		responseData, err = d.fetchPages(ctx, details, fullURL)
+	} else {
+		responseData, err = d.request(ctx, details, fullURL)
+	}
+	if err != nil {
+		if responseData == nil {
+			return nil, err
+		}
+		return responseData, err
+	}
+
+	// Pick the configured measurements out of the body, next to the body itself
+	if details.Extract != nil {
+		records, err := extractRESTRecords(responseData["body"], *details.Extract)
+		if err != nil {
+			return responseData, fmt.Errorf("extraction failed: %w", err)
+		}
+		responseData["extracted"] = records
+	}
+
+	return responseData, nil
+}
+
+// WriteData sends a resource's request as an action. The body is the write's body, the value
+// encoded as JSON, or the resource's own body, in that order. Reading the previous value could
+// itself trigger an action, so none is reported.
+func (d *RESTDriver) WriteData(ctx context.Context, resourceDetails string, value WriteValue) (*WriteResult, error) {
+	var details model.RESTResourceDetails
+	if err := json.Unmarshal([]byte(resourceDetails), &details); err != nil {
+		return nil, fmt.Errorf("invalid resource details: %w", err)
+	}
+	if details.Method == "" || details.Path == "" {
+		return nil, errors.New("method and path are required in resource details")
+	}
+	if details.Method == http.MethodGet {
+		return nil, errors.New("rest_endpoint resources with method GET cannot be written")
+	}
+
+	result := &WriteResult{}
+	switch {
+	case value.Body != nil:
+		details.Body = *value.Body
+		result.NewValue = *value.Body
+	case value.Value != nil:
+		body, err := json.Marshal(value.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode value: %w", err)
+		}
+		details.Body = string(body)
+		result.NewValue = value.Value
+	default:
+		result.NewValue = details.Body
+	}
+
+	target, err := d.resourceURL(details)
+	if err != nil {
+		return nil, err
+	}
+	response, err := d.request(ctx, details, target)
+	if response != nil {
+		result.Response = response // Error responses explain what went wrong
+	}
+	return result, err
+}
+
+// resourceURL joins the base URL and the resource path and adds the query parameters.
+func (d *RESTDriver) resourceURL(details model.RESTResourceDetails) (*url.URL, error) {
 	baseURL, err := url.Parse(d.BaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid base URL %s: %w", d.BaseURL, err)
@@ -86,47 +418,24 @@ func (d *RESTDriver) FetchData(ctx context.Context, resourceDetails string) (int
 		query.Set(key, value)
 	}
 	fullURL.RawQuery = query.Encode()
+	return &fullURL, nil
+}
 
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, details.Method, fullURL.String(), bytes.NewBufferString(details.Body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set authentication headers
-	switch d.Auth.Type {
-	case "api_key":
-		if d.Auth.APIKey == nil {
-			return nil, errors.New("API key missing for api_key authentication")
+// request sends one request for the resource to a URL and returns the structured response.
+// HTTP errors are returned together with the response.
+func (d *RESTDriver) request(ctx context.Context, details model.RESTResourceDetails, target *url.URL) (map[string]interface{}, error) {
+	resp, err := d.send(ctx, details.Method, target, details.Body, func(req *http.Request) {
+		// Set custom headers
+		for key, value := range details.Headers {
+			req.Header.Set(key, value)
 		}
-		req.Header.Set("X-API-Key", *d.Auth.APIKey)
-		logs.Debug("Setting API key header")
-	case "bearer":
-		if d.Auth.BearerToken == nil {
-			return nil, errors.New("bearer token missing for bearer authentication")
-		}
-		req.Header.Set("Authorization", "Bearer "+*d.Auth.BearerToken)
-		logs.Debug("Setting Bearer token header")
-	case "basic":
-		if d.Auth.BasicAuth == nil {
-			return nil, errors.New("basic auth configuration missing")
-		}
-		auth := base64.StdEncoding.EncodeToString([]byte(d.Auth.BasicAuth.Username + ":" + d.Auth.BasicAuth.Password))
-		req.Header.Set("Authorization", "Basic "+auth)
-		logs.Debug("Setting Basic auth header")
-	}
-
-	// Set custom headers
-	for key, value := range details.Headers {
-		req.Header.Set(key, value)
-	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
 
-	// Log request details
-	logs.Info("REST request: method=%s, url=%s, headers=%v, body=%s", details.Method, fullURL.String(), req.Header, details.Body)
-	resp, err := d.client.Do(req)
+		// Log request details; header values and query API keys carry credentials
+		logs.Info("REST request: method=%s, url=%s, headers=%s, body=%s", details.Method, d.redactURL(req.URL), headerNames(req.Header), details.Body)
+	})
 	if err != nil {
 		logs.Error("REST request failed: %v", err)
 		return nil, fmt.Errorf("request failed: %w", err)
@@ -171,38 +480,108 @@ func (d *RESTDriver) FetchData(ctx context.Context, resourceDetails string) (int
 	return responseData, nil
 }
 
-// ValidateConfig checks if the REST configuration is valid by sending a HEAD request.
+// ValidateConfig checks if the REST configuration is valid by sending a HEAD request. Many
+// APIs serve nothing at their base path or don't implement HEAD, so 404, 405 and 501 answers
+// still show the server is up; rejected credentials answer 401 or 403.
 func (d *RESTDriver) ValidateConfig(ctx context.Context) error {
-	req, err := http.NewRequestWithContext(ctx, "HEAD", d.BaseURL, nil)
+	resp, err := d.head(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create HEAD request: %w", err)
+		return err
 	}
+	defer resp.Body.Close()
 
-	// Set authentication headers
-	switch d.Auth.Type {
-	case "api_key":
-		req.Header.Set("X-API-Key", *d.Auth.APIKey)
-	case "bearer":
-		req.Header.Set("Authorization", "Bearer "+*d.Auth.BearerToken)
-	case "basic":
-		if d.Auth.BasicAuth == nil {
-			return errors.New("basic auth configuration missing")
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("validation failed with status: %s: %w", resp.Status, ErrAuthFailed)
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return nil
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("validation failed with status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// head sends an authenticated HEAD request to the base URL.
+func (d *RESTDriver) head(ctx context.Context) (*http.Response, error) {
+	baseURL, err := url.Parse(d.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL %s: %w", d.BaseURL, err)
+	}
+	resp, err := d.send(ctx, http.MethodHead, baseURL, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	return resp, nil
+}
+
+// send sends an authenticated request, with prepare setting any further headers. An OAuth2
+// token the API rejects is dropped and the request is sent once more with a new one, in case
+// the provider revoked it before it expired.
+func (d *RESTDriver) send(ctx context.Context, method string, target *url.URL, body string, prepare func(*http.Request)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target.String(), strings.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		if err := d.authorize(ctx, req); err != nil {
+			return nil, err
+		}
+		if prepare != nil {
+			prepare(req)
+		}
+
+		resp, err := d.client.Do(req)
+		if err != nil {
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				urlErr.URL = d.redactURL(req.URL)
+			}
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && d.tokens != nil && attempt == 0 {
+			resp.Body.Close()
+			d.tokens.Invalidate()
+			continue
 		}
-		auth := base64.StdEncoding.EncodeToString([]byte(d.Auth.BasicAuth.Username + ":" + d.Auth.BasicAuth.Password))
-		req.Header.Set("Authorization", "Basic "+auth)
+		return resp, nil
+	}
+}
+
+// headerNames lists the names of request headers, sorted, for logs that must not show values.
+func headerNames(header http.Header) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
 
-	resp, err := d.client.Do(req)
+// Endpoint returns the host and port of the base URL.
+func (d *RESTDriver) Endpoint() (*Endpoint, error) {
+	u, err := url.Parse(d.BaseURL)
 	if err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+		return nil, fmt.Errorf("invalid base URL: %w", err)
 	}
-	defer resp.Body.Close()
+	return d.http.endpoint(u)
+}
 
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("validation failed with status: %s", resp.Status)
+// Identify reports the Server header of the base URL.
+func (d *RESTDriver) Identify(ctx context.Context) (*ServerInfo, error) {
+	resp, err := d.head(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	return nil
+	server := resp.Header.Get("Server")
+	if server == "" {
+		return nil, errors.New("server did not identify itself")
+	}
+	product, version, _ := strings.Cut(server, "/")
+	return &ServerInfo{Product: product, Version: version}, nil
 }
 
 // TestResource tests a specific REST resource by executing the request.
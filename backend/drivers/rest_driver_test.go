@@ -2,11 +2,17 @@ package drivers
 
 import (
 	"app/model"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestRESTDriver_FetchData_URLConstruction(t *testing.T) {
@@ -55,6 +61,298 @@ func TestRESTDriver_FetchData_URLConstruction(t *testing.T) {
 	driver.client = originalClient
 }
 
+func TestRESTDriver_FetchData_Extraction(t *testing.T) {
+	metadataJSON, _ := json.Marshal(model.RESTMetadata{BaseEndpoint: "https://cmms.example.com", Auth: model.RESTAuth{Type: "none"}})
+	driver, err := NewRESTDriver(string(metadataJSON))
+	if err != nil {
+		t.Fatalf("Failed to create RESTDriver: %v", err)
+	}
+	body := `{"meta": {"page": 1}, "data": {"readings": [
+		{"ts": "2024-05-01T12:00:00Z", "temp": 20.5, "humidity": 40},
+		{"ts": "2024-05-01T12:01:00Z", "temp": 21.0}
+	]}}`
+	driver.client = &http.Client{
+		Transport: &mockTransport{
+			RoundTripFunc: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Status:     "200 OK",
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       io.NopCloser(strings.NewReader(body)),
+				}, nil
+			},
+		},
+	}
+
+	details := model.RESTResourceDetails{
+		Method: "GET",
+		Path:   "/readings",
+		Extract: &model.RESTExtraction{
+			Items:     "data.readings",
+			Value:     "temp",
+			Timestamp: "ts",
+			Fields:    map[string]string{"humidity": "humidity"},
+		},
+	}
+	detailsJSON, _ := json.Marshal(details)
+	validated, err := validateRESTResourceDetails(string(detailsJSON))
+	if err != nil {
+		t.Fatalf("Expected valid details, got %v", err)
+	}
+
+	result, err := driver.FetchData(context.Background(), validated)
+	if err != nil {
+		t.Fatalf("FetchData failed: %v", err)
+	}
+	response := result.(map[string]interface{})
+	if _, ok := response["body"].(map[string]interface{})["meta"]; !ok {
+		t.Error("Expected the raw body next to the extracted records")
+	}
+	records := response["extracted"].([]map[string]interface{})
+	if len(records) != 2 || records[0]["value"] != 20.5 || records[0]["timestamp"] != "2024-05-01T12:00:00Z" {
+		t.Fatalf("Unexpected records %v", records)
+	}
+	if fields := records[1]["fields"].(map[string]interface{}); fields["humidity"] != nil {
+		t.Errorf("Expected a missing field to be null, got %v", fields["humidity"])
+	}
+
+	// Endpoints with extraction rules give one point per record
+	resource := &model.Resource{Name: "temperature", Type: "rest_endpoint", Details: validated}
+	points, err := ResourcePoints(resource, result, time.Now(), false)
+	if err != nil {
+		t.Fatalf("ResourcePoints failed: %v", err)
+	}
+	if len(points) != 2 || !points[1].Timestamp.Equal(time.Date(2024, 5, 1, 12, 1, 0, 0, time.UTC)) || points[1].Value != json.Number("21") {
+		t.Errorf("Unexpected points %+v", points)
+	}
+
+	// A value path the body does not have fails, but still returns the response
+	details.Extract.Value = "pressure"
+	detailsJSON, _ = json.Marshal(details)
+	result, err = driver.FetchData(context.Background(), string(detailsJSON))
+	if err == nil || result.(map[string]interface{})["body"] == nil {
+		t.Errorf("Expected an extraction error with the response, got %v, %v", result, err)
+	}
+
+	details.Extract.Fields = map[string]string{"humidity": "readings[x]"}
+	detailsJSON, _ = json.Marshal(details)
+	if _, err := validateRESTResourceDetails(string(detailsJSON)); err == nil {
+		t.Error("Expected an error for a malformed field path")
+	}
+}
+
+func TestRESTDriver_FetchData_Pagination(t *testing.T) {
+	metadataJSON, _ := json.Marshal(model.RESTMetadata{BaseEndpoint: "https://cmms.example.com/api", Auth: model.RESTAuth{Type: "none"}})
+	driver, err := NewRESTDriver(string(metadataJSON))
+	if err != nil {
+		t.Fatalf("Failed to create RESTDriver: %v", err)
+	}
+	// Five items served two at a time, whatever the strategy asks with
+	driver.client = &http.Client{
+		Transport: &mockTransport{
+			RoundTripFunc: func(req *http.Request) (*http.Response, error) {
+				query := req.URL.Query()
+				start := 0
+				if page, err := strconv.Atoi(query.Get("page")); err == nil {
+					start = (page - 1) * 2
+				} else if offset, err := strconv.Atoi(query.Get("offset")); err == nil {
+					start = offset
+				} else if cursor, err := strconv.Atoi(query.Get("cursor")); err == nil {
+					start = cursor
+				}
+				page := map[string]interface{}{"items": []int{}, "next": nil}
+				header := http.Header{"Content-Type": []string{"application/json"}}
+				if start < 5 {
+					end := min(start+2, 5)
+					items := make([]int, 0, 2)
+					for i := start; i < end; i++ {
+						items = append(items, i+1)
+					}
+					page["items"] = items
+					if end < 5 {
+						page["next"] = strconv.Itoa(end)
+						link := fmt.Sprintf(`<items?cursor=%d>; rel="next", <items?cursor=4>; rel="last"`, end)
+						if query.Get("elsewhere") != "" {
+							link = fmt.Sprintf(`<https://other.example.com/items?cursor=%d>; rel="next"`, end)
+						}
+						header.Set("Link", link)
+					}
+				}
+				body, _ := json.Marshal(page)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Status:     "200 OK",
+					Header:     header,
+					Body:       io.NopCloser(bytes.NewReader(body)),
+				}, nil
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		pagination model.RESTPagination
+		query      map[string]string
+		items      int
+		pages      int
+		truncated  bool
+		wantErr    bool
+	}{
+		{name: "page numbers", pagination: model.RESTPagination{Type: "page", Items: "items", PageSize: 2, LimitParam: "per_page"}, items: 5, pages: 3},
+		{name: "offsets", pagination: model.RESTPagination{Type: "offset", Items: "items"}, items: 5, pages: 4},
+		{name: "cursor", pagination: model.RESTPagination{Type: "cursor", Items: "items", CursorPath: "next"}, items: 5, pages: 3},
+		{name: "link headers", pagination: model.RESTPagination{Type: "link", Items: "items"}, items: 5, pages: 3},
+		{name: "max items", pagination: model.RESTPagination{Type: "cursor", Items: "items", CursorPath: "next", MaxItems: 3}, items: 3, pages: 2, truncated: true},
+		{name: "max pages", pagination: model.RESTPagination{Type: "link", Items: "items", MaxPages: 2}, items: 4, pages: 2, truncated: true},
+		{name: "link to another host", pagination: model.RESTPagination{Type: "link", Items: "items"}, query: map[string]string{"elsewhere": "1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := model.RESTResourceDetails{Method: "GET", Path: "/items", QueryParams: tt.query, Pagination: &tt.pagination}
+			detailsJSON, _ := json.Marshal(details)
+			validated, err := validateRESTResourceDetails(string(detailsJSON))
+			if err != nil {
+				t.Fatalf("Expected valid details, got %v", err)
+			}
+
+			result, err := driver.FetchData(context.Background(), validated)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchData failed: %v", err)
+			}
+			response := result.(map[string]interface{})
+			items := response["body"].([]interface{})
+			if len(items) != tt.items || items[0] != 1.0 || items[len(items)-1] != float64(tt.items) {
+				t.Errorf("Expected items 1 to %d, got %v", tt.items, items)
+			}
+			if response["pages"] != tt.pages || response["truncated"] != tt.truncated {
+				t.Errorf("Expected %d pages and truncated %v, got %v and %v", tt.pages, tt.truncated, response["pages"], response["truncated"])
+			}
+
+			// Without extraction rules each merged item is a point
+			resource := &model.Resource{Name: "items", Type: "rest_endpoint", Details: validated}
+			points, err := ResourcePoints(resource, result, time.Now(), false)
+			if err != nil || len(points) != tt.items {
+				t.Errorf("Expected %d points, got %d: %v", tt.items, len(points), err)
+			}
+		})
+	}
+
+	for _, pagination := range []model.RESTPagination{
+		{Type: "scroll"},
+		{Type: "cursor"},
+		{Type: "page", Items: "data[x]"},
+		{Type: "page", MaxPages: 5000},
+	} {
+		details := model.RESTResourceDetails{Method: "GET", Path: "/items", Pagination: &pagination}
+		detailsJSON, _ := json.Marshal(details)
+		if _, err := validateRESTResourceDetails(string(detailsJSON)); err == nil {
+			t.Errorf("Expected an error for pagination %+v", pagination)
+		}
+	}
+}
+
+func TestRESTDriver_Retry(t *testing.T) {
+	metadata, err := ValidateMetadata("REST", `{"base_endpoint": "https://api.example.com", "auth": {"type": "none"},
+		"retry": {"max_retries": 2, "initial_backoff": "1ms", "max_backoff": "2ms"}, "circuit_breaker": {"failure_threshold": 3}}`)
+	if err != nil {
+		t.Fatalf("Expected valid metadata, got %v", err)
+	}
+	var settings struct {
+		Retry          model.HTTPRetry      `json:"retry"`
+		CircuitBreaker model.CircuitBreaker `json:"circuit_breaker"`
+	}
+	json.Unmarshal([]byte(metadata), &settings)
+	if fmt.Sprint(settings.Retry.RetryableStatus) != "[429 502 503 504]" || settings.CircuitBreaker.CoolDown != "30s" {
+		t.Errorf("Expected the defaults to be filled in, got %s", metadata)
+	}
+
+	driver, err := NewRESTDriver(metadata)
+	if err != nil {
+		t.Fatalf("Failed to create RESTDriver: %v", err)
+	}
+	// The first two attempts of every request fail
+	attempts := 0
+	driver.client.Transport.(*retryTransport).next = &mockTransport{
+		RoundTripFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			body, _ := io.ReadAll(req.Body)
+			switch {
+			case attempts%3 == 1:
+				return nil, errors.New("connection reset by peer")
+			case attempts%3 == 2:
+				return jsonResponse(http.StatusServiceUnavailable, `{}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{"body": "`+string(body)+`"}`), nil
+		},
+	}
+
+	result, err := driver.FetchData(context.Background(), `{"method": "PUT", "path": "/setpoint", "body": "42"}`)
+	if err != nil || attempts != 3 {
+		t.Fatalf("Expected success on the third attempt, got %d attempts: %v", attempts, err)
+	}
+	if body := result.(map[string]interface{})["body"].(map[string]interface{})["body"]; body != "42" {
+		t.Errorf("Expected the body to be sent again, got %v", body)
+	}
+
+	// POST is not idempotent and fails on the first error
+	attempts = 0
+	if _, err := driver.FetchData(context.Background(), `{"method": "POST", "path": "/reset"}`); err == nil || attempts != 1 {
+		t.Errorf("Expected one failed attempt, got %d: %v", attempts, err)
+	}
+
+	for _, retry := range []string{
+		`{"max_retries": 11}`,
+		`{"max_retries": 1, "initial_backoff": "soon"}`,
+		`{"max_retries": 1, "initial_backoff": "10s", "max_backoff": "1s"}`,
+		`{"max_retries": 1, "retryable_status": [200]}`,
+	} {
+		if _, err := validateRESTMetadata(`{"base_endpoint": "https://api.example.com", "retry": ` + retry + `}`); err == nil {
+			t.Errorf("Expected an error for retry %s", retry)
+		}
+	}
+	if _, err := ValidateMetadata("REST", `{"base_endpoint": "https://api.example.com", "circuit_breaker": {"failure_threshold": 0}}`); err == nil {
+		t.Error("Expected an error for a zero failure threshold")
+	}
+}
+
+func TestRESTDriver_ValidateConfig(t *testing.T) {
+	driver, err := NewRESTDriver(`{"base_endpoint": "https://api.example.com", "auth": {"type": "none"}}`)
+	if err != nil {
+		t.Fatalf("Failed to create RESTDriver: %v", err)
+	}
+	status := http.StatusOK
+	driver.client = &http.Client{
+		Transport: &mockTransport{
+			RoundTripFunc: func(req *http.Request) (*http.Response, error) {
+				return jsonResponse(status, `{}`), nil
+			},
+		},
+	}
+
+	// A server that answers is up, even when the base path serves nothing
+	for _, status = range []int{http.StatusOK, http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented} {
+		if err := driver.ValidateConfig(context.Background()); err != nil {
+			t.Errorf("Expected status %d to pass, got %v", status, err)
+		}
+	}
+	for _, status = range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		if err := driver.ValidateConfig(context.Background()); !errors.Is(err, ErrAuthFailed) {
+			t.Errorf("Expected status %d to fail authentication, got %v", status, err)
+		}
+	}
+	status = http.StatusInternalServerError
+	if err := driver.ValidateConfig(context.Background()); err == nil || errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Expected a server error to fail, got %v", err)
+	}
+}
+
 type mockTransport struct {
 	RoundTripFunc func(*http.Request) (*http.Response, error)
 }
@@ -62,3 +360,45 @@ type mockTransport struct {
 func (m *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	return m.RoundTripFunc(req)
 }
+
+func TestRESTDriver_WriteData(t *testing.T) {
+	metadataJSON, _ := json.Marshal(model.RESTMetadata{BaseEndpoint: "https://plc.example.com", Auth: model.RESTAuth{Type: "none"}})
+	driver, err := NewRESTDriver(string(metadataJSON))
+	if err != nil {
+		t.Fatalf("Failed to create RESTDriver: %v", err)
+	}
+	var sent string
+	driver.client = &http.Client{
+		Transport: &mockTransport{
+			RoundTripFunc: func(req *http.Request) (*http.Response, error) {
+				body, _ := io.ReadAll(req.Body)
+				sent = req.Method + " " + string(body)
+				return jsonResponse(http.StatusOK, `{"accepted": true}`), nil
+			},
+		},
+	}
+
+	ctx := context.Background()
+	details := `{"method": "PUT", "path": "/setpoint", "body": "{\"value\": 20}"}`
+	result, err := driver.WriteData(ctx, details, WriteValue{Value: map[string]interface{}{"value": 22.5}})
+	if err != nil {
+		t.Fatalf("Failed to write data: %v", err)
+	}
+	if sent != `PUT {"value":22.5}` || result.OldValue != nil {
+		t.Errorf("Unexpected request %q or old value %v", sent, result.OldValue)
+	}
+	if accepted := result.Response.(map[string]interface{})["body"].(map[string]interface{})["accepted"]; accepted != true {
+		t.Errorf("Expected the platform's response, got %v", result.Response)
+	}
+
+	body := `{"command": "reset"}`
+	if _, err := driver.WriteData(ctx, details, WriteValue{Body: &body}); err != nil || sent != "PUT "+body {
+		t.Errorf("Expected the body to replace the resource's, sent %q: %v", sent, err)
+	}
+	if _, err := driver.WriteData(ctx, details, WriteValue{}); err != nil || sent != `PUT {"value": 20}` {
+		t.Errorf("Expected the resource's own body, sent %q: %v", sent, err)
+	}
+	if _, err := driver.WriteData(ctx, `{"method": "GET", "path": "/setpoint"}`, WriteValue{Value: 1.0}); err == nil {
+		t.Errorf("Expected GET resources to be rejected")
+	}
+}
@@ -0,0 +1,245 @@
+package drivers
+
+import (
+	"app/model"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	restDefaultMaxPages = 10    // Pages requested when max_pages is not set
+	restMaxPages        = 1000  // Upper bound for max_pages
+	restDefaultMaxItems = 10000 // Items kept when max_items is not set
+)
+
+// validateRESTPagination checks pagination settings and applies their defaults.
+func validateRESTPagination(p *model.RESTPagination) error {
+	switch p.Type {
+	case "page":
+		if p.PageParam == "" {
+			p.PageParam = "page"
+		}
+		if p.FirstPage == nil {
+			first := 1
+			p.FirstPage = &first
+		}
+	case "offset":
+		if p.OffsetParam == "" {
+			p.OffsetParam = "offset"
+		}
+	case "cursor":
+		if p.CursorPath == "" {
+			return errors.New("pagination.cursor_path is required for cursor pagination")
+		}
+		if p.CursorParam == "" {
+			p.CursorParam = "cursor"
+		}
+	case "link":
+	default:
+		return errors.New("pagination.type must be page, offset, cursor or link")
+	}
+
+	if err := ValidateJSONPath(p.Items); err != nil {
+		return fmt.Errorf("pagination.items: %w", err)
+	}
+	if err := ValidateJSONPath(p.CursorPath); err != nil {
+		return fmt.Errorf("pagination.cursor_path: %w", err)
+	}
+	if p.PageSize < 0 || p.MaxPages < 0 || p.MaxItems < 0 {
+		return errors.New("pagination.page_size, max_pages and max_items must not be negative")
+	}
+	if p.MaxPages == 0 {
+		p.MaxPages = restDefaultMaxPages
+	}
+	if p.MaxPages > restMaxPages {
+		return fmt.Errorf("pagination.max_pages must be at most %d", restMaxPages)
+	}
+	if p.MaxItems == 0 {
+		p.MaxItems = restDefaultMaxItems
+	}
+	return nil
+}
+
+// fetchPages requests a paginated endpoint until the last page or a limit is reached and
+// returns the last page's response with the items of every page as its body. "pages" counts
+// the pages requested and "truncated" is set when a limit cut the result short.
+func (d *RESTDriver) fetchPages(ctx context.Context, details model.RESTResourceDetails, first *url.URL) (map[string]interface{}, error) {
+	p := details.Pagination
+	maxPages, maxItems := p.MaxPages, p.MaxItems
+	if maxPages <= 0 {
+		maxPages = restDefaultMaxPages
+	}
+	if maxItems <= 0 {
+		maxItems = restDefaultMaxItems
+	}
+
+	items := []interface{}{}
+	target := first
+	cursor := ""
+	var last map[string]interface{}
+	pages := 0
+	truncated := false
+	for page := 0; ; page++ {
+		pageURL := *target
+		query := pageURL.Query()
+		switch p.Type {
+		case "page":
+			firstPage := 1
+			if p.FirstPage != nil {
+				firstPage = *p.FirstPage
+			}
+			query.Set(p.PageParam, strconv.Itoa(firstPage+page))
+		case "offset":
+			query.Set(p.OffsetParam, strconv.Itoa(len(items)))
+		case "cursor":
+			if cursor != "" {
+				query.Set(p.CursorParam, cursor)
+			}
+		}
+		if p.LimitParam != "" && p.PageSize > 0 && p.Type != "link" && p.Type != "cursor" {
+			query.Set(p.LimitParam, strconv.Itoa(p.PageSize))
+		}
+		pageURL.RawQuery = query.Encode()
+
+		response, err := d.request(ctx, details, &pageURL)
+		if err != nil {
+			return response, fmt.Errorf("page %d: %w", page+1, err)
+		}
+		pageItems, err := restPageItems(response["body"], p.Items)
+		if err != nil {
+			return response, fmt.Errorf("page %d: %w", page+1, err)
+		}
+		items = append(items, pageItems...)
+		last = response
+		pages++
+
+		// Find the next page, if there is one
+		more := false
+		switch p.Type {
+		case "page", "offset":
+			more = len(pageItems) > 0 && (p.PageSize == 0 || len(pageItems) >= p.PageSize)
+		case "cursor":
+			next := restCursor(optionalJSONPath(response["body"], p.CursorPath))
+			more = next != "" && next != cursor
+			cursor = next
+		case "link":
+			header, _ := response["headers"].(http.Header)
+			if link := nextLink(header); link != "" {
+				next, err := restNextURL(&pageURL, link, first)
+				if err != nil {
+					return response, fmt.Errorf("page %d: %w", page+1, err)
+				}
+				target, more = next, true
+			}
+		}
+
+		if len(items) >= maxItems {
+			truncated = more || len(items) > maxItems
+			items = items[:maxItems]
+			break
+		}
+		if !more {
+			break
+		}
+		if page+1 >= maxPages {
+			truncated = true
+			break
+		}
+	}
+
+	return map[string]interface{}{
+		"status_code": last["status_code"],
+		"status":      last["status"],
+		"headers":     last["headers"],
+		"body":        items,
+		"pages":       pages,
+		"truncated":   truncated,
+	}, nil
+}
+
+// restPageItems returns the items of one page: the array at the items path, or the whole
+// body when the path is empty.
+func restPageItems(body interface{}, path string) ([]interface{}, error) {
+	if _, ok := body.(string); ok {
+		return nil, errors.New("pagination needs a JSON response body")
+	}
+	found, err := extractJSONPath(body, path)
+	if err != nil {
+		return nil, fmt.Errorf("items: %w", err)
+	}
+	if found == nil {
+		return nil, nil
+	}
+	items, ok := found.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("items: json path %q is not an array", path)
+	}
+	return items, nil
+}
+
+// restCursor turns the cursor found in a page into a query parameter value. Missing, null
+// and empty cursors mark the last page.
+func restCursor(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool, nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// nextLink returns the target of the rel="next" link in RFC 5988 Link headers such as
+// `<https://api.example.com/items?page=2>; rel="next", <...>; rel="last"`.
+func nextLink(header http.Header) string {
+	for _, value := range header.Values("Link") {
+		for {
+			start := strings.IndexByte(value, '<')
+			end := strings.IndexByte(value, '>')
+			if start < 0 || end < start {
+				break
+			}
+			target := value[start+1 : end]
+			value = value[end+1:]
+			params := value
+			if next := strings.IndexByte(value, '<'); next >= 0 {
+				params, value = value[:next], value[next:]
+			}
+			params = strings.TrimRight(strings.TrimSpace(params), ",")
+			for _, param := range strings.Split(params, ";") {
+				key, rels, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(key), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(rels), `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return target
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// restNextURL resolves a next link against the current page. Links to another scheme or host
+// are refused so the resource's credentials are only sent to its own endpoint.
+func restNextURL(current *url.URL, link string, first *url.URL) (*url.URL, error) {
+	ref, err := url.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("invalid next link %q: %w", link, err)
+	}
+	next := current.ResolveReference(ref)
+	if next.Scheme != first.Scheme || next.Host != first.Host {
+		return nil, fmt.Errorf("next link %q points to another host", link)
+	}
+	return next, nil
+}
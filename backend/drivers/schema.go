@@ -0,0 +1,136 @@
+package drivers
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// schemaFiles holds the JSON Schemas drivers publish for their metadata and resource details.
+//
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// schemaPrinter renders schema validation messages.
+var schemaPrinter = message.NewPrinter(language.English)
+
+// Schema is a compiled JSON Schema. It marshals to the schema document so it can be served to clients.
+type Schema struct {
+	raw      []byte
+	compiled *jsonschema.Schema
+}
+
+// mustLoadSchema compiles one of the embedded schema files. It panics if the file is missing or
+// not a valid schema, so mistakes surface when the driver registers.
+func mustLoadSchema(name string) *Schema {
+	raw, err := schemaFiles.ReadFile("schemas/" + name)
+	if err != nil {
+		panic(fmt.Sprintf("drivers: schema %s: %v", name, err))
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		panic(fmt.Sprintf("drivers: schema %s: %v", name, err))
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(name, doc); err != nil {
+		panic(fmt.Sprintf("drivers: schema %s: %v", name, err))
+	}
+	compiled, err := compiler.Compile(name)
+	if err != nil {
+		panic(fmt.Sprintf("drivers: schema %s: %v", name, err))
+	}
+	return &Schema{raw: raw, compiled: compiled}
+}
+
+// MarshalJSON returns the schema document.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	return s.raw, nil
+}
+
+// Validate checks a JSON document against the schema and returns a *ValidationError listing
+// every field that does not conform.
+func (s *Schema) Validate(document string) error {
+	instance, err := jsonschema.UnmarshalJSON(strings.NewReader(document))
+	if err != nil {
+		return &ValidationError{Fields: []FieldError{{Path: "", Message: "invalid JSON"}}}
+	}
+	err = s.compiled.Validate(instance)
+	if err == nil {
+		return nil
+	}
+	schemaErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return err
+	}
+
+	var fields []FieldError
+	collectFieldErrors(schemaErr, &fields)
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].Path < fields[j].Path })
+	return &ValidationError{Fields: fields}
+}
+
+// collectFieldErrors flattens the leaves of a schema error tree into field errors. Missing
+// required properties are reported at the property's own path.
+func collectFieldErrors(err *jsonschema.ValidationError, fields *[]FieldError) {
+	if len(err.Causes) > 0 {
+		for _, cause := range err.Causes {
+			collectFieldErrors(cause, fields)
+		}
+		return
+	}
+
+	path := jsonPointer(err.InstanceLocation)
+	if required, ok := err.ErrorKind.(*kind.Required); ok {
+		for _, name := range required.Missing {
+			*fields = append(*fields, FieldError{Path: path + "/" + escapePointerToken(name), Message: "is required"})
+		}
+		return
+	}
+	*fields = append(*fields, FieldError{Path: path, Message: err.ErrorKind.LocalizedString(schemaPrinter)})
+}
+
+// jsonPointer joins instance location tokens into a JSON Pointer, "" being the whole document.
+func jsonPointer(tokens []string) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteByte('/')
+		sb.WriteString(escapePointerToken(token))
+	}
+	return sb.String()
+}
+
+// escapePointerToken escapes a reference token as RFC 6901 requires.
+func escapePointerToken(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// FieldError is a validation failure of one field of a metadata or details document.
+type FieldError struct {
+	Path    string `json:"path"` // JSON Pointer into the document, e.g. "/auth/api_key"
+	Message string `json:"message"`
+}
+
+// ValidationError reports the fields of a metadata or details document that failed validation.
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+// Error lists the failing fields on one line.
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		path := field.Path
+		if path == "" {
+			path = "/"
+		}
+		messages[i] = path + ": " + field.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
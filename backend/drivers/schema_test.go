@@ -0,0 +1,94 @@
+package drivers
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSchema_PublishedForBuiltinTypes(t *testing.T) {
+	for _, r := range Registrations() {
+		if r.MetadataSchema == nil {
+			t.Errorf("%s has no metadata schema", r.Type)
+			continue
+		}
+		raw, err := json.Marshal(r.MetadataSchema)
+		if err != nil || !json.Valid(raw) {
+			t.Errorf("%s metadata schema does not marshal: %v", r.Type, err)
+		}
+		for _, rt := range r.ResourceTypes {
+			if rt.DetailsSchema == nil {
+				t.Errorf("%s has no details schema", rt.Type)
+			}
+		}
+	}
+}
+
+func TestSchema_FieldErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		validate     func() (string, error)
+		expectedPath string
+	}{
+		{
+			name:         "missing base endpoint",
+			validate:     func() (string, error) { return ValidateMetadata("REST", `{"timeout": 5}`) },
+			expectedPath: "/base_endpoint",
+		},
+		{
+			name: "api key auth without key",
+			validate: func() (string, error) {
+				return ValidateMetadata("REST", `{"base_endpoint": "https://api.example.com", "auth": {"type": "api_key"}}`)
+			},
+			expectedPath: "/auth/api_key",
+		},
+		{
+			name: "unknown auth type",
+			validate: func() (string, error) {
+				return ValidateMetadata("REST", `{"base_endpoint": "https://api.example.com", "auth": {"type": "oauth"}}`)
+			},
+			expectedPath: "/auth/type",
+		},
+		{
+			name: "MQTT qos out of range",
+			validate: func() (string, error) {
+				return ValidateMetadata("MQTT", `{"broker_url": "tcp://broker:1883", "qos": 3}`)
+			},
+			expectedPath: "/qos",
+		},
+		{
+			name: "Modbus address of wrong type",
+			validate: func() (string, error) {
+				return ValidateResourceDetails("modbus_register", `{"function_code": "holding_registers", "address": "ten"}`)
+			},
+			expectedPath: "/address",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.validate()
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected a ValidationError, got %v", err)
+			}
+			for _, field := range validationErr.Fields {
+				if field.Path == tt.expectedPath {
+					return
+				}
+			}
+			t.Errorf("Expected an error at %s, got %v", tt.expectedPath, validationErr.Fields)
+		})
+	}
+}
+
+func TestSchema_InvalidJSON(t *testing.T) {
+	_, err := ValidateMetadata("REST", `{"base_endpoint":`)
+	if err == nil || err.Error() != "invalid metadata JSON" {
+		t.Errorf("Expected invalid metadata JSON, got %v", err)
+	}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		t.Error("Expected a plain error for malformed JSON")
+	}
+}
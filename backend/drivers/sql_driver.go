@@ -0,0 +1,724 @@
+package drivers
+
+import (
+	"app/model"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+const defaultSQLMaxRows = 1000
+
+// sqlEngine describes how to talk to one database engine through database/sql.
+type sqlEngine struct {
+	driverName   string
+	placeholder  func(n int) string                  // Placeholder for the n-th (1-based) bound parameter
+	readOnlyTx   bool                                // Whether the driver supports read-only transactions
+	endpoint     func(dsn string) (*Endpoint, error) // Server address named by a DSN, nil when not over TCP
+	versionQuery string                              // Query returning the server version
+}
+
+// sqlEngines lists the supported database engines.
+var sqlEngines = map[string]sqlEngine{
+	"postgres": {
+		driverName:   "pgx",
+		placeholder:  func(n int) string { return "$" + strconv.Itoa(n) },
+		readOnlyTx:   true,
+		endpoint:     postgresEndpoint,
+		versionQuery: "SELECT version()",
+	},
+	"mysql": {
+		driverName:   "mysql",
+		placeholder:  func(int) string { return "?" },
+		readOnlyTx:   true,
+		endpoint:     mysqlEndpoint,
+		versionQuery: "SELECT VERSION()",
+	},
+	"sqlserver": {
+		driverName:   "sqlserver",
+		placeholder:  func(n int) string { return "@p" + strconv.Itoa(n) },
+		endpoint:     sqlServerEndpoint,
+		versionQuery: "SELECT @@VERSION",
+	},
+}
+
+// sqlParameters are the named parameters a query may reference.
+var sqlParameters = map[string]bool{"device_alias": true, "start": true, "stop": true, "limit": true}
+
+func init() {
+	Register(Registration{
+		Type:             "SQL",
+		Label:            "SQL Database",
+		New:              func(metadata string) (PlatformDriver, error) { return NewSQLDriver(metadata) },
+		MetadataSchema:   mustLoadSchema("sql_metadata.json"),
+		ValidateMetadata: validateSQLMetadata,
+		ResourceTypes: []ResourceType{{
+			Type:            "sql_query",
+			Label:           "SQL Query",
+			DetailsSchema:   mustLoadSchema("sql_query.json"),
+			ValidateDetails: validateSQLResourceDetails,
+			PrepareDetails:  prepareSQLResourceDetails,
+			Points:          PointMapping{Items: "rows"},
+			DetailsPoints:   sqlDetailsPoints,
+		}},
+	})
+}
+
+// SQLDriver implements the PlatformDriver interface for SQL databases.
+type SQLDriver struct {
+	config  model.SQLMetadata
+	engine  sqlEngine
+	timeout time.Duration
+	db      *sql.DB
+}
+
+// NewSQLDriver creates a new SQLDriver instance from platform metadata.
+func NewSQLDriver(metadata string) (*SQLDriver, error) {
+	var config model.SQLMetadata
+	if err := json.Unmarshal([]byte(metadata), &config); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	engine, ok := sqlEngines[config.Engine]
+	if !ok {
+		return nil, fmt.Errorf("unsupported engine %q: must be postgres, mysql or sqlserver", config.Engine)
+	}
+	if config.DSN == "" {
+		return nil, errors.New("missing dsn in metadata")
+	}
+	if config.MaxRows <= 0 {
+		config.MaxRows = defaultSQLMaxRows
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 // Default timeout
+	}
+	return &SQLDriver{
+		config:  config,
+		engine:  engine,
+		timeout: time.Duration(config.Timeout) * time.Second,
+	}, nil
+}
+
+// validateSQLMetadata validates and sanitizes SQL platform metadata.
+func validateSQLMetadata(metadataJSON string) (string, error) {
+	var metadata model.SQLMetadata
+	if err := json.Unmarshal([]byte(metadataJSON), &metadata); err != nil {
+		return "", errors.New("invalid metadata JSON")
+	}
+
+	metadata.Engine = strings.ToLower(strings.TrimSpace(metadata.Engine))
+	metadata.DSN = strings.TrimSpace(metadata.DSN)
+	if metadata.DSN == "" {
+		return "", errors.New("dsn is required for SQL platforms")
+	}
+	if metadata.MaxRows < 0 {
+		return "", errors.New("max_rows must not be negative")
+	}
+	if metadata.MaxRows == 0 {
+		metadata.MaxRows = defaultSQLMaxRows
+	}
+	if metadata.Timeout == 0 {
+		metadata.Timeout = 10
+	}
+
+	serialized, err := json.Marshal(metadata)
+	if err != nil {
+		return "", errors.New("failed to serialize sanitized metadata")
+	}
+
+	// Let the constructor check the engine
+	if _, err := NewSQLDriver(string(serialized)); err != nil {
+		return "", err
+	}
+	return string(serialized), nil
+}
+
+// validateSQLResourceDetails validates and sanitizes SQL query details.
+func validateSQLResourceDetails(detailsJSON string) (string, error) {
+	var details model.SQLResourceDetails
+	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
+		return "", errors.New("invalid details JSON")
+	}
+
+	details.Query = strings.TrimSpace(details.Query)
+	if err := ValidateSQLQuery(details.Query); err != nil {
+		return "", err
+	}
+	details.TimeRange = strings.TrimSpace(details.TimeRange)
+	if details.TimeRange != "" {
+		if _, err := ParseRelativeDuration(details.TimeRange); err != nil {
+			return "", err
+		}
+	}
+	if details.Limit < 0 {
+		return "", errors.New("limit must not be negative")
+	}
+	details.ValueColumn = strings.TrimSpace(details.ValueColumn)
+	if err := validateSQLColumn("value_column", details.ValueColumn); err != nil {
+		return "", err
+	}
+	details.TimestampColumn = strings.TrimSpace(details.TimestampColumn)
+	if err := validateSQLColumn("timestamp_column", details.TimestampColumn); err != nil {
+		return "", err
+	}
+	// The alias comes from the device-platform association at fetch time
+	details.DeviceAlias = ""
+
+	// Re-serialize sanitized details
+	serialized, err := json.Marshal(details)
+	if err != nil {
+		return "", errors.New("failed to serialize sanitized details")
+	}
+	return string(serialized), nil
+}
+
+// validateSQLColumn checks a column named in the details can be looked up in a row. Rows are
+// read with JSON paths, so names with dots or brackets need an alias in the query.
+func validateSQLColumn(field, column string) error {
+	if column == "" {
+		return fmt.Errorf("%s is required for sql_query", field)
+	}
+	if strings.ContainsAny(column, ".[]") || strings.HasPrefix(column, "$") {
+		return fmt.Errorf("%s %q cannot contain '.', '[' or ']' or start with '$', alias the column in the query", field, column)
+	}
+	return nil
+}
+
+// sqlDetailsPoints reads one point per row from the value and timestamp columns.
+func sqlDetailsPoints(detailsJSON string) *PointMapping {
+	var details model.SQLResourceDetails
+	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil || details.ValueColumn == "" {
+		return nil
+	}
+	return &PointMapping{Value: details.ValueColumn, Timestamp: details.TimestampColumn}
+}
+
+// prepareSQLResourceDetails sets the device alias and applies the time_range and limit query
+// parameters. They are bound as query parameters, never spliced into the SQL.
+func prepareSQLResourceDetails(detailsJSON, deviceAlias string, params url.Values) (string, error) {
+	var details model.SQLResourceDetails
+	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
+		return "", fmt.Errorf("invalid resource details: %w", err)
+	}
+	details.DeviceAlias = deviceAlias
+	if timeRange := params.Get("time_range"); timeRange != "" {
+		if _, err := ParseRelativeDuration(timeRange); err != nil {
+			return "", err
+		}
+		details.TimeRange = timeRange
+	}
+	if limit, err := strconv.Atoi(params.Get("limit")); err == nil && limit > 0 {
+		details.Limit = limit
+	}
+
+	serialized, err := json.Marshal(details)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize SQL details: %w", err)
+	}
+	return string(serialized), nil
+}
+
+// Connect opens the connection pool and checks the database is reachable.
+func (d *SQLDriver) Connect(ctx context.Context) error {
+	db, err := sql.Open(d.engine.driverName, d.config.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(2)
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		if isSQLAuthError(err) {
+			return fmt.Errorf("failed to connect to database: %w: %w", ErrAuthFailed, err)
+		}
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	d.db = db
+	return nil
+}
+
+// isSQLAuthError reports whether a connection error means the server rejected the login.
+func isSQLAuthError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "28") // invalid_authorization_specification class
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1045 // ER_ACCESS_DENIED_ERROR
+	}
+	var mssqlErr mssql.Error
+	if errors.As(err, &mssqlErr) {
+		return mssqlErr.Number == 18456 // Login failed
+	}
+	return false
+}
+
+// FetchData runs the resource's query inside a read-only transaction and returns typed rows.
+func (d *SQLDriver) FetchData(ctx context.Context, resourceDetails string) (interface{}, error) {
+	if d.db == nil {
+		return nil, errors.New("not connected to database")
+	}
+
+	var details model.SQLResourceDetails
+	if err := json.Unmarshal([]byte(resourceDetails), &details); err != nil {
+		return nil, fmt.Errorf("invalid resource details: %w", err)
+	}
+	if err := ValidateSQLQuery(details.Query); err != nil {
+		return nil, err
+	}
+
+	stop := time.Now().UTC()
+	start := stop
+	if details.TimeRange != "" {
+		window, err := ParseRelativeDuration(details.TimeRange)
+		if err != nil {
+			return nil, err
+		}
+		start = stop.Add(window)
+	}
+	limit := details.Limit
+	if limit <= 0 || limit > d.config.MaxRows {
+		limit = d.config.MaxRows
+	}
+	values := map[string]interface{}{
+		"device_alias": details.DeviceAlias,
+		"start":        start,
+		"stop":         stop,
+		"limit":        limit,
+	}
+
+	query, names, err := bindSQLParameters(details.Query, d.engine.placeholder)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = values[name]
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	tx, err := d.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: d.engine.readOnlyTx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Nothing is ever committed
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+	return d.readRows(rows)
+}
+
+// readRows collects up to MaxRows rows, converting values to JSON friendly types.
+func (d *SQLDriver) readRows(rows *sql.Rows) (map[string]interface{}, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	columns := make([]map[string]string, len(columnTypes))
+	for i, ct := range columnTypes {
+		columns[i] = map[string]string{"name": ct.Name(), "type": strings.ToLower(ct.DatabaseTypeName())}
+	}
+
+	result := []map[string]interface{}{}
+	truncated := false
+	for rows.Next() {
+		if len(result) >= d.config.MaxRows {
+			truncated = true
+			break
+		}
+		values := make([]interface{}, len(columnTypes))
+		pointers := make([]interface{}, len(columnTypes))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		row := make(map[string]interface{}, len(columnTypes))
+		for i, ct := range columnTypes {
+			row[ct.Name()] = sqlValue(values[i], columns[i]["type"])
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return map[string]interface{}{
+		"columns":   columns,
+		"rows":      result,
+		"row_count": len(result),
+		"truncated": truncated,
+	}, nil
+}
+
+// sqlValue converts raw driver values; numeric types some drivers return as bytes become numbers.
+func sqlValue(value interface{}, databaseType string) interface{} {
+	raw, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+	switch databaseType {
+	case "decimal", "numeric", "float", "double", "real", "money":
+		if f, err := strconv.ParseFloat(string(raw), 64); err == nil {
+			return f
+		}
+	case "int", "integer", "tinyint", "smallint", "mediumint", "bigint":
+		if n, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+			return n
+		}
+	}
+	return string(raw)
+}
+
+// sqlWriteKeywords are keywords of statements that change data, settings or the session,
+// which a query must not contain anywhere.
+var sqlWriteKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true, "DROP": true, "ALTER": true,
+	"CREATE": true, "TRUNCATE": true, "GRANT": true, "REVOKE": true, "DENY": true, "EXEC": true,
+	"EXECUTE": true, "CALL": true, "INTO": true, "COPY": true, "SET": true, "DECLARE": true,
+	"BEGIN": true, "COMMIT": true, "ROLLBACK": true, "KILL": true, "SHUTDOWN": true,
+	"DBCC": true, "BACKUP": true, "RESTORE": true, "BULK": true, "RECONFIGURE": true,
+	"WAITFOR": true, "REVERT": true, "SETUSER": true, "OPENROWSET": true, "OPENDATASOURCE": true,
+	"OPENQUERY": true,
+}
+
+// sqlSetOperators are the keywords after which a SELECT continues the same statement.
+var sqlSetOperators = map[string]bool{"UNION": true, "ALL": true, "DISTINCT": true, "INTERSECT": true, "EXCEPT": true}
+
+// ValidateSQLQuery checks a query is a single SELECT (or WITH ... SELECT) statement
+// whose named parameters are all supported. SQL Server runs batches of statements that
+// are not separated by semicolons and has no read-only transactions, so a SELECT outside
+// parentheses must continue the statement: the first one, the one after the common table
+// expressions of a WITH or one after a set operator such as UNION.
+func ValidateSQLQuery(query string) error {
+	if strings.TrimSpace(query) == "" {
+		return errors.New("query is required in resource details")
+	}
+	tokens, err := scanSQL(query)
+	if err != nil {
+		return err
+	}
+
+	first := ""
+	for _, t := range tokens {
+		if t.kind == sqlWord {
+			first = strings.ToUpper(t.text)
+			break
+		}
+	}
+	if first != "SELECT" && first != "WITH" {
+		return errors.New("query must be a SELECT statement")
+	}
+
+	depth := 0
+	selected := false // Whether the statement's main SELECT was seen
+	previous := ""    // Previous token other than whitespace, upper-cased
+	for i, t := range tokens {
+		if t.kind == sqlSpace {
+			continue
+		}
+		if t.kind == sqlSemicolon {
+			// A trailing semicolon is fine, anything after it is a second statement
+			for _, rest := range tokens[i+1:] {
+				if rest.kind != sqlSpace {
+					return errors.New("query must be a single statement")
+				}
+			}
+		}
+		if t.kind == sqlParameter && !sqlParameters[t.text] {
+			return fmt.Errorf("unknown parameter :%s, supported are :device_alias, :start, :stop and :limit", t.text)
+		}
+		switch t.text {
+		case "(":
+			depth++
+		case ")":
+			if depth--; depth < 0 {
+				return errors.New("unbalanced parentheses in query")
+			}
+		}
+
+		word := strings.ToUpper(t.text)
+		if t.kind == sqlWord {
+			if sqlWriteKeywords[word] {
+				return fmt.Errorf("query must be read-only, found %s", word)
+			}
+			if word == "SELECT" && depth == 0 {
+				switch {
+				case !selected && (first == "SELECT" || previous == ")"):
+					selected = true
+				case selected && sqlSetOperators[previous]:
+				default:
+					return errors.New("query must be a single statement")
+				}
+			}
+		}
+		previous = word
+	}
+	if depth != 0 {
+		return errors.New("unbalanced parentheses in query")
+	}
+	if !selected && first == "WITH" {
+		return errors.New("query must end with a SELECT after its WITH clause")
+	}
+	return nil
+}
+
+// bindSQLParameters replaces named parameters with engine placeholders and returns
+// the parameter name for each placeholder in order.
+func bindSQLParameters(query string, placeholder func(int) string) (string, []string, error) {
+	tokens, err := scanSQL(query)
+	if err != nil {
+		return "", nil, err
+	}
+	var b strings.Builder
+	var names []string
+	for _, t := range tokens {
+		if t.kind != sqlParameter {
+			b.WriteString(t.text)
+			continue
+		}
+		if !sqlParameters[t.text] {
+			return "", nil, fmt.Errorf("unknown parameter :%s", t.text)
+		}
+		names = append(names, t.text)
+		b.WriteString(placeholder(len(names)))
+	}
+	return strings.TrimRight(strings.TrimSpace(b.String()), ";"), names, nil
+}
+
+type sqlTokenKind int
+
+const (
+	sqlOther     sqlTokenKind = iota
+	sqlSpace                  // Whitespace and comments
+	sqlWord                   // Keywords and identifiers
+	sqlQuoted                 // String literals and quoted identifiers
+	sqlParameter              // :name, text holds the name without the colon
+	sqlSemicolon
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+}
+
+// scanSQL splits a query into tokens, so parameters and keywords are never matched
+// inside literals, quoted identifiers or comments.
+func scanSQL(query string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			end := i
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+			tokens = append(tokens, sqlToken{sqlSpace, string(runes[i:end])})
+			i = end
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			end := i + 2
+			for end+1 < len(runes) && !(runes[end] == '*' && runes[end+1] == '/') {
+				end++
+			}
+			if end+1 >= len(runes) {
+				return nil, errors.New("unterminated comment in query")
+			}
+			tokens = append(tokens, sqlToken{sqlSpace, string(runes[i : end+2])})
+			i = end + 2
+		case r == '\'' || r == '"' || r == '`' || r == '[':
+			closing := r
+			if r == '[' {
+				closing = ']'
+			}
+			end := i + 1
+			for {
+				if end >= len(runes) {
+					return nil, errors.New("unterminated quote in query")
+				}
+				if runes[end] == closing {
+					// Doubled quotes escape themselves
+					if end+1 < len(runes) && runes[end+1] == closing && closing != ']' {
+						end += 2
+						continue
+					}
+					break
+				}
+				if runes[end] == '\\' && closing == '\'' {
+					end++
+				}
+				end++
+			}
+			tokens = append(tokens, sqlToken{sqlQuoted, string(runes[i : end+1])})
+			i = end + 1
+		case r == ':' && i+1 < len(runes) && runes[i+1] == ':':
+			tokens = append(tokens, sqlToken{sqlOther, "::"}) // Postgres cast
+			i += 2
+		case r == ':' && i+1 < len(runes) && (unicode.IsLetter(runes[i+1]) || runes[i+1] == '_'):
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+				end++
+			}
+			tokens = append(tokens, sqlToken{sqlParameter, string(runes[i+1 : end])})
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_' || runes[end] == '$') {
+				end++
+			}
+			tokens = append(tokens, sqlToken{sqlWord, string(runes[i:end])})
+			i = end
+		case unicode.IsSpace(r):
+			end := i
+			for end < len(runes) && unicode.IsSpace(runes[end]) {
+				end++
+			}
+			tokens = append(tokens, sqlToken{sqlSpace, string(runes[i:end])})
+			i = end
+		case r == ';':
+			tokens = append(tokens, sqlToken{sqlSemicolon, ";"})
+			i++
+		default:
+			tokens = append(tokens, sqlToken{sqlOther, string(r)})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// ParseRelativeDuration parses a negative relative time range such as "-30m", "-12h", "-7d" or "-2w".
+func ParseRelativeDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "-") || len(value) < 3 {
+		return 0, fmt.Errorf("time_range must be a negative duration (e.g., '-1h', '-30m', '-1d'), got %q", value)
+	}
+	unit := value[len(value)-1]
+	amount, err := strconv.Atoi(value[1 : len(value)-1])
+	if err != nil || amount <= 0 {
+		return 0, fmt.Errorf("time_range must be a negative duration (e.g., '-1h', '-30m', '-1d'), got %q", value)
+	}
+	units := map[byte]time.Duration{'s': time.Second, 'm': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	scale, ok := units[unit]
+	if !ok {
+		return 0, fmt.Errorf("time_range unit must be s, m, h, d or w, got %q", value)
+	}
+	return -time.Duration(amount) * scale, nil
+}
+
+// Endpoint returns the database server's address. TLS is negotiated inside the database
+// protocol, so the endpoint never asks for a TLS handshake.
+func (d *SQLDriver) Endpoint() (*Endpoint, error) {
+	return d.engine.endpoint(d.config.DSN)
+}
+
+// postgresEndpoint reads the first host of a Postgres DSN.
+func postgresEndpoint(dsn string) (*Endpoint, error) {
+	config, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dsn: %w", err)
+	}
+	if strings.HasPrefix(config.Host, "/") {
+		return nil, nil
+	}
+	return urlEndpoint(config.Host, strconv.Itoa(int(config.Port)), "5432", nil)
+}
+
+// mysqlEndpoint reads the address of a MySQL DSN.
+func mysqlEndpoint(dsn string) (*Endpoint, error) {
+	config, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dsn: %w", err)
+	}
+	if config.Net != "tcp" {
+		return nil, nil
+	}
+	host, port, err := net.SplitHostPort(config.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dsn address: %w", err)
+	}
+	return urlEndpoint(host, port, "3306", nil)
+}
+
+// sqlServerEndpoint reads the address of a SQL Server DSN. Named instances are located through
+// the SQL Server Browser, so their port is not known up front.
+func sqlServerEndpoint(dsn string) (*Endpoint, error) {
+	config, err := msdsn.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dsn: %w", err)
+	}
+	if config.Port == 0 && config.Instance != "" {
+		return nil, nil
+	}
+	port := ""
+	if config.Port != 0 {
+		port = strconv.FormatUint(config.Port, 10)
+	}
+	return urlEndpoint(config.Host, port, "1433", nil)
+}
+
+// Identify reports the database server's version string.
+func (d *SQLDriver) Identify(ctx context.Context) (*ServerInfo, error) {
+	if d.db == nil {
+		if err := d.Connect(ctx); err != nil {
+			return nil, err
+		}
+		defer d.Disconnect(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	var version string
+	if err := d.db.QueryRowContext(ctx, d.engine.versionQuery).Scan(&version); err != nil {
+		return nil, fmt.Errorf("failed to query server version: %w", err)
+	}
+	return &ServerInfo{Product: d.config.Engine, Version: version}, nil
+}
+
+// Disconnect closes the connection pool.
+func (d *SQLDriver) Disconnect(ctx context.Context) error {
+	if d.db == nil {
+		return nil
+	}
+	err := d.db.Close()
+	d.db = nil
+	return err
+}
+
+// ValidateConfig checks the database is reachable with the configured DSN.
+func (d *SQLDriver) ValidateConfig(ctx context.Context) error {
+	if d.db != nil {
+		return nil
+	}
+	if err := d.Connect(ctx); err != nil {
+		return err
+	}
+	return d.Disconnect(ctx)
+}
+
+// TestResource tests a SQL query resource.
+func (d *SQLDriver) TestResource(ctx context.Context, resourceDetails string) (interface{}, error) {
+	return d.FetchData(ctx, resourceDetails)
+}
@@ -0,0 +1,209 @@
+package drivers
+
+import (
+	"app/model"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// newTestSQLDriver connects a SQLDriver to a SQLite file standing in for a historian database.
+func newTestSQLDriver(t *testing.T, maxRows int) *SQLDriver {
+	t.Helper()
+	sqlEngines["sqlite"] = sqlEngine{driverName: "sqlite", placeholder: func(int) string { return "?" }}
+	t.Cleanup(func() { delete(sqlEngines, "sqlite") })
+
+	dsn := filepath.Join(t.TempDir(), "historian.db")
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE readings (tag TEXT, ts TIMESTAMP, value REAL, quality INTEGER)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	now := time.Now().UTC()
+	for i, row := range []struct {
+		tag string
+		age time.Duration
+	}{{"press-1", time.Minute}, {"press-1", 2 * time.Minute}, {"press-1", 3 * time.Hour}, {"press-2", time.Minute}} {
+		if _, err := db.Exec(`INSERT INTO readings VALUES (?, ?, ?, ?)`, row.tag, now.Add(-row.age), 20.5+float64(i), 192); err != nil {
+			t.Fatalf("Failed to insert row: %v", err)
+		}
+	}
+
+	metadata, _ := json.Marshal(model.SQLMetadata{Engine: "sqlite", DSN: dsn, MaxRows: maxRows, Timeout: 5})
+	driver, err := NewSQLDriver(string(metadata))
+	if err != nil {
+		t.Fatalf("Failed to create SQLDriver: %v", err)
+	}
+	if err := driver.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { driver.Disconnect(context.Background()) })
+	return driver
+}
+
+func TestSQLDriver_FetchDataBindsParameters(t *testing.T) {
+	driver := newTestSQLDriver(t, 100)
+	details, _ := json.Marshal(model.SQLResourceDetails{
+		Query:       `SELECT tag, value, quality FROM readings WHERE tag = :device_alias AND ts >= :start AND ts <= :stop ORDER BY ts DESC LIMIT :limit`,
+		TimeRange:   "-1h",
+		Limit:       10,
+		DeviceAlias: "press-1",
+	})
+
+	result, err := driver.FetchData(context.Background(), string(details))
+	if err != nil {
+		t.Fatalf("Failed to fetch data: %v", err)
+	}
+	data := result.(map[string]interface{})
+	rows := data["rows"].([]map[string]interface{})
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows within the last hour, got %d", len(rows))
+	}
+	if rows[0]["tag"] != "press-1" || rows[0]["value"] != 20.5 || rows[0]["quality"] != int64(192) {
+		t.Errorf("Unexpected first row: %v", rows[0])
+	}
+	columns := data["columns"].([]map[string]string)
+	if columns[1]["name"] != "value" || columns[1]["type"] != "real" {
+		t.Errorf("Unexpected column description: %v", columns[1])
+	}
+
+	// An alias that tries to break out of the literal is just a value that matches nothing
+	details, _ = json.Marshal(model.SQLResourceDetails{
+		Query:       `SELECT tag FROM readings WHERE tag = :device_alias`,
+		DeviceAlias: "x' OR '1'='1",
+	})
+	result, err = driver.FetchData(context.Background(), string(details))
+	if err != nil {
+		t.Fatalf("Failed to fetch data: %v", err)
+	}
+	if count := result.(map[string]interface{})["row_count"]; count != 0 {
+		t.Errorf("Expected no rows for injected alias, got %v", count)
+	}
+}
+
+func TestSQLDriver_MaxRowsTruncates(t *testing.T) {
+	driver := newTestSQLDriver(t, 3)
+	result, err := driver.FetchData(context.Background(), `{"query": "SELECT * FROM readings"}`)
+	if err != nil {
+		t.Fatalf("Failed to fetch data: %v", err)
+	}
+	data := result.(map[string]interface{})
+	if data["row_count"] != 3 || data["truncated"] != true {
+		t.Errorf("Expected 3 truncated rows, got %v rows (truncated %v)", data["row_count"], data["truncated"])
+	}
+}
+
+func TestValidateSQLQuery(t *testing.T) {
+	valid := []string{
+		`SELECT * FROM readings WHERE tag = :device_alias;`,
+		`WITH recent AS (SELECT * FROM readings WHERE ts >= :start) SELECT * FROM recent`,
+		`SELECT ':not_a_param', ts::date FROM readings -- :comment`,
+		`SELECT TOP (:limit) ts, value FROM readings WITH (NOLOCK) WHERE tag IN (SELECT tag FROM tags)`,
+		`WITH a AS (SELECT 1 AS x), b (x) AS (SELECT 2) SELECT x FROM a UNION ALL SELECT x FROM b`,
+		`(SELECT ts FROM readings) EXCEPT (SELECT ts FROM archived)`,
+	}
+	for _, query := range valid {
+		if err := ValidateSQLQuery(query); err != nil {
+			t.Errorf("ValidateSQLQuery(%q) = %v, want nil", query, err)
+		}
+	}
+
+	invalid := []string{
+		``,
+		`DELETE FROM readings`,
+		`SELECT * FROM readings; DROP TABLE readings`,
+		`SELECT * INTO backup FROM readings`,
+		`SELECT * FROM readings WHERE tag = :tag`,
+		`SELECT 'unterminated FROM readings`,
+		// SQL Server batches without semicolons
+		`SELECT * FROM readings SELECT * FROM users`,
+		`SELECT 1 IF 1 = 1 SELECT name FROM sys.sql_logins`,
+		`WITH a AS (SELECT 1 AS x) SELECT x FROM a SELECT 2`,
+		`WITH a AS (SELECT 1 AS x)`,
+		`SELECT 1 KILL 53`,
+		`SELECT 1 SHUTDOWN WITH NOWAIT`,
+		`SELECT 1 DBCC DROPCLEANBUFFERS`,
+		`SELECT 1 BACKUP DATABASE plant TO DISK = 'x.bak'`,
+		`SELECT 1 RESTORE DATABASE plant FROM DISK = 'x.bak'`,
+		`SELECT 1 DENY SELECT ON readings TO public`,
+		`SELECT 1 SET NOCOUNT ON`,
+		`SELECT 1 DECLARE @x int`,
+		`SELECT 1 WAITFOR DELAY '01:00:00'`,
+		`SELECT * FROM OPENROWSET(BULK 'c:\\secrets.txt', SINGLE_CLOB) AS f`,
+		`SELECT (1))`,
+	}
+	for _, query := range invalid {
+		if err := ValidateSQLQuery(query); err == nil {
+			t.Errorf("ValidateSQLQuery(%q) = nil, want error", query)
+		}
+	}
+}
+
+func TestBindSQLParameters(t *testing.T) {
+	query := `SELECT * FROM r WHERE tag = :device_alias AND note <> ':start' AND ts BETWEEN :start AND :stop LIMIT :limit;`
+	tests := map[string]string{
+		"postgres":  `SELECT * FROM r WHERE tag = $1 AND note <> ':start' AND ts BETWEEN $2 AND $3 LIMIT $4`,
+		"mysql":     `SELECT * FROM r WHERE tag = ? AND note <> ':start' AND ts BETWEEN ? AND ? LIMIT ?`,
+		"sqlserver": `SELECT * FROM r WHERE tag = @p1 AND note <> ':start' AND ts BETWEEN @p2 AND @p3 LIMIT @p4`,
+	}
+	for engine, want := range tests {
+		got, names, err := bindSQLParameters(query, sqlEngines[engine].placeholder)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", engine, err)
+		}
+		if got != want {
+			t.Errorf("%s: got %q, want %q", engine, got, want)
+		}
+		if !reflect.DeepEqual(names, []string{"device_alias", "start", "stop", "limit"}) {
+			t.Errorf("%s: unexpected parameter order %v", engine, names)
+		}
+	}
+
+	if _, err := ParseRelativeDuration("-90x"); err == nil || !strings.Contains(err.Error(), "unit") {
+		t.Errorf("Expected unit error, got %v", err)
+	}
+}
+
+func TestSQLDriver_RowsArePoints(t *testing.T) {
+	for _, details := range []string{
+		`{"query": "SELECT ts, value FROM readings"}`,
+		`{"query": "SELECT ts, value FROM readings", "value_column": "value"}`,
+		`{"query": "SELECT r.ts, r.value FROM readings r", "value_column": "r.value", "timestamp_column": "r.ts"}`,
+	} {
+		if _, err := ValidateResourceDetails("sql_query", details); err == nil {
+			t.Errorf("Expected %s to be rejected", details)
+		}
+	}
+	details, err := ValidateResourceDetails("sql_query", `{"query": "SELECT ts, value FROM readings WHERE tag = :device_alias ORDER BY ts DESC", "value_column": "value", "timestamp_column": "ts"}`)
+	if err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+	details, err = PrepareResourceDetails("SQL", "sql_query", details, "press-2", nil)
+	if err != nil {
+		t.Fatalf("Preparing details failed: %v", err)
+	}
+
+	driver := newTestSQLDriver(t, 100)
+	result, err := driver.FetchData(context.Background(), details)
+	if err != nil {
+		t.Fatalf("Failed to fetch data: %v", err)
+	}
+	points, err := ResourcePoints(&model.Resource{Type: "sql_query", Details: details}, result, time.Now().Add(time.Hour), false)
+	if err != nil {
+		t.Fatalf("Failed to read points: %v", err)
+	}
+	if len(points) != 1 || fmt.Sprint(points[0].Value) != "23.5" || !points[0].Timestamp.Before(time.Now()) {
+		t.Errorf("Expected one point per row with the row's time, got %+v", points)
+	}
+}
@@ -0,0 +1,97 @@
+package drivers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// writeFloat converts a written value to a float. Values decoded from JSON are float64, or
+// json.Number when the decoder keeps numbers exact.
+func writeFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %s", v)
+		}
+		return f, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("expected a number, got %T", value)
+	}
+}
+
+// writeInt converts a written value to an integer within [min, max]. Fractions are rejected
+// rather than truncated.
+func writeInt(value interface{}, min, max int64) (int64, error) {
+	if n, ok := value.(json.Number); ok {
+		if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+			if i < min || i > max {
+				return 0, fmt.Errorf("value %d is out of range [%d, %d]", i, min, max)
+			}
+			return i, nil
+		}
+	}
+	f, err := writeFloat(value)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("expected an integer, got %v", f)
+	}
+	if f < float64(min) || f > float64(max) {
+		return 0, fmt.Errorf("value %v is out of range [%d, %d]", f, min, max)
+	}
+	return int64(f), nil
+}
+
+// writeUint converts a written value to an unsigned integer no larger than max.
+func writeUint(value interface{}, max uint64) (uint64, error) {
+	if n, ok := value.(json.Number); ok {
+		if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+			if u > max {
+				return 0, fmt.Errorf("value %d is out of range [0, %d]", u, max)
+			}
+			return u, nil
+		}
+	}
+	f, err := writeFloat(value)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("expected an integer, got %v", f)
+	}
+	if f < 0 || f > float64(max) {
+		return 0, fmt.Errorf("value %v is out of range [0, %d]", f, max)
+	}
+	return uint64(f), nil
+}
+
+// writeBool converts a written value to a boolean. The numbers 0 and 1 are accepted too.
+func writeBool(value interface{}) (bool, error) {
+	if b, ok := value.(bool); ok {
+		return b, nil
+	}
+	i, err := writeInt(value, 0, 1)
+	if err != nil {
+		return false, fmt.Errorf("expected a boolean, got %v", value)
+	}
+	return i == 1, nil
+}
@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"app/drivers"
+	"app/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/beego/beego/logs"
+)
+
+// ErrCircuitOpen is returned by DriverPool.Acquire while a platform's circuit breaker is
+// short-circuiting requests.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// circuitBreaker counts a platform's failed requests and, once failureThreshold fail in a
+// row, fails further requests fast for the cool-down. A limited number of trial requests then
+// decide whether the circuit closes again. Guarded by DriverPool.mu.
+type circuitBreaker struct {
+	platformID uint
+	policy     drivers.CircuitBreakerPolicy
+	state      string
+	failures   int // Failed requests in a row while closed
+	openedAt   time.Time
+	trials     int // Trial requests in flight while half-open
+	opens      uint64
+}
+
+func newCircuitBreaker(platformID uint, policy drivers.CircuitBreakerPolicy) *circuitBreaker {
+	return &circuitBreaker{platformID: platformID, policy: policy, state: model.CircuitClosed}
+}
+
+// allow reports whether a request may go through and whether it is a trial of a half-open
+// circuit. An open circuit turns half-open once the cool-down has passed.
+func (b *circuitBreaker) allow(now time.Time) (bool, error) {
+	if b.state == model.CircuitOpen {
+		if wait := b.retryAt().Sub(now); wait > 0 {
+			return false, fmt.Errorf("platform %d: %w, retrying in %s", b.platformID, ErrCircuitOpen, wait.Round(time.Second))
+		}
+		b.state = model.CircuitHalfOpen
+		b.trials = 0
+	}
+	if b.state == model.CircuitHalfOpen {
+		if b.trials >= b.policy.HalfOpenRequests {
+			return false, fmt.Errorf("platform %d: %w, waiting for trial requests", b.platformID, ErrCircuitOpen)
+		}
+		b.trials++
+		return true, nil
+	}
+	return false, nil
+}
+
+// record counts the outcome of a request. Requests the caller cancelled say nothing about the
+// platform and are not counted.
+func (b *circuitBreaker) record(err error, trial bool, now time.Time) {
+	if trial && b.state == model.CircuitHalfOpen {
+		b.trials--
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	switch {
+	case trial && b.state == model.CircuitHalfOpen:
+		if err != nil {
+			b.open(now, err)
+			return
+		}
+		logs.Info("Circuit breaker for platform %d closed after a successful trial request", b.platformID)
+		b.state = model.CircuitClosed
+		b.failures = 0
+	case b.state == model.CircuitClosed:
+		if err == nil {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.policy.FailureThreshold {
+			b.open(now, err)
+		}
+	}
+}
+
+func (b *circuitBreaker) open(now time.Time, err error) {
+	logs.Warn("Circuit breaker for platform %d opened for %s: %v", b.platformID, b.policy.CoolDown, err)
+	b.state = model.CircuitOpen
+	b.openedAt = now
+	b.failures = 0
+	b.opens++
+}
+
+// retryAt is when an open circuit lets trial requests through.
+func (b *circuitBreaker) retryAt() time.Time {
+	return b.openedAt.Add(b.policy.CoolDown)
+}
+
+// current returns the state the next request sees.
+func (b *circuitBreaker) current(now time.Time) string {
+	if b.state == model.CircuitOpen && !now.Before(b.retryAt()) {
+		return model.CircuitHalfOpen
+	}
+	return b.state
+}
@@ -0,0 +1,232 @@
+package gateway
+
+import (
+	"app/dal"
+	"app/drivers"
+	"app/model"
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/logs"
+)
+
+const (
+	healthCheckTimeout     = 30 * time.Second // Bound on one platform check
+	healthCheckConcurrency = 8                // Platforms checked at the same time
+	healthFailureThreshold = 3                // Consecutive failures before a Degraded platform is Disconnected
+	healthDegradedLatency  = 5 * time.Second  // Checks slower than this mark the platform Degraded
+	healthJitter           = 0.2              // Fraction of the interval each schedule is moved by at random
+)
+
+// HealthMonitor periodically tests every active platform and keeps its ConnectionState,
+// LastConnected and LastError current, recording each state change.
+type HealthMonitor struct {
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	failures map[uint]int       // Consecutive failed checks per platform
+	next     map[uint]time.Time // When each platform is checked next
+	running  map[uint]bool      // Platforms with a check in progress
+}
+
+// NewHealthMonitor creates a stopped health monitor.
+func NewHealthMonitor() *HealthMonitor {
+	return &HealthMonitor{
+		failures: make(map[uint]int),
+		next:     make(map[uint]time.Time),
+		running:  make(map[uint]bool),
+	}
+}
+
+var health = NewHealthMonitor()
+
+// Health returns the process-wide health monitor.
+func Health() *HealthMonitor {
+	return health
+}
+
+// Start checks every active platform about once per interval until Stop is called. Each
+// platform keeps its own schedule, moved by a random jitter so checks do not all run at once.
+func (m *HealthMonitor) Start(interval time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cancel != nil || interval <= 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	go m.supervise(ctx, interval)
+	logs.Info("Platform health monitor started with a %s interval", interval)
+}
+
+// Stop ends the supervisor. Checks in progress are cancelled.
+func (m *HealthMonitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+}
+
+// supervise wakes several times per interval to start the checks that are due.
+func (m *HealthMonitor) supervise(ctx context.Context, interval time.Duration) {
+	tick := max(interval/4, time.Second)
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	sem := make(chan struct{}, healthCheckConcurrency)
+	for {
+		m.startDueChecks(ctx, interval, sem)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startDueChecks starts a check for every active platform whose next check is due.
+func (m *HealthMonitor) startDueChecks(ctx context.Context, interval time.Duration, sem chan struct{}) {
+	q := dal.Q
+	platforms, err := q.Platform.Where(q.Platform.IsActive.Is(true)).Find()
+	if err != nil {
+		logs.Error("Health monitor failed to list platforms: %v", err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	active := make(map[uint]bool, len(platforms))
+	for _, platform := range platforms {
+		active[platform.ID] = true
+		next, scheduled := m.next[platform.ID]
+		if !scheduled {
+			// Spread the first checks over the interval instead of testing everything at startup
+			m.next[platform.ID] = now.Add(time.Duration(rand.Float64() * float64(interval)))
+			continue
+		}
+		if m.running[platform.ID] || now.Before(next) {
+			continue
+		}
+
+		m.running[platform.ID] = true
+		m.next[platform.ID] = now.Add(jittered(interval))
+		go func(platform *model.Platform) {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			m.check(ctx, platform)
+
+			m.mu.Lock()
+			delete(m.running, platform.ID)
+			m.mu.Unlock()
+		}(platform)
+	}
+
+	// Forget platforms that were deleted or deactivated
+	for id := range m.next {
+		if !active[id] {
+			delete(m.next, id)
+			delete(m.failures, id)
+		}
+	}
+}
+
+// check tests one platform and records the outcome.
+func (m *HealthMonitor) check(ctx context.Context, platform *model.Platform) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	var diag *drivers.Diagnostics
+	driver, err := drivers.GetDriver(platform.Type, platform.Metadata)
+	if err != nil {
+		diag = &drivers.Diagnostics{Error: err.Error(), Stages: []drivers.StageResult{}, TestedAt: time.Now()}
+	} else {
+		checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		diag = drivers.Diagnose(checkCtx, driver)
+		driver.Disconnect(checkCtx)
+		cancel()
+	}
+
+	// A check cut short by Stop says nothing about the platform
+	if ctx.Err() != nil {
+		return
+	}
+	if _, err := m.Record(platform.ID, diag); err != nil {
+		logs.Error("Health monitor failed to record the state of platform %d: %v", platform.ID, err)
+	}
+}
+
+// Record derives a platform's connection state from a connection test, stores it and adds a
+// state change to the history when the state moved. It returns the new state.
+func (m *HealthMonitor) Record(platformID uint, diag *drivers.Diagnostics) (string, error) {
+	q := dal.Q
+	current, err := q.Platform.Select(q.Platform.ID, q.Platform.ConnectionState).Where(q.Platform.ID.Eq(platformID)).First()
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	if diag.Success {
+		m.failures[platformID] = 0
+	} else {
+		m.failures[platformID]++
+	}
+	failures := m.failures[platformID]
+	m.mu.Unlock()
+
+	state := connectionState(current.ConnectionState, diag, failures)
+	updates := map[string]interface{}{
+		"connection_state": state,
+		"last_error":       diag.Error,
+	}
+	if diag.Success {
+		updates["last_connected"] = diag.TestedAt
+	}
+	if _, err := q.Platform.Where(q.Platform.ID.Eq(platformID)).Updates(updates); err != nil {
+		return "", err
+	}
+
+	if state != current.ConnectionState {
+		change := &model.PlatformStateChange{
+			PlatformID: platformID,
+			FromState:  current.ConnectionState,
+			ToState:    state,
+			Error:      diag.Error,
+			LatencyMs:  diag.LatencyMs,
+			ChangedAt:  diag.TestedAt,
+		}
+		if err := q.PlatformStateChange.Create(change); err != nil {
+			return "", err
+		}
+		logs.Info("Platform %d changed from %s to %s", platformID, current.ConnectionState, state)
+	}
+	return state, nil
+}
+
+// connectionState maps a connection test to a platform state. A platform that was up is only
+// reported Disconnected after healthFailureThreshold failures in a row, so one dropped check
+// shows as Degraded rather than an outage.
+func connectionState(previous string, diag *drivers.Diagnostics, failures int) string {
+	switch {
+	case diag.Success && diag.LatencyMs > float64(healthDegradedLatency.Milliseconds()):
+		return model.ConnectionDegraded
+	case diag.Success:
+		return model.ConnectionConnected
+	case diag.AuthFailed:
+		return model.ConnectionAuthFailed
+	case failures < healthFailureThreshold && (previous == model.ConnectionConnected || previous == model.ConnectionDegraded):
+		return model.ConnectionDegraded
+	default:
+		return model.ConnectionDisconnected
+	}
+}
+
+// jittered returns the interval moved by up to healthJitter of itself in either direction.
+func jittered(interval time.Duration) time.Duration {
+	return interval + time.Duration((rand.Float64()*2-1)*healthJitter*float64(interval))
+}
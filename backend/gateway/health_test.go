@@ -0,0 +1,47 @@
+package gateway
+
+import (
+	"app/drivers"
+	"app/model"
+	"testing"
+)
+
+func TestConnectionState(t *testing.T) {
+	passed := &drivers.Diagnostics{Success: true, LatencyMs: 40}
+	slow := &drivers.Diagnostics{Success: true, LatencyMs: 8000}
+	failed := &drivers.Diagnostics{FailedStage: drivers.StageTCP, Error: "connection refused"}
+	rejected := &drivers.Diagnostics{FailedStage: drivers.StageAuth, AuthFailed: true, Error: "authentication failed"}
+
+	tests := []struct {
+		name     string
+		previous string
+		diag     *drivers.Diagnostics
+		failures int
+		expected string
+	}{
+		{"check passes", model.ConnectionDisconnected, passed, 0, model.ConnectionConnected},
+		{"slow check", model.ConnectionConnected, slow, 0, model.ConnectionDegraded},
+		{"first failure while connected", model.ConnectionConnected, failed, 1, model.ConnectionDegraded},
+		{"failures below threshold while degraded", model.ConnectionDegraded, failed, healthFailureThreshold - 1, model.ConnectionDegraded},
+		{"failures reach threshold", model.ConnectionDegraded, failed, healthFailureThreshold, model.ConnectionDisconnected},
+		{"failure while disconnected", model.ConnectionDisconnected, failed, 1, model.ConnectionDisconnected},
+		{"credentials rejected", model.ConnectionConnected, rejected, 1, model.ConnectionAuthFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := connectionState(tt.previous, tt.diag, tt.failures); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestJittered(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		d := jittered(100)
+		if d < 80 || d > 120 {
+			t.Fatalf("Expected jittered interval within 20%%, got %d", d)
+		}
+	}
+}
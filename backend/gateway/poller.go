@@ -0,0 +1,419 @@
+package gateway
+
+import (
+	"app/dal"
+	"app/drivers"
+	"app/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/logs"
+)
+
+const (
+	pollTick           = time.Second      // How often due jobs are looked for
+	pollReloadInterval = 30 * time.Second // How often job definitions are reloaded from the database
+	pollMinInterval    = time.Second      // Shortest polling interval accepted
+	pollFetchTimeout   = 30 * time.Second // Bound on one fetch
+
+	// DefaultPollConcurrency is the number of jobs run at the same time against one platform.
+	DefaultPollConcurrency = 4
+)
+
+// Poller reads resources on a schedule and writes the results to the telemetry store. A
+// resource is polled for every device associated with its platform when its metadata, or the
+// metadata of the device-platform association, sets a poll_interval such as "30s". The
+// resource's interval takes precedence.
+type Poller struct {
+	mu          sync.Mutex
+	cancel      context.CancelFunc
+	concurrency int
+	jobs        map[pollKey]*pollJob
+	slots       map[uint]chan struct{} // Concurrency limit per platform
+}
+
+type pollKey struct {
+	deviceID   uint
+	resourceID uint
+}
+
+type pollJob struct {
+	deviceID    uint
+	platformID  uint
+	resource    *model.Resource
+	deviceAlias string
+	interval    time.Duration
+
+	// Guarded by Poller.mu
+	next         time.Time // When the job is due
+	running      bool
+	lastRun      time.Time
+	lastSuccess  time.Time
+	lastDuration time.Duration
+	lastError    string
+	lag          time.Duration // Delay between the due time and the start of the last run
+	runs         uint64
+	failures     uint64
+	overruns     uint64 // Times the job was due while its previous run had not finished
+}
+
+// NewPoller creates a stopped poller.
+func NewPoller() *Poller {
+	return &Poller{
+		concurrency: DefaultPollConcurrency,
+		jobs:        make(map[pollKey]*pollJob),
+		slots:       make(map[uint]chan struct{}),
+	}
+}
+
+var poller = NewPoller()
+
+// Polling returns the process-wide poller.
+func Polling() *Poller {
+	return poller
+}
+
+// Start runs the scheduled jobs until Stop is called, with at most concurrency jobs running
+// against one platform at the same time.
+func (p *Poller) Start(concurrency int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel != nil || concurrency <= 0 {
+		return
+	}
+	p.concurrency = concurrency
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	go p.run(ctx)
+	logs.Info("Poller started with %d concurrent jobs per platform", p.concurrency)
+}
+
+// Stop ends the scheduler. Runs in progress are cancelled.
+func (p *Poller) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel != nil {
+		p.cancel()
+		p.cancel = nil
+	}
+}
+
+func (p *Poller) run(ctx context.Context) {
+	ticker := time.NewTicker(pollTick)
+	defer ticker.Stop()
+
+	var loaded time.Time
+	for {
+		if time.Since(loaded) >= pollReloadInterval {
+			if err := p.reload(); err != nil {
+				logs.Error("Poller failed to load jobs: %v", err)
+			} else {
+				loaded = time.Now()
+			}
+		}
+		p.startDueJobs(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reload builds the job list from the resources and device-platform associations of active
+// platforms, keeping the schedule and status of jobs that still exist.
+func (p *Poller) reload() error {
+	q := dal.Q
+	platforms, err := q.Platform.Where(q.Platform.IsActive.Is(true)).Find()
+	if err != nil {
+		return err
+	}
+	platformIDs := make([]uint, 0, len(platforms))
+	platformTypes := make(map[uint]string, len(platforms))
+	for _, platform := range platforms {
+		platformIDs = append(platformIDs, platform.ID)
+		platformTypes[platform.ID] = platform.Type
+	}
+
+	associations, err := q.DevicePlatform.Where(q.DevicePlatform.PlatformID.In(platformIDs...)).Find()
+	if err != nil {
+		return err
+	}
+	resources, err := q.Resource.Where(q.Resource.PlatformID.In(platformIDs...)).Find()
+	if err != nil {
+		return err
+	}
+	byPlatform := make(map[uint][]*model.Resource)
+	for _, resource := range resources {
+		byPlatform[resource.PlatformID] = append(byPlatform[resource.PlatformID], resource)
+	}
+
+	jobs := make(map[pollKey]*pollJob)
+	for _, dp := range associations {
+		dpInterval, err := PollInterval(dp.Metadata)
+		if err != nil {
+			logs.Warn("Ignoring poll interval of device %d on platform %d: %v", dp.DeviceID, dp.PlatformID, err)
+		}
+		for _, resource := range byPlatform[dp.PlatformID] {
+			if drivers.CheckResourceType(platformTypes[dp.PlatformID], resource.Type) != nil {
+				continue
+			}
+			interval, err := PollInterval(resource.Metadata)
+			if err != nil {
+				logs.Warn("Ignoring poll interval of resource %d: %v", resource.ID, err)
+			}
+			if interval == 0 {
+				interval = dpInterval
+			}
+			if interval == 0 {
+				continue
+			}
+			jobs[pollKey{dp.DeviceID, resource.ID}] = &pollJob{
+				deviceID:    dp.DeviceID,
+				platformID:  dp.PlatformID,
+				resource:    resource,
+				deviceAlias: dp.DeviceAlias,
+				interval:    interval,
+			}
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.replaceJobsLocked(jobs, time.Now())
+	return nil
+}
+
+// replaceJobsLocked installs a new job list. Jobs that already existed keep their status, and
+// their schedule unless the interval changed. New jobs start at a random point within their
+// first interval so jobs added together do not all run at once.
+func (p *Poller) replaceJobsLocked(jobs map[pollKey]*pollJob, now time.Time) {
+	for key, job := range jobs {
+		old, ok := p.jobs[key]
+		if !ok {
+			job.next = now.Add(time.Duration(rand.Float64() * float64(job.interval)))
+			p.jobs[key] = job
+			continue
+		}
+		if old.interval != job.interval {
+			old.next = now.Add(time.Duration(rand.Float64() * float64(job.interval)))
+		}
+		old.platformID = job.platformID
+		old.resource = job.resource
+		old.deviceAlias = job.deviceAlias
+		old.interval = job.interval
+	}
+	for key := range p.jobs {
+		if _, ok := jobs[key]; !ok {
+			delete(p.jobs, key)
+		}
+	}
+}
+
+// startDueJobs starts every job that is due. A job still running when it is due again counts
+// as an overrun and skips that run.
+func (p *Poller) startDueJobs(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for _, job := range p.jobs {
+		if now.Before(job.next) {
+			continue
+		}
+		due := job.next
+		job.next = nextRun(job.next, job.interval, now)
+		if job.running {
+			job.overruns++
+			logs.Warn("Poll of resource %d for device %d is still running after %s, skipping a run", job.resource.ID, job.deviceID, job.interval)
+			continue
+		}
+		job.running = true
+		slots := p.slotsLocked(job.platformID)
+		go func(job *pollJob, due time.Time) {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				p.finish(job, due, time.Time{}, 0, ctx.Err())
+				return
+			}
+			defer func() { <-slots }()
+
+			started := time.Now()
+			err := p.poll(ctx, job)
+			p.finish(job, due, started, time.Since(started), err)
+		}(job, due)
+	}
+}
+
+func (p *Poller) slotsLocked(platformID uint) chan struct{} {
+	slots, ok := p.slots[platformID]
+	if !ok {
+		slots = make(chan struct{}, p.concurrency)
+		p.slots[platformID] = slots
+	}
+	return slots
+}
+
+// poll fetches one resource through the driver pool, stores its points and forwards them to
+// the telemetry sinks.
+func (p *Poller) poll(ctx context.Context, job *pollJob) error {
+	p.mu.Lock()
+	platformID, resource, alias := job.platformID, job.resource, job.deviceAlias
+	p.mu.Unlock()
+
+	q := dal.Q
+	platform, err := q.Platform.Where(q.Platform.ID.Eq(platformID)).First()
+	if err != nil {
+		return err
+	}
+	prepared, err := drivers.PrepareResourceDetails(platform.Type, resource.Type, resource.Details, alias, nil)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pollFetchTimeout)
+	defer cancel()
+	driver, release, err := Drivers().Acquire(ctx, platform)
+	if err != nil {
+		return err
+	}
+	data, err := driver.FetchData(ctx, prepared)
+	release(err)
+	if err != nil {
+		return err
+	}
+
+	points, err := drivers.ResourcePoints(resource, data, time.Now(), false)
+	if err != nil {
+		return err
+	}
+	samples := PointSamples(job.deviceID, points)
+	Sinks().Forward(platformID, samples)
+	return WriteSamples(samples)
+}
+
+// finish records the outcome of a run. A zero start means the run never started.
+func (p *Poller) finish(job *pollJob, due, started time.Time, duration time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	job.running = false
+	if started.IsZero() {
+		return
+	}
+	job.runs++
+	job.lastRun = started
+	job.lastDuration = duration
+	job.lag = started.Sub(due)
+	if err != nil {
+		job.failures++
+		job.lastError = err.Error()
+		logs.Error("Poll of resource %d for device %d failed: %v", job.resource.ID, job.deviceID, err)
+		return
+	}
+	job.lastError = ""
+	job.lastSuccess = started.Add(duration)
+}
+
+// nextRun returns the first run after now on a job's schedule, so a job that fell behind
+// skips the runs it missed instead of running them back to back.
+func nextRun(previous time.Time, interval time.Duration, now time.Time) time.Time {
+	next := previous.Add(interval)
+	if !next.After(now) {
+		missed := now.Sub(next)/interval + 1
+		next = next.Add(missed * interval)
+	}
+	return next
+}
+
+// PollInterval reads the poll_interval of resource or device-platform metadata. It returns
+// zero when the metadata does not set one.
+func PollInterval(metadata string) (time.Duration, error) {
+	if metadata == "" {
+		return 0, nil
+	}
+	var config struct {
+		PollInterval string `json:"poll_interval"`
+	}
+	if err := json.Unmarshal([]byte(metadata), &config); err != nil {
+		return 0, fmt.Errorf("invalid metadata JSON: %w", err)
+	}
+	if config.PollInterval == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(config.PollInterval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid poll_interval: %w", err)
+	}
+	if interval < pollMinInterval {
+		return 0, fmt.Errorf("poll_interval must be at least %s", pollMinInterval)
+	}
+	return interval, nil
+}
+
+// PollStatus describes one scheduled job.
+type PollStatus struct {
+	DeviceID       uint       `json:"device_id"`
+	ResourceID     uint       `json:"resource_id"`
+	PlatformID     uint       `json:"platform_id"`
+	ResourceName   string     `json:"resource_name"`
+	Interval       string     `json:"interval"`
+	Running        bool       `json:"running"`
+	NextRun        time.Time  `json:"next_run"`
+	LastRun        *time.Time `json:"last_run,omitempty"`
+	LastSuccess    *time.Time `json:"last_success,omitempty"`
+	LastDurationMs float64    `json:"last_duration_ms"`
+	LastError      string     `json:"last_error,omitempty"`
+	LagMs          float64    `json:"lag_ms"` // Delay between the last run's due time and its start
+	Runs           uint64     `json:"runs"`
+	Failures       uint64     `json:"failures"`
+	Overruns       uint64     `json:"overruns"`
+}
+
+// Status lists the scheduled jobs, ordered by device and resource.
+func (p *Poller) Status() []PollStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := make([]PollStatus, 0, len(p.jobs))
+	for _, job := range p.jobs {
+		s := PollStatus{
+			DeviceID:       job.deviceID,
+			ResourceID:     job.resource.ID,
+			PlatformID:     job.platformID,
+			ResourceName:   job.resource.Name,
+			Interval:       job.interval.String(),
+			Running:        job.running,
+			NextRun:        job.next,
+			LastDurationMs: float64(job.lastDuration.Microseconds()) / 1000,
+			LastError:      job.lastError,
+			LagMs:          float64(job.lag.Microseconds()) / 1000,
+			Runs:           job.runs,
+			Failures:       job.failures,
+			Overruns:       job.overruns,
+		}
+		if !job.lastRun.IsZero() {
+			lastRun := job.lastRun
+			s.LastRun = &lastRun
+		}
+		if !job.lastSuccess.IsZero() {
+			lastSuccess := job.lastSuccess
+			s.LastSuccess = &lastSuccess
+		}
+		status = append(status, s)
+	}
+	sort.Slice(status, func(i, j int) bool {
+		if status[i].DeviceID != status[j].DeviceID {
+			return status[i].DeviceID < status[j].DeviceID
+		}
+		return status[i].ResourceID < status[j].ResourceID
+	})
+	return status
+}
@@ -0,0 +1,106 @@
+package gateway
+
+import (
+	"app/model"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPollInterval(t *testing.T) {
+	tests := []struct {
+		metadata string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"", 0, false},
+		{"{}", 0, false},
+		{`{"poll_interval": "30s"}`, 30 * time.Second, false},
+		{`{"poll_interval": "1m30s", "owner": "line 2"}`, 90 * time.Second, false},
+		{`{"poll_interval": "100ms"}`, 0, true},
+		{`{"poll_interval": "often"}`, 0, true},
+		{`{"poll_interval": 30}`, 0, true},
+		{"not json", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.metadata, func(t *testing.T) {
+			interval, err := PollInterval(tt.metadata)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if interval != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, interval)
+			}
+		})
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	if got := nextRun(start, time.Minute, start.Add(5*time.Second)); !got.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected the next run one interval later, got %s", got)
+	}
+	// Runs missed while the poller was behind are skipped, keeping the schedule's phase
+	if got := nextRun(start, time.Minute, start.Add(3*time.Minute+10*time.Second)); !got.Equal(start.Add(4 * time.Minute)) {
+		t.Errorf("Expected the missed runs to be skipped, got %s", got)
+	}
+}
+
+func TestPoller_ReplaceJobsKeepsStatus(t *testing.T) {
+	p := NewPoller()
+	now := time.Now()
+	key := pollKey{deviceID: 1, resourceID: 2}
+
+	p.replaceJobsLocked(map[pollKey]*pollJob{
+		key:                          {deviceID: 1, resource: pollResource(2, "flow"), interval: time.Minute},
+		{deviceID: 1, resourceID: 3}: {deviceID: 1, resource: pollResource(3, "level"), interval: time.Minute},
+	}, now)
+	job := p.jobs[key]
+	if job.next.Before(now) || !job.next.Before(now.Add(time.Minute)) {
+		t.Errorf("Expected the first run within the first interval, got %s", job.next.Sub(now))
+	}
+	job.runs = 5
+	next := job.next
+
+	// Same interval: the job keeps its schedule and status; the other job is dropped
+	p.replaceJobsLocked(map[pollKey]*pollJob{
+		key: {deviceID: 1, resource: pollResource(2, "renamed"), interval: time.Minute},
+	}, now)
+	if len(p.jobs) != 1 || p.jobs[key] != job {
+		t.Fatalf("Expected the existing job to be kept alone, got %+v", p.jobs)
+	}
+	if job.runs != 5 || !job.next.Equal(next) || job.resource.Name != "renamed" {
+		t.Errorf("Unexpected job after reload %+v", job)
+	}
+
+	// A new interval reschedules the job
+	p.replaceJobsLocked(map[pollKey]*pollJob{
+		key: {deviceID: 1, resource: pollResource(2, "renamed"), interval: time.Hour},
+	}, now.Add(time.Second))
+	if job.interval != time.Hour || job.runs != 5 {
+		t.Errorf("Unexpected job after the interval changed %+v", job)
+	}
+}
+
+func TestPoller_CountsOverruns(t *testing.T) {
+	p := NewPoller()
+	due := time.Now().Add(-time.Second)
+	job := &pollJob{deviceID: 1, platformID: 3, resource: pollResource(2, "flow"), interval: time.Minute, next: due, running: true}
+	p.jobs[pollKey{1, 2}] = job
+
+	p.startDueJobs(context.Background())
+
+	status := p.Status()
+	if len(status) != 1 || status[0].Overruns != 1 || !status[0].Running {
+		t.Errorf("Expected one overrun for the running job, got %+v", status)
+	}
+	if !job.next.Equal(due.Add(time.Minute)) {
+		t.Errorf("Expected the job to be rescheduled one interval later, got %s", job.next)
+	}
+}
+
+func pollResource(id uint, name string) *model.Resource {
+	return &model.Resource{Model: model.Model{ID: id}, Name: name, Type: "rest_endpoint"}
+}
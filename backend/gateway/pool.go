@@ -0,0 +1,388 @@
+package gateway
+
+import (
+	"app/drivers"
+	"app/model"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/logs"
+)
+
+const (
+	poolIdleTimeout      = 5 * time.Minute  // Connections unused for this long are closed
+	poolBackoffMin       = time.Second      // Wait after the first failed connect
+	poolBackoffMax       = 2 * time.Minute  // Longest wait between connect attempts
+	poolErrorThreshold   = 3                // Failed uses in a row before the driver reconnects
+	poolDisconnectPeriod = 10 * time.Second // Bound on closing one connection
+)
+
+// DriverPool keeps one connected driver per platform so requests share connections instead
+// of connecting and disconnecting every time. Drivers are rebuilt when the platform's
+// configuration changes, reconnected with exponential backoff after failures and closed
+// once idle. Platforms that configure a circuit breaker are short-circuited while it is open.
+type DriverPool struct {
+	mu        sync.Mutex
+	entries   map[uint]*poolEntry
+	breakers  map[uint]*circuitBreaker // Kept across entries so closing idle drivers does not reset them
+	startOnce sync.Once
+}
+
+type poolEntry struct {
+	platformID   uint
+	platformType string
+	metadata     string // Configuration the driver was built from
+	driver       drivers.PlatformDriver
+
+	connectMu sync.Mutex // Held while connecting
+
+	// Guarded by DriverPool.mu
+	connected       bool
+	retired         bool // Replaced or invalidated; closed when the last user releases it
+	inUse           int
+	errorsInRow     int
+	failedConnects  int // Failed connects since the last success, drives the backoff
+	retryAt         time.Time
+	lastError       string
+	connectedAt     time.Time
+	lastUsed        time.Time
+	requests        uint64
+	errors          uint64
+	reconnects      uint64
+	connectFailures uint64
+}
+
+// NewDriverPool creates an empty driver pool.
+func NewDriverPool() *DriverPool {
+	return &DriverPool{entries: make(map[uint]*poolEntry), breakers: make(map[uint]*circuitBreaker)}
+}
+
+var pool = NewDriverPool()
+
+// Drivers returns the process-wide driver pool.
+func Drivers() *DriverPool {
+	return pool
+}
+
+// Acquire returns a connected driver for a platform. The driver is shared with other requests
+// and must not be disconnected by the caller; release must always be called instead, with the
+// error of the work done, so that a driver which keeps failing is reconnected. While the
+// platform's circuit breaker is open the error wraps ErrCircuitOpen.
+func (p *DriverPool) Acquire(ctx context.Context, platform *model.Platform) (drivers.PlatformDriver, func(err error), error) {
+	p.startOnce.Do(func() { go p.closeIdle() })
+
+	entry, trial, err := p.entry(platform)
+	if err != nil {
+		return nil, nil, err
+	}
+	release := func(err error) {
+		p.release(entry, err)
+		p.recordCircuit(entry.platformID, err, trial)
+	}
+
+	if err := p.connect(ctx, entry); err != nil {
+		p.release(entry, nil)
+		p.recordCircuit(entry.platformID, err, trial)
+		return nil, nil, err
+	}
+	return entry.driver, release, nil
+}
+
+// entry returns the platform's pool entry, replacing it when the configuration changed, and
+// marks it in use. It also reports whether the request is a trial of a half-open circuit.
+func (p *DriverPool) entry(platform *model.Platform) (*poolEntry, bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.entries[platform.ID]
+	if ok && (entry.platformType != platform.Type || entry.metadata != platform.Metadata) {
+		p.retireLocked(entry)
+		ok = false
+	}
+	if !ok {
+		driver, err := drivers.GetDriver(platform.Type, platform.Metadata)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := p.configureBreakerLocked(platform); err != nil {
+			return nil, false, err
+		}
+		entry = &poolEntry{
+			platformID:   platform.ID,
+			platformType: platform.Type,
+			metadata:     platform.Metadata,
+			driver:       driver,
+		}
+		p.entries[platform.ID] = entry
+	}
+	var trial bool
+	if breaker, ok := p.breakers[platform.ID]; ok {
+		var err error
+		if trial, err = breaker.allow(time.Now()); err != nil {
+			return nil, false, err
+		}
+	}
+	entry.inUse++
+	entry.requests++
+	entry.lastUsed = time.Now()
+	return entry, trial, nil
+}
+
+// configureBreakerLocked applies the circuit breaker settings of a platform's metadata,
+// keeping the state of an existing breaker.
+func (p *DriverPool) configureBreakerLocked(platform *model.Platform) error {
+	policy, err := drivers.ParseCircuitBreaker(platform.Metadata)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		delete(p.breakers, platform.ID)
+		return nil
+	}
+	if breaker, ok := p.breakers[platform.ID]; ok {
+		breaker.policy = *policy
+		return nil
+	}
+	p.breakers[platform.ID] = newCircuitBreaker(platform.ID, *policy)
+	return nil
+}
+
+// recordCircuit counts a request's outcome against the platform's circuit breaker, if any.
+func (p *DriverPool) recordCircuit(platformID uint, err error, trial bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if breaker, ok := p.breakers[platformID]; ok {
+		breaker.record(err, trial, time.Now())
+	}
+}
+
+// CircuitState returns the state of a platform's circuit breaker, or "" when it has none.
+func (p *DriverPool) CircuitState(platformID uint) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if breaker, ok := p.breakers[platformID]; ok {
+		return breaker.current(time.Now())
+	}
+	return ""
+}
+
+// connect connects an entry's driver unless it is connected or backing off after a failure.
+func (p *DriverPool) connect(ctx context.Context, entry *poolEntry) error {
+	entry.connectMu.Lock()
+	defer entry.connectMu.Unlock()
+
+	p.mu.Lock()
+	if entry.connected {
+		p.mu.Unlock()
+		return nil
+	}
+	if wait := time.Until(entry.retryAt); wait > 0 {
+		lastError := entry.lastError
+		p.mu.Unlock()
+		return fmt.Errorf("platform %d is unavailable, retrying in %s: %s", entry.platformID, wait.Round(time.Second), lastError)
+	}
+	p.mu.Unlock()
+
+	err := entry.driver.Connect(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		entry.failedConnects++
+		entry.connectFailures++
+		entry.retryAt = time.Now().Add(backoff(entry.failedConnects))
+		entry.lastError = err.Error()
+		logs.Error("Failed to connect driver for platform %d (attempt %d): %v", entry.platformID, entry.failedConnects, err)
+		return err
+	}
+	if !entry.connectedAt.IsZero() {
+		entry.reconnects++
+	}
+	entry.connected = true
+	entry.failedConnects = 0
+	entry.errorsInRow = 0
+	entry.retryAt = time.Time{}
+	entry.connectedAt = time.Now()
+	logs.Info("Connected pooled driver for platform %d", entry.platformID)
+	return nil
+}
+
+// release returns an entry after use. Drivers that fail poolErrorThreshold uses in a row are
+// replaced by a new driver, which the next request connects.
+func (p *DriverPool) release(entry *poolEntry, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry.inUse--
+	entry.lastUsed = time.Now()
+	if err != nil {
+		entry.errors++
+		entry.errorsInRow++
+		entry.lastError = err.Error()
+	} else {
+		entry.errorsInRow = 0
+	}
+
+	switch {
+	case entry.retired && entry.inUse == 0:
+		p.disconnectLocked(entry)
+	case entry.connected && !entry.retired && entry.errorsInRow >= poolErrorThreshold:
+		logs.Warn("Reconnecting driver for platform %d after %d failed requests", entry.platformID, entry.errorsInRow)
+		p.replaceLocked(entry)
+	}
+}
+
+// replaceLocked swaps a failing entry for one with a new driver instance, keeping its
+// statistics. Requests still using the old driver finish with it; it is closed when the last
+// of them releases it, so its connection is never torn down under them.
+func (p *DriverPool) replaceLocked(entry *poolEntry) {
+	driver, err := drivers.GetDriver(entry.platformType, entry.metadata)
+	if err != nil {
+		logs.Error("Failed to create driver for platform %d: %v", entry.platformID, err)
+		p.retireLocked(entry)
+		return
+	}
+	p.entries[entry.platformID] = &poolEntry{
+		platformID:      entry.platformID,
+		platformType:    entry.platformType,
+		metadata:        entry.metadata,
+		driver:          driver,
+		lastError:       entry.lastError,
+		connectedAt:     entry.connectedAt,
+		lastUsed:        entry.lastUsed,
+		requests:        entry.requests,
+		errors:          entry.errors,
+		reconnects:      entry.reconnects,
+		connectFailures: entry.connectFailures,
+	}
+	entry.retired = true
+	if entry.inUse == 0 {
+		p.disconnectLocked(entry)
+	}
+}
+
+// Invalidate drops a platform's driver and circuit breaker, e.g. after its configuration
+// changed or it was deleted. The driver is closed once no request uses it.
+func (p *DriverPool) Invalidate(platformID uint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.breakers, platformID)
+	if entry, ok := p.entries[platformID]; ok {
+		p.retireLocked(entry)
+	}
+}
+
+func (p *DriverPool) retireLocked(entry *poolEntry) {
+	delete(p.entries, entry.platformID)
+	entry.retired = true
+	if entry.inUse == 0 {
+		p.disconnectLocked(entry)
+	}
+}
+
+// disconnectLocked closes a retired entry's connection in the background, so the pool lock is
+// not held while the platform answers.
+func (p *DriverPool) disconnectLocked(entry *poolEntry) {
+	if !entry.connected {
+		return
+	}
+	entry.connected = false
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), poolDisconnectPeriod)
+		defer cancel()
+		entry.connectMu.Lock()
+		defer entry.connectMu.Unlock()
+		if err := entry.driver.Disconnect(ctx); err != nil {
+			logs.Warn("Failed to disconnect driver for platform %d: %v", entry.platformID, err)
+		}
+	}()
+}
+
+// closeIdle periodically closes connections that have not been used for poolIdleTimeout.
+func (p *DriverPool) closeIdle() {
+	ticker := time.NewTicker(poolIdleTimeout / 5)
+	defer ticker.Stop()
+	for range ticker.C {
+		p.mu.Lock()
+		for id, entry := range p.entries {
+			if entry.inUse == 0 && time.Since(entry.lastUsed) > poolIdleTimeout {
+				logs.Info("Closing idle driver for platform %d", id)
+				p.retireLocked(entry)
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// PoolStats describes one pooled driver.
+type PoolStats struct {
+	PlatformID      uint       `json:"platform_id"`
+	PlatformType    string     `json:"platform_type"`
+	Connected       bool       `json:"connected"`
+	InUse           int        `json:"in_use"`
+	Requests        uint64     `json:"requests"`
+	Errors          uint64     `json:"errors"`
+	Reconnects      uint64     `json:"reconnects"`
+	ConnectFailures uint64     `json:"connect_failures"`
+	ConnectedAt     *time.Time `json:"connected_at,omitempty"`
+	LastUsed        time.Time  `json:"last_used"`
+	RetryAt         *time.Time `json:"retry_at,omitempty"` // Set while backing off after a failed connect
+	LastError       string     `json:"last_error,omitempty"`
+	Circuit         string     `json:"circuit,omitempty"`          // Circuit breaker state, when configured
+	CircuitOpens    uint64     `json:"circuit_opens,omitempty"`    // Times the circuit opened
+	CircuitRetryAt  *time.Time `json:"circuit_retry_at,omitempty"` // Set while the circuit is open
+}
+
+// Stats lists the pooled drivers, ordered by platform.
+func (p *DriverPool) Stats() []PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]PoolStats, 0, len(p.entries))
+	for _, entry := range p.entries {
+		s := PoolStats{
+			PlatformID:      entry.platformID,
+			PlatformType:    entry.platformType,
+			Connected:       entry.connected,
+			InUse:           entry.inUse,
+			Requests:        entry.requests,
+			Errors:          entry.errors,
+			Reconnects:      entry.reconnects,
+			ConnectFailures: entry.connectFailures,
+			LastUsed:        entry.lastUsed,
+			LastError:       entry.lastError,
+		}
+		if entry.connected {
+			connectedAt := entry.connectedAt
+			s.ConnectedAt = &connectedAt
+		}
+		if time.Now().Before(entry.retryAt) {
+			retryAt := entry.retryAt
+			s.RetryAt = &retryAt
+		}
+		if breaker, ok := p.breakers[entry.platformID]; ok {
+			s.Circuit = breaker.current(time.Now())
+			s.CircuitOpens = breaker.opens
+			if s.Circuit == model.CircuitOpen {
+				retryAt := breaker.retryAt()
+				s.CircuitRetryAt = &retryAt
+			}
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].PlatformID < stats[j].PlatformID })
+	return stats
+}
+
+// backoff returns the wait before the next connect after a number of failed attempts.
+func backoff(failures int) time.Duration {
+	wait := poolBackoffMin
+	for i := 1; i < failures && wait < poolBackoffMax; i++ {
+		wait *= 2
+	}
+	return min(wait, poolBackoffMax)
+}
@@ -0,0 +1,267 @@
+package gateway
+
+import (
+	"app/drivers"
+	"app/model"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakePoolDriver counts connects and disconnects; metadata "down" makes Connect fail.
+type fakePoolDriver struct {
+	metadata    string
+	connects    atomic.Int32
+	disconnects atomic.Int32
+}
+
+func (d *fakePoolDriver) Connect(ctx context.Context) error {
+	d.connects.Add(1)
+	if d.metadata == "down" {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (d *fakePoolDriver) Disconnect(ctx context.Context) error {
+	d.disconnects.Add(1)
+	return nil
+}
+
+func (d *fakePoolDriver) FetchData(ctx context.Context, resourceDetails string) (interface{}, error) {
+	return nil, nil
+}
+
+func (d *fakePoolDriver) ValidateConfig(ctx context.Context) error { return nil }
+
+func (d *fakePoolDriver) TestResource(ctx context.Context, resourceDetails string) (interface{}, error) {
+	return nil, nil
+}
+
+var (
+	fakePoolMu      sync.Mutex
+	fakePoolDrivers []*fakePoolDriver
+)
+
+func init() {
+	drivers.Register(drivers.Registration{
+		Type: "PoolTest",
+		New: func(metadata string) (drivers.PlatformDriver, error) {
+			fakePoolMu.Lock()
+			defer fakePoolMu.Unlock()
+			d := &fakePoolDriver{metadata: metadata}
+			fakePoolDrivers = append(fakePoolDrivers, d)
+			return d, nil
+		},
+		ValidateMetadata: func(metadata string) (string, error) { return metadata, nil },
+	})
+}
+
+func lastFakePoolDriver() *fakePoolDriver {
+	fakePoolMu.Lock()
+	defer fakePoolMu.Unlock()
+	return fakePoolDrivers[len(fakePoolDrivers)-1]
+}
+
+func waitForDisconnects(t *testing.T, d *fakePoolDriver, want int32) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for d.disconnects.Load() < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d disconnects, got %d", want, d.disconnects.Load())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDriverPool_ReusesConnection(t *testing.T) {
+	p := NewDriverPool()
+	platform := &model.Platform{Model: model.Model{ID: 1}, Type: "PoolTest", Metadata: "v1"}
+
+	for i := 0; i < 3; i++ {
+		_, release, err := p.Acquire(context.Background(), platform)
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		release(nil)
+	}
+	first := lastFakePoolDriver()
+	if got := first.connects.Load(); got != 1 {
+		t.Errorf("Expected one connect for three requests, got %d", got)
+	}
+
+	// Changed metadata replaces the driver and closes the old connection
+	platform.Metadata = "v2"
+	_, release, err := p.Acquire(context.Background(), platform)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	release(nil)
+	if lastFakePoolDriver() == first {
+		t.Error("Expected a new driver after the metadata changed")
+	}
+	waitForDisconnects(t, first, 1)
+
+	second := lastFakePoolDriver()
+	p.Invalidate(platform.ID)
+	waitForDisconnects(t, second, 1)
+	if stats := p.Stats(); len(stats) != 0 {
+		t.Errorf("Expected no pooled drivers after invalidation, got %+v", stats)
+	}
+}
+
+func TestDriverPool_ReconnectsAfterErrors(t *testing.T) {
+	p := NewDriverPool()
+	platform := &model.Platform{Model: model.Model{ID: 2}, Type: "PoolTest", Metadata: "flaky"}
+
+	for i := 0; i < poolErrorThreshold+1; i++ {
+		_, release, err := p.Acquire(context.Background(), platform)
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		release(errors.New("read timeout"))
+	}
+	fakePoolMu.Lock()
+	failing, d := fakePoolDrivers[len(fakePoolDrivers)-2], fakePoolDrivers[len(fakePoolDrivers)-1]
+	fakePoolMu.Unlock()
+	if failing.connects.Load() != 1 || d.connects.Load() != 1 {
+		t.Errorf("Expected a new driver after %d failed requests, got %d and %d connects", poolErrorThreshold, failing.connects.Load(), d.connects.Load())
+	}
+	waitForDisconnects(t, failing, 1)
+	if stats := p.Stats(); len(stats) != 1 || stats[0].Reconnects != 1 || stats[0].Errors != uint64(poolErrorThreshold+1) {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestDriverPool_ReconnectKeepsDriverInUse(t *testing.T) {
+	p := NewDriverPool()
+	platform := &model.Platform{Model: model.Model{ID: 6}, Type: "PoolTest", Metadata: "shared"}
+
+	held, releaseHeld, err := p.Acquire(context.Background(), platform)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	for i := 0; i < poolErrorThreshold; i++ {
+		_, release, err := p.Acquire(context.Background(), platform)
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		release(errors.New("read timeout"))
+	}
+
+	// The next request gets a new driver while the held one stays connected
+	next, release, err := p.Acquire(context.Background(), platform)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	release(nil)
+	if next == held {
+		t.Fatal("Expected a new driver instance for the reconnect")
+	}
+	if got := held.(*fakePoolDriver).disconnects.Load(); got != 0 {
+		t.Fatalf("Expected the driver in use to stay connected, got %d disconnects", got)
+	}
+	releaseHeld(nil)
+	waitForDisconnects(t, held.(*fakePoolDriver), 1)
+}
+
+func TestDriverPool_BacksOffAfterFailedConnect(t *testing.T) {
+	p := NewDriverPool()
+	platform := &model.Platform{Model: model.Model{ID: 3}, Type: "PoolTest", Metadata: "down"}
+
+	if _, _, err := p.Acquire(context.Background(), platform); err == nil {
+		t.Fatal("Expected the connect to fail")
+	}
+	if _, _, err := p.Acquire(context.Background(), platform); err == nil {
+		t.Fatal("Expected the pool to back off")
+	}
+	if got := lastFakePoolDriver().connects.Load(); got != 1 {
+		t.Errorf("Expected no connect while backing off, got %d", got)
+	}
+	stats := p.Stats()
+	if len(stats) != 1 || stats[0].RetryAt == nil || stats[0].ConnectFailures != 1 || stats[0].InUse != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if backoff(1) != poolBackoffMin {
+		t.Errorf("Expected %s after one failure, got %s", poolBackoffMin, backoff(1))
+	}
+	if backoff(3) != 4*poolBackoffMin {
+		t.Errorf("Expected %s after three failures, got %s", 4*poolBackoffMin, backoff(3))
+	}
+	if backoff(50) != poolBackoffMax {
+		t.Errorf("Expected the backoff to be capped at %s, got %s", poolBackoffMax, backoff(50))
+	}
+}
+
+func TestDriverPool_CircuitBreaker(t *testing.T) {
+	p := NewDriverPool()
+	platform := &model.Platform{Model: model.Model{ID: 4}, Type: "PoolTest",
+		Metadata: `{"circuit_breaker": {"failure_threshold": 2, "cool_down": "50ms"}}`}
+	use := func(err error) error {
+		_, release, acquireErr := p.Acquire(context.Background(), platform)
+		if acquireErr != nil {
+			return acquireErr
+		}
+		release(err)
+		return nil
+	}
+
+	if state := p.CircuitState(platform.ID); state != "" {
+		t.Errorf("Expected no circuit before the first request, got %q", state)
+	}
+	for i := 0; i < 2; i++ {
+		if err := use(errors.New("read timeout")); err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+	}
+	if err := use(nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen after 2 failures, got %v", err)
+	}
+	if state := p.CircuitState(platform.ID); state != model.CircuitOpen {
+		t.Errorf("Expected an open circuit, got %q", state)
+	}
+	if stats := p.Stats(); len(stats) != 1 || stats[0].CircuitOpens != 1 || stats[0].CircuitRetryAt == nil {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	// After the cool-down one trial request goes through; its failure opens the circuit again
+	time.Sleep(60 * time.Millisecond)
+	if state := p.CircuitState(platform.ID); state != model.CircuitHalfOpen {
+		t.Errorf("Expected a half-open circuit after the cool-down, got %q", state)
+	}
+	_, release, err := p.Acquire(context.Background(), platform)
+	if err != nil {
+		t.Fatalf("Expected a trial request, got %v", err)
+	}
+	if _, _, err := p.Acquire(context.Background(), platform); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected a single trial request, got %v", err)
+	}
+	release(errors.New("read timeout"))
+	if err := use(nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected the failed trial to reopen the circuit, got %v", err)
+	}
+
+	// A successful trial closes it
+	time.Sleep(60 * time.Millisecond)
+	if err := use(nil); err != nil {
+		t.Fatalf("Expected a trial request, got %v", err)
+	}
+	if state := p.CircuitState(platform.ID); state != model.CircuitClosed {
+		t.Errorf("Expected a closed circuit, got %q", state)
+	}
+	if err := use(context.Canceled); err != nil || use(nil) != nil {
+		t.Error("Expected requests to go through a closed circuit")
+	}
+
+	// Removing the settings removes the breaker
+	platform.Metadata = `{}`
+	if err := use(nil); err != nil || p.CircuitState(platform.ID) != "" {
+		t.Errorf("Expected no circuit without settings, got %q: %v", p.CircuitState(platform.ID), err)
+	}
+}
@@ -0,0 +1,490 @@
+package gateway
+
+import (
+	"app/dal"
+	"app/drivers"
+	"app/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/logs"
+)
+
+const (
+	sinkTick           = time.Second      // How often sinks are checked for samples to write
+	sinkReloadInterval = 30 * time.Second // How often sink platforms and tag names are reloaded
+	sinkNamesMaxAge    = 10 * time.Second // Names older than this are reloaded when a sample's device is unknown
+	sinkWriteTimeout   = 30 * time.Second // Bound on one write
+)
+
+// SinkForwarder forwards the samples the gateway collects to the platforms whose metadata
+// enables a telemetry sink, tagged with the names of their device, site, value stream,
+// resource and platform. Each sink buffers samples in memory and writes them in batches; a
+// failed batch stays buffered and is retried with backoff, and once the buffer is full the
+// oldest samples are dropped.
+type SinkForwarder struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	sinks  map[uint]*sink // By platform ID
+	names  *sinkNames
+}
+
+type sink struct {
+	platform *model.Platform
+	config   model.TelemetrySink
+	interval time.Duration
+
+	// Guarded by SinkForwarder.mu
+	buffer    []*model.SensorData
+	inFlight  int       // Samples at the front of the buffer being written
+	next      time.Time // When buffered samples are written even if they do not fill a batch
+	failures  int       // Consecutive failed writes
+	written   uint64
+	dropped   uint64
+	lastWrite time.Time
+	lastError string
+}
+
+// sinkNames resolves the IDs of samples to the names they are tagged with.
+type sinkNames struct {
+	devices   map[uint]sinkDevice
+	resources map[uint]sinkResource
+	platforms map[uint]string
+	loaded    time.Time
+}
+
+type sinkDevice struct {
+	name        string
+	site        string
+	valueStream string
+}
+
+type sinkResource struct {
+	name       string
+	platformID uint
+}
+
+// NewSinkForwarder creates a stopped forwarder without sinks.
+func NewSinkForwarder() *SinkForwarder {
+	return &SinkForwarder{sinks: make(map[uint]*sink)}
+}
+
+var sinks = NewSinkForwarder()
+
+// Sinks returns the process-wide sink forwarder.
+func Sinks() *SinkForwarder {
+	return sinks
+}
+
+// Start loads the sink platforms and writes their buffered samples until Stop is called.
+func (f *SinkForwarder) Start() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+	go f.run(ctx)
+	logs.Info("Telemetry sink forwarder started")
+}
+
+// Stop ends forwarding. Writes in progress are cancelled and buffered samples are kept.
+func (f *SinkForwarder) Stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cancel != nil {
+		f.cancel()
+		f.cancel = nil
+	}
+}
+
+func (f *SinkForwarder) run(ctx context.Context) {
+	ticker := time.NewTicker(sinkTick)
+	defer ticker.Stop()
+
+	var loaded time.Time
+	for {
+		if time.Since(loaded) >= sinkReloadInterval {
+			if err := f.reload(); err != nil {
+				logs.Error("Sink forwarder failed to load sinks: %v", err)
+			} else {
+				loaded = time.Now()
+			}
+		}
+		f.startDueWrites(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reload reads the sink settings of active platforms and, when any platform is a sink, the
+// names samples are tagged with.
+func (f *SinkForwarder) reload() error {
+	q := dal.Q
+	platforms, err := q.Platform.Where(q.Platform.IsActive.Is(true)).Find()
+	if err != nil {
+		return err
+	}
+	if f.replaceSinks(platforms) == 0 {
+		return nil
+	}
+	names, err := loadSinkNames()
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.names = names
+	f.mu.Unlock()
+	return nil
+}
+
+// replaceSinks installs the sinks of platforms that enable one and returns how many there are.
+// Sinks that already existed keep their buffer; the samples of removed sinks are discarded.
+func (f *SinkForwarder) replaceSinks(platforms []*model.Platform) int {
+	configured := make(map[uint]*sink)
+	for _, platform := range platforms {
+		config, err := SinkConfig(platform.Metadata)
+		if err != nil {
+			logs.Warn("Ignoring telemetry sink of platform %d: %v", platform.ID, err)
+			continue
+		}
+		if config == nil {
+			continue
+		}
+		interval, _ := time.ParseDuration(config.FlushInterval)
+		configured[platform.ID] = &sink{platform: platform, config: *config, interval: interval}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	for id, s := range configured {
+		old, ok := f.sinks[id]
+		if !ok {
+			s.next = now.Add(s.interval)
+			f.sinks[id] = s
+			logs.Info("Forwarding telemetry to platform %d", id)
+			continue
+		}
+		old.platform, old.config, old.interval = s.platform, s.config, s.interval
+	}
+	for id, s := range f.sinks {
+		if _, ok := configured[id]; !ok {
+			if len(s.buffer) > 0 {
+				logs.Warn("Telemetry sink of platform %d removed, discarding %d buffered samples", id, len(s.buffer))
+			}
+			delete(f.sinks, id)
+		}
+	}
+	return len(f.sinks)
+}
+
+// SinkConfig reads the sink member of platform metadata and fills in its defaults. It returns
+// nil when the metadata does not enable a sink.
+func SinkConfig(metadata string) (*model.TelemetrySink, error) {
+	if metadata == "" {
+		return nil, nil
+	}
+	var config struct {
+		Sink *model.TelemetrySink `json:"sink"`
+	}
+	if err := json.Unmarshal([]byte(metadata), &config); err != nil {
+		return nil, fmt.Errorf("invalid metadata JSON: %w", err)
+	}
+	if config.Sink == nil || !config.Sink.Enabled {
+		return nil, nil
+	}
+	if err := drivers.NormalizeTelemetrySink(config.Sink); err != nil {
+		return nil, err
+	}
+	return config.Sink, nil
+}
+
+// loadSinkNames reads the names of every device, site, value stream, resource and platform.
+func loadSinkNames() (*sinkNames, error) {
+	q := dal.Q
+	devices, err := q.Device.Find()
+	if err != nil {
+		return nil, err
+	}
+	sites, err := q.Site.Find()
+	if err != nil {
+		return nil, err
+	}
+	streams, err := q.ValueStream.Find()
+	if err != nil {
+		return nil, err
+	}
+	resources, err := q.Resource.Find()
+	if err != nil {
+		return nil, err
+	}
+	platforms, err := q.Platform.Find()
+	if err != nil {
+		return nil, err
+	}
+
+	siteNames := make(map[uint]string, len(sites))
+	for _, site := range sites {
+		siteNames[site.ID] = site.Name
+	}
+	streamNames := make(map[uint]string, len(streams))
+	for _, stream := range streams {
+		streamNames[stream.ID] = stream.Name
+	}
+	names := &sinkNames{
+		devices:   make(map[uint]sinkDevice, len(devices)),
+		resources: make(map[uint]sinkResource, len(resources)),
+		platforms: make(map[uint]string, len(platforms)),
+		loaded:    time.Now(),
+	}
+	for _, device := range devices {
+		d := sinkDevice{name: device.Name}
+		if device.SiteID != nil {
+			d.site = siteNames[*device.SiteID]
+		}
+		if device.ValueStreamID != nil {
+			d.valueStream = streamNames[*device.ValueStreamID]
+		}
+		names.devices[device.ID] = d
+	}
+	for _, resource := range resources {
+		names.resources[resource.ID] = sinkResource{name: resource.Name, platformID: resource.PlatformID}
+	}
+	for _, platform := range platforms {
+		names.platforms[platform.ID] = platform.Name
+	}
+	return names, nil
+}
+
+// Forward buffers samples read from a platform for every sink other than that platform.
+// Samples without a value are not forwarded.
+func (f *SinkForwarder) Forward(platformID uint, samples []*model.SensorData) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.sinks) == 0 {
+		return
+	}
+
+	valued := make([]*model.SensorData, 0, len(samples))
+	for _, sample := range samples {
+		if sample.NumericValue != nil || sample.StringValue != nil || sample.BoolValue != nil {
+			valued = append(valued, sample)
+		}
+	}
+	for id, s := range f.sinks {
+		if id == platformID {
+			continue
+		}
+		s.buffer = append(s.buffer, valued...)
+		if excess := len(s.buffer) - s.config.BufferSize; excess > 0 {
+			// Drop the oldest samples that are not being written
+			s.buffer = append(s.buffer[:s.inFlight], s.buffer[s.inFlight+excess:]...)
+			s.dropped += uint64(excess)
+		}
+	}
+}
+
+// startDueWrites starts a write for every sink that has a full batch or whose flush interval
+// has passed, unless a write to it is still in progress.
+func (f *SinkForwarder) startDueWrites(ctx context.Context) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	for id, s := range f.sinks {
+		if s.inFlight > 0 || len(s.buffer) == 0 {
+			continue
+		}
+		// A full batch does not wait for the flush interval, but does wait out a backoff
+		full := len(s.buffer) >= s.config.BatchSize && s.failures == 0
+		if now.Before(s.next) && !full {
+			continue
+		}
+		s.inFlight = min(len(s.buffer), s.config.BatchSize)
+		go f.flush(ctx, id)
+	}
+}
+
+// flush writes the oldest batch of a sink's buffer. The batch is removed from the buffer once
+// the platform accepted it; after a failure it is retried when the backoff has passed.
+func (f *SinkForwarder) flush(ctx context.Context, platformID uint) error {
+	f.refreshNames()
+
+	f.mu.Lock()
+	s, ok := f.sinks[platformID]
+	if !ok {
+		f.mu.Unlock()
+		return nil
+	}
+	if s.inFlight == 0 {
+		s.inFlight = min(len(s.buffer), s.config.BatchSize)
+	}
+	platform, bucket := s.platform, s.config.Bucket
+	points := f.pointsLocked(s, s.buffer[:s.inFlight])
+	f.mu.Unlock()
+
+	err := writeSinkPoints(ctx, platform, bucket, points)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	if err != nil {
+		s.failures++
+		s.lastError = err.Error()
+		s.next = now.Add(backoff(s.failures))
+		logs.Error("Writing %d samples to the telemetry sink of platform %d failed: %v", s.inFlight, platformID, err)
+	} else {
+		s.buffer = s.buffer[s.inFlight:]
+		s.written += uint64(s.inFlight)
+		s.failures = 0
+		s.lastError = ""
+		s.lastWrite = now
+		s.next = now.Add(s.interval)
+	}
+	s.inFlight = 0
+	return err
+}
+
+// writeSinkPoints writes points through the driver pool.
+func writeSinkPoints(ctx context.Context, platform *model.Platform, bucket string, points []drivers.SinkPoint) error {
+	ctx, cancel := context.WithTimeout(ctx, sinkWriteTimeout)
+	defer cancel()
+	driver, release, err := Drivers().Acquire(ctx, platform)
+	if err != nil {
+		return err
+	}
+	sinkDriver, ok := driver.(drivers.Sink)
+	if !ok {
+		release(nil)
+		return fmt.Errorf("platform type %s cannot be a telemetry sink", platform.Type)
+	}
+	err = sinkDriver.WritePoints(ctx, bucket, points)
+	release(err)
+	return err
+}
+
+// refreshNames reloads the names when a buffered sample belongs to a device or resource
+// created since they were loaded, at most once per sinkNamesMaxAge.
+func (f *SinkForwarder) refreshNames() {
+	f.mu.Lock()
+	stale := f.names == nil || time.Since(f.names.loaded) >= sinkNamesMaxAge
+	missing := f.names == nil
+	for _, s := range f.sinks {
+		if missing {
+			break
+		}
+		for _, sample := range s.buffer {
+			_, knownDevice := f.names.devices[sample.DeviceID]
+			_, knownResource := f.names.resources[sample.ResourceID]
+			if missing = !knownDevice || !knownResource; missing {
+				break
+			}
+		}
+	}
+	f.mu.Unlock()
+	if !stale || !missing {
+		return
+	}
+
+	names, err := loadSinkNames()
+	if err != nil {
+		logs.Error("Sink forwarder failed to load names: %v", err)
+		return
+	}
+	f.mu.Lock()
+	f.names = names
+	f.mu.Unlock()
+}
+
+// pointsLocked converts samples to the points written to a sink: the value in the field of
+// its type (numeric_value, string_value or bool_value) and the configured tags. Tags whose
+// source has no name, such as a device without a site, are left out.
+func (f *SinkForwarder) pointsLocked(s *sink, samples []*model.SensorData) []drivers.SinkPoint {
+	names := f.names
+	if names == nil {
+		names = &sinkNames{}
+	}
+	points := make([]drivers.SinkPoint, 0, len(samples))
+	for _, sample := range samples {
+		device := names.devices[sample.DeviceID]
+		resource := names.resources[sample.ResourceID]
+		sources := map[string]string{
+			"device":       device.name,
+			"site":         device.site,
+			"value_stream": device.valueStream,
+			"resource":     resource.name,
+			"platform":     names.platforms[resource.platformID],
+			"quality":      sample.Quality,
+		}
+		tags := make(map[string]string, len(s.config.Tags))
+		for source, key := range s.config.Tags {
+			if value := sources[source]; value != "" {
+				tags[key] = value
+			}
+		}
+
+		fields := make(map[string]interface{}, 1)
+		switch {
+		case sample.NumericValue != nil:
+			fields["numeric_value"] = *sample.NumericValue
+		case sample.StringValue != nil:
+			fields["string_value"] = *sample.StringValue
+		case sample.BoolValue != nil:
+			fields["bool_value"] = *sample.BoolValue
+		}
+		points = append(points, drivers.SinkPoint{Measurement: s.config.Measurement, Tags: tags, Fields: fields, Time: sample.Timestamp})
+	}
+	return points
+}
+
+// SinkStatus describes the buffer and writes of one telemetry sink.
+type SinkStatus struct {
+	PlatformID   uint       `json:"platform_id"`
+	PlatformName string     `json:"platform_name"`
+	Buffered     int        `json:"buffered"` // Samples waiting to be written
+	Written      uint64     `json:"written"`
+	Dropped      uint64     `json:"dropped"`  // Samples dropped because the buffer was full
+	Failures     int        `json:"failures"` // Consecutive failed writes
+	Writing      bool       `json:"writing"`
+	NextWrite    time.Time  `json:"next_write"`
+	LastWrite    *time.Time `json:"last_write,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+// Status lists the telemetry sinks, ordered by platform.
+func (f *SinkForwarder) Status() []SinkStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	status := make([]SinkStatus, 0, len(f.sinks))
+	for id, s := range f.sinks {
+		st := SinkStatus{
+			PlatformID:   id,
+			PlatformName: s.platform.Name,
+			Buffered:     len(s.buffer),
+			Written:      s.written,
+			Dropped:      s.dropped,
+			Failures:     s.failures,
+			Writing:      s.inFlight > 0,
+			NextWrite:    s.next,
+			LastError:    s.lastError,
+		}
+		if !s.lastWrite.IsZero() {
+			lastWrite := s.lastWrite
+			st.LastWrite = &lastWrite
+		}
+		status = append(status, st)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].PlatformID < status[j].PlatformID })
+	return status
+}
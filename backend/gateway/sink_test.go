@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"app/drivers"
+	"app/model"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSinkDriver records the points written to it; failing makes writes fail.
+type fakeSinkDriver struct {
+	fakePoolDriver
+	mu      sync.Mutex
+	failing bool
+	buckets []string
+	points  []drivers.SinkPoint
+}
+
+func (d *fakeSinkDriver) WritePoints(ctx context.Context, bucket string, points []drivers.SinkPoint) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.failing {
+		return errors.New("connection refused")
+	}
+	d.buckets = append(d.buckets, bucket)
+	d.points = append(d.points, points...)
+	return nil
+}
+
+var fakeSink = &fakeSinkDriver{}
+
+func init() {
+	drivers.Register(drivers.Registration{
+		Type:             "SinkTest",
+		New:              func(metadata string) (drivers.PlatformDriver, error) { return fakeSink, nil },
+		ValidateMetadata: func(metadata string) (string, error) { return metadata, nil },
+	})
+}
+
+func TestSinkForwarder_BuffersAndRetries(t *testing.T) {
+	f := NewSinkForwarder()
+	if n := f.replaceSinks([]*model.Platform{
+		{Model: model.Model{ID: 1}, Name: "historian", Type: "SinkTest",
+			Metadata: `{"sink": {"enabled": true, "bucket": "plant", "batch_size": 2, "buffer_size": 3, "tags": {"device": "machine", "site": "plant", "value_stream": "line"}}}`},
+		{Model: model.Model{ID: 2}, Name: "plc", Type: "SinkTest", Metadata: `{}`},
+		{Model: model.Model{ID: 3}, Name: "off", Type: "SinkTest", Metadata: `{"sink": {"enabled": false}}`},
+	}); n != 1 {
+		t.Fatalf("Expected 1 sink, got %d", n)
+	}
+	f.names = &sinkNames{
+		devices:   map[uint]sinkDevice{7: {name: "Press 1", site: "North"}},
+		resources: map[uint]sinkResource{9: {name: "temperature", platformID: 2}},
+		platforms: map[uint]string{2: "plc"},
+		loaded:    time.Now(),
+	}
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	sample := func(i int, value interface{}) *model.SensorData {
+		return NewSample(7, 9, at.Add(time.Duration(i)*time.Second), value, "")
+	}
+	f.Forward(1, []*model.SensorData{sample(0, 1.0)}) // Read from the sink itself
+	f.Forward(2, []*model.SensorData{sample(1, 1.0), sample(2, nil), sample(3, 2.0), sample(4, "running"), sample(5, true)})
+	if status := f.Status(); len(status) != 1 || status[0].Buffered != 3 || status[0].Dropped != 1 {
+		t.Fatalf("Expected 3 buffered samples and 1 dropped, got %+v", status)
+	}
+
+	fakeSink.failing = true
+	if err := f.flush(context.Background(), 1); err == nil {
+		t.Fatal("Expected the write to fail")
+	}
+	if status := f.Status()[0]; status.Buffered != 3 || status.Failures != 1 || status.LastError == "" {
+		t.Fatalf("Expected the failed batch to stay buffered, got %+v", status)
+	}
+
+	fakeSink.failing = false
+	if err := f.flush(context.Background(), 1); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if status := f.Status()[0]; status.Buffered != 1 || status.Written != 2 || status.Failures != 0 {
+		t.Fatalf("Expected one batch written, got %+v", status)
+	}
+
+	fakeSink.mu.Lock()
+	defer fakeSink.mu.Unlock()
+	if len(fakeSink.points) != 2 || fakeSink.buckets[0] != "plant" {
+		t.Fatalf("Expected 2 points in bucket plant, got %v in %v", fakeSink.points, fakeSink.buckets)
+	}
+	point := fakeSink.points[0]
+	if point.Measurement != "telemetry" || point.Fields["numeric_value"] != 2.0 || !point.Time.Equal(at.Add(3*time.Second)) {
+		t.Errorf("Unexpected point %+v", point)
+	}
+	// The device has no value stream, so there is no line tag
+	if len(point.Tags) != 2 || point.Tags["machine"] != "Press 1" || point.Tags["plant"] != "North" {
+		t.Errorf("Unexpected tags %v", point.Tags)
+	}
+	if fakeSink.points[1].Fields["string_value"] != "running" {
+		t.Errorf("Expected a string_value field, got %v", fakeSink.points[1].Fields)
+	}
+}
+
+func TestSinkConfig(t *testing.T) {
+	config, err := SinkConfig(`{"sink": {"enabled": true}}`)
+	if err != nil || config == nil {
+		t.Fatalf("Expected a sink, got %v (%v)", config, err)
+	}
+	if config.Measurement != "telemetry" || config.BatchSize != 500 || config.FlushInterval != "5s" || config.Tags["value_stream"] != "value_stream" {
+		t.Errorf("Expected the defaults, got %+v", config)
+	}
+	for _, metadata := range []string{
+		`{"sink": {"enabled": true, "tags": {"operator": "op"}}}`,
+		`{"sink": {"enabled": true, "batch_size": 100, "buffer_size": 10}}`,
+		`{"sink": {"enabled": true, "flush_interval": "10ms"}}`,
+	} {
+		if _, err := SinkConfig(metadata); err == nil {
+			t.Errorf("Expected %s to be rejected", metadata)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+// Package gateway holds the long-lived platform state shared across API requests.
+package gateway
+
+import (
+	"app/drivers"
+	"app/model"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/logs"
+)
+
+const (
+	consumerBuffer          = 32               // Changes buffered per consumer before updates are dropped
+	subscribeConnectTimeout = 30 * time.Second // Bound on acquiring a platform's driver for subscriptions
+)
+
+// SubscriptionManager shares one pooled driver per platform and one driver subscription per
+// resource, fanning every change out to all consumers of that resource. Drivers are acquired
+// and subscriptions opened outside the manager's lock; callers asking for a platform or
+// resource that is still being opened wait for that attempt instead of starting their own.
+type SubscriptionManager struct {
+	mu        sync.Mutex
+	platforms map[uint]*platformSession
+}
+
+type platformSession struct {
+	ready      chan struct{} // Closed once the driver was acquired, or that failed with err
+	err        error
+	closed     bool // Set by Close; no new feeds are opened
+	driver     drivers.PlatformDriver
+	subscriber drivers.Subscriber
+	release    func(err error) // Returns the driver to the pool; nil once released
+	users      int             // Subscribe calls in progress, which keep the session open
+	resources  map[uint]*resourceFeed
+}
+
+type resourceFeed struct {
+	ready     chan struct{} // Closed once the driver subscription was opened, or that failed with err
+	err       error
+	cancel    context.CancelFunc
+	waiting   int // Subscribe calls about to become consumers, which keep the feed open
+	consumers map[chan drivers.DataChange]struct{}
+}
+
+// NewSubscriptionManager creates an empty subscription manager.
+func NewSubscriptionManager() *SubscriptionManager {
+	return &SubscriptionManager{platforms: make(map[uint]*platformSession)}
+}
+
+var subscriptions = NewSubscriptionManager()
+
+// Subscriptions returns the process-wide subscription manager.
+func Subscriptions() *SubscriptionManager {
+	return subscriptions
+}
+
+// Subscribe registers a consumer for a resource's value changes. The first consumer
+// of a resource opens the driver subscription; the returned function unregisters the
+// consumer and must always be called. The channel is closed when the feed ends.
+func (m *SubscriptionManager) Subscribe(platform *model.Platform, resource *model.Resource) (<-chan drivers.DataChange, func(), error) {
+	session, err := m.session(platform)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer m.leave(platform.ID, session)
+
+	m.mu.Lock()
+	if session.closed {
+		m.mu.Unlock()
+		return nil, nil, fmt.Errorf("subscriptions of platform %d were closed", platform.ID)
+	}
+	var ctx context.Context
+	feed, ok := session.resources[resource.ID]
+	if !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(context.Background())
+		feed = &resourceFeed{ready: make(chan struct{}), cancel: cancel, consumers: make(map[chan drivers.DataChange]struct{})}
+		session.resources[resource.ID] = feed
+	}
+	feed.waiting++
+	m.mu.Unlock()
+
+	if !ok {
+		m.open(ctx, platform.ID, resource, session, feed)
+	}
+	<-feed.ready
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	feed.waiting--
+	if feed.err != nil {
+		return nil, nil, feed.err
+	}
+	if feed.consumers == nil {
+		return nil, nil, fmt.Errorf("subscription for resource %d ended", resource.ID)
+	}
+	consumer := make(chan drivers.DataChange, consumerBuffer)
+	feed.consumers[consumer] = struct{}{}
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() { m.unsubscribe(platform.ID, resource.ID, session, feed, consumer) })
+	}
+	return consumer, unsubscribe, nil
+}
+
+// Close ends all subscriptions of a platform, e.g. after its configuration changed. The
+// pooled driver is released once their feeds have ended.
+func (m *SubscriptionManager) Close(platformID uint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.platforms[platformID]
+	if !ok {
+		return
+	}
+	delete(m.platforms, platformID)
+	session.closed = true
+	for _, feed := range session.resources {
+		feed.cancel()
+	}
+	logs.Info("Closed subscriptions for platform %d", platformID)
+}
+
+// session returns the session of a platform, acquiring its driver from the pool when needed,
+// and counts the caller as a user until leave.
+func (m *SubscriptionManager) session(platform *model.Platform) (*platformSession, error) {
+	m.mu.Lock()
+	session, ok := m.platforms[platform.ID]
+	if !ok {
+		session = &platformSession{ready: make(chan struct{}), resources: make(map[uint]*resourceFeed)}
+		m.platforms[platform.ID] = session
+	}
+	session.users++
+	m.mu.Unlock()
+
+	if !ok {
+		m.acquire(platform, session)
+	}
+	<-session.ready
+	if session.err != nil {
+		m.leave(platform.ID, session)
+		return nil, session.err
+	}
+	return session, nil
+}
+
+// acquire gets a session's driver from the pool and marks the session ready.
+func (m *SubscriptionManager) acquire(platform *model.Platform, session *platformSession) {
+	ctx, cancel := context.WithTimeout(context.Background(), subscribeConnectTimeout)
+	defer cancel()
+	driver, release, err := Drivers().Acquire(ctx, platform)
+	var subscriber drivers.Subscriber
+	if err == nil {
+		var ok bool
+		if subscriber, ok = driver.(drivers.Subscriber); !ok {
+			release(nil)
+			err = fmt.Errorf("platform type %s does not support subscriptions", platform.Type)
+		}
+	} else {
+		err = fmt.Errorf("failed to connect platform %d: %w", platform.ID, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		session.err = err
+		if m.platforms[platform.ID] == session {
+			delete(m.platforms, platform.ID)
+		}
+	} else {
+		session.driver, session.subscriber, session.release = driver, subscriber, release
+	}
+	close(session.ready)
+}
+
+// open subscribes to a resource on the driver and starts fanning its changes out.
+func (m *SubscriptionManager) open(ctx context.Context, platformID uint, resource *model.Resource, session *platformSession, feed *resourceFeed) {
+	changes, err := session.subscriber.Subscribe(ctx, resource.Details)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer close(feed.ready)
+	if err != nil {
+		feed.cancel()
+		feed.err = err
+		feed.consumers = nil
+		m.removeFeed(platformID, resource.ID, session, feed)
+		return
+	}
+	go m.fanOut(platformID, resource.ID, session, feed, changes)
+	logs.Info("Opened subscription for resource %d on platform %d", resource.ID, platformID)
+}
+
+// leave ends a Subscribe call's use of a session. Callers must not hold m.mu.
+func (m *SubscriptionManager) leave(platformID uint, session *platformSession) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session.users--
+	m.releaseSession(platformID, session)
+}
+
+// removeFeed drops an ended feed from its session. Callers hold m.mu.
+func (m *SubscriptionManager) removeFeed(platformID, resourceID uint, session *platformSession, feed *resourceFeed) {
+	if session.resources[resourceID] == feed {
+		delete(session.resources, resourceID)
+	}
+	m.releaseSession(platformID, session)
+}
+
+// releaseSession returns a session's driver to the pool once it has no feeds and no Subscribe
+// calls left. Callers hold m.mu.
+func (m *SubscriptionManager) releaseSession(platformID uint, session *platformSession) {
+	if len(session.resources) > 0 || session.users > 0 || session.release == nil {
+		return
+	}
+	if m.platforms[platformID] == session {
+		delete(m.platforms, platformID)
+	}
+	session.release(nil)
+	session.release = nil
+}
+
+// fanOut copies changes from the driver to every consumer. Slow consumers miss
+// updates instead of stalling the feed for everyone else.
+func (m *SubscriptionManager) fanOut(platformID, resourceID uint, session *platformSession, feed *resourceFeed, changes <-chan drivers.DataChange) {
+	for change := range changes {
+		m.mu.Lock()
+		for consumer := range feed.consumers {
+			select {
+			case consumer <- change:
+			default:
+			}
+		}
+		m.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for consumer := range feed.consumers {
+		close(consumer)
+	}
+	feed.consumers = nil
+	m.removeFeed(platformID, resourceID, session, feed)
+	logs.Info("Subscription for resource %d on platform %d ended", resourceID, platformID)
+}
+
+// unsubscribe removes a consumer and cancels the feed when it was the last one.
+func (m *SubscriptionManager) unsubscribe(platformID, resourceID uint, session *platformSession, feed *resourceFeed, consumer chan drivers.DataChange) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := feed.consumers[consumer]; !ok {
+		return // The feed already ended and closed the channel
+	}
+	delete(feed.consumers, consumer)
+	close(consumer)
+	if len(feed.consumers) > 0 || feed.waiting > 0 {
+		return
+	}
+
+	feed.cancel()
+	m.removeFeed(platformID, resourceID, session, feed)
+}
@@ -0,0 +1,192 @@
+package gateway
+
+import (
+	"app/drivers"
+	"app/model"
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeSubscribeDriver sends each subscription its details once and closes it when the context
+// ends. Metadata "slow" makes Connect wait for subscribeConnectGate.
+type fakeSubscribeDriver struct {
+	fakePoolDriver
+	subscriptions atomic.Int32 // Open driver subscriptions
+}
+
+var subscribeConnectGate chan struct{}
+
+func (d *fakeSubscribeDriver) Connect(ctx context.Context) error {
+	d.connects.Add(1)
+	if d.metadata == "slow" {
+		<-subscribeConnectGate
+	}
+	return nil
+}
+
+func (d *fakeSubscribeDriver) Subscribe(ctx context.Context, resourceDetails string) (<-chan drivers.DataChange, error) {
+	changes := make(chan drivers.DataChange, 1)
+	changes <- drivers.DataChange{Data: resourceDetails, ReceivedAt: time.Now()}
+	d.subscriptions.Add(1)
+	go func() {
+		<-ctx.Done()
+		d.subscriptions.Add(-1)
+		close(changes)
+	}()
+	return changes, nil
+}
+
+var (
+	fakeSubscribeMu      sync.Mutex
+	fakeSubscribeDrivers = map[string]*fakeSubscribeDriver{}
+)
+
+func init() {
+	drivers.Register(drivers.Registration{
+		Type: "SubscribeTest",
+		New: func(metadata string) (drivers.PlatformDriver, error) {
+			fakeSubscribeMu.Lock()
+			defer fakeSubscribeMu.Unlock()
+			d := &fakeSubscribeDriver{fakePoolDriver: fakePoolDriver{metadata: metadata}}
+			fakeSubscribeDrivers[metadata] = d
+			return d, nil
+		},
+		ValidateMetadata: func(metadata string) (string, error) { return metadata, nil },
+	})
+}
+
+func fakeSubscribeDriverFor(metadata string) *fakeSubscribeDriver {
+	fakeSubscribeMu.Lock()
+	defer fakeSubscribeMu.Unlock()
+	return fakeSubscribeDrivers[metadata]
+}
+
+// waitForRelease waits until no request or subscription uses a platform's pooled driver.
+func waitForRelease(t *testing.T, platformID uint) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		inUse := 0
+		for _, stats := range Drivers().Stats() {
+			if stats.PlatformID == platformID {
+				inUse = stats.InUse
+			}
+		}
+		if inUse == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the driver of platform %d to be released, %d in use", platformID, inUse)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSubscriptionManager_SharesPooledDriver(t *testing.T) {
+	m := NewSubscriptionManager()
+	platform := &model.Platform{Model: model.Model{ID: 20}, Type: "SubscribeTest", Metadata: "shared"}
+	temperature := &model.Resource{Model: model.Model{ID: 1}, Details: "temperature"}
+	pressure := &model.Resource{Model: model.Model{ID: 2}, Details: "pressure"}
+	defer Drivers().Invalidate(platform.ID)
+
+	first, unsubscribeFirst, err := m.Subscribe(platform, temperature)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if change := <-first; change.Data != "temperature" {
+		t.Errorf("Expected a temperature change, got %+v", change)
+	}
+	_, unsubscribeSecond, err := m.Subscribe(platform, temperature)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	_, unsubscribePressure, err := m.Subscribe(platform, pressure)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	// Requests get the driver the subscriptions use
+	driver, release, err := Drivers().Acquire(context.Background(), platform)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	release(nil)
+	d := fakeSubscribeDriverFor("shared")
+	if driver != d || d.connects.Load() != 1 {
+		t.Fatalf("Expected one pooled connection, got %d connects", d.connects.Load())
+	}
+	if got := d.subscriptions.Load(); got != 2 {
+		t.Errorf("Expected one driver subscription per resource, got %d", got)
+	}
+
+	unsubscribeFirst()
+	unsubscribeSecond()
+	unsubscribePressure()
+	waitForRelease(t, platform.ID)
+	if got := d.disconnects.Load(); got != 0 {
+		t.Errorf("Expected the driver to stay pooled, got %d disconnects", got)
+	}
+}
+
+func TestSubscriptionManager_ConnectsOutsideLock(t *testing.T) {
+	m := NewSubscriptionManager()
+	slow := &model.Platform{Model: model.Model{ID: 21}, Type: "SubscribeTest", Metadata: "slow"}
+	fast := &model.Platform{Model: model.Model{ID: 22}, Type: "SubscribeTest", Metadata: "fast"}
+	resource := &model.Resource{Model: model.Model{ID: 3}, Details: "level"}
+	defer Drivers().Invalidate(slow.ID)
+	defer Drivers().Invalidate(fast.ID)
+	subscribeConnectGate = make(chan struct{})
+
+	type subscribed struct {
+		changes     <-chan drivers.DataChange
+		unsubscribe func()
+		err         error
+	}
+	results := make(chan subscribed, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			changes, unsubscribe, err := m.Subscribe(slow, resource)
+			results <- subscribed{changes, unsubscribe, err}
+		}()
+	}
+
+	// Another platform is served while the slow one connects
+	done := make(chan error, 1)
+	go func() {
+		_, unsubscribe, err := m.Subscribe(fast, resource)
+		if err == nil {
+			unsubscribe()
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Subscribe failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a connecting platform not to block other platforms")
+	}
+
+	close(subscribeConnectGate)
+	for i := 0; i < 2; i++ {
+		result := <-results
+		if result.err != nil {
+			t.Fatalf("Subscribe failed: %v", result.err)
+		}
+		defer result.unsubscribe()
+	}
+	if got := fakeSubscribeDriverFor("slow").connects.Load(); got != 1 {
+		t.Errorf("Expected concurrent subscribers to share one connect, got %d", got)
+	}
+
+	// Closing the platform ends its feeds and returns the driver to the pool
+	m.Close(slow.ID)
+	waitForRelease(t, slow.ID)
+	if got := fakeSubscribeDriverFor("slow").subscriptions.Load(); got != 0 {
+		t.Errorf("Expected the driver subscription to be cancelled, got %d open", got)
+	}
+}
@@ -0,0 +1,138 @@
+package gateway
+
+import (
+	"app/dal"
+	"app/drivers"
+	"app/model"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"gorm.io/gen"
+	"gorm.io/gorm/clause"
+)
+
+const telemetryBatchSize = 500 // Samples inserted per statement
+
+// NewSample builds a sample of a resource value, storing it in the column matching its type.
+// Values that are not numbers, strings or booleans are stored as JSON text.
+func NewSample(deviceID, resourceID uint, timestamp time.Time, value interface{}, quality string) *model.SensorData {
+	sample := &model.SensorData{
+		DeviceID:   deviceID,
+		ResourceID: resourceID,
+		Timestamp:  timestamp,
+		Quality:    quality,
+	}
+	if sample.Quality == "" {
+		sample.Quality = model.QualityGood
+	}
+
+	switch v := value.(type) {
+	case nil:
+	case bool:
+		sample.BoolValue = &v
+	case string:
+		sample.StringValue = &v
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			sample.NumericValue = &f
+		} else {
+			s := v.String()
+			sample.StringValue = &s
+		}
+	case time.Time:
+		s := v.Format(time.RFC3339Nano)
+		sample.StringValue = &s
+	default:
+		rv := reflect.ValueOf(v)
+		switch {
+		case rv.CanInt():
+			f := float64(rv.Int())
+			sample.NumericValue = &f
+		case rv.CanUint():
+			f := float64(rv.Uint())
+			sample.NumericValue = &f
+		case rv.CanFloat():
+			f := rv.Float()
+			sample.NumericValue = &f
+		default:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				logs.Warn("Failed to encode value of resource %d: %v", resourceID, err)
+				sample.Quality = model.QualityBad
+				break
+			}
+			s := string(encoded)
+			sample.StringValue = &s
+		}
+	}
+	return sample
+}
+
+// PointSamples converts the points read for a device into samples.
+func PointSamples(deviceID uint, points []drivers.Point) []*model.SensorData {
+	samples := make([]*model.SensorData, 0, len(points))
+	for _, point := range points {
+		samples = append(samples, NewSample(deviceID, point.ResourceID, point.Timestamp, point.Value, point.Quality))
+	}
+	return samples
+}
+
+// WriteSamples stores samples in the telemetry store. Samples already stored for the same
+// device, resource and timestamp are skipped.
+func WriteSamples(samples []*model.SensorData) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	q := dal.Q
+	return q.SensorData.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(samples, telemetryBatchSize)
+}
+
+// TelemetryQuery selects a device's stored samples. Zero times leave the range open.
+type TelemetryQuery struct {
+	DeviceID    uint
+	ResourceIDs []uint // Empty for every resource
+	Start       time.Time
+	End         time.Time // Exclusive
+	Ascending   bool      // Oldest first instead of newest first
+	Limit       int
+	Offset      int
+}
+
+// QueryTelemetry returns one page of a device's samples and the number of samples matching.
+func QueryTelemetry(query TelemetryQuery) ([]*model.SensorData, int64, error) {
+	q := dal.Q
+	conds := []gen.Condition{q.SensorData.DeviceID.Eq(query.DeviceID)}
+	if len(query.ResourceIDs) > 0 {
+		conds = append(conds, q.SensorData.ResourceID.In(query.ResourceIDs...))
+	}
+	if !query.Start.IsZero() {
+		conds = append(conds, q.SensorData.Timestamp.Gte(query.Start))
+	}
+	if !query.End.IsZero() {
+		conds = append(conds, q.SensorData.Timestamp.Lt(query.End))
+	}
+
+	order := q.SensorData.Timestamp.Desc()
+	if query.Ascending {
+		order = q.SensorData.Timestamp
+	}
+	return q.SensorData.Where(conds...).Order(order, q.SensorData.ResourceID).FindByPage(query.Offset, query.Limit)
+}
+
+// SetupTelemetry turns the sensor_data table into a hypertable when the database has the
+// TimescaleDB extension. On plain Postgres the table is used as is.
+func SetupTelemetry() error {
+	db := dal.Q.SensorData.UnderlyingDB()
+
+	var timescale bool
+	if err := db.Raw("SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'timescaledb')").Scan(&timescale).Error; err != nil {
+		return err
+	}
+	if !timescale {
+		logs.Info("TimescaleDB is not installed, storing telemetry in a plain table")
+		return nil
+	}
+	return db.Exec("SELECT create_hypertable(?, ?, if_not_exists => TRUE, migrate_data => TRUE)", model.SensorData{}.TableName(), "timestamp").Error
+}
@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"app/model"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNewSample(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		value   interface{}
+		numeric *float64
+		text    *string
+		boolean *bool
+	}{
+		{"float", 21.5, ptr(21.5), nil, nil},
+		{"signed integer", int16(-4), ptr(-4.0), nil, nil},
+		{"unsigned integer", uint32(7), ptr(7.0), nil, nil},
+		{"json number", json.Number("3.25"), ptr(3.25), nil, nil},
+		{"string", "running", nil, ptr("running"), nil},
+		{"bool", true, nil, nil, ptr(true)},
+		{"time", at, nil, ptr("2024-05-01T12:00:00Z"), nil},
+		{"object", map[string]interface{}{"a": 1}, nil, ptr(`{"a":1}`), nil},
+		{"no value", nil, nil, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sample := NewSample(1, 2, at, tt.value, "")
+			if sample.DeviceID != 1 || sample.ResourceID != 2 || !sample.Timestamp.Equal(at) {
+				t.Errorf("Unexpected sample key %+v", sample)
+			}
+			if sample.Quality != model.QualityGood {
+				t.Errorf("Expected quality %s, got %s", model.QualityGood, sample.Quality)
+			}
+			checkValue(t, "numeric", sample.NumericValue, tt.numeric)
+			checkValue(t, "string", sample.StringValue, tt.text)
+			checkValue(t, "bool", sample.BoolValue, tt.boolean)
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func checkValue[T comparable](t *testing.T, column string, got, want *T) {
+	t.Helper()
+	switch {
+	case got == nil && want == nil:
+	case got == nil || want == nil:
+		t.Errorf("Expected %s value %v, got %v", column, want, got)
+	case *got != *want:
+		t.Errorf("Expected %s value %v, got %v", column, *want, *got)
+	}
+}
@@ -67,6 +67,8 @@ func main() {
 		model.DevicePlatform{},
 		model.Platform{},
 		model.Resource{},
+		model.PlatformStateChange{},
+		model.SensorData{},
 	)
 
 	// Apply custom query interfaces to respective models
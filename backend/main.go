@@ -2,6 +2,7 @@ package main
 
 import (
 	"app/dal"
+	"app/gateway"
 	"app/model"
 	_ "app/routers"
 	"app/seed"
@@ -9,6 +10,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/beego/beego/v2/core/logs"
 	beego "github.com/beego/beego/v2/server/web"
@@ -47,13 +49,35 @@ func main() {
 	}
 
 	// Create tables
-	db.AutoMigrate(&model.User{}, &model.Device{}, &model.ValueStream{}, &model.ApiKey{}, &model.Platform{}, &model.UserInteraction{}, &model.Site{}, &model.Resource{}, &model.DevicePlatform{})
+	db.AutoMigrate(&model.User{}, &model.Device{}, &model.ValueStream{}, &model.ApiKey{}, &model.Platform{}, &model.UserInteraction{}, &model.Site{}, &model.Resource{}, &model.DevicePlatform{}, &model.PlatformStateChange{}, &model.SensorData{})
 
 	dal.SetDefault(db)
 
+	// Partition the telemetry store by time when TimescaleDB is available
+	if err := gateway.SetupTelemetry(); err != nil {
+		log.Printf("Failed to set up the telemetry hypertable: %v", err)
+	}
+
 	// Seed admin user if no admin exists
 	seed.AdminUser(db)
 
+	// Keep platform connection states current; HEALTH_CHECK_INTERVAL=0 turns the monitor off
+	healthInterval, err := time.ParseDuration(getEnv("HEALTH_CHECK_INTERVAL", "1m"))
+	if err != nil {
+		log.Fatalf("Invalid HEALTH_CHECK_INTERVAL environment variable: %v", err)
+	}
+	gateway.Health().Start(healthInterval)
+
+	// Poll resources that set a poll_interval into the telemetry store; POLL_CONCURRENCY=0 turns polling off
+	pollConcurrency, err := strconv.Atoi(getEnv("POLL_CONCURRENCY", strconv.Itoa(gateway.DefaultPollConcurrency)))
+	if err != nil {
+		log.Fatalf("Invalid POLL_CONCURRENCY environment variable: %v", err)
+	}
+	gateway.Polling().Start(pollConcurrency)
+
+	// Forward polled samples to platforms whose metadata enables a telemetry sink
+	gateway.Sinks().Start()
+
 	// Initialize session
 	beego.BConfig.WebConfig.Session.SessionOn = true
 	beego.BConfig.WebConfig.Session.SessionProvider = "memory"
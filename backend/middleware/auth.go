@@ -92,7 +92,16 @@ func validateApiKey(ctx *context.Context, token string) bool {
 		}
 	}
 
+	// Keep the key's scopes for endpoints that need more than write, such as device writes
+	names := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if name, ok := scope.(string); ok {
+			names = append(names, name)
+		}
+	}
 	ctx.Input.SetData("user_id", apiKey.UserID)
+	ctx.Input.SetData("api_key_id", apiKey.ID)
+	ctx.Input.SetData("scopes", names)
 	return true
 }
 
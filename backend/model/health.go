@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// Platform connection states
+const (
+	ConnectionConnected    = "Connected"    // The last check passed
+	ConnectionDegraded     = "Degraded"     // Reachable but slow, or failing for less than the failure threshold
+	ConnectionDisconnected = "Disconnected" // Unreachable
+	ConnectionAuthFailed   = "AuthFailed"   // Reachable but the configured credentials were rejected
+)
+
+// PlatformStateChange records a platform moving from one connection state to another
+type PlatformStateChange struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	PlatformID uint      `gorm:"index:idx_platform_state_change;not null" json:"platform_id"`
+	FromState  string    `gorm:"size:50;not null" json:"from_state"`
+	ToState    string    `gorm:"size:50;not null" json:"to_state"`
+	Error      string    `gorm:"type:text" json:"error"` // Error of the check that caused the change, empty when recovering
+	LatencyMs  float64   `json:"latency_ms"`             // Duration of that check
+	ChangedAt  time.Time `gorm:"type:timestamp with time zone;index:idx_platform_state_change;not null" json:"changed_at"`
+}
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "Closed"   // Requests go through
+	CircuitOpen     = "Open"     // Requests fail fast until the cool-down has passed
+	CircuitHalfOpen = "HalfOpen" // Trial requests decide whether the circuit closes again
+)
+
+// CircuitBreaker defines when requests to a failing platform are short-circuited. Every
+// platform type accepts it under "circuit_breaker" in its metadata.
+type CircuitBreaker struct {
+	FailureThreshold int    `json:"failure_threshold"`            // Failed requests in a row that open the circuit
+	CoolDown         string `json:"cool_down,omitempty"`          // How long the circuit stays open, e.g. "30s"
+	HalfOpenRequests int    `json:"half_open_requests,omitempty"` // Trial requests let through after the cool-down
+}
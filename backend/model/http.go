@@ -0,0 +1,19 @@
+package model
+
+// HTTPTLS defines the TLS settings for platforms reached over HTTPS
+type HTTPTLS struct {
+	CACert             string `json:"ca_cert,omitempty"`     // PEM-encoded CA bundle, system roots when empty
+	Certificate        string `json:"certificate,omitempty"` // PEM-encoded client certificate for mutual TLS
+	PrivateKey         string `json:"private_key,omitempty"` // PEM-encoded client private key
+	ServerName         string `json:"server_name,omitempty"` // Name the server certificate is checked against, the URL's host when empty
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// HTTPRetry defines how failed requests to platforms reached over HTTP are retried. Only
+// idempotent requests are retried, after network errors and retryable status codes.
+type HTTPRetry struct {
+	MaxRetries      int    `json:"max_retries"`                // Attempts after the first one
+	InitialBackoff  string `json:"initial_backoff,omitempty"`  // Wait before the first retry, doubled for each further one, e.g. "200ms"
+	MaxBackoff      string `json:"max_backoff,omitempty"`      // Longest wait between attempts
+	RetryableStatus []int  `json:"retryable_status,omitempty"` // 429, 502, 503 and 504 when empty
+}
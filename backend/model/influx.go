@@ -1,18 +1,46 @@
 package model
 
-// InfluxDBMetadata defines the structure for InfluxDB platform metadata
+// InfluxDBMetadata defines the structure for InfluxDB platform metadata.
+// Version 2 (the default) uses Token, Org and Bucket; version 1 uses Username, Password,
+// Database and RetentionPolicy and queries with InfluxQL.
 type InfluxDBMetadata struct {
-	URL     string `json:"url"`               // e.g., "http://localhost:8086"
-	Token   string `json:"token"`             // InfluxDB API token
-	Org     string `json:"org"`               // Organization name
-	Bucket  string `json:"bucket"`            // Default bucket
-	Timeout int    `json:"timeout,omitempty"` // Timeout in seconds
+	URL             string         `json:"url"`                        // e.g., "http://localhost:8086"
+	Version         string         `json:"version,omitempty"`          // "2" (default) or "1" for InfluxDB 1.x
+	Token           string         `json:"token,omitempty"`            // InfluxDB API token
+	Org             string         `json:"org,omitempty"`              // Organization name
+	Bucket          string         `json:"bucket,omitempty"`           // Default bucket
+	Username        string         `json:"username,omitempty"`         // InfluxDB 1.x user, empty when authentication is disabled
+	Password        string         `json:"password,omitempty"`         // InfluxDB 1.x password
+	Database        string         `json:"database,omitempty"`         // InfluxDB 1.x database
+	RetentionPolicy string         `json:"retention_policy,omitempty"` // InfluxDB 1.x retention policy, the database's default when empty
+	Timeout         int            `json:"timeout,omitempty"`          // Timeout in seconds
+	TLS             *HTTPTLS       `json:"tls,omitempty"`
+	ProxyURL        string         `json:"proxy_url,omitempty"` // HTTP proxy, the environment's proxy settings when empty
+	Retry           *HTTPRetry     `json:"retry,omitempty"`
+	Sink            *TelemetrySink `json:"sink,omitempty"` // Forwards collected samples to the platform
 }
 
 // InfluxDBResourceDetails defines the structure for InfluxDB query details
 type InfluxDBResourceDetails struct {
-	Bucket      string `json:"bucket"`      // Bucket to query
-	Measurement string `json:"measurement"` // Measurement name
-	Field       string `json:"field"`       // Field to retrieve
-	TimeRange   string `json:"time_range"`  // e.g., "-1h" for last hour
+	Bucket      string            `json:"bucket"`               // Bucket to query
+	Measurement string            `json:"measurement"`          // Measurement name
+	Field       string            `json:"field,omitempty"`      // Field to retrieve
+	Fields      []string          `json:"fields,omitempty"`     // Several fields to retrieve, replacing Field
+	Tags        map[string]string `json:"tags,omitempty"`       // Tag filters, e.g., {"site": "north"}
+	TimeRange   string            `json:"time_range,omitempty"` // e.g., "-1h" for last hour, used when Start is empty
+	Start       string            `json:"start,omitempty"`      // RFC 3339 time or negative duration, e.g., "2024-05-01T00:00:00Z" or "-7d"
+	Stop        string            `json:"stop,omitempty"`       // RFC 3339 time or negative duration, now when empty
+	Every       string            `json:"every,omitempty"`      // Aggregate window, e.g., "1m"; records are returned as stored when empty
+	Fn          string            `json:"fn,omitempty"`         // Aggregate function: mean, min, max, sum, count, first, last or median, defaults to mean
+	Fill        string            `json:"fill,omitempty"`       // Windows without records: none (default), null or previous
+}
+
+// InfluxDBFluxDetails defines the structure for Flux script details.
+// The script reads params.device_alias, params.start, params.stop and params.every, which are
+// passed with the Flux params mechanism rather than spliced into the script.
+type InfluxDBFluxDetails struct {
+	Flux        string `json:"flux"`                   // e.g., from(bucket: "plant") |> range(start: time(v: params.start)) |> filter(fn: (r) => r.device == params.device_alias)
+	TimeRange   string `json:"time_range,omitempty"`   // e.g., "-1h", sets params.start to now minus the range and params.stop to now
+	Every       string `json:"every,omitempty"`        // e.g., "1m", the window for aggregateWindow, read with duration(v: params.every)
+	DeviceAlias string `json:"device_alias,omitempty"` // Bound to params.device_alias, set from the device-platform association
 }
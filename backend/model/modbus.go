@@ -0,0 +1,22 @@
+package model
+
+// ModbusTCPMetadata defines the structure for Modbus TCP platform metadata
+type ModbusTCPMetadata struct {
+	Host      string `json:"host"`                 // e.g., "192.168.1.20"
+	Port      int    `json:"port,omitempty"`       // Defaults to 502
+	UnitID    byte   `json:"unit_id"`              // Slave/unit identifier, 1 for most devices behind no gateway
+	Timeout   int    `json:"timeout,omitempty"`    // Timeout in seconds
+	ByteOrder string `json:"byte_order,omitempty"` // big (default) or little: byte order within a register
+	WordOrder string `json:"word_order,omitempty"` // high_first (default) or low_first: register order of 32/64-bit values
+}
+
+// ModbusRegisterDetails defines the structure for Modbus register map details
+type ModbusRegisterDetails struct {
+	FunctionCode string  `json:"function_code"`       // coils, discrete_inputs, holding_registers, input_registers
+	Address      uint16  `json:"address"`             // Zero-based start address
+	Count        uint16  `json:"count,omitempty"`     // Number of values to decode, defaults to 1
+	DataType     string  `json:"data_type,omitempty"` // bool, int16, uint16, int32, uint32, float32, int64, uint64, float64, scaled
+	Scale        float64 `json:"scale,omitempty"`     // Multiplier applied to numeric values, required for scaled
+	Offset       float64 `json:"offset,omitempty"`    // Added after scaling
+	Unit         string  `json:"unit,omitempty"`      // Engineering unit, e.g., "kWh"
+}
@@ -119,6 +119,8 @@ type Platform struct {
 	Type            string     `gorm:"size:50;not null" json:"type"` // CMMS, ERP, Database, Cloud, REST, OPC_UA, SDK, etc.
 	ConnectionState string     `gorm:"size:50;default:'Disconnected'" json:"connection_state"`
 	LastConnected   *time.Time `gorm:"type:timestamp with time zone" json:"last_connected"`
+	LastError       string     `gorm:"type:text" json:"last_error"` // Error of the last failed health check, cleared once it passes
+	CircuitState    string     `gorm:"-" json:"circuit_state,omitempty"` // Circuit breaker state when the platform configures one, see CircuitBreaker
 	OrganizationID  *int       `gorm:"index" json:"organization_id"`
 	IsActive        bool       `gorm:"default:true" json:"is_active"`
 	Devices         []Device   `gorm:"many2many:device_platforms" json:"devices"`
@@ -134,4 +136,5 @@ type Resource struct {
 	Type       string `gorm:"size:50;not null" json:"type"`        // e.g., 'rest_endpoint', 'opcua_node', 'sdk_method'
 	Details    string `gorm:"type:jsonb;not null" json:"details"`     // JSON string with type-specific details
 	Metadata   string `gorm:"type:jsonb;default:'{}'" json:"metadata"` // JSON string for resource metadata
+	Writable   bool   `gorm:"default:false" json:"writable"`           // Allows writes through the write endpoint, for resource types that support them
 }
@@ -0,0 +1,26 @@
+package model
+
+// MQTTMetadata defines the structure for MQTT platform metadata
+type MQTTMetadata struct {
+	BrokerURL string   `json:"broker_url"`          // e.g., "tcp://broker:1883", "ssl://broker:8883", "ws://broker:8080/mqtt"
+	ClientID  string   `json:"client_id,omitempty"` // Prefix of the client ID of each connection, generated when empty
+	Username  string   `json:"username,omitempty"`
+	Password  string   `json:"password,omitempty"`
+	QoS       byte     `json:"qos"` // 0, 1 or 2
+	TLS       *MQTTTLS `json:"tls,omitempty"`
+	Timeout   int      `json:"timeout,omitempty"` // Timeout in seconds
+}
+
+// MQTTTLS defines the TLS settings for MQTT brokers
+type MQTTTLS struct {
+	CACert             string `json:"ca_cert,omitempty"`     // PEM-encoded CA bundle, system roots when empty
+	Certificate        string `json:"certificate,omitempty"` // PEM-encoded client certificate
+	PrivateKey         string `json:"private_key,omitempty"` // PEM-encoded client private key
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// MQTTResourceDetails defines the structure for MQTT topic details
+type MQTTResourceDetails struct {
+	Topic    string `json:"topic"`               // Topic filter, e.g., "plant/:device_alias/temperature"
+	JSONPath string `json:"json_path,omitempty"` // e.g., "readings[0].value", empty for the whole payload
+}
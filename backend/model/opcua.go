@@ -0,0 +1,22 @@
+package model
+
+// OPCUAMetadata defines the structure for OPC UA platform metadata
+type OPCUAMetadata struct {
+	Endpoint       string `json:"endpoint"`                  // e.g., "opc.tcp://plc01:4840"
+	SecurityPolicy string `json:"security_policy,omitempty"` // None, Basic256Sha256, Aes128_Sha256_RsaOaep, Aes256_Sha256_RsaPss
+	SecurityMode   string `json:"security_mode,omitempty"`   // None, Sign, SignAndEncrypt
+	Certificate    string `json:"certificate,omitempty"`     // PEM-encoded client application certificate
+	PrivateKey     string `json:"private_key,omitempty"`     // PEM-encoded RSA private key for the client certificate
+	Username       string `json:"username,omitempty"`
+	Password       string `json:"password,omitempty"`
+	Timeout        int    `json:"timeout,omitempty"` // Timeout in seconds
+}
+
+// OPCUAResourceDetails defines the structure for OPC UA node details
+type OPCUAResourceDetails struct {
+	NodeID           string  `json:"node_id"`                     // e.g., "ns=2;s=Line1.Temperature"
+	SamplingInterval float64 `json:"sampling_interval,omitempty"` // Monitored item sampling interval in milliseconds
+	QueueSize        uint32  `json:"queue_size,omitempty"`        // Number of changes the server buffers between publishes
+	DeadbandType     string  `json:"deadband_type,omitempty"`     // None, Absolute, Percent
+	DeadbandValue    float64 `json:"deadband_value,omitempty"`    // Minimum change reported, in units or percent of EURange
+}
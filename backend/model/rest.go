@@ -2,17 +2,24 @@ package model
 
 // RESTMetadata defines the structure for REST platform metadata
 type RESTMetadata struct {
-	BaseEndpoint string   `json:"base_endpoint"`
-	Auth         RESTAuth `json:"auth"`
-	Timeout      int      `json:"timeout,omitempty"`
+	BaseEndpoint string     `json:"base_endpoint"`
+	Auth         RESTAuth   `json:"auth"`
+	Timeout      int        `json:"timeout,omitempty"`
+	TLS          *HTTPTLS   `json:"tls,omitempty"`
+	ProxyURL     string     `json:"proxy_url,omitempty"` // HTTP proxy, the environment's proxy settings when empty
+	Retry        *HTTPRetry `json:"retry,omitempty"`
 }
 
 // RESTAuth defines the authentication details for REST platforms
 type RESTAuth struct {
-	Type        string         `json:"type"` // none, api_key, bearer, basic
-	APIKey      *string        `json:"api_key,omitempty"`
-	BearerToken *string        `json:"bearer_token,omitempty"`
-	BasicAuth   *RESTBasicAuth `json:"basic_auth,omitempty"`
+	Type        string            `json:"type"` // none, api_key, bearer, basic, oauth2
+	APIKey      *string           `json:"api_key,omitempty"`
+	APIKeyIn    string            `json:"api_key_in,omitempty"`   // header (default) or query
+	APIKeyName  string            `json:"api_key_name,omitempty"` // Header or query parameter name, X-API-Key or api_key by default
+	BearerToken *string           `json:"bearer_token,omitempty"`
+	BasicAuth   *RESTBasicAuth    `json:"basic_auth,omitempty"`
+	OAuth2      *RESTOAuth2       `json:"oauth2,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"` // Header templates such as "Token {{api_key}}", replacing the type's own header
 }
 
 // RESTBasicAuth defines the username and password for Basic Auth
@@ -21,6 +28,17 @@ type RESTBasicAuth struct {
 	Password string `json:"password"`
 }
 
+// RESTOAuth2 defines an OAuth2 client credentials grant. Tokens are cached and requested again
+// shortly before they expire.
+type RESTOAuth2 struct {
+	TokenURL     string   `json:"token_url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes,omitempty"`
+	Audience     string   `json:"audience,omitempty"`
+	AuthStyle    string   `json:"auth_style,omitempty"` // header (default) sends the client with Basic auth, body in the form
+}
+
 // RESTResourceDetails defines the structure for REST endpoint details
 type RESTResourceDetails struct {
 	Method      string            `json:"method"` // GET, POST, PUT, DELETE
@@ -28,4 +46,32 @@ type RESTResourceDetails struct {
 	Headers     map[string]string `json:"headers,omitempty"`
 	QueryParams map[string]string `json:"query_params,omitempty"`
 	Body        string            `json:"body,omitempty"` // JSON string for request body template
+	Extract     *RESTExtraction   `json:"extract,omitempty"`
+	Pagination  *RESTPagination   `json:"pagination,omitempty"`
+}
+
+// RESTPagination describes how a paginated endpoint is requested page by page. The items of
+// every page are merged into one body.
+type RESTPagination struct {
+	Type        string `json:"type"`                   // page, offset, cursor or link
+	Items       string `json:"items,omitempty"`        // Path to the page's items; the whole body when empty
+	PageParam   string `json:"page_param,omitempty"`   // page: query parameter of the page number
+	FirstPage   *int   `json:"first_page,omitempty"`   // page: number of the first page
+	OffsetParam string `json:"offset_param,omitempty"` // offset: query parameter of the item offset
+	LimitParam  string `json:"limit_param,omitempty"`  // page, offset: query parameter of the page size
+	PageSize    int    `json:"page_size,omitempty"`    // page, offset: items requested per page
+	CursorPath  string `json:"cursor_path,omitempty"`  // cursor: path to the next page's cursor in the body
+	CursorParam string `json:"cursor_param,omitempty"` // cursor: query parameter the cursor is sent in
+	MaxPages    int    `json:"max_pages,omitempty"`    // Most pages requested
+	MaxItems    int    `json:"max_items,omitempty"`    // Most items kept
+}
+
+// RESTExtraction picks measurements out of a JSON response body with paths such as
+// "data.readings[0].value". When Items is set, each element of that array is one record and the
+// other paths are relative to it.
+type RESTExtraction struct {
+	Items     string            `json:"items,omitempty"`     // Array to iterate
+	Value     string            `json:"value,omitempty"`     // The record's value
+	Timestamp string            `json:"timestamp,omitempty"` // The record's timestamp
+	Fields    map[string]string `json:"fields,omitempty"`    // Further named values, path per name
 }
@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// Sample qualities
+const (
+	QualityGood      = "Good"      // Value read normally
+	QualityUncertain = "Uncertain" // Value read but the source flagged it as unreliable
+	QualityBad       = "Bad"       // The source reported an error instead of a value
+)
+
+// SensorData is one stored sample of a resource read for a device. Exactly one of the value
+// columns is set, unless the sample carries no value. The primary key includes the timestamp
+// so the table can be turned into a TimescaleDB hypertable partitioned on it, and a sample
+// collected twice is only stored once.
+type SensorData struct {
+	DeviceID     uint      `gorm:"primaryKey;autoIncrement:false;index:idx_sensor_data_device_time,priority:1" json:"device_id"`
+	ResourceID   uint      `gorm:"primaryKey;autoIncrement:false" json:"resource_id"`
+	Timestamp    time.Time `gorm:"primaryKey;type:timestamp with time zone;index:idx_sensor_data_device_time,priority:2,sort:desc" json:"timestamp"`
+	NumericValue *float64  `json:"numeric_value,omitempty"`
+	StringValue  *string   `gorm:"type:text" json:"string_value,omitempty"`
+	BoolValue    *bool     `json:"bool_value,omitempty"`
+	Quality      string    `gorm:"size:20;not null;default:Good" json:"quality"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName keeps the table name from the ER diagram; "data" has no plural.
+func (SensorData) TableName() string {
+	return "sensor_data"
+}
+
+// TelemetrySink makes a platform an output for the samples collected from other platforms.
+// It is read from the "sink" member of the platform's metadata.
+type TelemetrySink struct {
+	Enabled       bool              `json:"enabled"`
+	Bucket        string            `json:"bucket,omitempty"`         // Defaults to the platform's bucket or database
+	Measurement   string            `json:"measurement,omitempty"`    // Defaults to "telemetry"
+	Tags          map[string]string `json:"tags,omitempty"`           // Tag key per source: device, site, value_stream, resource, platform or quality
+	BatchSize     int               `json:"batch_size,omitempty"`     // Samples per write, defaults to 500
+	FlushInterval string            `json:"flush_interval,omitempty"` // Longest wait before buffered samples are written, defaults to "5s"
+	BufferSize    int               `json:"buffer_size,omitempty"`    // Samples kept while writes fail, oldest dropped first; defaults to 10000
+}
@@ -24,13 +24,33 @@ func init() {
 		// Device routes
 		web.NSRouter("/devices", &controllers.DeviceController{}, "get:GetAll;post:Post"),
 		web.NSRouter("/devices/:id", &controllers.DeviceController{}, "get:Get;put:Put;delete:Delete"),
+		web.NSRouter("/devices/:id/telemetry", &controllers.DeviceController{}, "get:Telemetry"),
 		web.NSRouter("/devices/:device_id/platforms", &controllers.DevicePlatformController{}, "get:GetAll;post:Post"),
 		web.NSRouter("/devices/:device_id/platforms/:platform_id", &controllers.DevicePlatformController{}, "delete:Delete"),
+		web.NSRouter("/devices/:device_id/platforms/:platform_id/resources/:id/write", &controllers.ResourceController{}, "post:Write"),
 
 		// Platform routes
+		web.NSRouter("/platform-types", &controllers.PlatformController{}, "get:PlatformTypes"),
 		web.NSRouter("/platforms", &controllers.PlatformController{}, "get:GetAll;post:Post"),
+		web.NSRouter("/platforms/test", &controllers.PlatformController{}, "post:TestConnection"),
 		web.NSRouter("/platforms/:id", &controllers.PlatformController{}, "get:Get;put:Put;delete:Delete"),
+		web.NSRouter("/platforms/:platform_id/devices/:device_id/data", &controllers.PlatformController{}, "get:FetchDeviceData"),
 		web.NSRouter("/platforms/:platform_id/resources", &controllers.ResourceController{}, "get:GetAll;post:Post"),
+		web.NSRouter("/platforms/:platform_id/resources/bulk", &controllers.ResourceController{}, "post:BulkPost"),
+		web.NSRouter("/platforms/:platform_id/resources/import-nodes", &controllers.ResourceController{}, "post:ImportNodes"),
+		web.NSRouter("/platforms/:id/browse", &controllers.PlatformController{}, "get:Browse"),
+		web.NSRouter("/platforms/:id/test", &controllers.PlatformController{}, "post:TestSavedConnection"),
+		web.NSRouter("/platforms/:id/health-history", &controllers.PlatformController{}, "get:HealthHistory"),
+
+		// Admin routes
+		web.NSRouter("/admin/driver-pool", &controllers.AdminController{}, "get:DriverPool"),
+		web.NSRouter("/admin/polling", &controllers.AdminController{}, "get:Polling"),
+		web.NSRouter("/admin/sinks", &controllers.AdminController{}, "get:Sinks"),
+
+		// OPC UA trust store routes
+		web.NSRouter("/opcua/trusted-certificates", &controllers.OPCUATrustController{}, "get:GetAll;post:Post"),
+		web.NSRouter("/opcua/trusted-certificates/:thumbprint", &controllers.OPCUATrustController{}, "delete:Delete"),
+
 		// Resource routes
 		web.NSRouter("/resources", &controllers.ResourceController{}, "get:GetAll;post:Post"),
 		web.NSRouter("/resources/:id", &controllers.ResourceController{}, "get:Get;put:Put;delete:Delete"),
@@ -43,6 +63,7 @@ func init() {
 		// Resources Routes
 		web.NSRouter("/resources/:id", &controllers.ResourceController{}, "get:Get;put:Put;delete:Delete"),
 		web.NSRouter("/resources/:id/test", &controllers.ResourceController{}, "post:TestResource"),
+		web.NSRouter("/resources/:id/stream", &controllers.ResourceController{}, "get:Stream"),
 	)
 	apiNs.Filter("before", middleware.ApiAuthFilter)
 	web.AddNamespace(apiNs, authNs)
@@ -0,0 +1,46 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	beego "github.com/beego/beego/v2/server/web"
+	beecontext "github.com/beego/beego/v2/server/web/context"
+)
+
+// route resolves a request the way the router does and returns the controller method it runs.
+func route(t *testing.T, method, path string) string {
+	t.Helper()
+	r := httptest.NewRequest(method, path, nil)
+	ctx := beecontext.NewContext()
+	ctx.Reset(httptest.NewRecorder(), r)
+	info, ok := beego.BeeApp.Handlers.FindRouter(ctx)
+	if !ok {
+		t.Fatalf("No route for %s %s", method, path)
+	}
+	return info.GetMethod()[method]
+}
+
+func TestRoutes(t *testing.T) {
+	for _, tc := range []struct {
+		method, path, handler string
+	}{
+		{http.MethodGet, "/api/devices/7/telemetry", "Telemetry"},
+		{http.MethodGet, "/api/devices/7", "Get"},
+		{http.MethodGet, "/api/platforms/3/devices/7/data", "FetchDeviceData"},
+	} {
+		if got := route(t, tc.method, tc.path); got != tc.handler {
+			t.Errorf("Expected %s %s to run %s, got %q", tc.method, tc.path, tc.handler, got)
+		}
+	}
+}
+
+func TestRoutes_RequireAuthentication(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/devices/7/telemetry?limit=10", nil)
+	w := httptest.NewRecorder()
+	beego.BeeApp.Handlers.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -70,6 +70,17 @@ function ApiKeyForm({ onSubmit, onCancel, error, isLoading }: ApiKeyFormProps) {
               Write
             </span>
           </label>
+          <label className="flex items-center">
+            <input
+              type="checkbox"
+              checked={scopes.includes("control")}
+              onChange={() => handleScopeChange("control")}
+              className="h-4 w-4 rounded border-secondary-300 dark:border-secondary-700 text-primary-600 focus:ring-primary-500"
+            />
+            <span className="ml-2 text-sm text-secondary-600 dark:text-secondary-400">
+              Control (write to devices)
+            </span>
+          </label>
         </div>
       </div>
       <div className="flex justify-end space-x-4">
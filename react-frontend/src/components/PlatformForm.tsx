@@ -1,11 +1,16 @@
 import { useState } from "react";
+import { usePlatformTypes } from "../hooks/useQueries";
 import type {
   Platform,
+  PlatformType,
   RESTMetadata,
   InfluxDBMetadata,
   ApiAuthMethodType,
 } from "../types/platform";
 
+// Types with a dedicated form; other registered types edit their metadata as JSON
+const dedicatedForms = ["REST", "InfluxDB"];
+
 interface PlatformFormProps {
   platform?: Platform;
   onSubmit: (platform: Partial<Platform>) => void;
@@ -22,7 +27,8 @@ function PlatformForm({
   isLoading,
 }: PlatformFormProps) {
   const [name, setName] = useState(platform?.name || "");
-  const [type, setType] = useState(platform?.type || "REST");
+  const { data: platformTypes } = usePlatformTypes();
+  const [type, setType] = useState<string>(platform?.type || "REST");
   const [restMetadata, setRestMetadata] = useState<Partial<RESTMetadata>>(
     platform?.type === "REST"
       ? JSON.parse(platform?.metadata || "{}")
@@ -35,14 +41,21 @@ function PlatformForm({
       ? JSON.parse(platform?.metadata || "{}")
       : { timeout: 10 },
   );
+  const [rawMetadata, setRawMetadata] = useState(
+    platform && !dedicatedForms.includes(platform.type)
+      ? platform.metadata
+      : "{}",
+  );
 
   const handleSubmit = (e: React.FormEvent) => {
     e.preventDefault();
     const metadata =
       type === "REST"
         ? JSON.stringify(restMetadata)
-        : JSON.stringify(influxMetadata);
-    onSubmit({ name, type, metadata });
+        : type === "InfluxDB"
+          ? JSON.stringify(influxMetadata)
+          : rawMetadata;
+    onSubmit({ name, type: type as PlatformType, metadata });
   };
 
   const handleRestAuthChange = (authType: ApiAuthMethodType) => {
@@ -85,13 +98,34 @@ function PlatformForm({
           name="platformType"
           title="Select a platform type"
           value={type}
-          onChange={(e) => setType(e.target.value as "REST" | "InfluxDB")}
+          onChange={(e) => setType(e.target.value)}
           className="block w-full px-4 py-3 rounded-lg border border-secondary-200 dark:border-secondary-700 focus:ring-2 focus:ring-primary-500 focus:border-transparent dark:bg-secondary-800 dark:text-secondary-100"
         >
-          <option value="REST">REST</option>
-          <option value="InfluxDB">InfluxDB</option>
+          {(
+            platformTypes ??
+            dedicatedForms.map((t) => ({ type: t, label: t }))
+          ).map((platformType) => (
+            <option key={platformType.type} value={platformType.type}>
+              {platformType.label}
+            </option>
+          ))}
         </select>
       </div>
+      {!dedicatedForms.includes(type) && (
+        <div>
+          <label className="block text-sm font-medium mb-2 text-secondary-700 dark:text-secondary-300">
+            Metadata (JSON)
+          </label>
+          <textarea
+            title="Platform metadata as JSON"
+            value={rawMetadata}
+            onChange={(e) => setRawMetadata(e.target.value)}
+            rows={8}
+            className="block w-full px-4 py-3 rounded-lg border border-secondary-200 dark:border-secondary-700 focus:ring-2 focus:ring-primary-500 focus:border-transparent dark:bg-secondary-800 dark:text-secondary-100 font-mono text-sm"
+            required
+          />
+        </div>
+      )}
       {type === "REST" && (
         <>
           <div>
@@ -3,7 +3,11 @@ import { Fragment } from "react";
 import PlatformForm from "./PlatformForm";
 import { Prism as SyntaxHighlighter } from "react-syntax-highlighter";
 import { dracula } from "react-syntax-highlighter/dist/esm/styles/prism";
-import type { Platform, TestConnectionInput } from "../types/platform";
+import type {
+  ConnectionDiagnostics,
+  Platform,
+  TestConnectionInput,
+} from "../types/platform";
 
 interface TestConnectionModalProps {
   isOpen: boolean;
@@ -12,7 +16,7 @@ interface TestConnectionModalProps {
   onSubmit: (input: TestConnectionInput) => void;
   error?: string;
   isLoading?: boolean;
-  testResult?: { message: string }; // Add testResult prop for connection test response
+  testResult?: ConnectionDiagnostics; // Staged diagnostics returned by the connection test
 }
 
 function TestConnectionModal({
@@ -26,7 +30,7 @@ function TestConnectionModal({
 }: TestConnectionModalProps) {
   const handleSubmit = (platformData: Partial<Platform>) => {
     onSubmit({
-      type: platformData.type as "REST" | "InfluxDB",
+      type: platformData.type || "",
       metadata: platformData.metadata || "",
     });
   };
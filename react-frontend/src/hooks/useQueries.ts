@@ -3,7 +3,7 @@ import axios from "axios";
 import { useNavigate } from "react-router-dom";
 import { useAuthStore } from "./useAuthStore";
 import type { PaginatedResponse, ApiResponse, User } from "../types/common";
-import type { Device } from "../types/device";
+import type { Device, SensorData, TelemetryParams } from "../types/device";
 import type { Site } from "../types/site";
 import type { ValueStream } from "../types/valueStream";
 import type { ApiKey, GenerateApiKeyRequest } from "../types/apikey";
@@ -11,8 +11,12 @@ import type {
   Platform,
   Resource,
   TestConnectionInput,
+  ConnectionDiagnostics,
+  PlatformStateChange,
   FetchDeviceDataResponse,
+  Point,
   ResourceType,
+  PlatformTypeInfo,
 } from "../types/platform";
 
 interface FetchParams {
@@ -89,6 +93,29 @@ export const useDevice = (id: number) => {
   });
 };
 
+export const useDeviceTelemetry = (id: number, params: TelemetryParams) => {
+  const { logout } = useAuthStore();
+  const navigate = useNavigate();
+
+  return useQuery({
+    queryKey: ["deviceTelemetry", id, params],
+    queryFn: async () => {
+      try {
+        const response = await axios.get<
+          ApiResponse<PaginatedResponse<SensorData>>
+        >(`/api/devices/${id}/telemetry`, {
+          params,
+          headers: getAuthHeaders(),
+        });
+        return response.data.data;
+      } catch (err: any) {
+        throw handleAuthError(err, logout, () => navigate("/login"));
+      }
+    },
+    enabled: !!id,
+  });
+};
+
 export const useCreateDevice = () => {
   const queryClient = useQueryClient();
   const { logout } = useAuthStore();
@@ -557,6 +584,84 @@ export const usePlatform = (id: number) => {
   });
 };
 
+export const usePlatformTypes = () => {
+  const { logout } = useAuthStore();
+  const navigate = useNavigate();
+
+  return useQuery({
+    queryKey: ["platformTypes"],
+    queryFn: async () => {
+      try {
+        const response = await axios.get<ApiResponse<PlatformTypeInfo[]>>(
+          "/api/platform-types",
+          {
+            headers: getAuthHeaders(),
+          },
+        );
+        return response.data.data;
+      } catch (err: any) {
+        throw handleAuthError(err, logout, () => navigate("/login"));
+      }
+    },
+    staleTime: Infinity,
+  });
+};
+
+export const usePlatformHealthHistory = (
+  platformId: number,
+  params: { limit: number; offset: number },
+) => {
+  const { logout } = useAuthStore();
+  const navigate = useNavigate();
+
+  return useQuery({
+    queryKey: ["platformHealthHistory", platformId, params],
+    queryFn: async () => {
+      try {
+        const response = await axios.get<
+          ApiResponse<PaginatedResponse<PlatformStateChange>>
+        >(`/api/platforms/${platformId}/health-history`, {
+          params,
+          headers: getAuthHeaders(),
+        });
+        return response.data.data;
+      } catch (err: any) {
+        throw handleAuthError(err, logout, () => navigate("/login"));
+      }
+    },
+    enabled: !!platformId,
+  });
+};
+
+export const useTestSavedPlatformConnection = () => {
+  const queryClient = useQueryClient();
+  const { logout } = useAuthStore();
+  const navigate = useNavigate();
+
+  return useMutation({
+    mutationFn: async (platformId: number) => {
+      try {
+        const response = await axios.post<ApiResponse<ConnectionDiagnostics>>(
+          `/api/platforms/${platformId}/test`,
+          null,
+          {
+            headers: getAuthHeaders(),
+          },
+        );
+        return response.data.data;
+      } catch (err: any) {
+        throw handleAuthError(err, logout, () => navigate("/login"));
+      }
+    },
+    onSuccess: (_, platformId) => {
+      queryClient.invalidateQueries({ queryKey: ["platforms"] });
+      queryClient.invalidateQueries({
+        queryKey: ["platformHealthHistory", platformId],
+      });
+    },
+  });
+};
+
 export const useCreatePlatform = () => {
   const queryClient = useQueryClient();
   const { logout } = useAuthStore();
@@ -644,7 +749,7 @@ export const useTestPlatformConnection = () => {
   return useMutation({
     mutationFn: async (input: TestConnectionInput) => {
       try {
-        const response = await axios.post<ApiResponse<{ message: string }>>(
+        const response = await axios.post<ApiResponse<ConnectionDiagnostics>>(
           "/api/platforms/test",
           input,
           {
@@ -888,6 +993,8 @@ export const useTestResource = () => {
             platform_id: number;
             platform_type: string;
             result: any;
+            points?: Point[];
+            points_error?: string;
           }>
         >(`/api/resources/${id}/test`, {}, { headers: getAuthHeaders() });
         return response.data.data;
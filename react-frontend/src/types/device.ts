@@ -8,4 +8,27 @@ export interface Device {
   site?: Site
   valueStreamId?: number
   valueStream?: ValueStream
-}
\ No newline at end of file
+}
+
+export type SampleQuality = "Good" | "Uncertain" | "Bad"
+
+// One stored sample; at most one of the value fields is set
+export interface SensorData {
+  device_id: number
+  resource_id: number
+  timestamp: string
+  numeric_value?: number
+  string_value?: string
+  bool_value?: boolean
+  quality: SampleQuality
+  created_at: string
+}
+
+export interface TelemetryParams {
+  start?: string
+  end?: string
+  resource_id?: string
+  sort?: "timestamp" | "-timestamp"
+  limit: number
+  offset: number
+}
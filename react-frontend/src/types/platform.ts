@@ -3,6 +3,7 @@ export const ApiAuthMethod = {
   API_KEY: "api_key",
   BEARER: "bearer",
   BASIC: "basic",
+  OAUTH2: "oauth2",
 };
 
 export type ApiAuthMethodType = keyof typeof ApiAuthMethod;
@@ -18,28 +19,125 @@ export type PlatformType = keyof typeof PlatformTypes;
 export const ResourceTypes = {
   REST: "rest_endpoint",
   InfluxDB: "influxdb_query",
+  InfluxDBFlux: "influxdb_flux",
 } as const;
 
 export type ResourceType = keyof typeof ResourceTypes;
 
+// Platform types registered by the backend drivers, served from /api/platform-types
+export interface PlatformTypeInfo {
+  type: string;
+  label: string;
+  metadata_schema?: Record<string, unknown>;
+  resource_types: ResourceTypeInfo[];
+}
+
+export interface ResourceTypeInfo {
+  type: string;
+  label: string;
+  details_schema?: Record<string, unknown>;
+  points: PointMapping;
+  writable: boolean; // Resources of this type can be marked writable
+}
+
+// JSON paths locating points in a fetch result; resources override them with "points" in their metadata
+export interface PointMapping {
+  items?: string;
+  value?: string;
+  timestamp?: string;
+  quality?: string;
+  unit_path?: string;
+  unit?: string;
+}
+
+// FieldError is returned alongside "error" when metadata or details fail schema validation.
+export interface FieldError {
+  path: string;
+  message: string;
+}
+
+export type ConnectionState =
+  | "Connected"
+  | "Degraded"
+  | "Disconnected"
+  | "AuthFailed";
+
+export type CircuitState = "Closed" | "Open" | "HalfOpen";
+
 export interface Platform {
   id: number;
   name: string;
   type: PlatformType
   metadata: string; // JSON string, parsed into RESTMetadata or InfluxDBMetadata
+  connection_state?: ConnectionState;
+  last_connected?: string | null;
+  last_error?: string;
+  circuit_state?: CircuitState; // Only set when the metadata configures a circuit breaker
+}
+
+export interface PlatformStateChange {
+  id: number;
+  platform_id: number;
+  from_state: ConnectionState;
+  to_state: ConnectionState;
+  error: string;
+  latency_ms: number;
+  changed_at: string;
 }
 
 export interface RESTMetadata {
   baseEndpoint: string;
   timeout: number;
   auth: RESTAuth;
+  tls?: HTTPTLS;
+  proxyUrl?: string;
+  retry?: HTTPRetry;
+  circuitBreaker?: CircuitBreaker;
+}
+
+// TLS settings for platforms reached over HTTPS; the certificates and key are PEM encoded
+export interface HTTPTLS {
+  caCert?: string;
+  certificate?: string;
+  privateKey?: string;
+  serverName?: string;
+  insecureSkipVerify?: boolean;
+}
+
+// Retries of idempotent requests; backoffs are durations such as "200ms"
+export interface HTTPRetry {
+  maxRetries: number;
+  initialBackoff?: string;
+  maxBackoff?: string;
+  retryableStatus?: number[];
+}
+
+// Accepted in the metadata of every platform type
+export interface CircuitBreaker {
+  failureThreshold: number;
+  coolDown?: string;
+  halfOpenRequests?: number;
 }
 
 export interface RESTAuth {
   type: ApiAuthMethodType;
   apiKey?: string;
+  apiKeyIn?: "header" | "query";
+  apiKeyName?: string;
   bearerToken?: string;
   basicAuth?: BasicAuth;
+  oauth2?: OAuth2ClientCredentials;
+  // Templates replacing the type's own header, e.g. "Token {{api_key}}"
+  headers?: Record<string, string>;
+}
+
+export interface OAuth2ClientCredentials {
+  tokenUrl: string;
+  clientId: string;
+  clientSecret: string;
+  scopes?: string[];
+  audience?: string;
+  authStyle?: "header" | "body";
 }
 
 export interface BasicAuth {
@@ -49,10 +147,31 @@ export interface BasicAuth {
 
 export interface InfluxDBMetadata {
   url: string;
+  version?: "1" | "2"; // Defaults to 2; version 1 uses the InfluxDB 1.x settings below
   token: string;
   org: string;
   bucket: string;
+  username?: string; // InfluxDB 1.x
+  password?: string;
+  database?: string;
+  retentionPolicy?: string;
   timeout: number;
+  tls?: HTTPTLS;
+  proxyUrl?: string;
+  retry?: HTTPRetry;
+  circuitBreaker?: CircuitBreaker;
+  sink?: TelemetrySink;
+}
+
+// Forwards the samples polled from other platforms to an InfluxDB platform
+export interface TelemetrySink {
+  enabled: boolean;
+  bucket?: string; // Defaults to the platform's bucket or database
+  measurement?: string; // Defaults to "telemetry"
+  tags?: Partial<Record<"device" | "site" | "value_stream" | "resource" | "platform" | "quality", string>>;
+  batch_size?: number;
+  flush_interval?: string; // e.g. "5s"
+  buffer_size?: number;
 }
 
 export interface Resource {
@@ -61,6 +180,7 @@ export interface Resource {
   name: string;
   type: ResourceType;
   details: string; // JSON string, parsed into RESTResourceDetails or InfluxDBResourceDetails
+  writable?: boolean; // Allows writes through the write endpoint
 }
 
 export interface RESTResourceDetails {
@@ -69,13 +189,52 @@ export interface RESTResourceDetails {
   headers?: Record<string, string>;
   queryParams?: Record<string, string>;
   body?: string;
+  extract?: RESTExtraction;
+  pagination?: RESTPagination;
+}
+
+// JSON paths picking measurements out of a REST response body
+export interface RESTExtraction {
+  items?: string;
+  value?: string;
+  timestamp?: string;
+  fields?: Record<string, string>;
+}
+
+// How a REST resource follows further pages; their items are merged into one array body
+export interface RESTPagination {
+  type: 'page' | 'offset' | 'cursor' | 'link';
+  items?: string;
+  page_param?: string;
+  first_page?: number;
+  offset_param?: string;
+  limit_param?: string;
+  page_size?: number;
+  cursor_path?: string;
+  cursor_param?: string;
+  max_pages?: number;
+  max_items?: number;
 }
 
 export interface InfluxDBResourceDetails {
   bucket: string;
   measurement: string;
   field: string;
+  fields?: string[]; // Several fields, replacing field
+  tags?: Record<string, string>;
   timeRange: string;
+  start?: string; // RFC 3339 time or negative duration such as "-7d"
+  stop?: string; // Defaults to now
+  every?: string; // Aggregate window such as "1m"
+  fn?: "mean" | "min" | "max" | "sum" | "count" | "first" | "last" | "median";
+  fill?: "none" | "null" | "previous";
+}
+
+// Flux script reading params.device_alias, params.start, params.stop and params.every
+export interface InfluxDBFluxDetails {
+  flux: string;
+  time_range?: string; // Defaults to -1h
+  every?: string; // Defaults to 1m
 }
 
 export interface DevicePlatform {
@@ -85,13 +244,75 @@ export interface DevicePlatform {
 }
 
 export interface TestConnectionInput {
-  type: "REST" | "InfluxDB";
+  type: string;
   metadata: string;
 }
 
+export interface ConnectionStageResult {
+  stage: "dns" | "tcp" | "tls" | "auth";
+  success: boolean;
+  latency_ms: number;
+  detail?: string;
+  error?: string;
+}
+
+export interface ConnectionDiagnostics {
+  success: boolean;
+  failed_stage?: ConnectionStageResult["stage"];
+  error?: string;
+  latency_ms: number;
+  stages: ConnectionStageResult[];
+  server?: { product?: string; version?: string; vendor?: string };
+  certificate?: {
+    subject: string;
+    issuer: string;
+    dns_names?: string[];
+    not_before: string;
+    not_after: string;
+  };
+  tested_at: string;
+}
+
+export interface Point {
+  timestamp: string;
+  value: unknown;
+  unit?: string;
+  quality: "Good" | "Uncertain" | "Bad";
+  resource_id: number;
+  resource: string;
+  raw?: unknown; // Only with include_raw=true
+}
+
 export interface FetchDeviceDataResponse {
   device_id: number;
   platform_id: number;
   alias: string;
-  data: Record<string, any>;
+  points: Point[];
+  errors: Record<string, string>; // Keyed by resource name
+  resources: Record<string, ResourceFetch>; // Keyed by resource name
+}
+
+export interface ResourceFetch {
+  status: "ok" | "error" | "timeout" | "cancelled";
+  latency_ms: number;
+  points: number;
+  error?: string;
+}
+
+// Body of POST /api/devices/:device_id/platforms/:platform_id/resources/:id/write
+export interface WriteRequest {
+  value?: unknown; // Converted to the resource's data type; a list for resources holding several values
+  body?: string; // Request body replacing the resource's own, for REST actions
+}
+
+export interface WriteResult {
+  old_value: unknown; // null when it cannot be read without side effects
+  new_value: unknown;
+  response?: unknown;
+}
+
+export interface WriteResponse {
+  resource_id: number;
+  audit_id: number; // User interaction recording the write
+  result: WriteResult;
 }